@@ -3,14 +3,18 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/circuitbreaker"
 	"github.com/authelia/authelia/internal/commands"
 	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/federation"
 	"github.com/authelia/authelia/internal/logging"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/notification"
@@ -71,16 +75,8 @@ func startServer() {
 		logger.Info("===> Authelia is running in development mode. <===")
 	}
 
-	var storageProvider storage.Provider
-
-	switch {
-	case config.Storage.PostgreSQL != nil:
-		storageProvider = storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL)
-	case config.Storage.MySQL != nil:
-		storageProvider = storage.NewMySQLProvider(*config.Storage.MySQL)
-	case config.Storage.Local != nil:
-		storageProvider = storage.NewSQLiteProvider(config.Storage.Local.Path)
-	default:
+	storageProvider := storage.NewProvider(config.Storage)
+	if storageProvider == nil {
 		logger.Fatalf("Unrecognized storage backend")
 	}
 
@@ -106,27 +102,72 @@ func startServer() {
 		logger.Fatalf("Unrecognized notifier")
 	}
 
-	if !config.Notifier.DisableStartupCheck {
-		_, err := notifier.StartupCheck()
-		if err != nil {
-			logger.Fatalf("Error during notifier startup check: %s", err)
-		}
-	}
-
 	clock := utils.RealClock{}
-	authorizer := authorization.NewAuthorizer(config.AccessControl)
+
+	if config.Notifier.CircuitBreaker != nil {
+		notifier = notification.NewCircuitBreakerNotifier(notifier,
+			circuitbreaker.NewCircuitBreaker("notifier", config.Notifier.CircuitBreaker, clock))
+	}
+	authorizer := authorization.NewAuthorizer(config.AccessControl, autheliaCertPool)
 	sessionProvider := session.NewProvider(config.Session, autheliaCertPool)
 	regulator := regulation.NewRegulator(config.Regulation, storageProvider, clock)
+	lastLoginRecorder := storage.NewLastLoginRecorder(storageProvider, clock)
+
+	var securityDigest *notification.SecurityDigest
+
+	if config.SecurityDigest != nil {
+		// The digest is sent with the notifier itself so that its own failures are not recorded in the next digest.
+		securityDigest = notification.NewSecurityDigest(*config.SecurityDigest, notifier, autheliaCertPool, clock)
+		notifier = securityDigest.Notifier(notifier)
+
+		regulator.SetObserver(securityDigest)
+	}
 
 	providers := middlewares.Providers{
-		Authorizer:      authorizer,
-		UserProvider:    userProvider,
-		Regulator:       regulator,
-		StorageProvider: storageProvider,
-		Notifier:        notifier,
-		SessionProvider: sessionProvider,
+		Authorizer:        authorizer,
+		UserProvider:      userProvider,
+		Regulator:         regulator,
+		StorageProvider:   storageProvider,
+		Notifier:          notifier,
+		SessionProvider:   sessionProvider,
+		LastLoginRecorder: lastLoginRecorder,
+	}
+
+	if config.OIDCUpstream != nil {
+		providers.OIDCUpstream = federation.NewOIDCUpstreamProvider(*config.OIDCUpstream, autheliaCertPool)
 	}
+
+	if config.TrustedHeader != nil {
+		providers.TrustedHeader = federation.NewTrustedHeaderProvider(*config.TrustedHeader, autheliaCertPool)
+	}
+
+	if failures := server.DoStartupChecks(*config, providers); len(failures) != 0 {
+		if config.Server.StartupChecks == schema.StartupChecksFail {
+			logger.Fatalf("Startup checks failed for: %s", strings.Join(failures, ", "))
+		}
+
+		logger.Warnf("Startup checks failed for: %s, continuing as startup_checks is set to %s",
+			strings.Join(failures, ", "), config.Server.StartupChecks)
+	}
+
+	pruner, err := storage.NewPruner(storageProvider, config.Storage.Pruning, clock)
+	if err != nil {
+		logger.Fatalf("Unable to configure the storage pruning: %s", err)
+	}
+
+	pruner.Start()
+	lastLoginRecorder.Start()
+
+	if securityDigest != nil {
+		securityDigest.Start()
+	}
+
 	server.StartServer(*config, providers)
+
+	// The server no longer accepts requests once it returns, save the last logins recorded since the last background write.
+	if err := lastLoginRecorder.Stop(); err != nil {
+		logger.Errorf("Unable to save the last logins: %s", err)
+	}
 }
 
 func main() {
@@ -149,7 +190,7 @@ func main() {
 	}
 
 	rootCmd.AddCommand(versionCmd, commands.HashPasswordCmd,
-		commands.ValidateConfigCmd, commands.CertificatesCmd)
+		commands.ValidateConfigCmd, commands.CertificatesCmd, commands.StorageCmd, commands.RegulationCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		logger.Fatal(err)
@@ -4,7 +4,11 @@ AUTHELIA_CONFIG=$(pgrep -af authelia | awk '{print $NF}')
 AUTHELIA_SCHEME=$(grep ^tls "${AUTHELIA_CONFIG}")
 AUTHELIA_HOST=$(grep ^host "${AUTHELIA_CONFIG}" | sed -e 's/host: //' -e 's/\r//')
 AUTHELIA_PORT=$(grep ^port "${AUTHELIA_CONFIG}" | sed -e 's/port: //' -e 's/\r//')
-AUTHELIA_PATH=$(grep ^\ \ path "${AUTHELIA_CONFIG}" | sed -e 's/  path: //' -e 's/\r//' -e 's/^/\//')
+AUTHELIA_PATH=$(grep ^\ \ base_path "${AUTHELIA_CONFIG}" | sed -e 's/  base_path: //' -e 's/["\r]//g')
+
+if [ -z "${AUTHELIA_PATH}" ]; then
+  AUTHELIA_PATH=$(grep ^\ \ path "${AUTHELIA_CONFIG}" | sed -e 's/  path: //' -e 's/\r//' -e 's/^/\//')
+fi
 
 if [ -z "${AUTHELIA_SCHEME}" ]; then
   AUTHELIA_SCHEME=http
@@ -51,9 +51,23 @@ const (
 // HashingPossibleSaltCharacters represents valid hashing runes.
 var HashingPossibleSaltCharacters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+/")
 
+// timingAttackPassword is the password hashed to generate the dummy hash checked for unknown users.
+const timingAttackPassword = "authelia-timing-attack-mitigation"
+
 // ErrUserNotFound indicates the user wasn't found in the authentication backend.
 var ErrUserNotFound = errors.New("user not found")
 
+// ErrUserAlreadyExists indicates a user with the same username or email address exists in the authentication backend.
+var ErrUserAlreadyExists = errors.New("user already exists")
+
+// ErrUserCreationNotSupported indicates the authentication backend does not allow Authelia to create users.
+var ErrUserCreationNotSupported = errors.New("user creation not supported")
+
+const (
+	schemeLDAP  = "ldap"
+	schemeLDAPS = "ldaps"
+)
+
 const argon2id = "argon2id"
 const sha512 = "sha512"
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -17,9 +18,13 @@ import (
 
 // FileUserProvider is a provider reading details from a file.
 type FileUserProvider struct {
-	configuration *schema.FileAuthenticationBackendConfiguration
-	database      *DatabaseModel
-	lock          *sync.Mutex
+	configuration    *schema.FileAuthenticationBackendConfiguration
+	database         *DatabaseModel
+	lock             *sync.Mutex
+	timingAttackHash string
+
+	// checkPassword checks a password against a hash, it is CheckPassword outside of the tests.
+	checkPassword func(password, hash string) (ok bool, err error)
 }
 
 // UserDetailsModel is the model of user details in the file database.
@@ -60,13 +65,66 @@ func NewFileUserProvider(configuration *schema.FileAuthenticationBackendConfigur
 		panic(err)
 	}
 
+	if duplicates := checkDuplicates(database); len(duplicates) != 0 {
+		if !configuration.WarnOnDuplicates {
+			panic(fmt.Errorf("The users database contains duplicates: %s", strings.Join(duplicates, "; ")))
+		}
+
+		for _, duplicate := range duplicates {
+			logger.Warnf("The users database contains duplicates: %s", duplicate)
+		}
+	}
+
+	passwordConfiguration := configuration.Password
+	if passwordConfiguration == nil {
+		passwordConfiguration = &schema.DefaultPasswordConfiguration
+	}
+
+	timingAttackHash, err := newTimingAttackHash(database, passwordConfiguration)
+	if err != nil {
+		panic(fmt.Errorf("Unable to generate the hash used to prevent timing attacks: %s", err))
+	}
+
 	return &FileUserProvider{
-		configuration: configuration,
-		database:      database,
-		lock:          &sync.Mutex{},
+		configuration:    configuration,
+		database:         database,
+		lock:             &sync.Mutex{},
+		timingAttackHash: timingAttackHash,
+		checkPassword:    CheckPassword,
 	}
 }
 
+// newTimingAttackHash generates the hash the password of unknown users is checked against. It uses the parameters of
+// the most expensive hash to check amongst the hashes of the database and the configured settings, the latter being
+// used for the passwords set afterwards, so that an unknown user can't be told apart from a user by being faster.
+// Checking the password of a user whose hash is cheaper than this one remains faster than checking the password of an
+// unknown user though, the users can only be entirely hidden by hashing all their passwords with the same parameters.
+func newTimingAttackHash(database *DatabaseModel, configuration *schema.PasswordConfiguration) (hash string, err error) {
+	algorithm, err := ConfigAlgoToCryptoAlgo(configuration.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	strongest := PasswordHash{
+		Algorithm:   algorithm,
+		Iterations:  configuration.Iterations,
+		KeyLength:   configuration.KeyLength,
+		Memory:      configuration.Memory * 1024,
+		Parallelism: configuration.Parallelism,
+	}
+
+	for _, details := range database.Users {
+		// The hashes are checked beforehand by checkPasswordHashes.
+		h, err := ParseHash(strings.ReplaceAll(details.HashedPassword, "{CRYPT}", ""))
+		if err == nil && h.cost() > strongest.cost() {
+			strongest = *h
+		}
+	}
+
+	return HashPassword(timingAttackPassword, "", strongest.Algorithm, strongest.Iterations, strongest.Memory,
+		strongest.Parallelism, strongest.KeyLength, configuration.SaltLength)
+}
+
 func checkPasswordHashes(database *DatabaseModel) error {
 	for u, v := range database.Users {
 		v.HashedPassword = strings.ReplaceAll(v.HashedPassword, "{CRYPT}", "")
@@ -82,6 +140,42 @@ func checkPasswordHashes(database *DatabaseModel) error {
 	return nil
 }
 
+// checkDuplicates returns the conflicts between users whose usernames only differ by their case or who share an email
+// address, since the email address is used to recover the account. Emails are compared case-insensitively.
+func checkDuplicates(database *DatabaseModel) []string {
+	usernames := make(map[string][]string)
+	emails := make(map[string][]string)
+
+	for username, details := range database.Users {
+		key := strings.ToLower(username)
+		usernames[key] = append(usernames[key], username)
+
+		if email := strings.ToLower(strings.TrimSpace(details.Email)); email != "" {
+			emails[email] = append(emails[email], username)
+		}
+	}
+
+	var duplicates []string
+
+	for _, users := range usernames {
+		if len(users) > 1 {
+			sort.Strings(users)
+			duplicates = append(duplicates, fmt.Sprintf("usernames %s only differ by their case", strings.Join(users, ", ")))
+		}
+	}
+
+	for email, users := range emails {
+		if len(users) > 1 {
+			sort.Strings(users)
+			duplicates = append(duplicates, fmt.Sprintf("users %s share the email address %s", strings.Join(users, ", "), email))
+		}
+	}
+
+	sort.Strings(duplicates)
+
+	return duplicates
+}
+
 func checkDatabase(path string) []error {
 	_, err := os.Stat(path)
 	if err != nil {
@@ -140,10 +234,19 @@ func readDatabase(path string) (*DatabaseModel, error) {
 	return &db, nil
 }
 
+// StartupCheck checks the users database file is still readable and valid.
+func (p *FileUserProvider) StartupCheck() (bool, error) {
+	if _, err := readDatabase(p.configuration.Path); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // CheckUserPassword checks if provided password matches for the given user.
 func (p *FileUserProvider) CheckUserPassword(username string, password string) (bool, error) {
 	if details, ok := p.database.Users[username]; ok {
-		ok, err := CheckPassword(password, details.HashedPassword)
+		ok, err := p.checkPassword(password, details.HashedPassword)
 		if err != nil {
 			return false, err
 		}
@@ -151,6 +254,9 @@ func (p *FileUserProvider) CheckUserPassword(username string, password string) (
 		return ok, nil
 	}
 
+	// Check the password against a dummy hash so that the response time does not disclose whether the user exists.
+	_, _ = p.checkPassword(password, p.timingAttackHash)
+
 	return false, ErrUserNotFound
 }
 
@@ -175,16 +281,7 @@ func (p *FileUserProvider) UpdatePassword(username string, newPassword string) e
 		return ErrUserNotFound
 	}
 
-	algorithm, err := ConfigAlgoToCryptoAlgo(p.configuration.Password.Algorithm)
-	if err != nil {
-		return err
-	}
-
-	hash, err := HashPassword(
-		newPassword, "", algorithm, p.configuration.Password.Iterations,
-		p.configuration.Password.Memory*1024, p.configuration.Password.Parallelism,
-		p.configuration.Password.KeyLength, p.configuration.Password.SaltLength)
-
+	hash, err := HashPasswordWithConfiguration(newPassword, p.configuration.Password)
 	if err != nil {
 		return err
 	}
@@ -205,3 +302,41 @@ func (p *FileUserProvider) UpdatePassword(username string, newPassword string) e
 
 	return err
 }
+
+// AddUser adds a user with an already hashed password to the users database. The user is refused if another one has
+// the same username, ignoring the case, or the same email address.
+func (p *FileUserProvider) AddUser(details UserDetails, hashedPassword string) error {
+	email := ""
+	if len(details.Emails) != 0 {
+		email = details.Emails[0]
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for username, existing := range p.database.Users {
+		if strings.EqualFold(username, details.Username) || (email != "" && strings.EqualFold(existing.Email, email)) {
+			return ErrUserAlreadyExists
+		}
+	}
+
+	p.database.Users[details.Username] = UserDetailsModel{
+		HashedPassword: hashedPassword,
+		DisplayName:    details.DisplayName,
+		Email:          email,
+		Groups:         details.Groups,
+	}
+
+	b, err := yaml.Marshal(p.database)
+	if err != nil {
+		delete(p.database.Users, details.Username)
+		return err
+	}
+
+	if err = ioutil.WriteFile(p.configuration.Path, b, fileAuthenticationMode); err != nil {
+		delete(p.database.Users, details.Username)
+		return err
+	}
+
+	return nil
+}
@@ -96,6 +96,24 @@ func TestShouldCheckUserPasswordIsWrong(t *testing.T) {
 	})
 }
 
+func TestShouldPassStartupCheckWhenDatabaseIsReadable(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		ok, err := provider.StartupCheck()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		require.NoError(t, os.Remove(path))
+
+		ok, err = provider.StartupCheck()
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
+
 func TestShouldCheckUserPasswordIsWrongForEnumerationCompare(t *testing.T) {
 	WithDatabase(UserDatabaseContent, func(path string) {
 		config := DefaultFileAuthenticationBackendConfiguration
@@ -120,6 +138,53 @@ func TestShouldCheckUserPasswordOfUserThatDoesNotExist(t *testing.T) {
 	})
 }
 
+func TestShouldCheckPasswordOfUserThatDoesNotExistAgainstTimingAttackHash(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		var checked []string
+
+		provider.checkPassword = func(password, hash string) (bool, error) {
+			checked = append(checked, hash)
+			return false, nil
+		}
+
+		ok, err := provider.CheckUserPassword("fake", "password")
+		assert.Equal(t, ErrUserNotFound, err)
+		assert.False(t, ok)
+
+		// The unknown user doesn't short-circuit the hash verification, it is checked against the dummy hash.
+		assert.Equal(t, []string{provider.timingAttackHash}, checked)
+
+		// The dummy hash has the parameters of the most expensive hash of the database, the SHA512 hash of harry.
+		hash, err := ParseHash(provider.timingAttackHash)
+		require.NoError(t, err)
+		assert.Equal(t, HashingAlgorithmSHA512, hash.Algorithm)
+		assert.Equal(t, 500000, hash.Iterations)
+	})
+}
+
+func TestShouldGenerateTimingAttackHashWithConfiguredParametersWhenStronger(t *testing.T) {
+	database := &DatabaseModel{Users: map[string]UserDetailsModel{
+		"john": {HashedPassword: "{CRYPT}$argon2id$v=19$m=65536,t=3,p=2$BpLnfgDsc2WD8F2q$o/vzA4myCqZZ36bUGsDY//8mKUYNZZaR0t4MFFSs+iM"},
+	}}
+
+	configuration := schema.DefaultCIPasswordConfiguration
+	configuration.Iterations = 4
+
+	timingAttackHash, err := newTimingAttackHash(database, &configuration)
+	require.NoError(t, err)
+
+	hash, err := ParseHash(timingAttackHash)
+	require.NoError(t, err)
+	assert.Equal(t, HashingAlgorithmArgon2id, hash.Algorithm)
+	assert.Equal(t, 4, hash.Iterations)
+	assert.Equal(t, 64*1024, hash.Memory)
+	assert.Equal(t, 8, hash.Parallelism)
+}
+
 func TestShouldRetrieveUserDetails(t *testing.T) {
 	WithDatabase(UserDatabaseContent, func(path string) {
 		config := DefaultFileAuthenticationBackendConfiguration
@@ -149,6 +214,54 @@ func TestShouldUpdatePassword(t *testing.T) {
 	})
 }
 
+func TestShouldAddUser(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		hash, err := HashPasswordWithConfiguration("newpassword", config.Password)
+		require.NoError(t, err)
+
+		err = provider.AddUser(UserDetails{
+			Username:    "luna",
+			DisplayName: "Luna Lovegood",
+			Emails:      []string{"luna@example.com"},
+			Groups:      []string{"partners"},
+		}, hash)
+		assert.NoError(t, err)
+
+		// Reset the provider to force a read from disk.
+		provider = NewFileUserProvider(&config)
+		ok, err := provider.CheckUserPassword("luna", "newpassword")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		details, err := provider.GetDetails("luna")
+		require.NoError(t, err)
+		assert.Equal(t, "Luna Lovegood", details.DisplayName)
+		assert.Equal(t, []string{"luna@example.com"}, details.Emails)
+		assert.Equal(t, []string{"partners"}, details.Groups)
+	})
+}
+
+func TestShouldNotAddExistingUser(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		err := provider.AddUser(UserDetails{Username: "John", DisplayName: "John", Emails: []string{"john@example.com"}}, "hash")
+		assert.ErrorIs(t, err, ErrUserAlreadyExists)
+
+		err = provider.AddUser(UserDetails{Username: "johnny", DisplayName: "Johnny", Emails: []string{"John.Doe@authelia.com"}}, "hash")
+		assert.ErrorIs(t, err, ErrUserAlreadyExists)
+
+		_, err = provider.GetDetails("johnny")
+		assert.Error(t, err)
+	})
+}
+
 // Checks both that the hashing algo changes and that it removes {CRYPT} from the start.
 func TestShouldUpdatePasswordHashingAlgorithmToArgon2id(t *testing.T) {
 	WithDatabase(UserDatabaseContent, func(path string) {
@@ -249,6 +362,30 @@ func TestShouldRaiseWhenLoadingDatabaseWithBadArgon2idHashSaltForTheFirstTime(t
 	})
 }
 
+func TestShouldRaiseWhenLoadingDatabaseWithDuplicates(t *testing.T) {
+	WithDatabase(DuplicatesUserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		assert.PanicsWithError(t, "The users database contains duplicates: usernames John, john only differ by their case; users harry, john share the email address john.doe@authelia.com", func() {
+			NewFileUserProvider(&config)
+		})
+	})
+}
+
+func TestShouldLoadDatabaseWithDuplicatesWhenWarningOnDuplicates(t *testing.T) {
+	WithDatabase(DuplicatesUserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		config.WarnOnDuplicates = true
+
+		provider := NewFileUserProvider(&config)
+
+		details, err := provider.GetDetails("harry")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"John.Doe@authelia.com"}, details.Emails)
+	})
+}
+
 func TestShouldSupportHashPasswordWithoutCRYPT(t *testing.T) {
 	WithDatabase(UserDatabaseWithoutCryptContent, func(path string) {
 		config := DefaultFileAuthenticationBackendConfiguration
@@ -307,7 +444,7 @@ users:
   enumeration:
     displayname: "Enumeration"
     password: "$argon2id$v=19$m=131072,p=8$BpLnfgDsc2WD8F2q$O126GHPeZ5fwj7OLSs7PndXsTbje76R+QW9/EGfhkJg"
-    email: james.dean@authelia.com
+    email: enumeration@authelia.com
 `)
 
 var MalformedUserDatabaseContent = []byte(`
@@ -346,6 +483,25 @@ users:
     email: james.dean@authelia.com
 `)
 
+var DuplicatesUserDatabaseContent = []byte(`
+users:
+  john:
+    displayname: "John Doe"
+    password: "$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+    email: john.doe@authelia.com
+  John:
+    displayname: "John Doe"
+    password: "$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+    email: another.john@authelia.com
+  harry:
+    displayname: "Harry Potter"
+    password: "$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+    email: John.Doe@authelia.com
+  bob:
+    displayname: "Bob Dylan"
+    password: "$6$rounds=500000$jgiCMRyGXzoqpxS3$w2pJeZnnH8bwW3zzvoMWtTRfQYsHbWbD/hquuQ5vUeIyl9gdwBIt6RWk2S6afBA0DPakbeWgD/4SZPiS0hYtU/"
+`)
+
 var BadSHA512HashContent = []byte(`
 users:
   john:
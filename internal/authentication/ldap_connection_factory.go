@@ -2,8 +2,13 @@ package authentication
 
 import (
 	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
 
 	"github.com/go-ldap/ldap/v3"
+
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // ********************* CONNECTION *********************.
@@ -77,3 +82,65 @@ func (lcf *LDAPConnectionFactoryImpl) DialURL(addr string, opts ldap.DialOpt) (L
 
 	return NewLDAPConnectionImpl(conn), nil
 }
+
+// LDAPProxyConnectionFactoryImpl the production implementation of an ldap connection factory tunneling connections
+// through a proxy.
+type LDAPProxyConnectionFactoryImpl struct {
+	dialer    utils.Dialer
+	tlsConfig *tls.Config
+}
+
+// NewLDAPProxyConnectionFactoryImpl create a concrete ldap connection factory dialing through the given proxy dialer.
+func NewLDAPProxyConnectionFactoryImpl(dialer utils.Dialer, tlsConfig *tls.Config) *LDAPProxyConnectionFactoryImpl {
+	return &LDAPProxyConnectionFactoryImpl{
+		dialer:    dialer,
+		tlsConfig: tlsConfig,
+	}
+}
+
+// DialURL creates a connection through the proxy from an LDAP URL when successful. The dial options are ignored since
+// the connection is established by the proxy dialer, ldaps connections use the TLS configuration of the factory.
+func (lcf *LDAPProxyConnectionFactoryImpl) DialURL(addr string, _ ldap.DialOpt) (LDAPConnection, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var port string
+
+	switch u.Scheme {
+	case schemeLDAP:
+		port = ldap.DefaultLdapPort
+	case schemeLDAPS:
+		port = ldap.DefaultLdapsPort
+	default:
+		return nil, fmt.Errorf("unknown scheme '%s'", u.Scheme)
+	}
+
+	if u.Port() != "" {
+		port = u.Port()
+	}
+
+	c, err := lcf.dialer.Dial("tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, ldap.NewError(ldap.ErrorNetwork, err)
+	}
+
+	isTLS := u.Scheme == schemeLDAPS
+
+	if isTLS {
+		tc := tls.Client(c, lcf.tlsConfig)
+
+		if err = tc.Handshake(); err != nil {
+			c.Close()
+			return nil, ldap.NewError(ldap.ErrorNetwork, err)
+		}
+
+		c = tc
+	}
+
+	conn := ldap.NewConn(c, isTLS)
+	conn.Start()
+
+	return NewLDAPConnectionImpl(conn), nil
+}
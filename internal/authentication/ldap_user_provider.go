@@ -4,11 +4,13 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
 	"golang.org/x/text/encoding/unicode"
 
+	"github.com/authelia/authelia/internal/circuitbreaker"
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
 	"github.com/authelia/authelia/internal/utils"
@@ -22,6 +24,12 @@ type LDAPUserProvider struct {
 	connectionFactory LDAPConnectionFactory
 	usersDN           string
 	groupsDN          string
+
+	// The circuit breaker protecting the connections to the LDAP server, nil if disabled.
+	breaker *circuitbreaker.CircuitBreaker
+
+	// The cache of the details of the users, nil if disabled.
+	cache *userDetailsCache
 }
 
 // NewLDAPUserProvider creates a new instance of LDAPUserProvider.
@@ -38,11 +46,19 @@ func NewLDAPUserProvider(configuration schema.LDAPAuthenticationBackendConfigura
 		dialOpts = ldap.DialWithTLSConfig(tlsConfig)
 	}
 
+	var connectionFactory LDAPConnectionFactory = NewLDAPConnectionFactoryImpl()
+
+	if configuration.Proxy != "" {
+		connectionFactory = newLDAPProxyConnectionFactory(configuration.Proxy, tlsConfig, connectionFactory)
+	}
+
 	provider := &LDAPUserProvider{
 		configuration:     configuration,
 		tlsConfig:         tlsConfig,
 		dialOpts:          dialOpts,
-		connectionFactory: NewLDAPConnectionFactoryImpl(),
+		connectionFactory: connectionFactory,
+		breaker:           circuitbreaker.NewCircuitBreaker("LDAP", configuration.CircuitBreaker, utils.RealClock{}),
+		cache:             newUserDetailsCache(configuration.Cache, utils.RealClock{}),
 	}
 
 	provider.parseDynamicConfiguration()
@@ -58,6 +74,26 @@ func NewLDAPUserProviderWithFactory(configuration schema.LDAPAuthenticationBacke
 	return provider
 }
 
+// newLDAPProxyConnectionFactory creates a connection factory dialing through the given proxy, the fallback factory is
+// returned if the proxy URL is invalid which is already reported by the configuration validation.
+func newLDAPProxyConnectionFactory(proxy string, tlsConfig *tls.Config, fallback LDAPConnectionFactory) LDAPConnectionFactory {
+	logger := logging.Logger()
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		logger.Errorf("Unable to parse the LDAP proxy URL: %v", err)
+		return fallback
+	}
+
+	dialer, err := utils.NewProxyDialer(proxyURL, nil, utils.ProxyDefaultTimeout)
+	if err != nil {
+		logger.Errorf("Unable to create the LDAP proxy dialer: %v", err)
+		return fallback
+	}
+
+	return NewLDAPProxyConnectionFactoryImpl(dialer, tlsConfig)
+}
+
 func (p *LDAPUserProvider) parseDynamicConfiguration() {
 	logger := logging.Logger() // Deprecated: This is temporary for deprecation notice purposes. TODO: Remove in 4.28.
 
@@ -86,6 +122,13 @@ func (p *LDAPUserProvider) parseDynamicConfiguration() {
 	p.configuration.UsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{mail_attribute}", p.configuration.MailAttribute)
 	p.configuration.UsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{display_name_attribute}", p.configuration.DisplayNameAttribute)
 
+	p.configuration.GroupsFilter = strings.ReplaceAll(p.configuration.GroupsFilter, "{username_attribute}", p.configuration.UsernameAttribute)
+	p.configuration.GroupsFilter = strings.ReplaceAll(p.configuration.GroupsFilter, "{mail_attribute}", p.configuration.MailAttribute)
+
+	// The {domain} placeholder is replaced by the configured domain, which defaults to the session domain.
+	p.configuration.UsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{domain}", ldap.EscapeFilter(p.configuration.Domain))
+	p.configuration.GroupsFilter = strings.ReplaceAll(p.configuration.GroupsFilter, "{domain}", ldap.EscapeFilter(p.configuration.Domain))
+
 	if p.configuration.AdditionalUsersDN != "" {
 		p.usersDN = p.configuration.AdditionalUsersDN + "," + p.configuration.BaseDN
 	} else {
@@ -100,17 +143,11 @@ func (p *LDAPUserProvider) parseDynamicConfiguration() {
 }
 
 func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnection, error) {
-	conn, err := p.connectionFactory.DialURL(p.configuration.URL, p.dialOpts)
+	conn, err := p.dial()
 	if err != nil {
 		return nil, err
 	}
 
-	if p.configuration.StartTLS {
-		if err := conn.StartTLS(p.tlsConfig); err != nil {
-			return nil, err
-		}
-	}
-
 	if err := conn.Bind(userDN, password); err != nil {
 		return nil, err
 	}
@@ -118,6 +155,39 @@ func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnecti
 	return conn, nil
 }
 
+// dial opens a connection to the LDAP server through the circuit breaker. Only the failures to reach the server trip
+// the circuit breaker, a failed bind is the sign of wrong credentials rather than of an unavailable server.
+func (p *LDAPUserProvider) dial() (conn LDAPConnection, err error) {
+	err = p.breaker.Execute(func() error {
+		conn, err = p.connectionFactory.DialURL(p.configuration.URL, p.dialOpts)
+		if err != nil {
+			return err
+		}
+
+		if p.configuration.StartTLS {
+			if err = conn.StartTLS(p.tlsConfig); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return conn, err
+}
+
+// StartupCheck checks the LDAP server is reachable and the service account is able to bind.
+func (p *LDAPUserProvider) StartupCheck() (bool, error) {
+	conn, err := p.connect(p.configuration.User, p.configuration.Password)
+	if err != nil {
+		return false, err
+	}
+
+	conn.Close()
+
+	return true, nil
+}
+
 // CheckUserPassword checks if provided password matches for the given user.
 func (p *LDAPUserProvider) CheckUserPassword(inputUsername string, password string) (bool, error) {
 	conn, err := p.connect(p.configuration.User, p.configuration.Password)
@@ -137,6 +207,9 @@ func (p *LDAPUserProvider) CheckUserPassword(inputUsername string, password stri
 	}
 	defer userConn.Close()
 
+	// The details are retrieved again after a login so that the changes made in the directory are taken into account.
+	p.cache.Invalidate(inputUsername, profile.Username)
+
 	return true, nil
 }
 
@@ -154,6 +227,7 @@ type ldapUserProfile struct {
 	Emails      []string
 	DisplayName string
 	Username    string
+	Attributes  map[string][]string
 }
 
 func (p *LDAPUserProvider) resolveUsersFilter(userFilter string, inputUsername string) string {
@@ -174,6 +248,9 @@ func (p *LDAPUserProvider) getUserProfile(conn LDAPConnection, inputUsername str
 		p.configuration.DisplayNameAttribute,
 		p.configuration.MailAttribute,
 		p.configuration.UsernameAttribute}
+	attributes = append(attributes, p.configuration.DisplayNameFallbackAttributes...)
+	attributes = append(attributes, p.configuration.MailFallbackAttributes...)
+	attributes = append(attributes, p.configuration.ExtraAttributes...)
 
 	// Search for the given username.
 	searchRequest := ldap.NewSearchRequest(
@@ -199,14 +276,6 @@ func (p *LDAPUserProvider) getUserProfile(conn LDAPConnection, inputUsername str
 	}
 
 	for _, attr := range sr.Entries[0].Attributes {
-		if attr.Name == p.configuration.DisplayNameAttribute {
-			userProfile.DisplayName = attr.Values[0]
-		}
-
-		if attr.Name == p.configuration.MailAttribute {
-			userProfile.Emails = attr.Values
-		}
-
 		if attr.Name == p.configuration.UsernameAttribute {
 			if len(attr.Values) != 1 {
 				return nil, fmt.Errorf("User %s cannot have multiple value for attribute %s",
@@ -221,9 +290,52 @@ func (p *LDAPUserProvider) getUserProfile(conn LDAPConnection, inputUsername str
 		return nil, fmt.Errorf("No DN has been found for user %s", inputUsername)
 	}
 
+	if values := getFirstAttributeValues(sr.Entries[0], p.configuration.DisplayNameAttribute, p.configuration.DisplayNameFallbackAttributes); len(values) != 0 {
+		userProfile.DisplayName = values[0]
+	} else {
+		logger.Warnf("No display name has been found for user %s, the username is used instead", inputUsername)
+
+		userProfile.DisplayName = userProfile.Username
+	}
+
+	if userProfile.Emails = getFirstAttributeValues(sr.Entries[0], p.configuration.MailAttribute, p.configuration.MailFallbackAttributes); len(userProfile.Emails) == 0 {
+		logger.Warnf("No email address has been found for user %s", inputUsername)
+	}
+
+	if len(p.configuration.ExtraAttributes) != 0 {
+		userProfile.Attributes = make(map[string][]string, len(p.configuration.ExtraAttributes))
+
+		// The attributes are keyed by their configured name since the directory may return them with another case.
+		for _, name := range p.configuration.ExtraAttributes {
+			if values := sr.Entries[0].GetEqualFoldAttributeValues(name); len(values) != 0 {
+				userProfile.Attributes[name] = values
+			}
+		}
+	}
+
 	return &userProfile, nil
 }
 
+// getFirstAttributeValues returns the non-empty values of the first attribute of the entry having some, trying the
+// attribute then the fallback attributes in order.
+func getFirstAttributeValues(entry *ldap.Entry, attribute string, fallbacks []string) []string {
+	for _, name := range append([]string{attribute}, fallbacks...) {
+		var values []string
+
+		for _, value := range entry.GetAttributeValues(name) {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+
+		if len(values) != 0 {
+			return values
+		}
+	}
+
+	return nil
+}
+
 func (p *LDAPUserProvider) resolveGroupsFilter(inputUsername string, profile *ldapUserProfile) (string, error) { //nolint:unparam
 	inputUsername = p.ldapEscape(inputUsername)
 
@@ -238,8 +350,23 @@ func (p *LDAPUserProvider) resolveGroupsFilter(inputUsername string, profile *ld
 	return groupFilter, nil
 }
 
-// GetDetails retrieve the groups a user belongs to.
+// GetDetails retrieve the groups a user belongs to, from the cache if it is enabled and they were recently retrieved.
 func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error) {
+	if details, ok := p.cache.Get(inputUsername); ok {
+		return details, nil
+	}
+
+	details, err := p.getDetails(inputUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cache.Set(inputUsername, *details)
+
+	return details, nil
+}
+
+func (p *LDAPUserProvider) getDetails(inputUsername string) (*UserDetails, error) {
 	logger := logging.Logger()
 
 	conn, err := p.connect(p.configuration.User, p.configuration.Password)
@@ -288,9 +415,15 @@ func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error
 		DisplayName: profile.DisplayName,
 		Emails:      profile.Emails,
 		Groups:      groups,
+		Attributes:  profile.Attributes,
 	}, nil
 }
 
+// AddUser is not supported by the LDAP backend, the users are managed in the directory.
+func (p *LDAPUserProvider) AddUser(_ UserDetails, _ string) error {
+	return ErrUserCreationNotSupported
+}
+
 // UpdatePassword update the password of the given user.
 func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword string) error {
 	conn, err := p.connect(p.configuration.User, p.configuration.Password)
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/authelia/authelia/internal/circuitbreaker"
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
 
@@ -350,6 +351,225 @@ func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
 	assert.Equal(t, details.Username, "john")
 }
 
+func TestShouldUseFallbackAttributesForDisplayNameAndEmails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                           "ldap://127.0.0.1:389",
+			User:                          "cn=admin,dc=example,dc=com",
+			Password:                      "password",
+			UsernameAttribute:             "uid",
+			DisplayNameAttribute:          "displayName",
+			DisplayNameFallbackAttributes: []string{"cn", "sAMAccountName"},
+			MailAttribute:                 "mail",
+			MailFallbackAttributes:        []string{"userPrincipalName"},
+			UsersFilter:                   "uid={input}",
+			AdditionalUsersDN:             "ou=users",
+			BaseDN:                        "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		DoAndReturn(func(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			assert.Equal(t, []string{"dn", "displayName", "mail", "uid", "cn", "sAMAccountName", "userPrincipalName"}, request.Attributes)
+
+			return &ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=test,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+							{
+								Name:   "displayName",
+								Values: []string{""},
+							},
+							{
+								Name:   "cn",
+								Values: []string{"John Doe"},
+							},
+							{
+								Name:   "sAMAccountName",
+								Values: []string{"jdoe"},
+							},
+							{
+								Name:   "userPrincipalName",
+								Values: []string{"john@example.com"},
+							},
+						},
+					},
+				},
+			}, nil
+		})
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "John Doe", details.DisplayName)
+	assert.Equal(t, []string{"john@example.com"}, details.Emails)
+}
+
+func TestShouldFetchExtraAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			DisplayNameAttribute: "displayName",
+			MailAttribute:        "mail",
+			ExtraAttributes:      []string{"department", "employeeType"},
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		DoAndReturn(func(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			assert.Equal(t, []string{"dn", "displayName", "mail", "uid", "department", "employeeType"}, request.Attributes)
+
+			return &ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=test,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+							{
+								Name:   "displayName",
+								Values: []string{"John Doe"},
+							},
+							{
+								// The directory may return the attribute with another case.
+								Name:   "Department",
+								Values: []string{"sales", "marketing"},
+							},
+						},
+					},
+				},
+			}, nil
+		})
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{"department": {"sales", "marketing"}}, details.Attributes)
+}
+
+func TestShouldUseUsernameAsDisplayNameWhenNoAttributeResolves(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                           "ldap://127.0.0.1:389",
+			User:                          "cn=admin,dc=example,dc=com",
+			Password:                      "password",
+			UsernameAttribute:             "uid",
+			DisplayNameAttribute:          "displayName",
+			DisplayNameFallbackAttributes: []string{"cn"},
+			MailAttribute:                 "mail",
+			UsersFilter:                   "uid={input}",
+			AdditionalUsersDN:             "ou=users",
+			BaseDN:                        "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues(), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.DisplayName)
+	assert.Empty(t, details.Emails)
+}
+
 func TestShouldReturnUsernameFromLDAP(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -729,6 +949,33 @@ func TestShouldParseDynamicConfiguration(t *testing.T) {
 	assert.Equal(t, "ou=groups,dc=example,dc=com", ldapClient.groupsDN)
 }
 
+func TestShouldReplaceDomainPlaceholderInFilters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "sAMAccountName",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayName",
+			UsersFilter:          "(|({username_attribute}={input})(userPrincipalName={input}@{domain}))",
+			GroupsFilter:         "(&(|(member={dn})({username_attribute}={username}))(domain={domain}))",
+			Domain:               "example(1).com",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	assert.Equal(t, "(|(sAMAccountName={input})(userPrincipalName={input}@example\\281\\29.com))", ldapClient.configuration.UsersFilter)
+	assert.Equal(t, "(&(|(member={dn})(sAMAccountName={username}))(domain=example\\281\\29.com))", ldapClient.configuration.GroupsFilter)
+	assert.Equal(t, "(|(sAMAccountName=john\\2a)(userPrincipalName=john\\2a@example\\281\\29.com))", ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, "john*"))
+}
+
 func TestShouldCallStartTLSWithInsecureSkipVerifyWhenSkipVerifyTrue(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -844,3 +1091,211 @@ func TestShouldReturnLDAPSAlreadySecuredWhenStartTLSAttempted(t *testing.T) {
 	_, err := ldapClient.GetDetails("john")
 	assert.EqualError(t, err, "LDAP Result Code 200 \"Network Error\": ldap: already encrypted")
 }
+
+func TestShouldPassStartupCheckWhenServiceAccountCanBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().Close()
+
+	ok, err := ldapClient.StartupCheck()
+
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestShouldFailStartupCheckWhenServiceAccountCannotBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(errors.New("invalid credentials"))
+
+	ok, err := ldapClient.StartupCheck()
+
+	assert.EqualError(t, err, "invalid credentials")
+	assert.False(t, ok)
+}
+
+func TestShouldFailFastWhenCircuitBreakerIsOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+			CircuitBreaker: &schema.CircuitBreakerConfiguration{
+				FailureThreshold: 2,
+				Cooldown:         "1m",
+			},
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(2)
+
+	_, err := ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "connection refused")
+
+	_, err = ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "connection refused")
+
+	_, err = ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "LDAP is unavailable: circuit breaker is open")
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitOpen)
+}
+
+func TestShouldCacheUserDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+			Cache:                &schema.LDAPCacheConfiguration{TTL: "1m", MaxEntries: 10},
+		},
+		nil,
+		mockFactory)
+
+	profile := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: "uid=test,dc=example,dc=com",
+				Attributes: []*ldap.EntryAttribute{
+					{
+						Name:   "displayname",
+						Values: []string{"John Doe"},
+					},
+					{
+						Name:   "mail",
+						Values: []string{"test@example.com"},
+					},
+					{
+						Name:   "uid",
+						Values: []string{"john"},
+					},
+				},
+			},
+		},
+	}
+
+	// The details are retrieved once, then again after the login invalidated them.
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(profile, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributes(&ldap.EntryAttribute{Name: "cn", Values: []string{"admins"}}), nil),
+		mockConn.EXPECT().
+			Close(),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(profile, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=test,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Close().Times(2),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(profile, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributes(&ldap.EntryAttribute{Name: "cn", Values: []string{"users"}}), nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admins"}, details.Groups)
+
+	details, err = ldapClient.GetDetails("john")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admins"}, details.Groups)
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	details, err = ldapClient.GetDetails("john")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users"}, details.Groups)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/simia-tech/crypt"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
 )
 
@@ -103,6 +104,16 @@ func ParseHash(hash string) (passwordHash *PasswordHash, err error) {
 	return h, nil
 }
 
+// cost estimates the cost of checking a password against the hash in a unit shared by both algorithms, the passes over
+// the memory blocks of 1 KiB of argon2id taking about as long as the rounds of SHA512.
+func (h PasswordHash) cost() int {
+	if h.Algorithm == HashingAlgorithmArgon2id {
+		return h.Memory * h.Iterations
+	}
+
+	return h.Iterations
+}
+
 // HashPassword generate a salt and hash the password with the salt and a constant number of rounds.
 func HashPassword(password, salt string, algorithm CryptAlgo, iterations, memory, parallelism, keyLength, saltLength int) (hash string, err error) {
 	var settings string
@@ -135,6 +146,19 @@ func HashPassword(password, salt string, algorithm CryptAlgo, iterations, memory
 	return hash, nil
 }
 
+// HashPasswordWithConfiguration hash the password with a random salt using the given hashing configuration.
+func HashPasswordWithConfiguration(password string, configuration *schema.PasswordConfiguration) (hash string, err error) {
+	algorithm, err := ConfigAlgoToCryptoAlgo(configuration.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	return HashPassword(
+		password, "", algorithm, configuration.Iterations,
+		configuration.Memory*1024, configuration.Parallelism,
+		configuration.KeyLength, configuration.SaltLength)
+}
+
 // CheckPassword check a password against a hash.
 func CheckPassword(password, hash string) (ok bool, err error) {
 	expectedHash, err := ParseHash(hash)
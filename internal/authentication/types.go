@@ -6,4 +6,7 @@ type UserDetails struct {
 	DisplayName string
 	Emails      []string
 	Groups      []string
+
+	// Attributes are the extra attributes of the user keyed by their name, only fetched by the LDAP backend.
+	Attributes map[string][]string
 }
@@ -0,0 +1,107 @@
+package authentication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// userDetailsCache keeps the details of the users for a short time so that the repeated lookups of the same user, for
+// instance when their session is refreshed, don't all reach the backend.
+type userDetailsCache struct {
+	ttl        time.Duration
+	maxEntries int
+	clock      utils.Clock
+
+	mutex   sync.Mutex
+	entries map[string]cachedUserDetails
+}
+
+type cachedUserDetails struct {
+	details   UserDetails
+	expiresAt time.Time
+}
+
+// newUserDetailsCache creates the cache of the given configuration, nil if the configuration is nil.
+func newUserDetailsCache(configuration *schema.LDAPCacheConfiguration, clock utils.Clock) *userDetailsCache {
+	if configuration == nil {
+		return nil
+	}
+
+	// Ignore the error as the duration is checked by the validator.
+	ttl, _ := utils.ParseDurationString(configuration.TTL)
+
+	return &userDetailsCache{
+		ttl:        ttl,
+		maxEntries: configuration.MaxEntries,
+		clock:      clock,
+		entries:    map[string]cachedUserDetails{},
+	}
+}
+
+// Get returns a copy of the cached details of the user if they are not expired.
+func (c *userDetailsCache) Get(username string) (*UserDetails, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[username]
+	if !ok {
+		return nil, false
+	}
+
+	if !c.clock.Now().Before(entry.expiresAt) {
+		delete(c.entries, username)
+		return nil, false
+	}
+
+	details := entry.details
+
+	return &details, true
+}
+
+// Set caches the details of the user. The expired entries are evicted when the cache is full, the whole cache being
+// cleared if they are all still valid.
+func (c *userDetailsCache) Set(username string, details UserDetails) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.clock.Now()
+
+	if _, ok := c.entries[username]; !ok && len(c.entries) >= c.maxEntries {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+
+		if len(c.entries) >= c.maxEntries {
+			c.entries = map[string]cachedUserDetails{}
+		}
+	}
+
+	c.entries[username] = cachedUserDetails{details: details, expiresAt: now.Add(c.ttl)}
+}
+
+// Invalidate removes the cached details of the users.
+func (c *userDetailsCache) Invalidate(usernames ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, username := range usernames {
+		delete(c.entries, username)
+	}
+}
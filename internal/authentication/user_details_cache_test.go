@@ -0,0 +1,94 @@
+package authentication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type testingClock struct {
+	now time.Time
+}
+
+func (c *testingClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testingClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func TestShouldNotCreateUserDetailsCacheWhenDisabled(t *testing.T) {
+	cache := newUserDetailsCache(nil, &testingClock{})
+	assert.Nil(t, cache)
+
+	cache.Set("john", UserDetails{Username: "john"})
+	cache.Invalidate("john")
+
+	_, ok := cache.Get("john")
+	assert.False(t, ok)
+}
+
+func TestShouldExpireCachedUserDetails(t *testing.T) {
+	clock := &testingClock{now: time.Unix(1000, 0)}
+	cache := newUserDetailsCache(&schema.LDAPCacheConfiguration{TTL: "1m", MaxEntries: 10}, clock)
+
+	cache.Set("john", UserDetails{Username: "john", Groups: []string{"admins"}})
+
+	clock.now = clock.now.Add(59 * time.Second)
+
+	details, ok := cache.Get("john")
+	require.True(t, ok)
+	assert.Equal(t, []string{"admins"}, details.Groups)
+
+	clock.now = clock.now.Add(time.Second)
+
+	_, ok = cache.Get("john")
+	assert.False(t, ok)
+}
+
+func TestShouldInvalidateCachedUserDetails(t *testing.T) {
+	cache := newUserDetailsCache(&schema.LDAPCacheConfiguration{TTL: "1m", MaxEntries: 10}, &testingClock{now: time.Unix(1000, 0)})
+
+	cache.Set("john", UserDetails{Username: "john"})
+	cache.Set("harry", UserDetails{Username: "harry"})
+
+	cache.Invalidate("john")
+
+	_, ok := cache.Get("john")
+	assert.False(t, ok)
+
+	_, ok = cache.Get("harry")
+	assert.True(t, ok)
+}
+
+func TestShouldBoundTheNumberOfCachedUserDetails(t *testing.T) {
+	clock := &testingClock{now: time.Unix(1000, 0)}
+	cache := newUserDetailsCache(&schema.LDAPCacheConfiguration{TTL: "1m", MaxEntries: 2}, clock)
+
+	cache.Set("john", UserDetails{Username: "john"})
+
+	clock.now = clock.now.Add(30 * time.Second)
+
+	cache.Set("harry", UserDetails{Username: "harry"})
+
+	clock.now = clock.now.Add(30 * time.Second)
+
+	// The expired entry of john is evicted to make room.
+	cache.Set("bob", UserDetails{Username: "bob"})
+	assert.Len(t, cache.entries, 2)
+
+	_, ok := cache.Get("harry")
+	assert.True(t, ok)
+
+	// The cache is cleared when all the entries are still valid.
+	cache.Set("james", UserDetails{Username: "james"})
+	assert.Len(t, cache.entries, 1)
+
+	_, ok = cache.Get("james")
+	assert.True(t, ok)
+}
@@ -6,4 +6,6 @@ type UserProvider interface {
 	CheckUserPassword(username string, password string) (bool, error)
 	GetDetails(username string) (*UserDetails, error)
 	UpdatePassword(username string, newPassword string) error
+	AddUser(details UserDetails, hashedPassword string) error
+	StartupCheck() (bool, error)
 }
@@ -0,0 +1,59 @@
+package authorization
+
+import (
+	"regexp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// AccessControlAttribute represents an ACL condition on an attribute of the subject.
+type AccessControlAttribute struct {
+	Name     string
+	Operator string
+
+	// Values are the expected values of the equals and in operators, they can reference the named groups of the
+	// resource matching the object with {name}.
+	Values []string
+
+	// Pattern is the regular expression of the regex operator.
+	Pattern *regexp.Regexp
+}
+
+// IsMatch returns true if one of the values of the attribute of the subject satisfies the condition. The captures are
+// the named groups of the resource of the rule matching the object.
+func (aca AccessControlAttribute) IsMatch(subject Subject, captures map[string]string) (match bool) {
+	for _, value := range subject.Attributes[aca.Name] {
+		if aca.Operator == schema.ACLAttributeOperatorRegex {
+			if aca.Pattern.MatchString(value) {
+				return true
+			}
+
+			continue
+		}
+
+		for _, expected := range aca.Values {
+			if expected, ok := expandAttributeValue(expected, captures); ok && value == expected {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// expandAttributeValue replaces the references to the named groups in the expected value of a condition. It returns
+// false if a referenced group did not capture anything so that an empty value never matches.
+func expandAttributeValue(value string, captures map[string]string) (expanded string, ok bool) {
+	ok = true
+
+	expanded = attributePlaceholderRegexp.ReplaceAllStringFunc(value, func(placeholder string) string {
+		capture := captures[placeholder[1:len(placeholder)-1]]
+		if capture == "" {
+			ok = false
+		}
+
+		return capture
+	})
+
+	return expanded, ok
+}
@@ -13,3 +13,22 @@ type AccessControlResource struct {
 func (acr AccessControlResource) IsMatch(object Object) (match bool) {
 	return acr.Pattern.MatchString(object.Path)
 }
+
+// Captures returns the values captured by the named groups of the ACL resource in the object path, nil if it doesn't
+// match.
+func (acr AccessControlResource) Captures(object Object) (captures map[string]string) {
+	submatches := acr.Pattern.FindStringSubmatch(object.Path)
+	if submatches == nil {
+		return nil
+	}
+
+	captures = map[string]string{}
+
+	for i, name := range acr.Pattern.SubexpNames() {
+		if name != "" {
+			captures[name] = submatches[i]
+		}
+	}
+
+	return captures
+}
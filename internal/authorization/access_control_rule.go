@@ -2,12 +2,15 @@ package authorization
 
 import (
 	"net"
+	"sort"
+	"time"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
 )
 
-// NewAccessControlRules converts a schema.AccessControlConfiguration into an AccessControlRule slice.
+// NewAccessControlRules converts a schema.AccessControlConfiguration into an AccessControlRule slice. The rules are
+// ordered by descending priority, rules with the same priority keep their order from the configuration.
 func NewAccessControlRules(config schema.AccessControlConfiguration) (rules []*AccessControlRule) {
 	networksMap, networksCacheMap := parseSchemaNetworks(config.Networks)
 
@@ -15,11 +18,18 @@ func NewAccessControlRules(config schema.AccessControlConfiguration) (rules []*A
 		rules = append(rules, NewAccessControlRule(schemaRule, networksMap, networksCacheMap))
 	}
 
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+
 	return rules
 }
 
 // NewAccessControlRule parses a schema ACL and generates an internal ACL.
 func NewAccessControlRule(rule schema.ACLRule, networksMap map[string][]*net.IPNet, networksCacheMap map[string]*net.IPNet) *AccessControlRule {
+	// Ignore the error as the duration is checked by the validator.
+	freshAuthMaxAge, _ := utils.ParseDurationString(rule.RequireFreshAuth)
+
 	return &AccessControlRule{
 		Domains:   schemaDomainsToACL(rule.Domains),
 		Resources: schemaResourcesToACL(rule.Resources),
@@ -27,6 +37,15 @@ func NewAccessControlRule(rule schema.ACLRule, networksMap map[string][]*net.IPN
 		Networks:  schemaNetworksToACL(rule.Networks, networksMap, networksCacheMap),
 		Subjects:  schemaSubjectsToACL(rule.Subjects),
 		Policy:    PolicyToLevel(rule.Policy),
+
+		Attributes: schemaAttributesToACL(rule.Attributes),
+
+		AnonymousIdentity: rule.AnonymousIdentity,
+		BasicAuth:         rule.BasicAuth,
+		DeniedResponse:    rule.DeniedResponse,
+		RedirectURL:       rule.RedirectURL,
+		FreshAuthMaxAge:   freshAuthMaxAge,
+		Priority:          rule.Priority,
 	}
 }
 
@@ -38,6 +57,28 @@ type AccessControlRule struct {
 	Networks  []*net.IPNet
 	Subjects  []AccessControlSubjects
 	Policy    Level
+
+	// Attributes are the conditions on the attributes of the subject, they must all match.
+	Attributes []AccessControlAttribute
+
+	// AnonymousIdentity is the pseudo username given to anonymous users accessing a resource bypassed by this rule.
+	AnonymousIdentity string
+
+	// BasicAuth allows anonymous clients to authenticate with HTTP Basic credentials on the resources of this rule.
+	BasicAuth bool
+
+	// DeniedResponse is the response sent to the requests denied by this rule, the global one if empty.
+	DeniedResponse string
+
+	// RedirectURL is the URL users denied access by this rule are redirected to instead of the portal, if not empty.
+	RedirectURL string
+
+	// FreshAuthMaxAge is the maximum age of the authentication of the users accessing the resources of this rule, 0 if
+	// any authentication within the session lifetime is accepted.
+	FreshAuthMaxAge time.Duration
+
+	// Priority is the priority of the rule, the matching rule with the highest priority applies.
+	Priority int
 }
 
 // IsMatch returns true if all elements of an AccessControlRule match the object and subject.
@@ -62,6 +103,10 @@ func (acr *AccessControlRule) IsMatch(subject Subject, object Object) (match boo
 		return false
 	}
 
+	if !isMatchForAttributes(subject, object, acr) {
+		return false
+	}
+
 	return true
 }
 
@@ -137,3 +182,28 @@ func isMatchForSubjects(subject Subject, acl *AccessControlRule) (match bool) {
 
 	return false
 }
+
+func isMatchForAttributes(subject Subject, object Object, acl *AccessControlRule) (match bool) {
+	// If there are no attribute conditions in this rule or the subject is anonymous then the attribute condition is a
+	// match, the anonymous subject being asked to authenticate.
+	if len(acl.Attributes) == 0 || subject.IsAnonymous() {
+		return true
+	}
+
+	var captures map[string]string
+
+	// The named groups are captured by the first resource matching the object, as when matching the resources.
+	for _, resource := range acl.Resources {
+		if captures = resource.Captures(object); captures != nil {
+			break
+		}
+	}
+
+	for _, attribute := range acl.Attributes {
+		if !attribute.IsMatch(subject, captures) {
+			return false
+		}
+	}
+
+	return true
+}
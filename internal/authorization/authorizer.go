@@ -1,32 +1,50 @@
 package authorization
 
 import (
+	"crypto/x509"
+	"time"
+
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
 )
 
 // Authorizer the component in charge of checking whether a user can access a given resource.
 type Authorizer struct {
-	defaultPolicy Level
-	rules         []*AccessControlRule
+	defaultPolicy     Level
+	anonymousIdentity string
+	deniedResponse    string
+	pathNormalization string
+	rules             []*AccessControlRule
+	external          *ExternalAuthorizer
 }
 
-// NewAuthorizer create an instance of authorizer with a given access control configuration.
-func NewAuthorizer(configuration schema.AccessControlConfiguration) *Authorizer {
-	return &Authorizer{
-		defaultPolicy: PolicyToLevel(configuration.DefaultPolicy),
-		rules:         NewAccessControlRules(configuration),
+// NewAuthorizer create an instance of authorizer with a given access control configuration. The certificate pool is
+// used to verify the external authorization webhook.
+func NewAuthorizer(configuration schema.AccessControlConfiguration, certPool *x509.CertPool) *Authorizer {
+	authorizer := &Authorizer{
+		defaultPolicy:     PolicyToLevel(configuration.DefaultPolicy),
+		anonymousIdentity: configuration.AnonymousIdentity,
+		deniedResponse:    configuration.DeniedResponse,
+		pathNormalization: configuration.PathNormalization,
+		rules:             NewAccessControlRules(configuration),
+	}
+
+	if configuration.ExternalAuthorization != nil {
+		authorizer.external = NewExternalAuthorizer(*configuration.ExternalAuthorization, certPool)
 	}
+
+	return authorizer
 }
 
-// IsSecondFactorEnabled return true if at least one policy is set to second factor.
+// IsSecondFactorEnabled return true if at least one policy is set to second factor, or may be decided as such by the
+// external authorization webhook.
 func (p *Authorizer) IsSecondFactorEnabled() bool {
 	if p.defaultPolicy == TwoFactor {
 		return true
 	}
 
 	for _, rule := range p.rules {
-		if rule.Policy == TwoFactor {
+		if rule.Policy == TwoFactor || rule.Policy == External {
 			return true
 		}
 	}
@@ -36,16 +54,103 @@ func (p *Authorizer) IsSecondFactorEnabled() bool {
 
 // GetRequiredLevel retrieve the required level of authorization to access the object.
 func (p *Authorizer) GetRequiredLevel(subject Subject, object Object) Level {
+	level, _ := p.GetRequiredLevelWithReason(subject, object)
+
+	return level
+}
+
+// GetRequiredLevelWithReason retrieve the required level of authorization to access the object along with the reason
+// why the access is denied when the level is Denied, DenyReasonNone otherwise.
+func (p *Authorizer) GetRequiredLevelWithReason(subject Subject, object Object) (Level, DenyReason) {
 	logger := logging.Logger()
 	logger.Tracef("Check authorization of subject %s and url %s.", subject.String(), object.String())
 
+	if rule := p.GetMatchingRule(subject, object); rule != nil {
+		if rule.Policy != External {
+			if len(rule.Networks) != 0 {
+				return rule.Policy, denyReason(rule.Policy, DenyReasonNetwork)
+			}
+
+			return rule.Policy, denyReason(rule.Policy, DenyReasonRule)
+		}
+
+		if p.external == nil {
+			logger.Errorf("No external authorization is configured to decide the policy of url %s, denying the access", object.String())
+			return Denied, DenyReasonExternal
+		}
+
+		object.Path = normalizePath(object.Path, p.pathNormalization)
+		level := p.external.GetRequiredLevel(subject, object)
+
+		return level, denyReason(level, DenyReasonExternal)
+	}
+
+	logger.Tracef("No matching rule for subject %s and url %s... Applying default policy.", subject.String(), object.String())
+
+	return p.defaultPolicy, denyReason(p.defaultPolicy, DenyReasonNoMatchingRule)
+}
+
+// GetMatchingRule retrieve the first rule matching the subject accessing the object, nil if none matches in which case
+// the default policy applies. The path of the object is normalized beforehand so that equivalent paths match the same
+// rules.
+func (p *Authorizer) GetMatchingRule(subject Subject, object Object) *AccessControlRule {
+	object.Path = normalizePath(object.Path, p.pathNormalization)
+
 	for _, rule := range p.rules {
 		if rule.IsMatch(subject, object) {
-			return rule.Policy
+			return rule
 		}
 	}
 
-	logger.Tracef("No matching rule for subject %s and url %s... Applying default policy.", subject.String(), object.String())
+	return nil
+}
+
+// GetAnonymousIdentity retrieve the pseudo username given to an anonymous subject accessing the object. It is the
+// identity of the first matching bypass rule if defined, the global anonymous identity otherwise.
+func (p *Authorizer) GetAnonymousIdentity(subject Subject, object Object) string {
+	if rule := p.GetMatchingRule(subject, object); rule != nil && rule.Policy == Bypass && rule.AnonymousIdentity != "" {
+		return rule.AnonymousIdentity
+	}
+
+	return p.anonymousIdentity
+}
+
+// GetDeniedResponse retrieve the response to send when the subject is denied access to the object. It is the response
+// of the first matching rule if defined, the global denied response otherwise.
+func (p *Authorizer) GetDeniedResponse(subject Subject, object Object) string {
+	if rule := p.GetMatchingRule(subject, object); rule != nil && rule.DeniedResponse != "" {
+		return rule.DeniedResponse
+	}
+
+	return p.deniedResponse
+}
+
+// GetRedirectURL retrieve the URL the subject denied access to the object is redirected to instead of the portal. It
+// is the URL of the first matching rule, empty if the rule doesn't define one.
+func (p *Authorizer) GetRedirectURL(subject Subject, object Object) string {
+	if rule := p.GetMatchingRule(subject, object); rule != nil {
+		return rule.RedirectURL
+	}
+
+	return ""
+}
+
+// GetFreshAuthentication retrieve the maximum age of the authentication required by the first matching rule and the
+// level it applies to, i.e. the policy of the rule. A zero age means no fresh authentication is required.
+func (p *Authorizer) GetFreshAuthentication(subject Subject, object Object) (maxAge time.Duration, level Level) {
+	if rule := p.GetMatchingRule(subject, object); rule != nil {
+		return rule.FreshAuthMaxAge, rule.Policy
+	}
 
-	return p.defaultPolicy
+	return 0, p.defaultPolicy
+}
+
+// IsBasicAuthAllowed returns true if the first rule matching the anonymous subject accessing the object allows the
+// subject to authenticate with HTTP Basic credentials.
+func (p *Authorizer) IsBasicAuthAllowed(subject Subject, object Object) bool {
+	if rule := p.GetMatchingRule(subject, object); rule != nil {
+		return rule.BasicAuth
+	}
+
+	return false
 }
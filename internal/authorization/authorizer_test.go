@@ -4,6 +4,7 @@ import (
 	"net"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -21,7 +22,7 @@ type AuthorizerTester struct {
 
 func NewAuthorizerTester(config schema.AccessControlConfiguration) *AuthorizerTester {
 	return &AuthorizerTester{
-		NewAuthorizer(config),
+		NewAuthorizer(config, nil),
 	}
 }
 
@@ -222,6 +223,36 @@ func (s *AuthorizerSuite) TestShouldCheckRulePrecedence() {
 	tester.CheckAuthorizations(s.T(), John, "https://public.example.com/", "GET", TwoFactor)
 }
 
+func (s *AuthorizerSuite) TestShouldCheckRulePriority() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy("deny").
+		WithRule(schema.ACLRule{
+			Domains: []string{"*.example.com"},
+			Policy:  "two_factor",
+		}).
+		WithRule(schema.ACLRule{
+			Domains:   []string{"protected.example.com"},
+			Resources: []string{"^/public/.*$"},
+			Policy:    "bypass",
+			Priority:  10,
+		}).
+		WithRule(schema.ACLRule{
+			Domains:  []string{"protected.example.com"},
+			Policy:   "one_factor",
+			Priority: 5,
+		}).
+		WithRule(schema.ACLRule{
+			Domains:  []string{"protected.example.com"},
+			Policy:   "deny",
+			Priority: 5,
+		}).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), John, "https://protected.example.com/public/index.html", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), John, "https://protected.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://public.example.com/", "GET", TwoFactor)
+}
+
 func (s *AuthorizerSuite) TestShouldCheckUserMatching() {
 	tester := NewAuthorizerBuilder().
 		WithDefaultPolicy("deny").
@@ -325,6 +356,54 @@ func (s *AuthorizerSuite) TestShouldCheckIPMatching() {
 	tester.CheckAuthorizations(s.T(), Sam, "https://ipv6.example.com/", "GET", TwoFactor)
 }
 
+func (s *AuthorizerSuite) TestShouldRequireSecondFactorOffNetworkOnly() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: "deny",
+		Networks: []schema.ACLNetwork{{
+			Name:     "office",
+			Networks: []string{"10.0.0.0/8", "fec0::/64"},
+		}},
+		Rules: []schema.ACLRule{{
+			Domains:  []string{"app.example.com"},
+			Policy:   "one_factor",
+			Networks: []string{"office"},
+		}, {
+			Domains: []string{"app.example.com"},
+			Policy:  "two_factor",
+		}},
+	})
+
+	onNetwork := Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("10.0.0.8")}
+	offNetwork := Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("203.0.113.5")}
+
+	tester.CheckAuthorizations(s.T(), onNetwork, "https://app.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), offNetwork, "https://app.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), onNetwork, "https://app.example.com/", "GET", OneFactor)
+
+	tester.CheckAuthorizations(s.T(), Sam, "https://app.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), Subject{Username: "sam", IP: net.ParseIP("2001:db8::1")}, "https://app.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), Subject{Username: "john"}, "https://app.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/", "GET", TwoFactor)
+}
+
+func (s *AuthorizerSuite) TestShouldShadowNetworkRuleListedAfterRuleWithoutNetworks() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy("deny").
+		WithRule(schema.ACLRule{
+			Domains: []string{"app.example.com"},
+			Policy:  "two_factor",
+		}).
+		WithRule(schema.ACLRule{
+			Domains:  []string{"app.example.com"},
+			Policy:   "one_factor",
+			Networks: []string{"10.0.0.0/8"},
+		}).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), John, "https://app.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), Subject{Username: "john", IP: net.ParseIP("203.0.113.5")}, "https://app.example.com/", "GET", TwoFactor)
+}
+
 func (s *AuthorizerSuite) TestShouldCheckMethodMatching() {
 	tester := NewAuthorizerBuilder().
 		WithDefaultPolicy("deny").
@@ -381,6 +460,61 @@ func (s *AuthorizerSuite) TestShouldCheckResourceMatching() {
 	tester.CheckAuthorizations(s.T(), John, "https://resource.example.com/xyz/embedded/abc", "GET", Bypass)
 }
 
+func (s *AuthorizerSuite) TestShouldCheckAttributesMatching() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy("deny").
+		WithRule(schema.ACLRule{
+			Domains: []string{"protected.example.com"},
+			Policy:  "one_factor",
+			Attributes: []schema.ACLAttributeCondition{
+				{Attribute: "department", Operator: "in", Values: []string{"sales", "marketing"}},
+				{Attribute: "employeeType", Operator: "equals", Value: "staff"},
+				{Attribute: "mail", Operator: "regex", Value: "@example\\.com$"},
+			},
+		}).
+		Build()
+
+	john := John
+	john.Attributes = map[string][]string{
+		"department":   {"engineering", "marketing"},
+		"employeeType": {"staff"},
+		"mail":         {"john@example.com"},
+	}
+
+	bob := Bob
+	bob.Attributes = map[string][]string{
+		"department":   {"sales"},
+		"employeeType": {"contractor"},
+		"mail":         {"bob@example.com"},
+	}
+
+	tester.CheckAuthorizations(s.T(), john, "https://protected.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), bob, "https://protected.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), Sam, "https://protected.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://protected.example.com/", "GET", OneFactor)
+}
+
+func (s *AuthorizerSuite) TestShouldCheckAttributesMatchingResourceGroups() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy("deny").
+		WithRule(schema.ACLRule{
+			Domains:   []string{"protected.example.com"},
+			Policy:    "one_factor",
+			Resources: []string{"^/tenants/(?P<tenant>[^/]+)/.*$"},
+			Attributes: []schema.ACLAttributeCondition{
+				{Attribute: "department", Operator: "equals", Value: "{tenant}"},
+			},
+		}).
+		Build()
+
+	john := John
+	john.Attributes = map[string][]string{"department": {"sales"}}
+
+	tester.CheckAuthorizations(s.T(), john, "https://protected.example.com/tenants/sales/report", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), john, "https://protected.example.com/tenants/hr/report", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), john, "https://protected.example.com/sales/report", "GET", Denied)
+}
+
 // This test assures that rules without domains (not allowed by schema validator at this time) will pass validation correctly.
 func (s *AuthorizerSuite) TestShouldMatchAnyDomainIfBlank() {
 	tester := NewAuthorizerBuilder().
@@ -478,11 +612,276 @@ func (s *AuthorizerSuite) TestShouldMatchResourceWithSubjectRules() {
 	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://private.example.com", "GET", TwoFactor)
 }
 
+func (s *AuthorizerSuite) TestShouldGetAnonymousIdentity() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy:     "bypass",
+		AnonymousIdentity: "anonymous",
+		Rules: []schema.ACLRule{
+			{
+				Domains:           []string{"public.example.com"},
+				Policy:            "bypass",
+				AnonymousIdentity: "visitor",
+			},
+			{
+				Domains: []string{"docs.example.com"},
+				Policy:  "bypass",
+			},
+			{
+				Domains: []string{"secure.example.com"},
+				Policy:  "one_factor",
+			},
+		},
+	})
+
+	getIdentity := func(requestURI string) string {
+		url, _ := url.ParseRequestURI(requestURI)
+		return tester.GetAnonymousIdentity(AnonymousUser, NewObject(url, "GET"))
+	}
+
+	s.Assert().Equal("visitor", getIdentity("https://public.example.com/"))
+	s.Assert().Equal("anonymous", getIdentity("https://docs.example.com/"))
+	s.Assert().Equal("anonymous", getIdentity("https://secure.example.com/"))
+	s.Assert().Equal("anonymous", getIdentity("https://other.example.com/"))
+}
+
+func (s *AuthorizerSuite) TestShouldCheckBasicAuthIsAllowed() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: "deny",
+		Rules: []schema.ACLRule{
+			{
+				Domains:   []string{"api.example.com"},
+				Policy:    "one_factor",
+				Networks:  []string{"10.0.0.0/8"},
+				BasicAuth: true,
+			},
+			{
+				Domains: []string{"api.example.com"},
+				Policy:  "one_factor",
+			},
+		},
+	})
+
+	object := NewObject(&url.URL{Scheme: "https", Host: "api.example.com", Path: "/"}, "GET")
+
+	s.Assert().True(tester.IsBasicAuthAllowed(Subject{IP: net.ParseIP("10.0.0.8")}, object))
+	s.Assert().False(tester.IsBasicAuthAllowed(Subject{IP: net.ParseIP("203.0.113.5")}, object))
+	s.Assert().False(tester.IsBasicAuthAllowed(AnonymousUser, NewObject(&url.URL{Scheme: "https", Host: "other.example.com", Path: "/"}, "GET")))
+}
+
+func (s *AuthorizerSuite) TestShouldGetDeniedResponse() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy:  "deny",
+		DeniedResponse: "unauthorized",
+		Rules: []schema.ACLRule{
+			{
+				Domains:        []string{"waf.example.com"},
+				Policy:         "two_factor",
+				Subjects:       [][]string{{"group:admins"}},
+				DeniedResponse: "forbidden",
+			},
+			{
+				Domains: []string{"waf.example.com"},
+				Policy:  "one_factor",
+			},
+		},
+	})
+
+	object := NewObject(&url.URL{Scheme: "https", Host: "waf.example.com", Path: "/"}, "GET")
+
+	s.Assert().Equal("forbidden", tester.GetDeniedResponse(Subject{Username: "john", Groups: []string{"admins"}}, object))
+	s.Assert().Equal("unauthorized", tester.GetDeniedResponse(Subject{Username: "bob", Groups: []string{"dev"}}, object))
+	s.Assert().Equal("unauthorized", tester.GetDeniedResponse(AnonymousUser, NewObject(&url.URL{Scheme: "https", Host: "other.example.com", Path: "/"}, "GET")))
+}
+
+func (s *AuthorizerSuite) TestShouldGetDenyReason() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: "deny",
+		Rules: []schema.ACLRule{
+			{
+				Domains:  []string{"private.example.com"},
+				Policy:   "deny",
+				Networks: []string{"10.0.0.0/8"},
+			},
+			{
+				Domains: []string{"admin.example.com"},
+				Policy:  "deny",
+			},
+			{
+				Domains: []string{"public.example.com"},
+				Policy:  "one_factor",
+			},
+			{
+				Domains: []string{"external.example.com"},
+				Policy:  "external",
+			},
+		},
+	})
+
+	check := func(host string, expectedLevel Level, expectedReason DenyReason) {
+		level, reason := tester.GetRequiredLevelWithReason(John, NewObject(&url.URL{Scheme: "https", Host: host, Path: "/"}, "GET"))
+
+		s.Assert().Equal(expectedLevel, level, host)
+		s.Assert().Equal(expectedReason, reason, host)
+	}
+
+	check("private.example.com", Denied, DenyReasonNetwork)
+	check("admin.example.com", Denied, DenyReasonRule)
+	check("public.example.com", OneFactor, DenyReasonNone)
+	check("other.example.com", Denied, DenyReasonNoMatchingRule)
+	check("external.example.com", Denied, DenyReasonExternal)
+}
+
+func (s *AuthorizerSuite) TestShouldGetFreshAuthenticationOfMatchingRule() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: "deny",
+		Rules: []schema.ACLRule{
+			{
+				Domains:          []string{"admin.example.com"},
+				Policy:           "two_factor",
+				RequireFreshAuth: "5m",
+			},
+			{
+				Domains: []string{"app.example.com"},
+				Policy:  "one_factor",
+			},
+		},
+	})
+
+	maxAge, level := tester.GetFreshAuthentication(John, NewObject(&url.URL{Scheme: "https", Host: "admin.example.com", Path: "/"}, "GET"))
+	s.Assert().Equal(5*time.Minute, maxAge)
+	s.Assert().Equal(TwoFactor, level)
+
+	maxAge, level = tester.GetFreshAuthentication(John, NewObject(&url.URL{Scheme: "https", Host: "app.example.com", Path: "/"}, "GET"))
+	s.Assert().Equal(time.Duration(0), maxAge)
+	s.Assert().Equal(OneFactor, level)
+
+	maxAge, _ = tester.GetFreshAuthentication(John, NewObject(&url.URL{Scheme: "https", Host: "other.example.com", Path: "/"}, "GET"))
+	s.Assert().Equal(time.Duration(0), maxAge)
+}
+
+func (s *AuthorizerSuite) TestShouldGetRedirectURLOfMatchingRule() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: "deny",
+		Rules: []schema.ACLRule{
+			{
+				Domains:     []string{"app.example.com"},
+				Policy:      "two_factor",
+				Subjects:    [][]string{{"group:admins"}},
+				RedirectURL: "https://login.example.com/admins",
+			},
+			{
+				Domains:     []string{"app.example.com"},
+				Policy:      "one_factor",
+				RedirectURL: "https://login.example.com/app",
+			},
+			{
+				Domains: []string{"wiki.example.com"},
+				Policy:  "one_factor",
+			},
+		},
+	})
+
+	object := NewObject(&url.URL{Scheme: "https", Host: "app.example.com", Path: "/"}, "GET")
+
+	rule := tester.GetMatchingRule(Subject{Username: "bob", Groups: []string{"dev"}}, object)
+	s.Require().NotNil(rule)
+	s.Assert().Equal(OneFactor, rule.Policy)
+	s.Assert().Nil(tester.GetMatchingRule(AnonymousUser, NewObject(&url.URL{Scheme: "https", Host: "other.example.com", Path: "/"}, "GET")))
+
+	s.Assert().Equal("https://login.example.com/admins", tester.GetRedirectURL(Subject{Username: "john", Groups: []string{"admins"}}, object))
+	s.Assert().Equal("https://login.example.com/app", tester.GetRedirectURL(Subject{Username: "bob", Groups: []string{"dev"}}, object))
+	s.Assert().Equal("", tester.GetRedirectURL(AnonymousUser, NewObject(&url.URL{Scheme: "https", Host: "wiki.example.com", Path: "/"}, "GET")))
+	s.Assert().Equal("", tester.GetRedirectURL(AnonymousUser, NewObject(&url.URL{Scheme: "https", Host: "other.example.com", Path: "/"}, "GET")))
+}
+
+func (s *AuthorizerSuite) TestShouldNormalizePathBeforeMatchingRules() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy("bypass").
+		WithRule(schema.ACLRule{
+			Domains:   []string{"app.example.com"},
+			Policy:    "two_factor",
+			Resources: []string{"^/admin$", "^/secret/.*$"},
+		}).
+		Build()
+
+	for _, requestURI := range []string{
+		"https://app.example.com/admin",
+		"https://app.example.com//admin",
+		"https://app.example.com/./admin",
+		"https://app.example.com/public/../admin",
+		"https://app.example.com/public/%2e%2e/admin",
+		"https://app.example.com/public/..%2fadmin",
+		"https://app.example.com/admin/.",
+		"https://app.example.com/secret/file",
+		"https://app.example.com//secret//file",
+		"https://app.example.com/public/../secret/./file",
+	} {
+		tester.CheckAuthorizations(s.T(), AnonymousUser, requestURI, "GET", TwoFactor)
+	}
+
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/administrator", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/secret/../public", "GET", Bypass)
+}
+
+func (s *AuthorizerSuite) TestShouldPreserveTrailingSlashByDefault() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy("bypass").
+		WithRule(schema.ACLRule{
+			Domains:   []string{"app.example.com"},
+			Policy:    "two_factor",
+			Resources: []string{"^/x/.*$"},
+		}).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/x/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/x/page", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com//x//", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/public/../x/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/x", "GET", Bypass)
+}
+
+func (s *AuthorizerSuite) TestShouldRemoveTrailingSlashWhenStrict() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy:     "bypass",
+		PathNormalization: schema.PathNormalizationStrict,
+		Rules: []schema.ACLRule{
+			{
+				Domains:   []string{"app.example.com"},
+				Policy:    "two_factor",
+				Resources: []string{"^/admin$"},
+			},
+		},
+	})
+
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/admin/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com//admin//", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/public/../admin/", "GET", TwoFactor)
+}
+
+func (s *AuthorizerSuite) TestShouldMatchExactPathWhenNormalizationDisabled() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy:     "bypass",
+		PathNormalization: schema.PathNormalizationNone,
+		Rules: []schema.ACLRule{
+			{
+				Domains:   []string{"app.example.com"},
+				Policy:    "two_factor",
+				Resources: []string{"^/admin$"},
+			},
+		},
+	})
+
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/admin", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/admin/", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/./admin", "GET", Bypass)
+}
+
 func (s *AuthorizerSuite) TestPolicyToLevel() {
 	s.Assert().Equal(Bypass, PolicyToLevel("bypass"))
 	s.Assert().Equal(OneFactor, PolicyToLevel("one_factor"))
 	s.Assert().Equal(TwoFactor, PolicyToLevel("two_factor"))
 	s.Assert().Equal(Denied, PolicyToLevel("deny"))
+	s.Assert().Equal(External, PolicyToLevel("external"))
 
 	s.Assert().Equal(Denied, PolicyToLevel("whatever"))
 }
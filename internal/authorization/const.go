@@ -1,5 +1,9 @@
 package authorization
 
+import (
+	"regexp"
+)
+
 // Level is the type representing an authorization level.
 type Level int
 
@@ -12,7 +16,39 @@ const (
 	TwoFactor Level = iota
 	// Denied denied level.
 	Denied Level = iota
+	// External level decided by the external authorization webhook.
+	External Level = iota
 )
 
+// DenyReason is the reason why an access is denied.
+type DenyReason string
+
+const (
+	// DenyReasonNone means the access is not denied.
+	DenyReasonNone DenyReason = ""
+	// DenyReasonNoMatchingRule means no rule matches the request and the default policy is deny.
+	DenyReasonNoMatchingRule DenyReason = "no_matching_rule"
+	// DenyReasonRule means the rule matching the request has the deny policy.
+	DenyReasonRule DenyReason = "rule"
+	// DenyReasonNetwork means the rule matching the request has the deny policy and a networks criterion.
+	DenyReasonNetwork DenyReason = "network"
+	// DenyReasonExternal means the external authorization webhook denied the request.
+	DenyReasonExternal DenyReason = "external"
+	// DenyReasonNotAuthenticated means the user must authenticate to access the resource.
+	DenyReasonNotAuthenticated DenyReason = "not_authenticated"
+	// DenyReasonInsufficientLevel means the user is not authenticated with enough factors to access the resource.
+	DenyReasonInsufficientLevel DenyReason = "insufficient_level"
+	// DenyReasonStaleAuthentication means the authentication of the user is older than the matching rule allows.
+	DenyReasonStaleAuthentication DenyReason = "stale_authentication"
+	// DenyReasonBanned means the user is banned by the regulation.
+	DenyReasonBanned DenyReason = "banned"
+	// DenyReasonAuthenticationFailed means the credentials or the session of the user could not be verified.
+	DenyReasonAuthenticationFailed DenyReason = "authentication_failed"
+)
+
+// attributePlaceholderRegexp matches the references of the values of the attribute conditions to the named groups of
+// the resources.
+var attributePlaceholderRegexp = regexp.MustCompile(`{([a-zA-Z0-9_]+)}`)
+
 const userPrefix = "user:"
 const groupPrefix = "group:"
@@ -0,0 +1,188 @@
+package authorization
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// externalAuthorizationCacheMaxEntries is the number of decisions above which the expired decisions are evicted from
+// the cache, the whole cache being cleared if they are all still valid.
+const externalAuthorizationCacheMaxEntries = 10000
+
+// ExternalAuthorizer delegates the decision of the rules with the external policy to a webhook, caching the decisions
+// for a short while.
+type ExternalAuthorizer struct {
+	url           string
+	client        *http.Client
+	cacheDuration time.Duration
+	failurePolicy Level
+
+	mutex sync.Mutex
+	cache map[string]externalDecision
+}
+
+type externalDecision struct {
+	level     Level
+	expiresAt time.Time
+}
+
+type externalAuthorizationRequestBody struct {
+	Subject externalAuthorizationSubject `json:"subject"`
+	Object  externalAuthorizationObject  `json:"object"`
+}
+
+type externalAuthorizationSubject struct {
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+	IP       string   `json:"ip"`
+}
+
+type externalAuthorizationObject struct {
+	Scheme string `json:"scheme"`
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	Method string `json:"method"`
+}
+
+type externalAuthorizationResponseBody struct {
+	Policy string `json:"policy"`
+}
+
+// NewExternalAuthorizer creates an authorizer calling the webhook of the given configuration.
+func NewExternalAuthorizer(configuration schema.ExternalAuthorizationConfiguration, certPool *x509.CertPool) *ExternalAuthorizer {
+	// Ignore the errors as the durations are checked by the validator.
+	timeout, _ := utils.ParseDurationString(configuration.Timeout)
+	cacheDuration, _ := utils.ParseDurationString(configuration.CacheDuration)
+
+	return &ExternalAuthorizer{
+		url: configuration.URL,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12},
+			},
+		},
+		cacheDuration: cacheDuration,
+		failurePolicy: PolicyToLevel(configuration.FailurePolicy),
+		cache:         map[string]externalDecision{},
+	}
+}
+
+// GetRequiredLevel retrieve the level decided by the webhook for the subject accessing the object, the failure policy
+// applying when the webhook can't be reached or replies an unexpected response.
+func (e *ExternalAuthorizer) GetRequiredLevel(subject Subject, object Object) Level {
+	key := fmt.Sprintf("%s method=%s url=%s", subject.String(), object.Method, object.String())
+
+	if level, ok := e.getCachedDecision(key); ok {
+		return level
+	}
+
+	level, err := e.requestDecision(subject, object)
+	if err != nil {
+		logging.Logger().Errorf("Unable to retrieve the external authorization decision of subject %s and url %s, applying the failure policy: %s",
+			subject.String(), object.String(), err)
+
+		return e.failurePolicy
+	}
+
+	e.cacheDecision(key, level)
+
+	return level
+}
+
+func (e *ExternalAuthorizer) requestDecision(subject Subject, object Object) (Level, error) {
+	body := externalAuthorizationRequestBody{
+		Subject: externalAuthorizationSubject{Username: subject.Username, Groups: subject.Groups},
+		Object: externalAuthorizationObject{
+			Scheme: object.Scheme,
+			Domain: object.Domain,
+			Path:   object.Path,
+			Method: object.Method,
+		},
+	}
+
+	if subject.IP != nil {
+		body.Subject.IP = subject.IP.String()
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Denied, err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return Denied, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Denied, fmt.Errorf("webhook replied with status %d", resp.StatusCode)
+	}
+
+	var decision externalAuthorizationResponseBody
+
+	if err = json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return Denied, fmt.Errorf("unable to decode the response of the webhook: %w", err)
+	}
+
+	switch decision.Policy {
+	case "bypass", "one_factor", "two_factor", "deny":
+		return PolicyToLevel(decision.Policy), nil
+	default:
+		return Denied, fmt.Errorf("webhook replied with the unknown policy '%s'", decision.Policy)
+	}
+}
+
+func (e *ExternalAuthorizer) getCachedDecision(key string) (Level, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	decision, ok := e.cache[key]
+	if !ok {
+		return Denied, false
+	}
+
+	if time.Now().After(decision.expiresAt) {
+		delete(e.cache, key)
+		return Denied, false
+	}
+
+	return decision.level, true
+}
+
+func (e *ExternalAuthorizer) cacheDecision(key string, level Level) {
+	if e.cacheDuration <= 0 {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := time.Now()
+
+	if len(e.cache) >= externalAuthorizationCacheMaxEntries {
+		for k, decision := range e.cache {
+			if now.After(decision.expiresAt) {
+				delete(e.cache, k)
+			}
+		}
+
+		if len(e.cache) >= externalAuthorizationCacheMaxEntries {
+			e.cache = map[string]externalDecision{}
+		}
+	}
+
+	e.cache[key] = externalDecision{level: level, expiresAt: now.Add(e.cacheDuration)}
+}
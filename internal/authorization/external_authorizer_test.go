@@ -0,0 +1,133 @@
+package authorization
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newExternalAuthorizationServer(t *testing.T, status int, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+
+		var body externalAuthorizationRequestBody
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.WriteHeader(status)
+
+		policy := "deny"
+		if body.Subject.Username == "john" && body.Object.Path == "/admin" {
+			policy = "two_factor"
+		} else if body.Subject.Username == "" {
+			policy = "one_factor"
+		}
+
+		_ = json.NewEncoder(w).Encode(externalAuthorizationResponseBody{Policy: policy})
+	}))
+}
+
+func newExternalAuthorizationTester(url, failurePolicy string) *AuthorizerTester {
+	configuration := schema.DefaultExternalAuthorizationConfiguration
+	configuration.URL = url
+
+	if failurePolicy != "" {
+		configuration.FailurePolicy = failurePolicy
+	}
+
+	return NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy:         "bypass",
+		ExternalAuthorization: &configuration,
+		Rules: []schema.ACLRule{
+			{
+				Domains: []string{"app.example.com"},
+				Policy:  "external",
+			},
+		},
+	})
+}
+
+func TestShouldDelegateExternalPolicyToWebhook(t *testing.T) {
+	var calls int32
+
+	server := newExternalAuthorizationServer(t, http.StatusOK, &calls)
+	defer server.Close()
+
+	tester := newExternalAuthorizationTester(server.URL, "")
+	john := Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("10.0.0.8")}
+
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "GET", TwoFactor)
+	tester.CheckAuthorizations(t, john, "https://app.example.com/public/../admin", "GET", TwoFactor)
+	tester.CheckAuthorizations(t, john, "https://app.example.com/other", "GET", Denied)
+	tester.CheckAuthorizations(t, AnonymousUser, "https://app.example.com/admin", "GET", OneFactor)
+	tester.CheckAuthorizations(t, john, "https://public.example.com/admin", "GET", Bypass)
+
+	assert.True(t, tester.IsSecondFactorEnabled())
+}
+
+func TestShouldCacheExternalAuthorizationDecisions(t *testing.T) {
+	var calls int32
+
+	server := newExternalAuthorizationServer(t, http.StatusOK, &calls)
+	defer server.Close()
+
+	tester := newExternalAuthorizationTester(server.URL, "")
+	john := Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("10.0.0.8")}
+
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "GET", TwoFactor)
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "GET", TwoFactor)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "POST", TwoFactor)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestShouldApplyFailurePolicyWhenWebhookFails(t *testing.T) {
+	var calls int32
+
+	server := newExternalAuthorizationServer(t, http.StatusInternalServerError, &calls)
+	defer server.Close()
+
+	john := Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("10.0.0.8")}
+
+	tester := newExternalAuthorizationTester(server.URL, "")
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "GET", Denied)
+
+	tester = newExternalAuthorizationTester(server.URL, "two_factor")
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "GET", TwoFactor)
+	tester.CheckAuthorizations(t, john, "https://app.example.com/admin", "GET", TwoFactor)
+
+	// The failures are not cached.
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestShouldApplyFailurePolicyWhenWebhookIsUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	tester := newExternalAuthorizationTester(url, "one_factor")
+	tester.CheckAuthorizations(t, AnonymousUser, "https://app.example.com/admin", "GET", OneFactor)
+}
+
+func TestShouldDenyExternalPolicyWithoutExternalAuthorization(t *testing.T) {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: "bypass",
+		Rules: []schema.ACLRule{
+			{
+				Domains: []string{"app.example.com"},
+				Policy:  "external",
+			},
+		},
+	})
+
+	tester.CheckAuthorizations(t, AnonymousUser, "https://app.example.com/admin", "GET", Denied)
+}
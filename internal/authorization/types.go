@@ -12,6 +12,9 @@ type Subject struct {
 	Username string
 	Groups   []string
 	IP       net.IP
+
+	// Attributes are the extra attributes of the user fetched from the authentication backend.
+	Attributes map[string][]string
 }
 
 // String returns a string representation of the Subject.
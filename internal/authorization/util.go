@@ -2,6 +2,7 @@ package authorization
 
 import (
 	"net"
+	"path"
 	"regexp"
 	"strings"
 
@@ -19,6 +20,8 @@ func PolicyToLevel(policy string) Level {
 		return TwoFactor
 	case "deny":
 		return Denied
+	case "external":
+		return External
 	}
 	// By default the deny policy applies.
 	return Denied
@@ -74,6 +77,28 @@ func schemaResourcesToACL(resourceRules []string) (resources []AccessControlReso
 	return resources
 }
 
+func schemaAttributesToACL(conditions []schema.ACLAttributeCondition) (attributes []AccessControlAttribute) {
+	for _, condition := range conditions {
+		attribute := AccessControlAttribute{
+			Name:     condition.Attribute,
+			Operator: condition.Operator,
+		}
+
+		switch condition.Operator {
+		case schema.ACLAttributeOperatorRegex:
+			attribute.Pattern = regexp.MustCompile(condition.Value)
+		case schema.ACLAttributeOperatorEquals:
+			attribute.Values = []string{condition.Value}
+		default:
+			attribute.Values = condition.Values
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes
+}
+
 func schemaMethodsToACL(methodRules []string) (methods []string) {
 	for _, method := range methodRules {
 		methods = append(methods, strings.ToUpper(method))
@@ -175,3 +200,36 @@ func domainToPrefixSuffix(domain string) (prefix, suffix string) {
 
 	return parts[0], strings.Join(parts[1:], ".")
 }
+
+// normalizePath resolves the dot segments and collapses the duplicate slashes of a path possibly followed by a query
+// string, according to the path normalization of the access control configuration. The trailing slash is only removed
+// by the strict normalization so that `/admin/` still matches the rules such as `^/admin/.*$`, an empty normalization
+// meaning the one preserving the trailing slash.
+func normalizePath(requestPath, normalization string) string {
+	if normalization == schema.PathNormalizationNone {
+		return requestPath
+	}
+
+	query := ""
+
+	if i := strings.IndexByte(requestPath, '?'); i >= 0 {
+		requestPath, query = requestPath[:i], requestPath[i:]
+	}
+
+	normalized := path.Clean("/" + requestPath)
+
+	if normalization != schema.PathNormalizationStrict && normalized != "/" && strings.HasSuffix(requestPath, "/") {
+		normalized += "/"
+	}
+
+	return normalized + query
+}
+
+// denyReason returns the given reason if the level denies the access, DenyReasonNone otherwise.
+func denyReason(level Level, reason DenyReason) DenyReason {
+	if level != Denied {
+		return DenyReasonNone
+	}
+
+	return reason
+}
@@ -182,3 +182,33 @@ func TestShouldParseACLNetworks(t *testing.T) {
 	assert.Equal(t, fourthNetwork, networksCacheMap["fec0::1"])
 	assert.Equal(t, fourthNetwork, networksCacheMap["fec0::1/128"])
 }
+
+func TestShouldNormalizePath(t *testing.T) {
+	testCases := []struct {
+		path, normalization, expected string
+	}{
+		{"", "", "/"},
+		{"/", "", "/"},
+		{"/admin", "", "/admin"},
+		{"/admin/", "", "/admin/"},
+		{"//admin///page", "", "/admin/page"},
+		{"/./admin/./page", "", "/admin/page"},
+		{"/public/../admin", "", "/admin"},
+		{"/../../admin", "", "/admin"},
+		{"/admin/..", "", "/"},
+		{"/admin/?a=/../b", "", "/admin/?a=/../b"},
+		{"/admin/?a=/../b", schema.PathNormalizationStrict, "/admin?a=/../b"},
+		{"/admin//", schema.PathNormalizationStrict, "/admin"},
+		{"/admin//", schema.PathNormalizationPreserveTrailingSlash, "/admin/"},
+		{"/public/../admin/.", schema.PathNormalizationPreserveTrailingSlash, "/admin"},
+		{"//", schema.PathNormalizationPreserveTrailingSlash, "/"},
+		{"/admin/?a=b", schema.PathNormalizationPreserveTrailingSlash, "/admin/?a=b"},
+		{"//admin/../x/", schema.PathNormalizationNone, "//admin/../x/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path+"|"+tc.normalization, func(t *testing.T) {
+			assert.Equal(t, tc.expected, normalizePath(tc.path, tc.normalization))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// CircuitBreaker protects the calls to a backend. It trips after a number of consecutive failures and rejects the calls
+// for a cooldown period, after which a single call probes the backend. A nil CircuitBreaker lets every call through.
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	cooldown         time.Duration
+	clock            utils.Clock
+
+	mutex    sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker creates a circuit breaker protecting the backend with the given name. It returns nil, which lets
+// every call through, if the configuration is nil or the failure threshold is 0.
+func NewCircuitBreaker(name string, configuration *schema.CircuitBreakerConfiguration, clock utils.Clock) *CircuitBreaker {
+	if configuration == nil || configuration.FailureThreshold == 0 {
+		return nil
+	}
+
+	cooldown, err := utils.ParseDurationString(configuration.Cooldown)
+	if err != nil {
+		panic(err)
+	}
+
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: configuration.FailureThreshold,
+		cooldown:         cooldown,
+		clock:            clock,
+	}
+}
+
+// Execute calls fn unless the circuit breaker is open, in which case an error wrapping ErrCircuitOpen is returned
+// without calling fn. The result of fn is recorded to decide the next state of the circuit breaker.
+func (b *CircuitBreaker) Execute(fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+
+	if !b.allow() {
+		return fmt.Errorf("%s is unavailable: %w", b.name, ErrCircuitOpen)
+	}
+
+	err := fn()
+
+	b.record(err == nil)
+
+	return err
+}
+
+// State returns the current state of the circuit breaker, a nil CircuitBreaker is always closed.
+func (b *CircuitBreaker) State() State {
+	if b == nil {
+		return Closed
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+			return false
+		}
+
+		b.transition(HalfOpen)
+
+		return true
+	case HalfOpen:
+		// A probe is already in progress.
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) record(successful bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if successful {
+		b.failures = 0
+
+		if b.state != Closed {
+			b.transition(Closed)
+		}
+
+		return
+	}
+
+	b.failures++
+
+	if b.state == HalfOpen || b.failures >= b.failureThreshold {
+		b.openedAt = b.clock.Now()
+		b.transition(Open)
+	}
+}
+
+func (b *CircuitBreaker) transition(state State) {
+	logger := logging.Logger()
+
+	switch state {
+	case Open:
+		logger.Warnf("Circuit breaker of %s is open after %d consecutive failures, calls are rejected for %s",
+			b.name, b.failures, b.cooldown)
+	case HalfOpen:
+		logger.Infof("Circuit breaker of %s is half-open, the next call probes the backend", b.name)
+	case Closed:
+		logger.Infof("Circuit breaker of %s is closed, the backend recovered", b.name)
+	}
+
+	b.state = state
+}
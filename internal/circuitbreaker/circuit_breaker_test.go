@@ -0,0 +1,135 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type testingClock struct {
+	now time.Time
+}
+
+func (c *testingClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testingClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type CircuitBreakerSuite struct {
+	suite.Suite
+
+	clock   *testingClock
+	breaker *CircuitBreaker
+	calls   int
+}
+
+func (s *CircuitBreakerSuite) SetupTest() {
+	s.clock = &testingClock{now: time.Unix(1000, 0)}
+	s.breaker = NewCircuitBreaker("backend", &schema.CircuitBreakerConfiguration{
+		FailureThreshold: 3,
+		Cooldown:         "30s",
+	}, s.clock)
+	s.calls = 0
+}
+
+func (s *CircuitBreakerSuite) call(err error) error {
+	return s.breaker.Execute(func() error {
+		s.calls++
+		return err
+	})
+}
+
+func (s *CircuitBreakerSuite) trip() {
+	for i := 0; i < 3; i++ {
+		s.Require().EqualError(s.call(errors.New("failure")), "failure")
+	}
+
+	s.Require().Equal(Open, s.breaker.State())
+}
+
+func (s *CircuitBreakerSuite) TestShouldStayClosedBelowThreshold() {
+	s.Assert().Error(s.call(errors.New("failure")))
+	s.Assert().Error(s.call(errors.New("failure")))
+	s.Assert().NoError(s.call(nil))
+	s.Assert().Error(s.call(errors.New("failure")))
+	s.Assert().Error(s.call(errors.New("failure")))
+
+	s.Assert().Equal(Closed, s.breaker.State())
+	s.Assert().Equal(5, s.calls)
+}
+
+func (s *CircuitBreakerSuite) TestShouldFailFastWhenOpen() {
+	s.trip()
+
+	err := s.call(nil)
+
+	s.Assert().EqualError(err, "backend is unavailable: circuit breaker is open")
+	s.Assert().ErrorIs(err, ErrCircuitOpen)
+	s.Assert().Equal(3, s.calls)
+}
+
+func (s *CircuitBreakerSuite) TestShouldCloseAfterSuccessfulProbe() {
+	s.trip()
+
+	s.clock.now = s.clock.now.Add(30 * time.Second)
+
+	s.Assert().NoError(s.call(nil))
+	s.Assert().Equal(Closed, s.breaker.State())
+	s.Assert().Equal(4, s.calls)
+}
+
+func (s *CircuitBreakerSuite) TestShouldReopenAfterFailedProbe() {
+	s.trip()
+
+	s.clock.now = s.clock.now.Add(time.Minute)
+
+	s.Assert().EqualError(s.call(errors.New("failure")), "failure")
+	s.Assert().Equal(Open, s.breaker.State())
+
+	s.clock.now = s.clock.now.Add(10 * time.Second)
+
+	s.Assert().ErrorIs(s.call(nil), ErrCircuitOpen)
+	s.Assert().Equal(4, s.calls)
+}
+
+func (s *CircuitBreakerSuite) TestShouldRejectCallsWhileProbing() {
+	s.trip()
+
+	s.clock.now = s.clock.now.Add(time.Minute)
+
+	err := s.breaker.Execute(func() error {
+		s.Assert().Equal(HalfOpen, s.breaker.State())
+		s.Assert().ErrorIs(s.call(nil), ErrCircuitOpen)
+
+		return nil
+	})
+
+	s.Assert().NoError(err)
+	s.Assert().Equal(Closed, s.breaker.State())
+}
+
+func TestRunCircuitBreakerSuite(t *testing.T) {
+	suite.Run(t, new(CircuitBreakerSuite))
+}
+
+func TestShouldLetEveryCallThroughWhenDisabled(t *testing.T) {
+	assert.Nil(t, NewCircuitBreaker("backend", nil, &testingClock{}))
+
+	breaker := NewCircuitBreaker("backend", &schema.CircuitBreakerConfiguration{}, &testingClock{})
+	require.Nil(t, breaker)
+
+	for i := 0; i < 10; i++ {
+		assert.EqualError(t, breaker.Execute(func() error { return errors.New("failure") }), "failure")
+	}
+
+	assert.Equal(t, Closed, breaker.State())
+}
@@ -0,0 +1,30 @@
+package circuitbreaker
+
+import "errors"
+
+// ErrCircuitOpen is returned when a call is rejected because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed means calls go through the circuit breaker.
+	Closed State = iota
+	// Open means calls are rejected until the cooldown elapses.
+	Open
+	// HalfOpen means a single call probes the backend to decide whether the circuit breaker closes again.
+	HalfOpen
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
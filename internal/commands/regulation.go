@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+func init() {
+	RegulationResetCmd.Flags().StringP("user", "u", "", "the username of the user to lift the ban of")
+
+	RegulationCmd.AddCommand(RegulationResetCmd)
+}
+
+// RegulationCmd is the command grouping the regulation related commands.
+var RegulationCmd = &cobra.Command{
+	Use:   "regulation",
+	Short: "Manage the regulation of the authentication attempts.",
+}
+
+// RegulationResetCmd lifts the ban of a user by removing its failed authentication attempts from the storage backend
+// configured in the given configuration.
+var RegulationResetCmd = &cobra.Command{
+	Use:   "reset [yaml]",
+	Short: "Lift the ban of a user by removing its failed authentication attempts.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		username, _ := cobraCmd.Flags().GetString("user")
+		if username == "" {
+			log.Fatal("The user to lift the ban of must be provided")
+		}
+
+		configPath := args[0]
+		if _, err := os.Stat(configPath); err != nil {
+			log.Fatalf("Error Loading Configuration: %s\n", err)
+		}
+
+		config, errs := configuration.Read(configPath)
+		if len(errs) != 0 {
+			errors := ""
+			for _, err := range errs {
+				errors += fmt.Sprintf("\t%s\n", err.Error())
+			}
+			log.Fatalf("Errors occurred parsing configuration:\n%s", errors)
+		}
+
+		provider := storage.NewProvider(config.Storage)
+		if provider == nil {
+			log.Fatal("Unrecognized storage backend")
+		}
+
+		count, err := regulation.NewRegulator(config.Regulation, provider, utils.RealClock{}).Reset(username)
+		if err != nil {
+			log.Fatalf("Error occurred resetting the regulation of user %s: %s", username, err)
+		}
+
+		log.Printf("Regulation of user %s reset by %s, %d failed authentication attempt(s) removed.\n",
+			username, operatorName(), count)
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// operatorName returns the name of the system user running the command for audit purposes.
+func operatorName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return "an unknown system user"
+}
@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// backupPassphraseEnv is the environment variable holding the passphrase of the backups when it is not given as a flag,
+// which keeps it out of the shell history and of the process list.
+const backupPassphraseEnv = "AUTHELIA_STORAGE_BACKUP_PASSPHRASE"
+
+func init() {
+	StorageReencryptCmd.Flags().IntP("batch-size", "b", 100, "the number of secrets re-encrypted at once")
+
+	StorageExportCmd.Flags().StringP("file", "f", "", "the path of the backup file to create")
+	StorageExportCmd.Flags().StringP("passphrase", "p", "", "the passphrase encrypting the backup, defaults to the "+backupPassphraseEnv+" environment variable")
+
+	StorageImportCmd.Flags().StringP("file", "f", "", "the path of the backup file to import")
+	StorageImportCmd.Flags().StringP("passphrase", "p", "", "the passphrase of the backup, defaults to the "+backupPassphraseEnv+" environment variable")
+	StorageImportCmd.Flags().Bool("force", false, "import the backup even if the storage already holds second factor enrollments")
+
+	StorageCmd.AddCommand(StoragePruneCmd, StorageReencryptCmd, StorageExportCmd, StorageImportCmd)
+}
+
+// StorageCmd is the command grouping the storage related commands.
+var StorageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Manage the storage backend.",
+}
+
+// StoragePruneCmd removes the expired records from the storage backend configured in the given configuration.
+var StoragePruneCmd = &cobra.Command{
+	Use:   "prune [yaml]",
+	Short: "Remove the expired identity verification tokens and old authentication logs from the storage backend.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		configPath := args[0]
+		if _, err := os.Stat(configPath); err != nil {
+			log.Fatalf("Error Loading Configuration: %s\n", err)
+		}
+
+		config, errs := configuration.Read(configPath)
+		if len(errs) != 0 {
+			errors := ""
+			for _, err := range errs {
+				errors += fmt.Sprintf("\t%s\n", err.Error())
+			}
+			log.Fatalf("Errors occurred parsing configuration:\n%s", errors)
+		}
+
+		provider := storage.NewProvider(config.Storage)
+		if provider == nil {
+			log.Fatal("Unrecognized storage backend")
+		}
+
+		pruner, err := storage.NewPruner(provider, config.Storage.Pruning, utils.RealClock{})
+		if err != nil {
+			log.Fatalf("Unable to configure the storage pruning: %s", err)
+		}
+
+		report, err := pruner.Prune()
+		if err != nil {
+			log.Fatalf("Error occurred pruning the storage: %s", err)
+		}
+
+		log.Printf("Removed %d identity verification token(s), %d authentication log(s) and %d login device(s).\n",
+			report.IdentityVerificationTokens, report.AuthenticationLogs, report.LoginDevices)
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// StorageReencryptCmd encrypts the secrets stored in clear or with a previous key with the current encryption key of
+// the storage backend configured in the given configuration.
+var StorageReencryptCmd = &cobra.Command{
+	Use:   "reencrypt [yaml]",
+	Short: "Re-encrypt the secrets of the storage backend with the current encryption key after a key rotation.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		batchSize, _ := cobraCmd.Flags().GetInt("batch-size")
+		if batchSize <= 0 {
+			log.Fatal("The batch size must be above 0")
+		}
+
+		configPath := args[0]
+		if _, err := os.Stat(configPath); err != nil {
+			log.Fatalf("Error Loading Configuration: %s\n", err)
+		}
+
+		config, errs := configuration.Read(configPath)
+		if len(errs) != 0 {
+			errors := ""
+			for _, err := range errs {
+				errors += fmt.Sprintf("\t%s\n", err.Error())
+			}
+			log.Fatalf("Errors occurred parsing configuration:\n%s", errors)
+		}
+
+		if config.Storage.EncryptionKey == "" {
+			log.Fatal("A storage encryption_key must be configured to re-encrypt the secrets")
+		}
+
+		provider := storage.NewProvider(config.Storage)
+		if provider == nil {
+			log.Fatal("Unrecognized storage backend")
+		}
+
+		count, err := provider.ReencryptTOTPSecrets(batchSize)
+		if err != nil {
+			log.Fatalf("Error occurred re-encrypting the TOTP secrets after %d of them: %s", count, err)
+		}
+
+		log.Printf("Re-encrypted %d TOTP secret(s).\n", count)
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// StorageExportCmd exports the second factor devices and preferences of the users to an encrypted backup file.
+var StorageExportCmd = &cobra.Command{
+	Use:   "export [yaml]",
+	Short: "Export the TOTP secrets, U2F devices and second factor preferences of the users to an encrypted backup file.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		path, passphrase := backupFlags(cobraCmd)
+
+		provider := loadStorageProvider(args[0])
+
+		data, count, err := storage.ExportBackup(provider, passphrase, time.Now())
+		if err != nil {
+			log.Fatalf("Error occurred exporting the backup: %s", err)
+		}
+
+		if err = ioutil.WriteFile(path, data, 0600); err != nil {
+			log.Fatalf("Unable to write the backup file: %s", err)
+		}
+
+		log.Printf("Exported the second factor enrollments of %d user(s) to %s.\n", count, path)
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// StorageImportCmd restores the second factor devices and preferences of the users from an encrypted backup file.
+var StorageImportCmd = &cobra.Command{
+	Use:   "import [yaml]",
+	Short: "Import the TOTP secrets, U2F devices and second factor preferences of the users from an encrypted backup file.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		path, passphrase := backupFlags(cobraCmd)
+		force, _ := cobraCmd.Flags().GetBool("force")
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Unable to read the backup file: %s", err)
+		}
+
+		provider := loadStorageProvider(args[0])
+
+		count, err := storage.ImportBackup(provider, data, passphrase, force, time.Now())
+		if errors.Is(err, storage.ErrStorageNotEmpty) {
+			log.Fatalf("%s, use --force to replace the enrollments of the users of the backup", err)
+		}
+
+		if err != nil {
+			log.Fatalf("Error occurred importing the backup: %s", err)
+		}
+
+		log.Printf("Imported the second factor enrollments of %d user(s) from %s.\n", count, path)
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// backupFlags returns the path and the passphrase of the backup given to the export and import commands.
+func backupFlags(cobraCmd *cobra.Command) (path, passphrase string) {
+	path, _ = cobraCmd.Flags().GetString("file")
+	if path == "" {
+		log.Fatal("The path of the backup file must be given with --file")
+	}
+
+	passphrase, _ = cobraCmd.Flags().GetString("passphrase")
+	if passphrase == "" {
+		passphrase = os.Getenv(backupPassphraseEnv)
+	}
+
+	if passphrase == "" {
+		log.Fatalf("The passphrase of the backup must be given with --passphrase or the %s environment variable", backupPassphraseEnv)
+	}
+
+	return path, passphrase
+}
+
+// loadStorageProvider returns the storage provider configured in the given configuration.
+func loadStorageProvider(configPath string) storage.Provider {
+	if _, err := os.Stat(configPath); err != nil {
+		log.Fatalf("Error Loading Configuration: %s\n", err)
+	}
+
+	config, errs := configuration.Read(configPath)
+	if len(errs) != 0 {
+		messages := ""
+		for _, err := range errs {
+			messages += fmt.Sprintf("\t%s\n", err.Error())
+		}
+		log.Fatalf("Errors occurred parsing configuration:\n%s", messages)
+	}
+
+	provider := storage.NewProvider(config.Storage)
+	if provider == nil {
+		log.Fatal("Unrecognized storage backend")
+	}
+
+	return provider
+}
@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+const minimumTOTPSecretLength = 10
+
+func init() {
+	StorageTOTPImportCmd.Flags().StringP("username", "u", "", "the username of the user to import the TOTP secret for")
+	StorageTOTPImportCmd.Flags().StringP("secret", "s", "", "the base32 encoded TOTP secret")
+	StorageTOTPImportCmd.Flags().StringP("file", "f", "", "a file containing one username,secret pair per line to import in bulk")
+	StorageTOTPImportCmd.Flags().IntP("period", "p", 0, "the period of the TOTP secret in seconds, defaults to the configured period")
+	StorageTOTPImportCmd.Flags().IntP("digits", "d", 6, "the number of digits of the TOTP codes")
+	StorageTOTPImportCmd.Flags().StringP("algorithm", "a", "SHA1", "the algorithm of the TOTP secret")
+
+	StorageCmd.AddCommand(StorageTOTPImportCmd)
+}
+
+type totpImport struct {
+	username string
+	secret   string
+}
+
+// StorageTOTPImportCmd imports existing TOTP secrets in the storage backend configured in the given configuration, the
+// users being considered enrolled.
+var StorageTOTPImportCmd = &cobra.Command{
+	Use:   "totp-import [yaml]",
+	Short: "Import existing TOTP secrets of users migrating from another system.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		username, _ := cobraCmd.Flags().GetString("username")
+		secret, _ := cobraCmd.Flags().GetString("secret")
+		file, _ := cobraCmd.Flags().GetString("file")
+		period, _ := cobraCmd.Flags().GetInt("period")
+		digits, _ := cobraCmd.Flags().GetInt("digits")
+		algorithm, _ := cobraCmd.Flags().GetString("algorithm")
+
+		configPath := args[0]
+		if _, err := os.Stat(configPath); err != nil {
+			log.Fatalf("Error Loading Configuration: %s\n", err)
+		}
+
+		config, errs := configuration.Read(configPath)
+		if len(errs) != 0 {
+			errors := ""
+			for _, err := range errs {
+				errors += fmt.Sprintf("\t%s\n", err.Error())
+			}
+			log.Fatalf("Errors occurred parsing configuration:\n%s", errors)
+		}
+
+		if err := validateTOTPImportParameters(config.TOTP, period, digits, algorithm); err != nil {
+			log.Fatalf("Unable to import TOTP secrets: %s", err)
+		}
+
+		var imports []totpImport
+
+		switch {
+		case file != "" && (username != "" || secret != ""):
+			log.Fatal("Either provide a file or a username and a secret, not both")
+		case file != "":
+			f, err := os.Open(file)
+			if err != nil {
+				log.Fatalf("Unable to open the TOTP secrets file: %s", err)
+			}
+
+			imports, err = readTOTPImports(f)
+			f.Close()
+
+			if err != nil {
+				log.Fatalf("Unable to read the TOTP secrets file: %s", err)
+			}
+		case username != "" && secret != "":
+			imports = []totpImport{{username: username, secret: secret}}
+		default:
+			log.Fatal("A username and a secret or a file must be provided")
+		}
+
+		for i, imp := range imports {
+			normalized, err := normalizeTOTPSecret(imp.secret)
+			if err != nil {
+				log.Fatalf("Invalid TOTP secret for user %s: %s", imp.username, err)
+			}
+
+			imports[i].secret = normalized
+		}
+
+		provider := storage.NewProvider(config.Storage)
+		if provider == nil {
+			log.Fatal("Unrecognized storage backend")
+		}
+
+		for _, imp := range imports {
+			if err := provider.SaveTOTPSecret(imp.username, imp.secret); err != nil {
+				log.Fatalf("Unable to save the TOTP secret of user %s: %s", imp.username, err)
+			}
+
+			// Revoke the sessions elevated with the replaced secret.
+			if err := provider.SaveSecondFactorVersion(imp.username, time.Now().UnixNano()); err != nil {
+				log.Fatalf("Unable to save the second factor version of user %s: %s", imp.username, err)
+			}
+		}
+
+		log.Printf("Imported the TOTP secret of %d user(s).\n", len(imports))
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// validateTOTPImportParameters checks the parameters of imported secrets are the ones Authelia validates codes with.
+func validateTOTPImportParameters(config *schema.TOTPConfiguration, period, digits int, algorithm string) error {
+	configuredPeriod := schema.DefaultTOTPConfiguration.Period
+	if config != nil && config.Period != 0 {
+		configuredPeriod = config.Period
+	}
+
+	if period != 0 && period != configuredPeriod {
+		return fmt.Errorf("the period %d is not supported, it must match the configured period of %d", period, configuredPeriod)
+	}
+
+	if digits != 6 {
+		return fmt.Errorf("%d digits are not supported, only 6 digits are", digits)
+	}
+
+	if !strings.EqualFold(algorithm, "SHA1") {
+		return fmt.Errorf("the algorithm %s is not supported, only SHA1 is", algorithm)
+	}
+
+	return nil
+}
+
+// normalizeTOTPSecret checks the secret is valid base32 and returns it upper cased without spaces nor padding.
+func normalizeTOTPSecret(secret string) (string, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return "", errors.New("the secret is not valid base32")
+	}
+
+	if len(decoded) < minimumTOTPSecretLength {
+		return "", fmt.Errorf("the secret must be at least %d bytes long", minimumTOTPSecretLength)
+	}
+
+	return secret, nil
+}
+
+// readTOTPImports reads the username,secret pairs to import, ignoring empty lines and lines starting with #.
+func readTOTPImports(reader io.Reader) (imports []totpImport, err error) {
+	scanner := bufio.NewScanner(reader)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.Split(text, ",")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("line %d must be of the form username,secret", line)
+		}
+
+		imports = append(imports, totpImport{username: strings.TrimSpace(parts[0]), secret: strings.TrimSpace(parts[1])})
+	}
+
+	return imports, scanner.Err()
+}
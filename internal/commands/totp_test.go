@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldNormalizeTOTPSecret(t *testing.T) {
+	secret, err := normalizeTOTPSecret("jbsw y3dp ehpk 3pxp====")
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+}
+
+func TestShouldRejectInvalidTOTPSecret(t *testing.T) {
+	_, err := normalizeTOTPSecret("not-base32!")
+	assert.EqualError(t, err, "the secret is not valid base32")
+
+	_, err = normalizeTOTPSecret("JBSWY3DP")
+	assert.EqualError(t, err, "the secret must be at least 10 bytes long")
+}
+
+func TestShouldValidateTOTPImportParameters(t *testing.T) {
+	config := &schema.TOTPConfiguration{Period: 60}
+
+	assert.NoError(t, validateTOTPImportParameters(config, 0, 6, "sha1"))
+	assert.NoError(t, validateTOTPImportParameters(config, 60, 6, "SHA1"))
+	assert.NoError(t, validateTOTPImportParameters(nil, 30, 6, "SHA1"))
+
+	assert.EqualError(t, validateTOTPImportParameters(config, 30, 6, "SHA1"), "the period 30 is not supported, it must match the configured period of 60")
+	assert.EqualError(t, validateTOTPImportParameters(config, 0, 8, "SHA1"), "8 digits are not supported, only 6 digits are")
+	assert.EqualError(t, validateTOTPImportParameters(config, 0, 6, "SHA256"), "the algorithm SHA256 is not supported, only SHA1 is")
+}
+
+func TestShouldReadTOTPImports(t *testing.T) {
+	imports, err := readTOTPImports(strings.NewReader("# username,secret\njohn, JBSWY3DPEHPK3PXP\n\nharry,KRSXG5CTMVRXEZLU\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []totpImport{
+		{username: "john", secret: "JBSWY3DPEHPK3PXP"},
+		{username: "harry", secret: "KRSXG5CTMVRXEZLU"},
+	}, imports)
+
+	_, err = readTOTPImports(strings.NewReader("john\n"))
+	assert.EqualError(t, err, "line 1 must be of the form username,secret")
+}
@@ -8,13 +8,24 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/configuration/validator"
 )
 
+func init() {
+	ValidateConfigCmd.Flags().Bool("rules", false, "validate the given files as access control rules files instead of configurations")
+}
+
 // ValidateConfigCmd uses the internal configuration reader to validate the configuration.
 var ValidateConfigCmd = &cobra.Command{
 	Use:   "validate-config [yaml]",
 	Short: "Check a configuration against the internal configuration validation mechanisms.",
 	Run: func(cobraCmd *cobra.Command, args []string) {
+		if rules, _ := cobraCmd.Flags().GetBool("rules"); rules {
+			validateRulesFiles(args)
+			return
+		}
+
 		configPath := args[0]
 		if _, err := os.Stat(configPath); err != nil {
 			log.Fatalf("Error Loading Configuration: %s\n", err)
@@ -23,18 +34,64 @@ var ValidateConfigCmd = &cobra.Command{
 		// TODO: Actually use the configuration to validate some providers like Notifier
 		_, errs := configuration.Read(configPath)
 		if len(errs) != 0 {
-			str := "Errors"
-			if len(errs) == 1 {
-				str = "Error"
-			}
-			errors := ""
-			for _, err := range errs {
-				errors += fmt.Sprintf("\t%s\n", err.Error())
-			}
-			log.Fatalf("%s occurred parsing configuration:\n%s", str, errors)
+			log.Fatalf("%s occurred parsing configuration:\n%s", errorsTitle(errs), formatErrors(errs))
 		} else {
 			log.Println("Configuration parsed successfully without errors.")
 		}
 	},
 	Args: cobra.MinimumNArgs(1),
 }
+
+// validateRulesFiles validates each access control rules file on its own.
+func validateRulesFiles(paths []string) {
+	failed := false
+
+	for _, path := range paths {
+		rules, err := configuration.ReadAccessControlRulesFile(path)
+		if err != nil {
+			log.Printf("Error occurred parsing access control rules file %s:\n\t%s\n", path, err)
+
+			failed = true
+
+			continue
+		}
+
+		val := schema.NewStructValidator()
+		validator.ValidateRulesFile(rules, val)
+
+		for _, warn := range val.Warnings() {
+			log.Printf("Warning for access control rules file %s: %s\n", path, warn)
+		}
+
+		if val.HasErrors() {
+			log.Printf("%s occurred parsing access control rules file %s:\n%s", errorsTitle(val.Errors()), path, formatErrors(val.Errors()))
+
+			failed = true
+
+			continue
+		}
+
+		log.Printf("Access control rules file %s parsed successfully without errors.\n", path)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func errorsTitle(errs []error) string {
+	if len(errs) == 1 {
+		return "Error"
+	}
+
+	return "Errors"
+}
+
+func formatErrors(errs []error) string {
+	errors := ""
+	for _, err := range errs {
+		errors += fmt.Sprintf("\t%s\n", err.Error())
+	}
+
+	return errors
+}
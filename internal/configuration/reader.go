@@ -68,6 +68,10 @@ func Read(configPath string) (*schema.Configuration, []error) {
 
 	viper.Unmarshal(&configuration) //nolint:errcheck // TODO: Legacy code, consider refactoring time permitting.
 
+	if errs := readAccessControlRulesFiles(&configuration, configPath); len(errs) != 0 {
+		return nil, errs
+	}
+
 	val := schema.NewStructValidator()
 	validator.ValidateSecrets(&configuration, val, viper.GetViper())
 	validator.ValidateConfiguration(&configuration, val)
@@ -0,0 +1,55 @@
+package configuration
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ReadAccessControlRulesFile reads the access control rules of a rules file, a YAML file whose only key is rules.
+func ReadAccessControlRulesFile(path string) ([]schema.ACLRule, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("Unable to read access control rules file %s: %s", path, err)
+	}
+
+	for _, key := range v.AllKeys() {
+		if key != "rules" {
+			return nil, fmt.Errorf("Access control rules file %s has an invalid key: %s", path, key)
+		}
+	}
+
+	var rules []schema.ACLRule
+
+	if err := v.UnmarshalKey("rules", &rules); err != nil {
+		return nil, fmt.Errorf("Unable to parse access control rules file %s: %s", path, err)
+	}
+
+	return rules, nil
+}
+
+// readAccessControlRulesFiles appends in order the rules of the access control rules files to the rules of the
+// configuration, the relative paths being resolved from the directory of the configuration file.
+func readAccessControlRulesFiles(configuration *schema.Configuration, configPath string) (errs []error) {
+	for _, path := range configuration.AccessControl.RulesFiles {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(configPath), path)
+		}
+
+		rules, err := ReadAccessControlRulesFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		configuration.AccessControl.Rules = append(configuration.AccessControl.Rules, rules...)
+	}
+
+	return errs
+}
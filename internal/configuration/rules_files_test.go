@@ -0,0 +1,72 @@
+package configuration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldReadAccessControlRulesFile(t *testing.T) {
+	dir := t.TempDir()
+
+	createTestingTempFile(t, dir, "team.yml", `
+rules:
+  - domain: team.example.com
+    policy: one_factor
+    subject: "group:team"
+  - domain:
+      - a.team.example.com
+      - b.team.example.com
+    policy: two_factor
+`)
+
+	rules, err := ReadAccessControlRulesFile(filepath.Join(dir, "team.yml"))
+	require.NoError(t, err)
+
+	require.Len(t, rules, 2)
+	assert.Equal(t, []string{"team.example.com"}, rules[0].Domains)
+	assert.Equal(t, [][]string{{"group:team"}}, rules[0].Subjects)
+	assert.Equal(t, []string{"a.team.example.com", "b.team.example.com"}, rules[1].Domains)
+	assert.Equal(t, "two_factor", rules[1].Policy)
+}
+
+func TestShouldRejectAccessControlRulesFileWithOtherKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	createTestingTempFile(t, dir, "team.yml", `
+default_policy: bypass
+rules:
+  - domain: team.example.com
+    policy: one_factor
+`)
+
+	path := filepath.Join(dir, "team.yml")
+
+	_, err := ReadAccessControlRulesFile(path)
+	assert.EqualError(t, err, "Access control rules file "+path+" has an invalid key: default_policy")
+}
+
+func TestShouldAppendRulesFilesRelativeToConfigurationFile(t *testing.T) {
+	dir := t.TempDir()
+
+	createTestingTempFile(t, dir, "a.yml", "rules:\n  - domain: a.example.com\n    policy: one_factor\n")
+	createTestingTempFile(t, dir, "b.yml", "rules:\n  - domain: b.example.com\n    policy: two_factor\n")
+
+	configuration := &schema.Configuration{}
+	configuration.AccessControl.Rules = []schema.ACLRule{{Domains: []string{"main.example.com"}, Policy: "bypass"}}
+	configuration.AccessControl.RulesFiles = []string{"a.yml", filepath.Join(dir, "b.yml"), "missing.yml"}
+
+	errs := readAccessControlRulesFiles(configuration, filepath.Join(dir, "configuration.yml"))
+
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "Unable to read access control rules file "+filepath.Join(dir, "missing.yml"))
+
+	require.Len(t, configuration.AccessControl.Rules, 3)
+	assert.Equal(t, []string{"main.example.com"}, configuration.AccessControl.Rules[0].Domains)
+	assert.Equal(t, []string{"a.example.com"}, configuration.AccessControl.Rules[1].Domains)
+	assert.Equal(t, []string{"b.example.com"}, configuration.AccessControl.Rules[2].Domains)
+}
@@ -2,9 +2,26 @@ package schema
 
 // AccessControlConfiguration represents the configuration related to ACLs.
 type AccessControlConfiguration struct {
-	DefaultPolicy string       `mapstructure:"default_policy"`
-	Networks      []ACLNetwork `mapstructure:"networks"`
-	Rules         []ACLRule    `mapstructure:"rules"`
+	DefaultPolicy            string       `mapstructure:"default_policy"`
+	AnonymousIdentity        string       `mapstructure:"anonymous_identity"`
+	AnonymousIdentityHeaders bool         `mapstructure:"anonymous_identity_headers"`
+	DeniedResponse           string       `mapstructure:"denied_response"`
+	DenyReasonHeader         string       `mapstructure:"deny_reason_header"`
+	PathNormalization        string       `mapstructure:"path_normalization"`
+	Networks                 []ACLNetwork `mapstructure:"networks"`
+	Rules                    []ACLRule    `mapstructure:"rules"`
+	RulesFiles               []string     `mapstructure:"rules_files"`
+
+	ExternalAuthorization *ExternalAuthorizationConfiguration `mapstructure:"external_authorization"`
+}
+
+// ExternalAuthorizationConfiguration represents the configuration of the webhook deciding the policy of the rules with
+// the external policy.
+type ExternalAuthorizationConfiguration struct {
+	URL           string `mapstructure:"url"`
+	Timeout       string `mapstructure:"timeout"`
+	CacheDuration string `mapstructure:"cache_duration"`
+	FailurePolicy string `mapstructure:"failure_policy"`
 }
 
 // ACLNetwork represents one ACL network group entry; "weak" coerces a single value into slice.
@@ -15,12 +32,36 @@ type ACLNetwork struct {
 
 // ACLRule represents one ACL rule entry; "weak" coerces a single value into slice.
 type ACLRule struct {
-	Domains   []string   `mapstructure:"domain,weak"`
-	Policy    string     `mapstructure:"policy"`
-	Subjects  [][]string `mapstructure:"subject,weak"`
-	Networks  []string   `mapstructure:"networks"`
-	Resources []string   `mapstructure:"resources"`
-	Methods   []string   `mapstructure:"methods"`
+	Domains           []string   `mapstructure:"domain,weak"`
+	Policy            string     `mapstructure:"policy"`
+	Subjects          [][]string `mapstructure:"subject,weak"`
+	Networks          []string   `mapstructure:"networks"`
+	Resources         []string   `mapstructure:"resources"`
+	Methods           []string   `mapstructure:"methods"`
+	AnonymousIdentity string     `mapstructure:"anonymous_identity"`
+	BasicAuth         bool       `mapstructure:"basic_auth"`
+	DeniedResponse    string     `mapstructure:"denied_response"`
+	RedirectURL       string     `mapstructure:"redirect_url"`
+	RequireFreshAuth  string     `mapstructure:"require_fresh_auth"`
+	Priority          int        `mapstructure:"priority"`
+
+	Attributes []ACLAttributeCondition `mapstructure:"attributes"`
+}
+
+// ACLAttributeCondition represents a condition of an ACL rule on an attribute of the user, i.e. an LDAP attribute listed
+// in the extra_attributes of the LDAP backend.
+type ACLAttributeCondition struct {
+	Attribute string   `mapstructure:"attribute"`
+	Operator  string   `mapstructure:"operator"`
+	Value     string   `mapstructure:"value"`
+	Values    []string `mapstructure:"values"`
+}
+
+// DefaultExternalAuthorizationConfiguration represents the default values of the ExternalAuthorizationConfiguration.
+var DefaultExternalAuthorizationConfiguration = ExternalAuthorizationConfiguration{
+	Timeout:       "2s",
+	CacheDuration: "30s",
+	FailurePolicy: "deny",
 }
 
 // DefaultACLNetwork represents the default configuration related to access control network group configuration.
@@ -2,29 +2,52 @@ package schema
 
 // LDAPAuthenticationBackendConfiguration represents the configuration related to LDAP server.
 type LDAPAuthenticationBackendConfiguration struct {
-	Implementation       string     `mapstructure:"implementation"`
-	URL                  string     `mapstructure:"url"`
-	BaseDN               string     `mapstructure:"base_dn"`
-	AdditionalUsersDN    string     `mapstructure:"additional_users_dn"`
-	UsersFilter          string     `mapstructure:"users_filter"`
-	AdditionalGroupsDN   string     `mapstructure:"additional_groups_dn"`
-	GroupsFilter         string     `mapstructure:"groups_filter"`
-	GroupNameAttribute   string     `mapstructure:"group_name_attribute"`
-	UsernameAttribute    string     `mapstructure:"username_attribute"`
-	MailAttribute        string     `mapstructure:"mail_attribute"`
-	DisplayNameAttribute string     `mapstructure:"display_name_attribute"`
-	User                 string     `mapstructure:"user"`
-	Password             string     `mapstructure:"password"`
-	StartTLS             bool       `mapstructure:"start_tls"`
-	TLS                  *TLSConfig `mapstructure:"tls"`
-	SkipVerify           *bool      `mapstructure:"skip_verify"`         // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.SkipVerify. TODO: Remove in 4.28.
-	MinimumTLSVersion    string     `mapstructure:"minimum_tls_version"` // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.MinimumVersion. TODO: Remove in 4.28.
+	Implementation                string                       `mapstructure:"implementation"`
+	URL                           string                       `mapstructure:"url"`
+	BaseDN                        string                       `mapstructure:"base_dn"`
+	AdditionalUsersDN             string                       `mapstructure:"additional_users_dn"`
+	UsersFilter                   string                       `mapstructure:"users_filter"`
+	AdditionalGroupsDN            string                       `mapstructure:"additional_groups_dn"`
+	GroupsFilter                  string                       `mapstructure:"groups_filter"`
+	Domain                        string                       `mapstructure:"domain"`
+	GroupNameAttribute            string                       `mapstructure:"group_name_attribute"`
+	UsernameAttribute             string                       `mapstructure:"username_attribute"`
+	MailAttribute                 string                       `mapstructure:"mail_attribute"`
+	DisplayNameAttribute          string                       `mapstructure:"display_name_attribute"`
+	DisplayNameFallbackAttributes []string                     `mapstructure:"display_name_fallback_attributes"`
+	MailFallbackAttributes        []string                     `mapstructure:"mail_fallback_attributes"`
+	ExtraAttributes               []string                     `mapstructure:"extra_attributes"`
+	DefaultGroups                 []string                     `mapstructure:"default_groups"`
+	User                          string                       `mapstructure:"user"`
+	Password                      string                       `mapstructure:"password"`
+	StartTLS                      bool                         `mapstructure:"start_tls"`
+	Proxy                         string                       `mapstructure:"proxy"`
+	TLS                           *TLSConfig                   `mapstructure:"tls"`
+	CircuitBreaker                *CircuitBreakerConfiguration `mapstructure:"circuit_breaker"`
+	Cache                         *LDAPCacheConfiguration      `mapstructure:"cache"`
+	SkipVerify                    *bool                        `mapstructure:"skip_verify"`         // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.SkipVerify. TODO: Remove in 4.28.
+	MinimumTLSVersion             string                       `mapstructure:"minimum_tls_version"` // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.MinimumVersion. TODO: Remove in 4.28.
+}
+
+// LDAPCacheConfiguration represents the configuration of the cache of the details of the users retrieved from LDAP.
+type LDAPCacheConfiguration struct {
+	TTL        string `mapstructure:"ttl"`
+	MaxEntries int    `mapstructure:"max_entries"`
 }
 
 // FileAuthenticationBackendConfiguration represents the configuration related to file-based backend.
 type FileAuthenticationBackendConfiguration struct {
-	Path     string                 `mapstructure:"path"`
-	Password *PasswordConfiguration `mapstructure:"password"`
+	Path             string                       `mapstructure:"path"`
+	Password         *PasswordConfiguration       `mapstructure:"password"`
+	PasswordHistory  PasswordHistoryConfiguration `mapstructure:"password_history"`
+	WarnOnDuplicates bool                         `mapstructure:"warn_on_duplicates"`
+	DefaultGroups    []string                     `mapstructure:"default_groups"`
+}
+
+// PasswordHistoryConfiguration represents the configuration related to the reuse of previous passwords.
+type PasswordHistoryConfiguration struct {
+	HistorySize int    `mapstructure:"history_size"`
+	MinAge      string `mapstructure:"min_age"`
 }
 
 // PasswordConfiguration represents the configuration related to password hashing.
@@ -41,6 +64,7 @@ type PasswordConfiguration struct {
 type AuthenticationBackendConfiguration struct {
 	DisableResetPassword bool                                    `mapstructure:"disable_reset_password"`
 	RefreshInterval      string                                  `mapstructure:"refresh_interval"`
+	DefaultGroups        []string                                `mapstructure:"default_groups"`
 	Ldap                 *LDAPAuthenticationBackendConfiguration `mapstructure:"ldap"`
 	File                 *FileAuthenticationBackendConfiguration `mapstructure:"file"`
 }
@@ -72,6 +96,11 @@ var DefaultPasswordSHA512Configuration = PasswordConfiguration{
 	Algorithm:  "sha512",
 }
 
+// DefaultPasswordHistoryConfiguration represents the default configuration related to the password history.
+var DefaultPasswordHistoryConfiguration = PasswordHistoryConfiguration{
+	MinAge: "0",
+}
+
 // DefaultLDAPAuthenticationBackendConfiguration represents the default LDAP config.
 var DefaultLDAPAuthenticationBackendConfiguration = LDAPAuthenticationBackendConfiguration{
 	Implementation:       LDAPImplementationCustom,
@@ -84,6 +113,12 @@ var DefaultLDAPAuthenticationBackendConfiguration = LDAPAuthenticationBackendCon
 	},
 }
 
+// DefaultLDAPCacheConfiguration represents the default values of the LDAPCacheConfiguration.
+var DefaultLDAPCacheConfiguration = LDAPCacheConfiguration{
+	TTL:        "1m",
+	MaxEntries: 1000,
+}
+
 // DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration represents the default LDAP config for the MSAD Implementation.
 var DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration = LDAPAuthenticationBackendConfiguration{
 	UsersFilter:          "(&(|({username_attribute}={input})({mail_attribute}={input}))(objectCategory=person)(objectClass=user)(!userAccountControl:1.2.840.113556.1.4.803:=2)(!pwdLastSet=0))",
@@ -0,0 +1,9 @@
+package schema
+
+// BreakGlassConfiguration represents the configuration of the local credential allowed to access the diagnostics when
+// the authentication backend and the storage are unavailable.
+type BreakGlassConfiguration struct {
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	Networks []string `mapstructure:"networks"`
+}
@@ -13,13 +13,26 @@ type Configuration struct {
 	LogFilePath           string `mapstructure:"log_file_path"`
 	JWTSecret             string `mapstructure:"jwt_secret"`
 	DefaultRedirectionURL string `mapstructure:"default_redirection_url"`
+	LogoutRedirectionURL  string `mapstructure:"logout_redirection_url"`
+	Default2FAMethod      string `mapstructure:"default_2fa_method"`
+
+	AllowedRedirectionDomains []string `mapstructure:"allowed_redirection_domains"`
 
 	AuthenticationBackend AuthenticationBackendConfiguration `mapstructure:"authentication_backend"`
 	Session               SessionConfiguration               `mapstructure:"session"`
 	TOTP                  *TOTPConfiguration                 `mapstructure:"totp"`
 	DuoAPI                *DuoAPIConfiguration               `mapstructure:"duo_api"`
+	OIDCUpstream          *OIDCUpstreamConfiguration         `mapstructure:"oidc_upstream"`
 	AccessControl         AccessControlConfiguration         `mapstructure:"access_control"`
 	Regulation            *RegulationConfiguration           `mapstructure:"regulation"`
+	BreakGlass            *BreakGlassConfiguration           `mapstructure:"break_glass"`
+	TrustedHeader         *TrustedHeaderConfiguration        `mapstructure:"trusted_header"`
+	LoginNotification     LoginNotificationConfiguration     `mapstructure:"login_notification"`
+	LastLogin             LastLoginConfiguration             `mapstructure:"last_login"`
+	SecurityDigest        *SecurityDigestConfiguration       `mapstructure:"security_digest"`
+	IdentityVerification  IdentityVerificationConfiguration  `mapstructure:"identity_verification"`
+	EmailVerification     EmailVerificationConfiguration     `mapstructure:"email_verification"`
+	Registration          RegistrationConfiguration          `mapstructure:"registration"`
 	Storage               StorageConfiguration               `mapstructure:"storage"`
 	Notifier              *NotifierConfiguration             `mapstructure:"notifier"`
 	Server                ServerConfiguration                `mapstructure:"server"`
@@ -23,3 +23,118 @@ const LDAPImplementationCustom = "custom"
 
 // LDAPImplementationActiveDirectory is the string for the Active Directory LDAP implementation.
 const LDAPImplementationActiveDirectory = "activedirectory"
+
+// StartupChecksFail represents a value for startup_checks that aborts the startup when a check fails.
+const StartupChecksFail = "fail"
+
+// StartupChecksWarn represents a value for startup_checks that only logs a warning when a check fails.
+const StartupChecksWarn = "warn"
+
+// StorageFailureModeClosed represents a value for failure_mode that denies authentication when the storage is unavailable.
+const StorageFailureModeClosed = "fail_closed"
+
+// StorageFailureModeOpen represents a value for failure_mode that lets authentication go on in a degraded mode when the
+// storage is unavailable.
+const StorageFailureModeOpen = "fail_open"
+
+// TrustedHeaderFailureModeClosed represents a value for the trusted header failure_mode that rejects the requests
+// carrying a trusted header when the key set can't be retrieved in time.
+const TrustedHeaderFailureModeClosed = "fail_closed"
+
+// TrustedHeaderFailureModeOpen represents a value for the trusted header failure_mode that ignores the trusted header
+// when the key set can't be retrieved in time, the requests being then handled like any request without it.
+const TrustedHeaderFailureModeOpen = "fail_open"
+
+// SecurityEventBan represents the security event of a user banned by the regulation.
+const SecurityEventBan = "ban"
+
+// SecurityEventAuthenticationFailure represents the security event of a failed authentication attempt.
+const SecurityEventAuthenticationFailure = "authentication_failure"
+
+// SecurityEventNotificationFailure represents the security event of a notification the notifier failed to send.
+const SecurityEventNotificationFailure = "notification_failure"
+
+// SecondFactorChangeDowngrade represents a value for second_factor_change that downgrades the elevated sessions to one
+// factor when the second factor devices of the user change.
+const SecondFactorChangeDowngrade = "downgrade"
+
+// SecondFactorChangeDestroy represents a value for second_factor_change that destroys the elevated sessions when the
+// second factor devices of the user change.
+const SecondFactorChangeDestroy = "destroy"
+
+// SecondFactorChangeIgnore represents a value for second_factor_change that keeps the elevated sessions when the second
+// factor devices of the user change.
+const SecondFactorChangeIgnore = "ignore"
+
+// SessionInactivityGracePolicyDestroy represents a value for inactivity_grace_policy that destroys the sessions inactive
+// for too long, the grace period being disabled.
+const SessionInactivityGracePolicyDestroy = "destroy"
+
+// SessionInactivityGracePolicyDowngrade represents a value for inactivity_grace_policy that downgrades the sessions
+// inactive for too long to one factor during the inactivity_grace_period instead of destroying them.
+const SessionInactivityGracePolicyDowngrade = "downgrade"
+
+// SessionDomainCheckError represents a value for domain_check that refuses to start when a protected domain can't
+// receive the session cookie.
+const SessionDomainCheckError = "error"
+
+// SessionDomainCheckWarn represents a value for domain_check that logs a warning when a protected domain can't receive
+// the session cookie.
+const SessionDomainCheckWarn = "warn"
+
+// SessionDomainCheckDisable represents a value for domain_check that disables the check of the protected domains.
+const SessionDomainCheckDisable = "disable"
+
+// VerifyProfileDefault represents the verify endpoint profile reading the target URL from the X-Original-URL header or
+// the X-Forwarded-* headers, and redirecting unauthorized users to the portal given in the rd parameter.
+const VerifyProfileDefault = "default"
+
+// VerifyProfileTraefik represents the verify endpoint profile of forward authentication proxies like Traefik, reading
+// the target URL from the X-Forwarded-* headers and redirecting unauthorized users to the portal given in the rd
+// parameter.
+const VerifyProfileTraefik = "traefik"
+
+// VerifyProfileNGINX represents the verify endpoint profile of the NGINX auth_request module, reading the target URL
+// from the X-Original-URL header and replying 401 to unauthorized users, the redirection being done by the proxy.
+const VerifyProfileNGINX = "nginx"
+
+// VerifyProfileHAProxy represents the verify endpoint profile of HAProxy, reading the target URL from the
+// X-Forwarded-* headers and replying 401 to unauthorized users, the redirection being done by the proxy.
+const VerifyProfileHAProxy = "haproxy"
+
+// UnixSocketHostPrefix is the prefix of a host value listening on a unix domain socket instead of a TCP port.
+const UnixSocketHostPrefix = "unix:"
+
+// DeniedResponseRedirect represents a value for denied_response that redirects the users to the portal when the proxy
+// provides its URL and the request is not an API request, and replies 401 otherwise.
+const DeniedResponseRedirect = "redirect"
+
+// DeniedResponseUnauthorized represents a value for denied_response that always replies 401 to the denied requests.
+const DeniedResponseUnauthorized = "unauthorized"
+
+// DeniedResponseForbidden represents a value for denied_response that always replies 403 to the denied requests.
+const DeniedResponseForbidden = "forbidden"
+
+// ACLAttributeOperatorEquals represents an operator of the attribute conditions matching when a value of the attribute
+// equals the value of the condition.
+const ACLAttributeOperatorEquals = "equals"
+
+// ACLAttributeOperatorIn represents an operator of the attribute conditions matching when a value of the attribute is
+// one of the values of the condition.
+const ACLAttributeOperatorIn = "in"
+
+// ACLAttributeOperatorRegex represents an operator of the attribute conditions matching when a value of the attribute
+// matches the regular expression of the condition.
+const ACLAttributeOperatorRegex = "regex"
+
+// PathNormalizationStrict represents a value for path_normalization that resolves the dot segments, collapses the
+// duplicate slashes and removes the trailing slash of the paths before matching the access control rules.
+const PathNormalizationStrict = "strict"
+
+// PathNormalizationPreserveTrailingSlash represents a value for path_normalization that resolves the dot segments and
+// collapses the duplicate slashes of the paths but keeps their trailing slash, it is the default.
+const PathNormalizationPreserveTrailingSlash = "preserve_trailing_slash"
+
+// PathNormalizationNone represents a value for path_normalization that matches the access control rules against the
+// paths exactly as requested.
+const PathNormalizationNone = "none"
@@ -0,0 +1,7 @@
+package schema
+
+// EmailVerificationConfiguration represents the configuration related to the verification of the email address of the
+// users before they can log in.
+type EmailVerificationConfiguration struct {
+	Enabled bool `mapstructure:"enabled"`
+}
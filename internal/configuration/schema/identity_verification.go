@@ -0,0 +1,12 @@
+package schema
+
+// IdentityVerificationConfiguration represents the configuration of the identity verification process sending the
+// users a link by email before resetting their password or registering a device.
+type IdentityVerificationConfiguration struct {
+	TokenLifetime string `mapstructure:"token_lifetime"`
+}
+
+// DefaultIdentityVerificationConfiguration represents the default values of the IdentityVerificationConfiguration.
+var DefaultIdentityVerificationConfiguration = IdentityVerificationConfiguration{
+	TokenLifetime: "5m",
+}
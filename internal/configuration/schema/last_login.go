@@ -0,0 +1,7 @@
+package schema
+
+// LastLoginConfiguration represents the configuration related to the last login of the users.
+type LastLoginConfiguration struct {
+	// The groups whose members are allowed to retrieve the last login of other users from the admin API.
+	AdminGroups []string `mapstructure:"admin_groups"`
+}
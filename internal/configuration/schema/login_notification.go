@@ -0,0 +1,9 @@
+package schema
+
+// LoginNotificationConfiguration represents the configuration related to the notification of logins from unrecognised
+// devices or IP addresses.
+type LoginNotificationConfiguration struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	RequireSecondFactor bool   `mapstructure:"require_second_factor"`
+	LocationHeader      string `mapstructure:"location_header"`
+}
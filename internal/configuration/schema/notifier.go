@@ -27,6 +27,7 @@ type NotifierConfiguration struct {
 	DisableStartupCheck bool                             `mapstructure:"disable_startup_check"`
 	FileSystem          *FileSystemNotifierConfiguration `mapstructure:"filesystem"`
 	SMTP                *SMTPNotifierConfiguration       `mapstructure:"smtp"`
+	CircuitBreaker      *CircuitBreakerConfiguration     `mapstructure:"circuit_breaker"`
 }
 
 // DefaultSMTPNotifierConfiguration represents default configuration parameters for the SMTP notifier.
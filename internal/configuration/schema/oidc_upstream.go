@@ -0,0 +1,45 @@
+package schema
+
+// OIDCUpstreamConfiguration represents the configuration of an upstream OpenID Connect provider users can
+// authenticate against as a first factor.
+type OIDCUpstreamConfiguration struct {
+	DisplayName  string                          `mapstructure:"display_name"`
+	Issuer       string                          `mapstructure:"issuer"`
+	ClientID     string                          `mapstructure:"client_id"`
+	ClientSecret string                          `mapstructure:"client_secret"`
+	RedirectURI  string                          `mapstructure:"redirect_uri"`
+	Scopes       []string                        `mapstructure:"scopes"`
+	Timeout      string                          `mapstructure:"timeout"`
+	Claims       OIDCUpstreamClaimsConfiguration `mapstructure:"claims"`
+
+	GroupAssignments []OIDCUpstreamGroupAssignmentConfiguration `mapstructure:"group_assignments"`
+}
+
+// OIDCUpstreamClaimsConfiguration represents the mapping of the ID token claims to the user session.
+type OIDCUpstreamClaimsConfiguration struct {
+	Username    string `mapstructure:"username"`
+	DisplayName string `mapstructure:"display_name"`
+	Email       string `mapstructure:"email"`
+	Groups      string `mapstructure:"groups"`
+}
+
+// OIDCUpstreamGroupAssignmentConfiguration represents groups assigned locally to the users authenticated by the
+// upstream provider whose username is either the given username or matches the given pattern.
+type OIDCUpstreamGroupAssignmentConfiguration struct {
+	Username        string   `mapstructure:"username"`
+	UsernamePattern string   `mapstructure:"username_pattern"`
+	Groups          []string `mapstructure:"groups"`
+}
+
+// DefaultOIDCUpstreamConfiguration represents the default values of the OIDCUpstreamConfiguration.
+var DefaultOIDCUpstreamConfiguration = OIDCUpstreamConfiguration{
+	DisplayName: "SSO",
+	Scopes:      []string{"openid", "profile", "email", "groups"},
+	Timeout:     "10s",
+	Claims: OIDCUpstreamClaimsConfiguration{
+		Username:    "sub",
+		DisplayName: "name",
+		Email:       "email",
+		Groups:      "groups",
+	},
+}
@@ -0,0 +1,11 @@
+package schema
+
+// RegistrationConfiguration represents the configuration related to the self-service registration of accounts in the
+// file authentication backend.
+type RegistrationConfiguration struct {
+	Enabled         bool     `mapstructure:"enabled"`
+	AllowedDomains  []string `mapstructure:"allowed_domains"`
+	RequireApproval bool     `mapstructure:"require_approval"`
+	AdminGroups     []string `mapstructure:"admin_groups"`
+	Groups          []string `mapstructure:"groups"`
+}
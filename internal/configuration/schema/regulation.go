@@ -5,11 +5,21 @@ type RegulationConfiguration struct {
 	MaxRetries int    `mapstructure:"max_retries"`
 	FindTime   string `mapstructure:"find_time"`
 	BanTime    string `mapstructure:"ban_time"`
+
+	// Each failed attempt since the last successful one delays the next attempts of the user by delay_increment, up
+	// to max_delay.
+	DelayIncrement string `mapstructure:"delay_increment"`
+	MaxDelay       string `mapstructure:"max_delay"`
+
+	// The groups whose members are allowed to reset the regulation of other users from the admin API.
+	AdminGroups []string `mapstructure:"admin_groups"`
 }
 
 // DefaultRegulationConfiguration represents default configuration parameters for the regulator.
 var DefaultRegulationConfiguration = RegulationConfiguration{
-	MaxRetries: 3,
-	FindTime:   "2m",
-	BanTime:    "5m",
+	MaxRetries:     3,
+	FindTime:       "2m",
+	BanTime:        "5m",
+	DelayIncrement: "0",
+	MaxDelay:       "10s",
 }
@@ -0,0 +1,17 @@
+package schema
+
+// SecurityDigestConfiguration represents the configuration of the periodic digest of the security events, such as the
+// bans and the failed notifications, sent to the administrators by email and/or to a webhook.
+type SecurityDigestConfiguration struct {
+	Interval        string   `mapstructure:"interval"`
+	Recipients      []string `mapstructure:"recipients"`
+	WebhookURL      string   `mapstructure:"webhook_url"`
+	Timeout         string   `mapstructure:"timeout"`
+	ImmediateEvents []string `mapstructure:"immediate_events"`
+}
+
+// DefaultSecurityDigestConfiguration represents the default values of the SecurityDigestConfiguration.
+var DefaultSecurityDigestConfiguration = SecurityDigestConfiguration{
+	Interval: "1d",
+	Timeout:  "5s",
+}
@@ -2,13 +2,118 @@ package schema
 
 // ServerConfiguration represents the configuration of the http server.
 type ServerConfiguration struct {
-	Path            string `mapstructure:"path"`
+	Path            string `mapstructure:"path"` // Deprecated: Replaced with BasePath. TODO: Remove in 4.30.
+	BasePath        string `mapstructure:"base_path"`
 	ReadBufferSize  int    `mapstructure:"read_buffer_size"`
 	WriteBufferSize int    `mapstructure:"write_buffer_size"`
+	StartupChecks   string `mapstructure:"startup_checks"`
+	ForwardedHops   int    `mapstructure:"forwarded_hops"`
+	UnixSocketMode  string `mapstructure:"unix_socket_mode"`
+	ExternalURL     string `mapstructure:"external_url"`
+
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	HTTPRedirect *ServerHTTPRedirectConfiguration `mapstructure:"http_redirect"`
+	APIRequests  ServerAPIRequestsConfiguration   `mapstructure:"api_requests"`
+	RequestID    ServerRequestIDConfiguration     `mapstructure:"request_id"`
+	CacheControl ServerCacheControlConfiguration  `mapstructure:"cache_control"`
+
+	RequestSampling ServerRequestSamplingConfiguration `mapstructure:"request_sampling"`
+
+	VerifyEndpoints []ServerVerifyEndpointConfiguration `mapstructure:"verify_endpoints"`
+}
+
+// ServerVerifyEndpointConfiguration represents an additional verify endpoint tailored to a kind of proxy, the profile
+// defining which headers the target URL is read from and whether unauthorized users are redirected to the portal.
+type ServerVerifyEndpointConfiguration struct {
+	Path    string                                   `mapstructure:"path"`
+	Profile string                                   `mapstructure:"profile"`
+	Headers ServerVerifyEndpointHeadersConfiguration `mapstructure:"headers"`
+}
+
+// ServerVerifyEndpointHeadersConfiguration represents the names of the headers forwarding the identity of the user to
+// the backends.
+type ServerVerifyEndpointHeadersConfiguration struct {
+	User   string `mapstructure:"user"`
+	Groups string `mapstructure:"groups"`
+	Name   string `mapstructure:"name"`
+	Email  string `mapstructure:"email"`
+}
+
+// ServerRequestIDConfiguration represents how each request is identified in the logs and in the response headers in
+// order to correlate the logs of the proxy, Authelia and the backends.
+type ServerRequestIDConfiguration struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	Headers        []string `mapstructure:"headers"`
+	ResponseHeader string   `mapstructure:"response_header"`
+}
+
+// ServerRequestSamplingConfiguration represents the sampling of the requests whose headers are logged in full along
+// with the response at the debug level, in order to diagnose intermittent issues of the integration with the proxies.
+type ServerRequestSamplingConfiguration struct {
+	Rate            float64  `mapstructure:"rate"`
+	MaxPerMinute    int      `mapstructure:"max_per_minute"`
+	RedactedHeaders []string `mapstructure:"redacted_headers"`
+}
+
+// ServerCacheControlConfiguration represents the Cache-Control policies of the responses, the sensitive policy applying
+// to the portal and the API and the static policy to the static assets of the portal.
+type ServerCacheControlConfiguration struct {
+	Sensitive string `mapstructure:"sensitive"`
+	Static    string `mapstructure:"static"`
+}
+
+// ServerAPIRequestsConfiguration represents how the verify endpoint detects requests made by scripts, which are
+// answered with a 401 instead of being redirected to the portal.
+type ServerAPIRequestsConfiguration struct {
+	RequestedWith bool     `mapstructure:"requested_with"`
+	AcceptTypes   []string `mapstructure:"accept_types"`
+}
+
+// ServerHTTPRedirectConfiguration represents the configuration of the listener redirecting HTTP requests to HTTPS.
+type ServerHTTPRedirectConfiguration struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
 }
 
 // DefaultServerConfiguration represents the default values of the ServerConfiguration.
 var DefaultServerConfiguration = ServerConfiguration{
 	ReadBufferSize:  4096,
 	WriteBufferSize: 4096,
+	StartupChecks:   StartupChecksFail,
+	UnixSocketMode:  "0660",
+}
+
+// DefaultServerRequestIDConfiguration represents the default values of the ServerRequestIDConfiguration.
+var DefaultServerRequestIDConfiguration = ServerRequestIDConfiguration{
+	Headers:        []string{"X-Request-ID", "X-Correlation-ID"},
+	ResponseHeader: "X-Request-ID",
+}
+
+// DefaultServerRequestSamplingConfiguration represents the default values of the ServerRequestSamplingConfiguration.
+var DefaultServerRequestSamplingConfiguration = ServerRequestSamplingConfiguration{
+	MaxPerMinute: 60,
+}
+
+// DefaultServerCacheControlConfiguration represents the default values of the ServerCacheControlConfiguration.
+var DefaultServerCacheControlConfiguration = ServerCacheControlConfiguration{
+	Sensitive: "no-store",
+}
+
+// DefaultServerVerifyEndpointConfiguration represents the default values of the ServerVerifyEndpointConfiguration,
+// which are the ones of the /api/verify endpoint.
+var DefaultServerVerifyEndpointConfiguration = ServerVerifyEndpointConfiguration{
+	Path:    "/api/verify",
+	Profile: VerifyProfileDefault,
+	Headers: ServerVerifyEndpointHeadersConfiguration{
+		User:   "Remote-User",
+		Groups: "Remote-Groups",
+		Name:   "Remote-Name",
+		Email:  "Remote-Email",
+	},
+}
+
+// DefaultServerHTTPRedirectConfiguration represents the default values of the ServerHTTPRedirectConfiguration.
+var DefaultServerHTTPRedirectConfiguration = ServerHTTPRedirectConfiguration{
+	Port: 80,
 }
@@ -28,21 +28,40 @@ type RedisSessionConfiguration struct {
 	HighAvailability         *RedisHighAvailabilityConfiguration `mapstructure:"high_availability"`
 }
 
+// MemorySessionConfiguration represents the configuration related to the in-memory session store.
+type MemorySessionConfiguration struct {
+	MaxSessions int `mapstructure:"max_sessions"`
+}
+
 // SessionConfiguration represents the configuration related to user sessions.
 type SessionConfiguration struct {
-	Name               string                     `mapstructure:"name"`
-	Secret             string                     `mapstructure:"secret"`
-	Expiration         string                     `mapstructure:"expiration"`
-	Inactivity         string                     `mapstructure:"inactivity"`
-	RememberMeDuration string                     `mapstructure:"remember_me_duration"`
-	Domain             string                     `mapstructure:"domain"`
-	Redis              *RedisSessionConfiguration `mapstructure:"redis"`
+	Name                   string                     `mapstructure:"name"`
+	Secret                 string                     `mapstructure:"secret"`
+	EncryptionKey          string                     `mapstructure:"encryption_key"`
+	PreviousEncryptionKeys []string                   `mapstructure:"previous_encryption_keys"`
+	Expiration             string                     `mapstructure:"expiration"`
+	Inactivity             string                     `mapstructure:"inactivity"`
+	InactivityGracePeriod  string                     `mapstructure:"inactivity_grace_period"`
+	InactivityGracePolicy  string                     `mapstructure:"inactivity_grace_policy"`
+	RememberMeDuration     string                     `mapstructure:"remember_me_duration"`
+	MaxSessionsPerUser     int                        `mapstructure:"max_sessions_per_user"`
+	SecondFactorChange     string                     `mapstructure:"second_factor_change"`
+	Domain                 string                     `mapstructure:"domain"`
+	DomainCheck            string                     `mapstructure:"domain_check"`
+	Redis                  *RedisSessionConfiguration `mapstructure:"redis"`
+	Memory                 MemorySessionConfiguration `mapstructure:"memory"`
 }
 
 // DefaultSessionConfiguration is the default session configuration.
 var DefaultSessionConfiguration = SessionConfiguration{
-	Name:               "authelia_session",
-	Expiration:         "1h",
-	Inactivity:         "5m",
-	RememberMeDuration: "1M",
+	Name:                  "authelia_session",
+	Expiration:            "1h",
+	Inactivity:            "5m",
+	InactivityGracePolicy: SessionInactivityGracePolicyDestroy,
+	RememberMeDuration:    "1M",
+	SecondFactorChange:    SecondFactorChangeDowngrade,
+	DomainCheck:           SessionDomainCheckWarn,
+	Memory: MemorySessionConfiguration{
+		MaxSessions: 10000,
+	},
 }
@@ -6,3 +6,15 @@ type TLSConfig struct {
 	SkipVerify     bool   `mapstructure:"skip_verify"`
 	ServerName     string `mapstructure:"server_name"`
 }
+
+// CircuitBreakerConfiguration represents the configuration of a circuit breaker protecting the calls to a backend.
+type CircuitBreakerConfiguration struct {
+	FailureThreshold int    `mapstructure:"failure_threshold"`
+	Cooldown         string `mapstructure:"cooldown"`
+}
+
+// DefaultCircuitBreakerConfiguration represents the default values of the CircuitBreakerConfiguration.
+var DefaultCircuitBreakerConfiguration = CircuitBreakerConfiguration{
+	FailureThreshold: 5,
+	Cooldown:         "30s",
+}
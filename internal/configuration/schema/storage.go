@@ -2,7 +2,13 @@ package schema
 
 // LocalStorageConfiguration represents the configuration when using local storage.
 type LocalStorageConfiguration struct {
-	Path string `mapstructure:"path"`
+	Path        string `mapstructure:"path"`
+	BusyTimeout string `mapstructure:"busy_timeout"`
+}
+
+// DefaultLocalStorageConfiguration represents the default values of the LocalStorageConfiguration.
+var DefaultLocalStorageConfiguration = LocalStorageConfiguration{
+	BusyTimeout: "5s",
 }
 
 // SQLStorageConfiguration represents the configuration of the SQL database.
@@ -12,6 +18,11 @@ type SQLStorageConfiguration struct {
 	Database string `mapstructure:"database"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
+
+	// The connection pool settings, 0 means the default of the database/sql package.
+	MaxOpenConns    int    `mapstructure:"max_open_conns"`
+	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime string `mapstructure:"conn_max_lifetime"`
 }
 
 // MySQLStorageConfiguration represents the configuration of a MySQL database.
@@ -25,9 +36,31 @@ type PostgreSQLStorageConfiguration struct {
 	SSLMode                 string `mapstructure:"sslmode"`
 }
 
+// StoragePruningConfiguration represents the configuration of the removal of expired records from the storage.
+type StoragePruningConfiguration struct {
+	Interval                    string `mapstructure:"interval"`
+	AuthenticationLogsRetention string `mapstructure:"authentication_logs_retention"`
+	LoginDevicesRetention       string `mapstructure:"login_devices_retention"`
+	BatchSize                   int    `mapstructure:"batch_size"`
+}
+
+// DefaultStoragePruningConfiguration represents the default values of the StoragePruningConfiguration.
+var DefaultStoragePruningConfiguration = StoragePruningConfiguration{
+	Interval:                    "0",
+	AuthenticationLogsRetention: "1M",
+	LoginDevicesRetention:       "1y",
+	BatchSize:                   1000,
+}
+
 // StorageConfiguration represents the configuration of the storage backend.
 type StorageConfiguration struct {
 	Local      *LocalStorageConfiguration      `mapstructure:"local"`
 	MySQL      *MySQLStorageConfiguration      `mapstructure:"mysql"`
 	PostgreSQL *PostgreSQLStorageConfiguration `mapstructure:"postgres"`
+	Pruning    *StoragePruningConfiguration    `mapstructure:"pruning"`
+
+	FailureMode string `mapstructure:"failure_mode"`
+
+	EncryptionKey          string   `mapstructure:"encryption_key"`
+	PreviousEncryptionKeys []string `mapstructure:"previous_encryption_keys"`
 }
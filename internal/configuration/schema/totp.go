@@ -5,6 +5,15 @@ type TOTPConfiguration struct {
 	Issuer string `mapstructure:"issuer"`
 	Period int    `mapstructure:"period"`
 	Skew   *int   `mapstructure:"skew"`
+
+	DomainIssuers []TOTPDomainIssuerConfiguration `mapstructure:"domain_issuers"`
+}
+
+// TOTPDomainIssuerConfiguration represents the issuer displayed to the users registering their application from the
+// portal served under the domain.
+type TOTPDomainIssuerConfiguration struct {
+	Domain string `mapstructure:"domain"`
+	Issuer string `mapstructure:"issuer"`
 }
 
 var defaultOtpSkew = 1
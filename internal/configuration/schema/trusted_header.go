@@ -0,0 +1,36 @@
+package schema
+
+// TrustedHeaderConfiguration represents the configuration of the header a trusted proxy, such as the sidecar of a
+// service mesh, uses to assert the identity of the user with a signed JWT.
+type TrustedHeaderConfiguration struct {
+	Header        string                           `mapstructure:"header"`
+	PublicKey     string                           `mapstructure:"public_key"`
+	JWKSURL       string                           `mapstructure:"jwks_url"`
+	Issuer        string                           `mapstructure:"issuer"`
+	Audience      string                           `mapstructure:"audience"`
+	Timeout       string                           `mapstructure:"timeout"`
+	LookupTimeout string                           `mapstructure:"lookup_timeout"`
+	FailureMode   string                           `mapstructure:"failure_mode"`
+	Claims        TrustedHeaderClaimsConfiguration `mapstructure:"claims"`
+}
+
+// TrustedHeaderClaimsConfiguration represents the mapping of the JWT claims to the identity of the user.
+type TrustedHeaderClaimsConfiguration struct {
+	Username    string `mapstructure:"username"`
+	DisplayName string `mapstructure:"display_name"`
+	Email       string `mapstructure:"email"`
+	Groups      string `mapstructure:"groups"`
+}
+
+// DefaultTrustedHeaderConfiguration represents the default values of the TrustedHeaderConfiguration.
+var DefaultTrustedHeaderConfiguration = TrustedHeaderConfiguration{
+	Timeout:       "10s",
+	LookupTimeout: "2s",
+	FailureMode:   TrustedHeaderFailureModeClosed,
+	Claims: TrustedHeaderClaimsConfiguration{
+		Username:    "sub",
+		DisplayName: "name",
+		Email:       "email",
+		Groups:      "groups",
+	},
+}
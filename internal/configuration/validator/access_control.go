@@ -3,6 +3,7 @@ package validator
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -10,9 +11,13 @@ import (
 	"github.com/authelia/authelia/internal/utils"
 )
 
+// aclAttributePlaceholderRegexp matches the references of the values of the attribute conditions to the named groups of
+// the resources of their rule.
+var aclAttributePlaceholderRegexp = regexp.MustCompile(`{([a-zA-Z0-9_]+)}`)
+
 // IsPolicyValid check if policy is valid.
 func IsPolicyValid(policy string) (isValid bool) {
-	return policy == denyPolicy || policy == "one_factor" || policy == "two_factor" || policy == bypassPolicy
+	return policy == denyPolicy || policy == oneFactorPolicy || policy == twoFactorPolicy || policy == bypassPolicy
 }
 
 // IsResourceValid check if a resource is valid.
@@ -49,12 +54,47 @@ func IsNetworkValid(network string) (isValid bool) {
 	return true
 }
 
+// IsDeniedResponseValid check if a denied response is valid, an empty one meaning the default one.
+func IsDeniedResponseValid(response string) (isValid bool) {
+	return response == "" || response == schema.DeniedResponseRedirect ||
+		response == schema.DeniedResponseUnauthorized || response == schema.DeniedResponseForbidden
+}
+
+// IsPathNormalizationValid check if a path normalization is valid, an empty one meaning the default one.
+func IsPathNormalizationValid(normalization string) (isValid bool) {
+	return normalization == "" || normalization == schema.PathNormalizationStrict ||
+		normalization == schema.PathNormalizationPreserveTrailingSlash || normalization == schema.PathNormalizationNone
+}
+
 // ValidateAccessControl validates access control configuration.
 func ValidateAccessControl(configuration schema.AccessControlConfiguration, validator *schema.StructValidator) {
 	if !IsPolicyValid(configuration.DefaultPolicy) {
 		validator.Push(fmt.Errorf("'default_policy' must either be 'deny', 'two_factor', 'one_factor' or 'bypass'"))
 	}
 
+	if !IsDeniedResponseValid(configuration.DeniedResponse) {
+		validator.Push(fmt.Errorf("'denied_response' must either be 'redirect', 'unauthorized' or 'forbidden'"))
+	}
+
+	if configuration.DenyReasonHeader != "" && !headerNameRegexp.MatchString(configuration.DenyReasonHeader) {
+		validator.Push(fmt.Errorf("The access control deny_reason_header '%s' must be a valid header name", configuration.DenyReasonHeader))
+	}
+
+	if !IsPathNormalizationValid(configuration.PathNormalization) {
+		validator.Push(fmt.Errorf("'path_normalization' must either be 'strict', 'preserve_trailing_slash' or 'none'"))
+	}
+
+	if configuration.ExternalAuthorization != nil {
+		validateExternalAuthorization(configuration.ExternalAuthorization, validator)
+	} else {
+		for _, r := range configuration.Rules {
+			if r.Policy == externalPolicy {
+				validator.Push(fmt.Errorf("The access control external_authorization must be configured when a rule uses the external policy"))
+				break
+			}
+		}
+	}
+
 	if configuration.Networks != nil {
 		for _, n := range configuration.Networks {
 			for _, networks := range n.Networks {
@@ -73,8 +113,8 @@ func ValidateRules(configuration schema.AccessControlConfiguration, validator *s
 			validator.Push(fmt.Errorf("No access control rules have been defined"))
 		}
 
-		if !IsPolicyValid(r.Policy) {
-			validator.Push(fmt.Errorf("Policy [%s] for domain: %s is invalid, a policy must either be 'deny', 'two_factor', 'one_factor' or 'bypass'", r.Policy, r.Domains))
+		if r.Policy != externalPolicy && !IsPolicyValid(r.Policy) {
+			validator.Push(fmt.Errorf("Policy [%s] for domain: %s is invalid, a policy must either be 'deny', 'two_factor', 'one_factor', 'bypass' or 'external'", r.Policy, r.Domains))
 		}
 
 		validateNetworks(r, configuration, validator)
@@ -85,9 +125,135 @@ func ValidateRules(configuration schema.AccessControlConfiguration, validator *s
 
 		validateMethods(r, validator)
 
+		validateAttributeConditions(r, validator)
+
 		if r.Policy == bypassPolicy && len(r.Subjects) != 0 {
 			validator.Push(fmt.Errorf(errAccessControlInvalidPolicyWithSubjects, r.Domains, r.Subjects))
 		}
+
+		if r.Policy == bypassPolicy && len(r.Attributes) != 0 {
+			validator.Push(fmt.Errorf(errAccessControlInvalidPolicyWithAttributes, r.Domains))
+		}
+
+		if r.AnonymousIdentity != "" && r.Policy != bypassPolicy {
+			validator.Push(fmt.Errorf(errAccessControlInvalidPolicyWithAnonymousIdentity, r.AnonymousIdentity, r.Domains))
+		}
+
+		if r.BasicAuth && r.Policy != oneFactorPolicy {
+			validator.Push(fmt.Errorf(errAccessControlInvalidPolicyWithBasicAuth, r.Domains, r.Policy))
+		}
+
+		if !IsDeniedResponseValid(r.DeniedResponse) {
+			validator.Push(fmt.Errorf("Denied response [%s] for domain: %s is invalid, it must either be 'redirect', 'unauthorized' or 'forbidden'", r.DeniedResponse, r.Domains))
+		}
+
+		validateRedirectURL(r, validator)
+
+		validateRequireFreshAuth(r, validator)
+	}
+
+	validatePriorities(configuration, validator)
+}
+
+// validateExternalAuthorization checks the configuration of the webhook deciding the policy of the external rules
+// and sets its defaults.
+func validateExternalAuthorization(configuration *schema.ExternalAuthorizationConfiguration, validator *schema.StructValidator) {
+	if configuration.URL == "" {
+		validator.Push(fmt.Errorf("The access control external_authorization url must be provided"))
+	} else if webhookURL, err := url.ParseRequestURI(configuration.URL); err != nil || (webhookURL.Scheme != "https" && webhookURL.Scheme != "http") || webhookURL.Host == "" {
+		validator.Push(fmt.Errorf("The access control external_authorization url '%s' must be an absolute http or https URL", configuration.URL))
+	}
+
+	if configuration.Timeout == "" {
+		configuration.Timeout = schema.DefaultExternalAuthorizationConfiguration.Timeout
+	} else if _, err := utils.ParseDurationString(configuration.Timeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing access control external_authorization timeout string: %s", err))
+	}
+
+	if configuration.CacheDuration == "" {
+		configuration.CacheDuration = schema.DefaultExternalAuthorizationConfiguration.CacheDuration
+	} else if _, err := utils.ParseDurationString(configuration.CacheDuration); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing access control external_authorization cache_duration string: %s", err))
+	}
+
+	if configuration.FailurePolicy == "" {
+		configuration.FailurePolicy = schema.DefaultExternalAuthorizationConfiguration.FailurePolicy
+	} else if !IsPolicyValid(configuration.FailurePolicy) {
+		validator.Push(fmt.Errorf("The access control external_authorization failure_policy must either be 'deny', 'two_factor', 'one_factor' or 'bypass'"))
+	}
+}
+
+// validateRequireFreshAuth checks the maximum age of the authentication required by a rule. Only the authentication of
+// the one_factor and two_factor rules can be required to be fresh.
+func validateRequireFreshAuth(rule schema.ACLRule, validator *schema.StructValidator) {
+	if rule.RequireFreshAuth == "" {
+		return
+	}
+
+	if maxAge, err := utils.ParseDurationString(rule.RequireFreshAuth); err != nil || maxAge <= 0 {
+		validator.Push(fmt.Errorf("Require fresh auth [%s] for domain: %s is invalid, it must be a positive duration", rule.RequireFreshAuth, rule.Domains))
+	}
+
+	if rule.Policy != oneFactorPolicy && rule.Policy != twoFactorPolicy {
+		validator.Push(fmt.Errorf("Require fresh auth for domain: %s is only supported with the 'one_factor' and 'two_factor' policies", rule.Domains))
+	}
+}
+
+func validateRedirectURL(rule schema.ACLRule, validator *schema.StructValidator) {
+	if rule.RedirectURL == "" {
+		return
+	}
+
+	if redirectURL, err := url.ParseRequestURI(rule.RedirectURL); err != nil || redirectURL.Scheme != "https" || redirectURL.Host == "" {
+		validator.Push(fmt.Errorf("Redirect URL [%s] for domain: %s is invalid, it must be an absolute https URL", rule.RedirectURL, rule.Domains))
+	}
+
+	if rule.DeniedResponse != "" && rule.DeniedResponse != schema.DeniedResponseRedirect {
+		validator.Push(fmt.Errorf("Redirect URL [%s] for domain: %s requires the denied response to be 'redirect'", rule.RedirectURL, rule.Domains))
+	}
+}
+
+// ValidateRulesFile validates the access control rules of a rules file on their own. The network groups are defined in
+// the main configuration so the networks which are neither an IP nor a CIDR are assumed to be network groups, a
+// warning being raised for each of them.
+func ValidateRulesFile(rules []schema.ACLRule, validator *schema.StructValidator) {
+	configuration := schema.AccessControlConfiguration{Rules: rules}
+
+	for _, r := range rules {
+		for _, network := range r.Networks {
+			if IsNetworkValid(network) || IsNetworkGroupValid(configuration, network) {
+				continue
+			}
+
+			validator.PushWarning(fmt.Errorf("Network %s for domain: %s is assumed to be a network group of the main configuration", network, r.Domains))
+			configuration.Networks = append(configuration.Networks, schema.ACLNetwork{Name: network})
+		}
+	}
+
+	ValidateRules(configuration, validator)
+}
+
+// validatePriorities warns about rules sharing a domain with the same explicit priority since the order of the rules
+// in the configuration is then used to choose between them.
+func validatePriorities(configuration schema.AccessControlConfiguration, validator *schema.StructValidator) {
+	for i, r := range configuration.Rules {
+		if r.Priority == 0 {
+			continue
+		}
+
+		for _, other := range configuration.Rules[i+1:] {
+			if other.Priority != r.Priority {
+				continue
+			}
+
+			for _, domain := range r.Domains {
+				if utils.IsStringInSliceFold(domain, other.Domains) {
+					validator.PushWarning(fmt.Errorf("Rules for domains %s and %s have the same priority %d, the first one in the configuration takes precedence",
+						r.Domains, other.Domains, r.Priority))
+					break
+				}
+			}
+		}
 	}
 }
 
@@ -126,3 +292,80 @@ func validateMethods(r schema.ACLRule, validator *schema.StructValidator) {
 		}
 	}
 }
+
+// validateAttributeConditions checks the operators and values of the attribute conditions of a rule. The values of the
+// equals and in operators can only reference the named groups defined by all the resources of the rule since the
+// resource matching the request provides them.
+func validateAttributeConditions(r schema.ACLRule, validator *schema.StructValidator) {
+	for i, condition := range r.Attributes {
+		if strings.TrimSpace(condition.Attribute) == "" {
+			validator.Push(fmt.Errorf("Attribute condition at position %d for domain: %s has no attribute", i+1, r.Domains))
+		}
+
+		var values []string
+
+		switch condition.Operator {
+		case schema.ACLAttributeOperatorEquals:
+			if condition.Value == "" || len(condition.Values) != 0 {
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s with the equals operator requires a value and no values", condition.Attribute, r.Domains))
+			}
+
+			values = []string{condition.Value}
+		case schema.ACLAttributeOperatorIn:
+			if len(condition.Values) == 0 || condition.Value != "" {
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s with the in operator requires values and no value", condition.Attribute, r.Domains))
+			}
+
+			values = condition.Values
+		case schema.ACLAttributeOperatorRegex:
+			if len(condition.Values) != 0 {
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s with the regex operator requires a value and no values", condition.Attribute, r.Domains))
+			} else if _, err := regexp.Compile(condition.Value); err != nil {
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s has an invalid regex, %s", condition.Attribute, r.Domains, err))
+			}
+		default:
+			validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s has an invalid operator %s, it must either be 'equals', 'in' or 'regex'",
+				condition.Attribute, r.Domains, condition.Operator))
+		}
+
+		validateAttributeConditionPlaceholders(r, condition, values, validator)
+	}
+}
+
+func validateAttributeConditionPlaceholders(r schema.ACLRule, condition schema.ACLAttributeCondition, values []string, validator *schema.StructValidator) {
+	for _, value := range values {
+		for _, match := range aclAttributePlaceholderRegexp.FindAllStringSubmatch(value, -1) {
+			if len(r.Resources) == 0 {
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s references the group %s but the rule has no resources", condition.Attribute, r.Domains, match[1]))
+				continue
+			}
+
+			for _, resource := range r.Resources {
+				pattern, err := regexp.Compile(resource)
+				if err != nil {
+					// The invalid resources are reported by validateResources.
+					continue
+				}
+
+				if !utils.IsStringInSlice(match[1], pattern.SubexpNames()) {
+					validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s references the group %s which is not defined by the resource %s", condition.Attribute, r.Domains, match[1], resource))
+				}
+			}
+		}
+	}
+}
+
+// validateAttributeConditionsBackend ensures the attributes the rules have conditions on are fetched from the LDAP
+// backend, the other attributes being always missing from the sessions.
+func validateAttributeConditionsBackend(configuration *schema.Configuration, validator *schema.StructValidator) {
+	for _, r := range configuration.AccessControl.Rules {
+		for _, condition := range r.Attributes {
+			switch {
+			case configuration.AuthenticationBackend.Ldap == nil:
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s requires the LDAP authentication backend", condition.Attribute, r.Domains))
+			case !utils.IsStringInSlice(condition.Attribute, configuration.AuthenticationBackend.Ldap.ExtraAttributes):
+				validator.Push(fmt.Errorf("Attribute condition on %s for domain: %s references an attribute which is not fetched, it must be listed in the LDAP extra_attributes", condition.Attribute, r.Domains))
+			}
+		}
+	}
+}
@@ -18,6 +18,10 @@ type AccessControl struct {
 func (suite *AccessControl) SetupTest() {
 	suite.validator = schema.NewStructValidator()
 	suite.configuration.DefaultPolicy = denyPolicy
+	suite.configuration.DeniedResponse = ""
+	suite.configuration.DenyReasonHeader = ""
+	suite.configuration.PathNormalization = ""
+	suite.configuration.ExternalAuthorization = nil
 	suite.configuration.Networks = schema.DefaultACLNetwork
 	suite.configuration.Rules = schema.DefaultACLRule
 }
@@ -65,7 +69,7 @@ func (suite *AccessControl) TestShouldRaiseErrorNoRulesDefined() {
 	suite.Require().Len(suite.validator.Errors(), 2)
 
 	suite.Assert().EqualError(suite.validator.Errors()[0], "No access control rules have been defined")
-	suite.Assert().EqualError(suite.validator.Errors()[1], "Policy [] for domain: [] is invalid, a policy must either be 'deny', 'two_factor', 'one_factor' or 'bypass'")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Policy [] for domain: [] is invalid, a policy must either be 'deny', 'two_factor', 'one_factor', 'bypass' or 'external'")
 }
 
 func (suite *AccessControl) TestShouldRaiseErrorInvalidPolicy() {
@@ -81,7 +85,7 @@ func (suite *AccessControl) TestShouldRaiseErrorInvalidPolicy() {
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Require().Len(suite.validator.Errors(), 1)
 
-	suite.Assert().EqualError(suite.validator.Errors()[0], "Policy [invalid] for domain: [public.example.com] is invalid, a policy must either be 'deny', 'two_factor', 'one_factor' or 'bypass'")
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Policy [invalid] for domain: [public.example.com] is invalid, a policy must either be 'deny', 'two_factor', 'one_factor', 'bypass' or 'external'")
 }
 
 func (suite *AccessControl) TestShouldRaiseErrorInvalidNetwork() {
@@ -155,6 +159,346 @@ func (suite *AccessControl) TestShouldRaiseErrorInvalidSubject() {
 	suite.Assert().EqualError(suite.validator.Errors()[1], fmt.Sprintf(errAccessControlInvalidPolicyWithSubjects, domains, subjects))
 }
 
+func (suite *AccessControl) TestShouldRaiseErrorAnonymousIdentityWithoutBypass() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:           []string{"public.example.com"},
+			Policy:            "bypass",
+			AnonymousIdentity: "visitor",
+		},
+		{
+			Domains:           []string{"secure.example.com"},
+			Policy:            "one_factor",
+			AnonymousIdentity: "visitor",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Anonymous identity visitor for domain [secure.example.com] is invalid, it is only supported with the bypass policy")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorBasicAuthWithoutOneFactor() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:   []string{"api.example.com"},
+			Policy:    "one_factor",
+			BasicAuth: true,
+		},
+		{
+			Domains:   []string{"secure.example.com"},
+			Policy:    "two_factor",
+			BasicAuth: true,
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Basic auth for domain [secure.example.com] is invalid with the policy two_factor, it is only supported with the one_factor policy")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidDeniedResponse() {
+	suite.configuration.DeniedResponse = "teapot"
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:        []string{"waf.example.com"},
+			Policy:         "two_factor",
+			DeniedResponse: "forbidden",
+		},
+		{
+			Domains:        []string{"secure.example.com"},
+			Policy:         "two_factor",
+			DeniedResponse: "403",
+		},
+	}
+
+	ValidateAccessControl(suite.configuration, suite.validator)
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "'denied_response' must either be 'redirect', 'unauthorized' or 'forbidden'")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Denied response [403] for domain: [secure.example.com] is invalid, it must either be 'redirect', 'unauthorized' or 'forbidden'")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidDenyReasonHeader() {
+	suite.configuration.DenyReasonHeader = "X-Deny Reason"
+
+	ValidateAccessControl(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "The access control deny_reason_header 'X-Deny Reason' must be a valid header name")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidPathNormalization() {
+	suite.configuration.PathNormalization = "clean"
+
+	ValidateAccessControl(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "'path_normalization' must either be 'strict', 'preserve_trailing_slash' or 'none'")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorExternalPolicyWithoutExternalAuthorization() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"app.example.com"},
+			Policy:  "external",
+		},
+	}
+
+	ValidateAccessControl(suite.configuration, suite.validator)
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "The access control external_authorization must be configured when a rule uses the external policy")
+}
+
+func (suite *AccessControl) TestShouldSetDefaultExternalAuthorizationValues() {
+	suite.configuration.ExternalAuthorization = &schema.ExternalAuthorizationConfiguration{URL: "https://authz.example.com/decide"}
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"app.example.com"},
+			Policy:  "external",
+		},
+	}
+
+	ValidateAccessControl(suite.configuration, suite.validator)
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+
+	suite.Assert().Equal("2s", suite.configuration.ExternalAuthorization.Timeout)
+	suite.Assert().Equal("30s", suite.configuration.ExternalAuthorization.CacheDuration)
+	suite.Assert().Equal("deny", suite.configuration.ExternalAuthorization.FailurePolicy)
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidExternalAuthorization() {
+	suite.configuration.ExternalAuthorization = &schema.ExternalAuthorizationConfiguration{
+		URL:           "authz.example.com/decide",
+		Timeout:       "2 seconds",
+		CacheDuration: "forever",
+		FailurePolicy: "external",
+	}
+
+	ValidateAccessControl(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 4)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "The access control external_authorization url 'authz.example.com/decide' must be an absolute http or https URL")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Error occurred parsing access control external_authorization timeout string: Could not convert the input string of 2 seconds into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Error occurred parsing access control external_authorization cache_duration string: Could not convert the input string of forever into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[3], "The access control external_authorization failure_policy must either be 'deny', 'two_factor', 'one_factor' or 'bypass'")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidRedirectURL() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:     []string{"app.example.com"},
+			Policy:      "one_factor",
+			RedirectURL: "https://login.example.com/app",
+		},
+		{
+			Domains:     []string{"legacy.example.com"},
+			Policy:      "one_factor",
+			RedirectURL: "http://login.example.com",
+		},
+		{
+			Domains:        []string{"waf.example.com"},
+			Policy:         "two_factor",
+			DeniedResponse: "forbidden",
+			RedirectURL:    "https://login.example.com/waf",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Redirect URL [http://login.example.com] for domain: [legacy.example.com] is invalid, it must be an absolute https URL")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Redirect URL [https://login.example.com/waf] for domain: [waf.example.com] requires the denied response to be 'redirect'")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidRequireFreshAuth() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:          []string{"admin.example.com"},
+			Policy:           "two_factor",
+			RequireFreshAuth: "5m",
+		},
+		{
+			Domains:          []string{"app.example.com"},
+			Policy:           "one_factor",
+			RequireFreshAuth: "5 minutes",
+		},
+		{
+			Domains:          []string{"public.example.com"},
+			Policy:           "bypass",
+			RequireFreshAuth: "5m",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Require fresh auth [5 minutes] for domain: [app.example.com] is invalid, it must be a positive duration")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Require fresh auth for domain: [public.example.com] is only supported with the 'one_factor' and 'two_factor' policies")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidAttributeConditions() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:   []string{"app.example.com"},
+			Policy:    "one_factor",
+			Resources: []string{"^/tenants/(?P<tenant>[^/]+)/.*$"},
+			Attributes: []schema.ACLAttributeCondition{
+				{Attribute: "department", Operator: "equals", Value: "{tenant}"},
+				{Attribute: "employeeType", Operator: "in", Values: []string{"staff", "intern"}},
+				{Attribute: "mail", Operator: "regex", Value: "@example\\.com$"},
+			},
+		},
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			Attributes: []schema.ACLAttributeCondition{
+				{Operator: "equals", Value: "staff"},
+				{Attribute: "department", Operator: "equals", Values: []string{"sales"}},
+				{Attribute: "department", Operator: "in", Value: "sales"},
+				{Attribute: "mail", Operator: "regex", Value: "^(abc$"},
+				{Attribute: "department", Operator: "contains", Value: "sales"},
+				{Attribute: "department", Operator: "equals", Value: "{tenant}"},
+			},
+		},
+		{
+			Domains:   []string{"report.example.com"},
+			Policy:    "bypass",
+			Resources: []string{"^/tenants/(?P<tenant>[^/]+)/.*$", "^/reports/.*$"},
+			Attributes: []schema.ACLAttributeCondition{
+				{Attribute: "department", Operator: "in", Values: []string{"{tenant}"}},
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 8)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Attribute condition at position 1 for domain: [admin.example.com] has no attribute")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Attribute condition on department for domain: [admin.example.com] with the equals operator requires a value and no values")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Attribute condition on department for domain: [admin.example.com] with the in operator requires values and no value")
+	suite.Assert().EqualError(suite.validator.Errors()[3], "Attribute condition on mail for domain: [admin.example.com] has an invalid regex, error parsing regexp: missing closing ): `^(abc$`")
+	suite.Assert().EqualError(suite.validator.Errors()[4], "Attribute condition on department for domain: [admin.example.com] has an invalid operator contains, it must either be 'equals', 'in' or 'regex'")
+	suite.Assert().EqualError(suite.validator.Errors()[5], "Attribute condition on department for domain: [admin.example.com] references the group tenant but the rule has no resources")
+	suite.Assert().EqualError(suite.validator.Errors()[6], "Attribute condition on department for domain: [report.example.com] references the group tenant which is not defined by the resource ^/reports/.*$")
+	suite.Assert().EqualError(suite.validator.Errors()[7], fmt.Sprintf(errAccessControlInvalidPolicyWithAttributes, []string{"report.example.com"}))
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorAttributeConditionsNotFetched() {
+	configuration := &schema.Configuration{
+		AccessControl: schema.AccessControlConfiguration{
+			Rules: []schema.ACLRule{
+				{
+					Domains: []string{"app.example.com"},
+					Policy:  "one_factor",
+					Attributes: []schema.ACLAttributeCondition{
+						{Attribute: "department", Operator: "equals", Value: "sales"},
+						{Attribute: "employeeType", Operator: "equals", Value: "staff"},
+					},
+				},
+			},
+		},
+		AuthenticationBackend: schema.AuthenticationBackendConfiguration{
+			Ldap: &schema.LDAPAuthenticationBackendConfiguration{ExtraAttributes: []string{"department"}},
+		},
+	}
+
+	validateAttributeConditionsBackend(configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Attribute condition on employeeType for domain: [app.example.com] references an attribute which is not fetched, it must be listed in the LDAP extra_attributes")
+
+	suite.validator = schema.NewStructValidator()
+	configuration.AuthenticationBackend.Ldap = nil
+
+	validateAttributeConditionsBackend(configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Attribute condition on department for domain: [app.example.com] requires the LDAP authentication backend")
+}
+
+func (suite *AccessControl) TestShouldValidateRulesFileAssumingNetworkGroups() {
+	ValidateRulesFile([]schema.ACLRule{
+		{
+			Domains:  []string{"team.example.com"},
+			Policy:   "one_factor",
+			Networks: []string{"10.0.0.0/8", "internal"},
+		},
+		{
+			Domains: []string{"team.example.com"},
+			Policy:  "invalid",
+		},
+	}, suite.validator)
+
+	suite.Require().Len(suite.validator.Warnings(), 1)
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Warnings()[0], "Network internal for domain: [team.example.com] is assumed to be a network group of the main configuration")
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Policy [invalid] for domain: [team.example.com] is invalid, a policy must either be 'deny', 'two_factor', 'one_factor', 'bypass' or 'external'")
+}
+
+func (suite *AccessControl) TestShouldRaiseWarningOnPriorityTie() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:  []string{"public.example.com", "docs.example.com"},
+			Policy:   "bypass",
+			Priority: 10,
+		},
+		{
+			Domains:  []string{"docs.example.com"},
+			Policy:   "one_factor",
+			Priority: 10,
+		},
+		{
+			Domains:  []string{"secure.example.com"},
+			Policy:   "two_factor",
+			Priority: 10,
+		},
+		{
+			Domains: []string{"docs.example.com"},
+			Policy:  "deny",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasErrors())
+	suite.Require().Len(suite.validator.Warnings(), 1)
+
+	suite.Assert().EqualError(suite.validator.Warnings()[0], "Rules for domains [public.example.com docs.example.com] and [docs.example.com] have the same priority 10, the first one in the configuration takes precedence")
+}
+
 func TestAccessControl(t *testing.T) {
 	suite.Run(t, new(AccessControl))
 }
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
 )
 
+var ldapFilterPlaceholderRegexp = regexp.MustCompile(`{([^{}()=]*)}`)
+
 //nolint:gocyclo // TODO: Consider refactoring/simplifying, time permitting.
 func validateFileAuthenticationBackend(configuration *schema.FileAuthenticationBackendConfiguration, validator *schema.StructValidator) {
 	if configuration.Path == "" {
@@ -70,6 +73,20 @@ func validateFileAuthenticationBackend(configuration *schema.FileAuthenticationB
 			}
 		}
 	}
+
+	validateFilePasswordHistory(&configuration.PasswordHistory, validator)
+}
+
+func validateFilePasswordHistory(configuration *schema.PasswordHistoryConfiguration, validator *schema.StructValidator) {
+	if configuration.HistorySize < 0 || configuration.HistorySize > passwordHistoryMaxSize {
+		validator.Push(fmt.Errorf("The password history_size must be between 0 and %d, you configured %d", passwordHistoryMaxSize, configuration.HistorySize))
+	}
+
+	if configuration.MinAge == "" {
+		configuration.MinAge = schema.DefaultPasswordHistoryConfiguration.MinAge
+	} else if _, err := utils.ParseDurationString(configuration.MinAge); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing password history min_age string: %s", err))
+	}
 }
 
 // Wrapper for test purposes to exclude the hostname from the return.
@@ -95,6 +112,44 @@ func validateLdapURL(ldapURL string, validator *schema.StructValidator) (finalUR
 	return parsedURL.String(), parsedURL.Hostname()
 }
 
+func validateLdapProxyURL(proxyURL string, validator *schema.StructValidator) {
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		validator.Push(fmt.Errorf("Unable to parse the URL of the LDAP proxy: %v", err))
+		return
+	}
+
+	if !(parsedURL.Scheme == schemeSOCKS5 || parsedURL.Scheme == schemeHTTP) {
+		validator.Push(errors.New("Unknown scheme for the LDAP proxy url, should be socks5:// or http://"))
+		return
+	}
+
+	if parsedURL.Hostname() == "" {
+		validator.Push(errors.New("The LDAP proxy url must contain a host"))
+	}
+}
+
+// validateLdapCache validates and update the configuration of the cache of the user details.
+func validateLdapCache(configuration *schema.LDAPCacheConfiguration, validator *schema.StructValidator) {
+	if configuration == nil {
+		return
+	}
+
+	if configuration.TTL == "" {
+		configuration.TTL = schema.DefaultLDAPCacheConfiguration.TTL
+	} else if ttl, err := utils.ParseDurationString(configuration.TTL); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing the LDAP cache ttl string: %s", err))
+	} else if ttl <= 0 || ttl > ldapCacheMaximumTTL {
+		validator.Push(fmt.Errorf("The LDAP cache ttl must be greater than 0 and at most %s but it is configured as %s", ldapCacheMaximumTTL, configuration.TTL))
+	}
+
+	if configuration.MaxEntries == 0 {
+		configuration.MaxEntries = schema.DefaultLDAPCacheConfiguration.MaxEntries
+	} else if configuration.MaxEntries < 0 {
+		validator.Push(fmt.Errorf("The LDAP cache max_entries must be greater than 0 but it is configured as %d", configuration.MaxEntries))
+	}
+}
+
 //nolint:gocyclo // TODO: Consider refactoring/simplifying, time permitting.
 func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
 	if configuration.Implementation == "" {
@@ -153,6 +208,17 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 		}
 	}
 
+	if configuration.Proxy != "" {
+		validateLdapProxyURL(configuration.Proxy, validator)
+	}
+
+	validateCircuitBreaker("LDAP", configuration.CircuitBreaker, validator)
+	validateLdapCache(configuration.Cache, validator)
+
+	validateLdapAttributeNames("display_name_fallback_attributes", configuration.DisplayNameFallbackAttributes, validator)
+	validateLdapAttributeNames("mail_fallback_attributes", configuration.MailFallbackAttributes, validator)
+	validateLdapAttributeNames("extra_attributes", configuration.ExtraAttributes, validator)
+
 	// TODO: see if it's possible to disable this check if disable_reset_password is set and when anonymous/user binding is supported (#101 and #387)
 	if configuration.User == "" {
 		validator.Push(errors.New("Please provide a user name to connect to the LDAP server"))
@@ -184,12 +250,48 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 			validator.Push(errors.New("Unable to detect {input} placeholder in users_filter, your configuration might be broken. " +
 				"Please review configuration options listed at https://docs.authelia.com/configuration/authentication/ldap.html"))
 		}
+
+		validateLdapFilterPlaceholders("users_filter", configuration.UsersFilter, validLdapUsersFilterPlaceholders, []string{"0"}, validator)
 	}
 
 	if configuration.GroupsFilter == "" {
 		validator.Push(errors.New("Please provide a groups filter with `groups_filter` attribute"))
-	} else if !strings.HasPrefix(configuration.GroupsFilter, "(") || !strings.HasSuffix(configuration.GroupsFilter, ")") {
-		validator.Push(errors.New("The groups filter should contain enclosing parenthesis. For instance cn={input} should be (cn={input})"))
+	} else {
+		if !strings.HasPrefix(configuration.GroupsFilter, "(") || !strings.HasSuffix(configuration.GroupsFilter, ")") {
+			validator.Push(errors.New("The groups filter should contain enclosing parenthesis. For instance cn={input} should be (cn={input})"))
+		}
+
+		validateLdapFilterPlaceholders("groups_filter", configuration.GroupsFilter, validLdapGroupsFilterPlaceholders, []string{"0", "1"}, validator)
+	}
+}
+
+// validateLdapAttributeNames ensures the attributes of a list are all named.
+func validateLdapAttributeNames(name string, attributes []string, validator *schema.StructValidator) {
+	for i, attribute := range attributes {
+		if strings.TrimSpace(attribute) == "" {
+			validator.Push(fmt.Errorf("The attribute at position %d of the LDAP %s is empty", i+1, name))
+		}
+	}
+}
+
+// validateDefaultGroups ensures the groups every authenticated user belongs to are all named.
+func validateDefaultGroups(name string, groups []string, validator *schema.StructValidator) {
+	for i, group := range groups {
+		if strings.TrimSpace(group) == "" {
+			validator.Push(fmt.Errorf("The default group at position %d of `%s` is empty", i+1, name))
+		}
+	}
+}
+
+// validateLdapFilterPlaceholders ensures the filter only references the placeholders available to it.
+func validateLdapFilterPlaceholders(name, filter string, placeholders, deprecated []string, validator *schema.StructValidator) {
+	for _, match := range ldapFilterPlaceholderRegexp.FindAllStringSubmatch(filter, -1) {
+		if utils.IsStringInSlice(match[1], placeholders) || utils.IsStringInSlice(match[1], deprecated) {
+			continue
+		}
+
+		validator.Push(fmt.Errorf("The %s references the undefined placeholder %s, the available placeholders are {%s}",
+			name, match[0], strings.Join(placeholders, "}, {")))
 	}
 }
 
@@ -249,10 +351,14 @@ func ValidateAuthenticationBackend(configuration *schema.AuthenticationBackendCo
 
 	if configuration.File != nil {
 		validateFileAuthenticationBackend(configuration.File, validator)
+		validateDefaultGroups("authentication_backend.file", configuration.File.DefaultGroups, validator)
 	} else if configuration.Ldap != nil {
 		validateLdapAuthenticationBackend(configuration.Ldap, validator)
+		validateDefaultGroups("authentication_backend.ldap", configuration.Ldap.DefaultGroups, validator)
 	}
 
+	validateDefaultGroups("authentication_backend", configuration.DefaultGroups, validator)
+
 	if configuration.RefreshInterval == "" {
 		configuration.RefreshInterval = schema.RefreshIntervalDefault
 	} else {
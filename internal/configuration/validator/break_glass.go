@@ -0,0 +1,57 @@
+package validator
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ValidateBreakGlass validates the break-glass credential, which must be hashed with argon2id and enough memory as it
+// is not protected by the regulation.
+func ValidateBreakGlass(configuration *schema.BreakGlassConfiguration, validator *schema.StructValidator) {
+	if configuration.Username == "" {
+		validator.Push(fmt.Errorf("Break-glass username must be provided"))
+	}
+
+	for i, network := range configuration.Networks {
+		if _, _, err := net.ParseCIDR(network); err == nil {
+			continue
+		}
+
+		ip := net.ParseIP(network)
+
+		switch {
+		case ip == nil:
+			validator.Push(fmt.Errorf("Break-glass network '%s' must be an IP address or a network in CIDR notation", network))
+		case ip.To4() != nil:
+			configuration.Networks[i] = network + "/32"
+		default:
+			configuration.Networks[i] = network + "/128"
+		}
+	}
+
+	if configuration.Password == "" {
+		validator.Push(fmt.Errorf("Break-glass password must be provided"))
+		return
+	}
+
+	hash, err := authentication.ParseHash(configuration.Password)
+
+	switch {
+	case err != nil:
+		validator.Push(fmt.Errorf("Break-glass password must be a password hash: %s", err))
+		return
+	case hash.Algorithm != authentication.HashingAlgorithmArgon2id:
+		validator.Push(fmt.Errorf("Break-glass password must be hashed with argon2id"))
+		return
+	}
+
+	if hash.Memory < breakGlassMinimumArgon2idMemory {
+		validator.Push(fmt.Errorf("Break-glass password hash must use at least %d KiB of memory, it uses %d KiB",
+			breakGlassMinimumArgon2idMemory, hash.Memory))
+	}
+
+	validator.PushWarning(fmt.Errorf("Break-glass credential of user '%s' is enabled, it bypasses the authentication backend and must only be configured for disaster recovery", configuration.Username))
+}
@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+const testBreakGlassHash = "$argon2id$v=19$m=65536,t=3,p=2$BpLnfgDsc2WD8F2q$o/vzA4myCqZZ36bUGsDY//8mKUYNZZaR0t4MFFSs+iM"
+
+func TestShouldValidateBreakGlassAndWarn(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateBreakGlass(&schema.BreakGlassConfiguration{Username: "breakglass", Password: testBreakGlassHash}, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	require.Len(t, validator.Warnings(), 1)
+	assert.EqualError(t, validator.Warnings()[0], "Break-glass credential of user 'breakglass' is enabled, it bypasses the authentication backend and must only be configured for disaster recovery")
+}
+
+func TestShouldRaiseErrorsWhenBreakGlassIsIncomplete(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateBreakGlass(&schema.BreakGlassConfiguration{}, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "Break-glass username must be provided")
+	assert.EqualError(t, validator.Errors()[1], "Break-glass password must be provided")
+}
+
+func TestShouldNormalizeBreakGlassNetworks(t *testing.T) {
+	validator := schema.NewStructValidator()
+	configuration := &schema.BreakGlassConfiguration{
+		Username: "breakglass",
+		Password: testBreakGlassHash,
+		Networks: []string{"10.0.0.0/8", "192.168.1.1", "fd00::1", "office"},
+	}
+
+	ValidateBreakGlass(configuration, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Break-glass network 'office' must be an IP address or a network in CIDR notation")
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::1/128", "office"}, configuration.Networks)
+}
+
+func TestShouldRaiseErrorWhenBreakGlassPasswordIsNotHashed(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateBreakGlass(&schema.BreakGlassConfiguration{Username: "breakglass", Password: "password"}, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.Contains(t, validator.Errors()[0].Error(), "Break-glass password must be a password hash: ")
+}
+
+func TestShouldRaiseErrorWhenBreakGlassPasswordIsHashedWithSHA512(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateBreakGlass(&schema.BreakGlassConfiguration{
+		Username: "breakglass",
+		Password: "$6$rounds=50000$aFr56HjK3DrB8t3S$zhPQiS85cgBlNhUKKE6n/AHMlpqrvYSnSL3fEVkK0yHFQ.oFFAd8D4OhPAy18K5U61Z2eBhxQXExGU/eknXlY1",
+	}, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Break-glass password must be hashed with argon2id")
+}
+
+func TestShouldRaiseErrorWhenBreakGlassPasswordHashIsWeak(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateBreakGlass(&schema.BreakGlassConfiguration{
+		Username: "breakglass",
+		Password: "$argon2id$v=19$m=1024,t=1,p=8$NEVOTmF1UHpWYkJQM2Jwcw$hhP9ae+A+ZLTDjINe+iTN5W/dK1Y5dr9Nnn0vmRjAzk",
+	}, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Break-glass password hash must use at least 65536 KiB of memory, it uses 1024 KiB")
+}
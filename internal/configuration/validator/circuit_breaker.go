@@ -0,0 +1,27 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// validateCircuitBreaker validates and update the circuit breaker configuration of the backend with the given name.
+func validateCircuitBreaker(name string, configuration *schema.CircuitBreakerConfiguration, validator *schema.StructValidator) {
+	if configuration == nil {
+		return
+	}
+
+	if configuration.FailureThreshold == 0 {
+		configuration.FailureThreshold = schema.DefaultCircuitBreakerConfiguration.FailureThreshold
+	} else if configuration.FailureThreshold < 0 {
+		validator.Push(fmt.Errorf("The %s circuit breaker failure_threshold must be greater than 0 but it is configured as %d", name, configuration.FailureThreshold))
+	}
+
+	if configuration.Cooldown == "" {
+		configuration.Cooldown = schema.DefaultCircuitBreakerConfiguration.Cooldown
+	} else if _, err := utils.ParseDurationString(configuration.Cooldown); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing the %s circuit breaker cooldown string: %s", name, err))
+	}
+}
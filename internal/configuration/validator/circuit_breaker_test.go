@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldSetDefaultCircuitBreakerValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	configuration := &schema.CircuitBreakerConfiguration{}
+
+	validateCircuitBreaker("LDAP", configuration, validator)
+
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.DefaultCircuitBreakerConfiguration, *configuration)
+}
+
+func TestShouldNotRequireCircuitBreaker(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	validateCircuitBreaker("LDAP", nil, validator)
+
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorsOnInvalidCircuitBreakerValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	configuration := &schema.CircuitBreakerConfiguration{
+		FailureThreshold: -1,
+		Cooldown:         "abc",
+	}
+
+	validateCircuitBreaker("notifier", configuration, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "The notifier circuit breaker failure_threshold must be greater than 0 but it is configured as -1")
+	assert.EqualError(t, validator.Errors()[1], "Error occurred parsing the notifier circuit breaker cooldown string: Could not convert the input string of abc into a duration")
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 var defaultPort = 9091
@@ -18,7 +20,9 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 		configuration.Host = "0.0.0.0"
 	}
 
-	if configuration.Port == 0 {
+	if strings.HasPrefix(configuration.Host, schema.UnixSocketHostPrefix) {
+		validateUnixSocket(configuration, validator)
+	} else if configuration.Port == 0 {
 		configuration.Port = defaultPort
 	}
 
@@ -52,6 +56,16 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 		}
 	}
 
+	validateAllowedRedirectionDomains(configuration.AllowedRedirectionDomains, validator)
+
+	if configuration.Default2FAMethod != "" {
+		if !utils.IsStringInSlice(configuration.Default2FAMethod, validSecondFactorMethods) {
+			validator.Push(fmt.Errorf("The default 2FA method '%s' is invalid, it should be one of %s", configuration.Default2FAMethod, strings.Join(validSecondFactorMethods, ", ")))
+		} else if configuration.Default2FAMethod == "mobile_push" && configuration.DuoAPI == nil {
+			validator.Push(fmt.Errorf("The default 2FA method 'mobile_push' requires the duo_api configuration"))
+		}
+	}
+
 	if configuration.Theme == "" {
 		configuration.Theme = "light"
 	}
@@ -66,6 +80,14 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	ValidateAuthenticationBackend(&configuration.AuthenticationBackend, validator)
 
+	if configuration.OIDCUpstream != nil {
+		if configuration.OIDCUpstream.RedirectURI == "" && configuration.Server.ExternalURL != "" {
+			configuration.OIDCUpstream.RedirectURI = strings.TrimSuffix(configuration.Server.ExternalURL, "/") + oidcUpstreamCallbackPath
+		}
+
+		ValidateOIDCUpstream(configuration.OIDCUpstream, validator)
+	}
+
 	if configuration.AccessControl.DefaultPolicy == "" {
 		configuration.AccessControl.DefaultPolicy = denyPolicy
 	}
@@ -74,19 +96,69 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	if configuration.AccessControl.Rules != nil {
 		ValidateRules(configuration.AccessControl, validator)
+		validateAttributeConditionsBackend(configuration, validator)
 	}
 
 	ValidateSession(&configuration.Session, validator)
 
+	if configuration.AuthenticationBackend.Ldap != nil && configuration.AuthenticationBackend.Ldap.Domain == "" {
+		configuration.AuthenticationBackend.Ldap.Domain = configuration.Session.Domain
+	}
+
+	if configuration.LogoutRedirectionURL != "" {
+		validateLogoutRedirectionURL(configuration, validator)
+	}
+
 	if configuration.Regulation == nil {
 		configuration.Regulation = &schema.DefaultRegulationConfiguration
 	}
 
 	ValidateRegulation(configuration.Regulation, validator)
 
+	if configuration.BreakGlass != nil {
+		ValidateBreakGlass(configuration.BreakGlass, validator)
+	}
+
+	if configuration.TrustedHeader != nil {
+		ValidateTrustedHeader(configuration.TrustedHeader, validator)
+		validateTrustedHeaderProxies(configuration, validator)
+	}
+
+	ValidateLoginNotification(&configuration.LoginNotification, validator)
+
+	ValidateLastLogin(&configuration.LastLogin, validator)
+
+	if configuration.SecurityDigest != nil {
+		ValidateSecurityDigest(configuration.SecurityDigest, validator)
+	}
+
+	ValidateIdentityVerification(&configuration.IdentityVerification, validator)
+
+	ValidateRegistration(&configuration.Registration, validator)
+	validateRegistrationBackend(configuration, validator)
+
 	ValidateServer(&configuration.Server, validator)
 
-	ValidateStorage(configuration.Storage, validator)
+	if configuration.Server.RequestSampling.Rate > 0 && configuration.LogLevel != "debug" && configuration.LogLevel != "trace" {
+		validator.PushWarning(fmt.Errorf("The server request_sampling only logs at the debug level, no request will be logged with the log_level %s", configuration.LogLevel))
+	}
+
+	if configuration.Server.ExternalURL != "" {
+		validateServerExternalURLDomain(configuration, validator)
+	}
+
+	validateTOTPDomainIssuersDomain(configuration, validator)
+
+	validateSessionDomainCoverage(configuration, validator)
+
+	validateRulesRedirectURL(configuration, validator)
+
+	if configuration.Server.HTTPRedirect != nil {
+		ValidateServerHTTPRedirect(configuration, validator)
+	}
+
+	ValidateStorage(&configuration.Storage, validator)
+	validateStoragePruningRetention(configuration.Storage.Pruning, configuration.Regulation, validator)
 
 	if configuration.Notifier == nil {
 		validator.Push(fmt.Errorf("A notifier configuration must be provided"))
@@ -94,3 +166,129 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 		ValidateNotifier(configuration.Notifier, validator)
 	}
 }
+
+func validateServerExternalURLDomain(configuration *schema.Configuration, validator *schema.StructValidator) {
+	externalURL, err := url.Parse(configuration.Server.ExternalURL)
+	if err != nil || externalURL.Host == "" || configuration.Session.Domain == "" {
+		return
+	}
+
+	if !isDomainUnderSessionDomain(externalURL.Hostname(), configuration.Session.Domain) {
+		validator.Push(fmt.Errorf("The server external_url '%s' must be under the session domain '%s'", configuration.Server.ExternalURL, configuration.Session.Domain))
+	}
+}
+
+// validateTOTPDomainIssuersDomain checks the domains of the TOTP domain issuers are under the session domain since the
+// portal can't be served from other domains.
+func validateTOTPDomainIssuersDomain(configuration *schema.Configuration, validator *schema.StructValidator) {
+	if configuration.Session.Domain == "" {
+		return
+	}
+
+	for _, domainIssuer := range configuration.TOTP.DomainIssuers {
+		if domainIssuer.Domain == "" {
+			continue
+		}
+
+		if !isDomainUnderSessionDomain(domainIssuer.Domain, configuration.Session.Domain) {
+			validator.Push(fmt.Errorf("The TOTP domain issuer domain '%s' must be under the session domain '%s'", domainIssuer.Domain, configuration.Session.Domain))
+		}
+	}
+}
+
+// validateSessionDomainCoverage checks the domains of the access control rules and the host of the default redirection
+// URL are under the session domain, otherwise the session cookie is never sent to them. Depending on the domain_check
+// of the session, a mismatch is an error or a warning.
+func validateSessionDomainCoverage(configuration *schema.Configuration, validator *schema.StructValidator) {
+	if configuration.Session.Domain == "" || configuration.Session.DomainCheck == schema.SessionDomainCheckDisable {
+		return
+	}
+
+	push := validator.PushWarning
+	if configuration.Session.DomainCheck == schema.SessionDomainCheckError {
+		push = validator.Push
+	}
+
+	for i, rule := range configuration.AccessControl.Rules {
+		for _, domain := range rule.Domains {
+			if !isDomainUnderSessionDomain(trimDomainWildcard(strings.ToLower(domain)), configuration.Session.Domain) {
+				push(fmt.Errorf("The domain '%s' of the access control rule at position %d is not under the session domain '%s', it will never receive the session cookie",
+					domain, i+1, configuration.Session.Domain))
+			}
+		}
+	}
+
+	if configuration.DefaultRedirectionURL == "" {
+		return
+	}
+
+	if defaultRedirectionURL, err := url.ParseRequestURI(configuration.DefaultRedirectionURL); err == nil &&
+		!isDomainUnderSessionDomain(strings.ToLower(defaultRedirectionURL.Hostname()), configuration.Session.Domain) {
+		push(fmt.Errorf("The default redirection url '%s' is not under the session domain '%s', it will never receive the session cookie",
+			configuration.DefaultRedirectionURL, configuration.Session.Domain))
+	}
+}
+
+// trimDomainWildcard returns the domain matched by a wildcard access control rule domain.
+func trimDomainWildcard(domain string) string {
+	for _, prefix := range []string{"*.", "{user}.", "{group}."} {
+		if strings.HasPrefix(domain, prefix) {
+			return domain[len(prefix):]
+		}
+	}
+
+	return domain
+}
+
+// isDomainUnderSessionDomain returns true if the domain is the session domain or one of its subdomains.
+func isDomainUnderSessionDomain(domain, sessionDomain string) bool {
+	return domain == sessionDomain || strings.HasSuffix(domain, "."+sessionDomain)
+}
+
+func validateLogoutRedirectionURL(configuration *schema.Configuration, validator *schema.StructValidator) {
+	logoutURL, err := url.ParseRequestURI(configuration.LogoutRedirectionURL)
+	if err != nil {
+		validator.Push(fmt.Errorf("Unable to parse logout redirection url"))
+		return
+	}
+
+	if !utils.IsRedirectionSafe(*logoutURL, configuration.Session.Domain) ||
+		!utils.IsRedirectionAllowed(*logoutURL, configuration.AllowedRedirectionDomains) {
+		validator.Push(fmt.Errorf("The logout redirection url '%s' must be an https url under the session domain and match the allowed redirection domains", configuration.LogoutRedirectionURL))
+	}
+}
+
+// validateRulesRedirectURL checks the redirect URLs of the access control rules match the redirection allowlist, i.e. are
+// under the session domain and match the allowed redirection domains.
+func validateRulesRedirectURL(configuration *schema.Configuration, validator *schema.StructValidator) {
+	for _, rule := range configuration.AccessControl.Rules {
+		if rule.RedirectURL == "" {
+			continue
+		}
+
+		redirectURL, err := url.ParseRequestURI(rule.RedirectURL)
+		if err != nil {
+			continue
+		}
+
+		if !utils.IsRedirectionSafe(*redirectURL, configuration.Session.Domain) ||
+			!utils.IsRedirectionAllowed(*redirectURL, configuration.AllowedRedirectionDomains) {
+			validator.Push(fmt.Errorf("The redirect url '%s' of the access control rule for domain %s must be an https url under the session domain and match the allowed redirection domains", rule.RedirectURL, rule.Domains))
+		}
+	}
+}
+
+func validateAllowedRedirectionDomains(domains []string, validator *schema.StructValidator) {
+	for _, domain := range domains {
+		switch {
+		case domain == "":
+			validator.Push(fmt.Errorf("An allowed redirection domain must not be empty"))
+		case strings.Contains(domain, "/") || strings.Contains(domain, ":"):
+			validator.Push(fmt.Errorf("The allowed redirection domain '%s' must be a domain without a scheme, port or path", domain))
+		case strings.Contains(strings.TrimPrefix(domain, "*."), "*"):
+			validator.Push(fmt.Errorf("The allowed redirection domain '%s' is invalid, wildcards are only supported as the first label", domain))
+		case strings.HasPrefix(domain, "*.") && !strings.Contains(domain[2:], "."):
+			validator.Push(fmt.Errorf("The allowed redirection domain '%s' is too broad, wildcards must be followed by at least two labels", domain))
+		}
+	}
+}
@@ -69,6 +69,35 @@ func TestShouldValidateAndUpdateHost(t *testing.T) {
 	assert.Equal(t, "0.0.0.0", config.Host)
 }
 
+func TestShouldValidateUnixSocketHost(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Host = "unix:/var/run/authelia.sock"
+	config.Port = 0
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, 0, config.Port)
+	assert.Equal(t, "0660", config.Server.UnixSocketMode)
+}
+
+func TestShouldRaiseErrorsOnInvalidUnixSocketHost(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Host = "unix:authelia.sock"
+	config.Server.UnixSocketMode = "0999"
+	config.Server.HTTPRedirect = &schema.ServerHTTPRedirectConfiguration{}
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 4)
+	assert.EqualError(t, validator.Errors()[0], "The unix socket path 'authelia.sock' of the host must be absolute")
+	assert.EqualError(t, validator.Errors()[1], "The port must not be configured when the host is a unix socket")
+	assert.EqualError(t, validator.Errors()[2], "server unix_socket_mode '0999' must be an octal file mode such as 0660")
+	assert.EqualError(t, validator.Errors()[3], "server http_redirect can't be used when the host is a unix socket")
+}
+
 func TestShouldValidateAndUpdateLogsLevel(t *testing.T) {
 	validator := schema.NewStructValidator()
 	config := newDefaultConfig()
@@ -154,6 +183,71 @@ func TestShouldRaiseErrorWithBadDefaultRedirectionURL(t *testing.T) {
 	assert.EqualError(t, validator.Errors()[0], "Unable to parse default redirection url")
 }
 
+func TestShouldRaiseErrorWithBadLogoutRedirectionURL(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.LogoutRedirectionURL = "abc"
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Unable to parse logout redirection url")
+}
+
+func TestShouldRaiseErrorWithUnsafeLogoutRedirectionURL(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.LogoutRedirectionURL = "https://home.example.org/"
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The logout redirection url 'https://home.example.org/' must be an https url under the session domain and match the allowed redirection domains")
+
+	validator = schema.NewStructValidator()
+	config.LogoutRedirectionURL = "https://home.example.com/"
+	config.AllowedRedirectionDomains = []string{"*.apps.example.com"}
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The logout redirection url 'https://home.example.com/' must be an https url under the session domain and match the allowed redirection domains")
+
+	validator = schema.NewStructValidator()
+	config.LogoutRedirectionURL = "https://portal.apps.example.com/"
+
+	ValidateConfiguration(&config, validator)
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldRaiseErrorWithUnsafeRuleRedirectURL(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.AccessControl.Rules = []schema.ACLRule{
+		{Domains: []string{"app.example.com"}, Policy: "one_factor", RedirectURL: "https://login.example.org/"},
+		{Domains: []string{"wiki.example.com"}, Policy: "one_factor", RedirectURL: "https://wiki-login.example.com/"},
+	}
+	config.AllowedRedirectionDomains = []string{"*.example.com"}
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The redirect url 'https://login.example.org/' of the access control rule for domain [app.example.com] must be an https url under the session domain and match the allowed redirection domains")
+}
+
+func TestShouldWarnWhenRequestSamplingIsEnabledWithoutDebugLogLevel(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Server.RequestSampling.Rate = 0.1
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+	require.Len(t, validator.Warnings(), 1)
+	assert.EqualError(t, validator.Warnings()[0], "The server request_sampling only logs at the debug level, no request will be logged with the log_level info")
+
+	validator = schema.NewStructValidator()
+	config.LogLevel = "debug"
+
+	ValidateConfiguration(&config, validator)
+	assert.Len(t, validator.Warnings(), 0)
+}
+
 func TestShouldNotOverrideCertificatesDirectoryAndShouldPassWhenBlank(t *testing.T) {
 	validator := schema.NewStructValidator()
 	config := newDefaultConfig()
@@ -195,3 +289,204 @@ func TestShouldNotRaiseErrorOnValidCertificatesDirectory(t *testing.T) {
 
 	require.Len(t, validator.Errors(), 0)
 }
+
+func TestShouldRaiseErrorOnInvalidDefault2FAMethod(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Default2FAMethod = "sms"
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The default 2FA method 'sms' is invalid, it should be one of totp, u2f, mobile_push")
+
+	validator = schema.NewStructValidator()
+	config.Default2FAMethod = "mobile_push"
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The default 2FA method 'mobile_push' requires the duo_api configuration")
+}
+
+func TestShouldNotRaiseErrorOnValidDefault2FAMethod(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Default2FAMethod = "u2f"
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "u2f", config.Default2FAMethod)
+}
+
+func TestShouldRaiseErrorOnInvalidAllowedRedirectionDomains(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.AllowedRedirectionDomains = []string{"", "https://example.com", "a.*.example.com", "*.com", "*"}
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 5)
+	assert.EqualError(t, validator.Errors()[0], "An allowed redirection domain must not be empty")
+	assert.EqualError(t, validator.Errors()[1], "The allowed redirection domain 'https://example.com' must be a domain without a scheme, port or path")
+	assert.EqualError(t, validator.Errors()[2], "The allowed redirection domain 'a.*.example.com' is invalid, wildcards are only supported as the first label")
+	assert.EqualError(t, validator.Errors()[3], "The allowed redirection domain '*.com' is too broad, wildcards must be followed by at least two labels")
+	assert.EqualError(t, validator.Errors()[4], "The allowed redirection domain '*' is invalid, wildcards are only supported as the first label")
+}
+
+func TestShouldNotRaiseErrorOnValidAllowedRedirectionDomains(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.AllowedRedirectionDomains = []string{"home.example.com", "*.apps.example.com"}
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldDeriveOIDCUpstreamRedirectURIFromExternalURL(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Server.ExternalURL = "https://login.example.com/authelia/"
+	config.OIDCUpstream = &schema.OIDCUpstreamConfiguration{
+		Issuer:       "https://idp.example.com",
+		ClientID:     "authelia",
+		ClientSecret: "secret",
+	}
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+
+	assert.Equal(t, "https://login.example.com/authelia/api/oidc-upstream/callback", config.OIDCUpstream.RedirectURI)
+	assert.Equal(t, "https://login.example.com/authelia", config.Server.ExternalURL)
+}
+
+func TestShouldRaiseErrorWhenTrustedHeaderIsConfiguredWithoutTrustedProxies(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.TrustedHeader = &schema.TrustedHeaderConfiguration{
+		Header:   "X-Mesh-Identity",
+		JWKSURL:  "https://mesh.example.com/jwks",
+		Audience: "authelia",
+	}
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The trusted_header requires the server trusted_proxies to be configured")
+
+	validator = schema.NewStructValidator()
+	config.Server.TrustedProxies = []string{"10.0.0.0/8"}
+
+	ValidateConfiguration(&config, validator)
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldRaiseErrorWhenRegistrationIsEnabledWithLDAPBackend(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Registration = schema.RegistrationConfiguration{
+		Enabled:        true,
+		AllowedDomains: []string{"example.com"},
+	}
+
+	ValidateConfiguration(&config, validator)
+	assert.Len(t, validator.Errors(), 0)
+
+	validator = schema.NewStructValidator()
+	config.AuthenticationBackend.File = nil
+	config.AuthenticationBackend.Ldap = &schema.LDAPAuthenticationBackendConfiguration{
+		URL:          "ldap://ldap",
+		User:         "user",
+		Password:     "password",
+		BaseDN:       "dc=example,dc=com",
+		UsersFilter:  "({username_attribute}={input})",
+		GroupsFilter: "(member={dn})",
+	}
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Registration requires the file authentication backend")
+}
+
+func TestShouldRaiseErrorWhenTOTPDomainIssuerIsNotUnderSessionDomain(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.TOTP = &schema.TOTPConfiguration{
+		DomainIssuers: []schema.TOTPDomainIssuerConfiguration{
+			{Domain: "brand.example.com", Issuer: "Brand"},
+			{Domain: "example.com", Issuer: "Example"},
+			{Domain: "brand.example.org", Issuer: "Other Brand"},
+		},
+	}
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The TOTP domain issuer domain 'brand.example.org' must be under the session domain 'example.com'")
+}
+
+func TestShouldRaiseErrorWhenExternalURLIsNotUnderSessionDomain(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Server.ExternalURL = "https://login.example.org"
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The server external_url 'https://login.example.org' must be under the session domain 'example.com'")
+
+	validator = schema.NewStructValidator()
+	config.Server.ExternalURL = "http://localhost:9091"
+	config.Session.Domain = "localhost"
+
+	ValidateConfiguration(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+}
+
+func newDomainCoverageConfig() schema.Configuration {
+	config := newDefaultConfig()
+	config.DefaultRedirectionURL = "https://home.example.org/"
+	config.AccessControl.Rules = []schema.ACLRule{
+		{Domains: []string{"public.example.com", "example.com"}, Policy: "bypass"},
+		{Domains: []string{"*.example.com", "{user}.example.com", "{group}.Example.com"}, Policy: "one_factor"},
+		{Domains: []string{"secure.example.com", "secure.notexample.com"}, Policy: "two_factor"},
+		{Domains: []string{"*.example.org"}, Policy: "two_factor"},
+	}
+
+	return config
+}
+
+func TestShouldWarnWhenProtectedDomainsAreNotUnderSessionDomain(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDomainCoverageConfig()
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	require.Len(t, validator.Warnings(), 3)
+	assert.EqualError(t, validator.Warnings()[0], "The domain 'secure.notexample.com' of the access control rule at position 3 is not under the session domain 'example.com', it will never receive the session cookie")
+	assert.EqualError(t, validator.Warnings()[1], "The domain '*.example.org' of the access control rule at position 4 is not under the session domain 'example.com', it will never receive the session cookie")
+	assert.EqualError(t, validator.Warnings()[2], "The default redirection url 'https://home.example.org/' is not under the session domain 'example.com', it will never receive the session cookie")
+}
+
+func TestShouldRaiseErrorsWhenProtectedDomainsAreNotUnderSessionDomainAndCheckIsError(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDomainCoverageConfig()
+	config.Session.DomainCheck = schema.SessionDomainCheckError
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 3)
+	assert.Len(t, validator.Warnings(), 0)
+	assert.EqualError(t, validator.Errors()[0], "The domain 'secure.notexample.com' of the access control rule at position 3 is not under the session domain 'example.com', it will never receive the session cookie")
+}
+
+func TestShouldNotCheckProtectedDomainsWhenCheckIsDisabled(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDomainCoverageConfig()
+	config.Session.DomainCheck = schema.SessionDomainCheckDisable
+
+	ValidateConfiguration(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Len(t, validator.Warnings(), 0)
+}
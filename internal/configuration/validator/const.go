@@ -1,17 +1,42 @@
 package validator
 
+import (
+	"time"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
 const (
 	errFmtSessionSecretRedisProvider      = "The session secret must be set when using the %s session provider"
 	errFmtSessionRedisPortRange           = "The port must be between 1 and 65535 for the %s session provider"
 	errFmtSessionRedisHostRequired        = "The host must be provided when using the %s session provider"
 	errFmtSessionRedisHostOrNodesRequired = "Either the host or a node must be provided when using the %s session provider"
+	errFmtSessionEncryptionKeyTooShort    = "The session %s must be at least %d characters long"
+	errFmtStorageEncryptionKeyTooShort    = "The storage %s must be at least %d characters long"
+
+	sessionEncryptionKeyMinLength = 32
+	storageEncryptionKeyMinLength = 32
+
+	passwordHistoryMaxSize = 24
+
+	breakGlassMinimumArgon2idMemory = 65536
+
+	// regulationMaximumDelay caps the delay of authentication attempts so a request is never held for too long.
+	regulationMaximumDelay = 30 * time.Second
+
+	// ldapCacheMaximumTTL caps how long the details of a user are cached so that the changes of their groups made in
+	// the directory are taken into account quickly.
+	ldapCacheMaximumTTL = 10 * time.Minute
 
 	errFileHashing  = "config key incorrect: authentication_backend.file.hashing should be authentication_backend.file.password"
 	errFilePHashing = "config key incorrect: authentication_backend.file.password_hashing should be authentication_backend.file.password"
 	errFilePOptions = "config key incorrect: authentication_backend.file.password_options should be authentication_backend.file.password"
 
-	denyPolicy   = "deny"
-	bypassPolicy = "bypass"
+	denyPolicy      = "deny"
+	bypassPolicy    = "bypass"
+	oneFactorPolicy = "one_factor"
+	twoFactorPolicy = "two_factor"
+	externalPolicy  = "external"
 
 	argon2id = "argon2id"
 	sha512   = "sha512"
@@ -19,6 +44,11 @@ const (
 	schemeLDAP  = "ldap"
 	schemeLDAPS = "ldaps"
 
+	schemeSOCKS5 = "socks5"
+	schemeHTTP   = "http"
+
+	oidcUpstreamCallbackPath = "/api/oidc-upstream/callback"
+
 	testBadTimer      = "-1"
 	testInvalidPolicy = "invalid"
 	testJWTSecret     = "a_secret"
@@ -33,14 +63,33 @@ const (
 	errAccessControlInvalidPolicyWithSubjects = "Policy [bypass] for domain %s with subjects %s is invalid. It is " +
 		"not supported to configure both policy bypass and subjects. For more information see: " +
 		"https://www.authelia.com/docs/configuration/access-control.html#combining-subjects-and-the-bypass-policy"
+	errAccessControlInvalidPolicyWithAttributes = "Policy [bypass] for domain %s with attribute conditions is invalid, " +
+		"the attributes of the anonymous users are unknown"
+	errAccessControlInvalidPolicyWithAnonymousIdentity = "Anonymous identity %s for domain %s is invalid, it is only " +
+		"supported with the bypass policy"
+	errAccessControlInvalidPolicyWithBasicAuth = "Basic auth for domain %s is invalid with the policy %s, it is only " +
+		"supported with the one_factor policy"
 )
 
+var validSecondFactorMethods = []string{"totp", "u2f", "mobile_push"}
+
+// validLdapUsersFilterPlaceholders are the placeholders which can be used in the LDAP users filter. The deprecated {0}
+// placeholder is accepted as well. TODO: Remove {0} in 4.28.
+var validLdapUsersFilterPlaceholders = []string{"input", "username_attribute", "mail_attribute", "display_name_attribute", "domain"}
+
+// validLdapGroupsFilterPlaceholders are the placeholders which can be used in the LDAP groups filter. The deprecated
+// {0} and {1} placeholders are accepted as well. TODO: Remove {0} and {1} in 4.28.
+var validLdapGroupsFilterPlaceholders = []string{"input", "username", "dn", "username_attribute", "mail_attribute", "domain"}
+
+var validSecurityEvents = []string{schema.SecurityEventBan, schema.SecurityEventAuthenticationFailure, schema.SecurityEventNotificationFailure}
+
 var validRequestMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "TRACE", "CONNECT", "OPTIONS"}
 
 // SecretNames contains a map of secret names.
 var SecretNames = map[string]string{
 	"JWTSecret":             "jwt_secret",
 	"SessionSecret":         "session.secret",
+	"SessionEncryptionKey":  "session.encryption_key",
 	"DUOSecretKey":          "duo_api.secret_key",
 	"RedisPassword":         "session.redis.password",
 	"RedisSentinelPassword": "session.redis.high_availability.sentinel_password",
@@ -48,6 +97,8 @@ var SecretNames = map[string]string{
 	"SMTPPassword":          "notifier.smtp.password",
 	"MySQLPassword":         "storage.mysql.password",
 	"PostgreSQLPassword":    "storage.postgres.password",
+	"StorageEncryptionKey":  "storage.encryption_key",
+	"OIDCUpstreamSecret":    "oidc_upstream.client_secret",
 }
 
 // validKeys is a list of valid keys that are not secret names. For the sake of consistency please place any secret in
@@ -60,6 +111,9 @@ var validKeys = []string{
 	"log_format",
 	"log_file_path",
 	"default_redirection_url",
+	"logout_redirection_url",
+	"default_2fa_method",
+	"allowed_redirection_domains",
 	"theme",
 	"tls_key",
 	"tls_cert",
@@ -68,24 +122,61 @@ var validKeys = []string{
 	// Server Keys.
 	"server.read_buffer_size",
 	"server.write_buffer_size",
-	"server.path",
+	"server.path", // TODO: Deprecated: Remove in 4.30.
+	"server.base_path",
+	"server.startup_checks",
+	"server.forwarded_hops",
+	"server.external_url",
+	"server.trusted_proxies",
+	"server.unix_socket_mode",
+	"server.http_redirect.host",
+	"server.http_redirect.port",
+	"server.api_requests.requested_with",
+	"server.api_requests.accept_types",
+	"server.request_id.enabled",
+	"server.request_id.headers",
+	"server.request_id.response_header",
+	"server.cache_control.sensitive",
+	"server.cache_control.static",
+	"server.request_sampling.rate",
+	"server.request_sampling.max_per_minute",
+	"server.request_sampling.redacted_headers",
+	"server.verify_endpoints",
 
 	// TOTP Keys.
 	"totp.issuer",
 	"totp.period",
 	"totp.skew",
+	"totp.domain_issuers",
 
 	// Access Control Keys.
 	"access_control.rules",
+	"access_control.rules_files",
 	"access_control.default_policy",
+	"access_control.anonymous_identity",
+	"access_control.anonymous_identity_headers",
+	"access_control.denied_response",
+	"access_control.deny_reason_header",
+	"access_control.path_normalization",
+	"access_control.external_authorization.url",
+	"access_control.external_authorization.timeout",
+	"access_control.external_authorization.cache_duration",
+	"access_control.external_authorization.failure_policy",
 	"access_control.networks",
 
 	// Session Keys.
 	"session.name",
 	"session.expiration",
 	"session.inactivity",
+	"session.inactivity_grace_period",
+	"session.inactivity_grace_policy",
+	"session.max_sessions_per_user",
+	"session.second_factor_change",
 	"session.remember_me_duration",
 	"session.domain",
+	"session.domain_check",
+	"session.previous_encryption_keys",
+	"session.memory.max_sessions",
 
 	// Redis Session Keys.
 	"session.redis.host",
@@ -109,23 +200,40 @@ var validKeys = []string{
 
 	// Local Storage Keys.
 	"storage.local.path",
+	"storage.local.busy_timeout",
 
 	// MySQL Storage Keys.
 	"storage.mysql.host",
 	"storage.mysql.port",
 	"storage.mysql.database",
 	"storage.mysql.username",
+	"storage.mysql.max_open_conns",
+	"storage.mysql.max_idle_conns",
+	"storage.mysql.conn_max_lifetime",
 
 	// PostgreSQL Storage Keys.
 	"storage.postgres.host",
 	"storage.postgres.port",
 	"storage.postgres.database",
 	"storage.postgres.username",
+	"storage.postgres.max_open_conns",
+	"storage.postgres.max_idle_conns",
+	"storage.postgres.conn_max_lifetime",
 	"storage.postgres.sslmode",
 
+	"storage.pruning.interval",
+	"storage.pruning.authentication_logs_retention",
+	"storage.pruning.login_devices_retention",
+	"storage.pruning.batch_size",
+
+	"storage.failure_mode",
+	"storage.previous_encryption_keys",
+
 	// FileSystem Notifier Keys.
 	"notifier.filesystem.filename",
 	"notifier.disable_startup_check",
+	"notifier.circuit_breaker.failure_threshold",
+	"notifier.circuit_breaker.cooldown",
 
 	// SMTP Notifier Keys.
 	"notifier.smtp.username",
@@ -147,14 +255,78 @@ var validKeys = []string{
 	"regulation.max_retries",
 	"regulation.find_time",
 	"regulation.ban_time",
+	"regulation.delay_increment",
+	"regulation.max_delay",
+	"regulation.admin_groups",
+
+	// Last Login Keys.
+	"last_login.admin_groups",
+
+	// Identity Verification Keys.
+	"identity_verification.token_lifetime",
+
+	// Email Verification Keys.
+	"email_verification.enabled",
+
+	// Registration Keys.
+	"registration.enabled",
+	"registration.allowed_domains",
+	"registration.require_approval",
+	"registration.admin_groups",
+	"registration.groups",
+
+	// Login Notification Keys.
+	"login_notification.enabled",
+	"login_notification.require_second_factor",
+	"login_notification.location_header",
+
+	// Security Digest Keys.
+	"security_digest.interval",
+	"security_digest.recipients",
+	"security_digest.webhook_url",
+	"security_digest.timeout",
+	"security_digest.immediate_events",
 
 	// DUO API Keys.
 	"duo_api.hostname",
 	"duo_api.integration_key",
 
+	// OpenID Connect Upstream Keys.
+	"oidc_upstream.display_name",
+	"oidc_upstream.issuer",
+	"oidc_upstream.client_id",
+	"oidc_upstream.redirect_uri",
+	"oidc_upstream.scopes",
+	"oidc_upstream.timeout",
+	"oidc_upstream.claims.username",
+	"oidc_upstream.claims.display_name",
+	"oidc_upstream.claims.email",
+	"oidc_upstream.claims.groups",
+	"oidc_upstream.group_assignments",
+
+	// Break Glass Keys.
+	"break_glass.username",
+	"break_glass.password",
+	"break_glass.networks",
+
+	// Trusted Header Keys.
+	"trusted_header.header",
+	"trusted_header.public_key",
+	"trusted_header.jwks_url",
+	"trusted_header.issuer",
+	"trusted_header.audience",
+	"trusted_header.timeout",
+	"trusted_header.lookup_timeout",
+	"trusted_header.failure_mode",
+	"trusted_header.claims.username",
+	"trusted_header.claims.display_name",
+	"trusted_header.claims.email",
+	"trusted_header.claims.groups",
+
 	// Authentication Backend Keys.
 	"authentication_backend.disable_reset_password",
 	"authentication_backend.refresh_interval",
+	"authentication_backend.default_groups",
 
 	// LDAP Authentication Backend Keys.
 	"authentication_backend.ldap.implementation",
@@ -165,14 +337,24 @@ var validKeys = []string{
 	"authentication_backend.ldap.users_filter",
 	"authentication_backend.ldap.additional_groups_dn",
 	"authentication_backend.ldap.groups_filter",
+	"authentication_backend.ldap.domain",
 	"authentication_backend.ldap.group_name_attribute",
 	"authentication_backend.ldap.mail_attribute",
 	"authentication_backend.ldap.display_name_attribute",
+	"authentication_backend.ldap.display_name_fallback_attributes",
+	"authentication_backend.ldap.mail_fallback_attributes",
+	"authentication_backend.ldap.extra_attributes",
+	"authentication_backend.ldap.default_groups",
 	"authentication_backend.ldap.user",
 	"authentication_backend.ldap.start_tls",
+	"authentication_backend.ldap.proxy",
 	"authentication_backend.ldap.tls.minimum_version",
 	"authentication_backend.ldap.tls.skip_verify",
 	"authentication_backend.ldap.tls.server_name",
+	"authentication_backend.ldap.circuit_breaker.failure_threshold",
+	"authentication_backend.ldap.circuit_breaker.cooldown",
+	"authentication_backend.ldap.cache.ttl",
+	"authentication_backend.ldap.cache.max_entries",
 	"authentication_backend.ldap.skip_verify",         // TODO: Deprecated: Remove in 4.28.
 	"authentication_backend.ldap.minimum_tls_version", // TODO: Deprecated: Remove in 4.28.
 
@@ -184,6 +366,10 @@ var validKeys = []string{
 	"authentication_backend.file.password.salt_length",
 	"authentication_backend.file.password.memory",
 	"authentication_backend.file.password.parallelism",
+	"authentication_backend.file.password_history.history_size",
+	"authentication_backend.file.password_history.min_age",
+	"authentication_backend.file.warn_on_duplicates",
+	"authentication_backend.file.default_groups",
 }
 
 var specificErrorKeys = map[string]string{
@@ -0,0 +1,22 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ValidateIdentityVerification validates and update the identity verification configuration.
+func ValidateIdentityVerification(configuration *schema.IdentityVerificationConfiguration, validator *schema.StructValidator) {
+	if configuration.TokenLifetime == "" {
+		configuration.TokenLifetime = schema.DefaultIdentityVerificationConfiguration.TokenLifetime
+	}
+
+	lifetime, err := utils.ParseDurationString(configuration.TokenLifetime)
+	if err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing identity verification token_lifetime string: %s", err))
+	} else if lifetime <= 0 {
+		validator.Push(fmt.Errorf("Identity verification token_lifetime must be above 0"))
+	}
+}
@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldSetDefaultIdentityVerificationTokenLifetime(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityVerificationConfiguration{}
+
+	ValidateIdentityVerification(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "5m", config.TokenLifetime)
+}
+
+func TestShouldRaiseErrorWhenIdentityVerificationTokenLifetimeIsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityVerificationConfiguration{TokenLifetime: "abc"}
+
+	ValidateIdentityVerification(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Error occurred parsing identity verification token_lifetime string: Could not convert the input string of abc into a duration")
+
+	validator = schema.NewStructValidator()
+	config = schema.IdentityVerificationConfiguration{TokenLifetime: "0"}
+
+	ValidateIdentityVerification(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Identity verification token_lifetime must be above 0")
+}
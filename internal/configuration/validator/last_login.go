@@ -0,0 +1,17 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ValidateLastLogin validates the last login configuration.
+func ValidateLastLogin(configuration *schema.LastLoginConfiguration, validator *schema.StructValidator) {
+	for i, group := range configuration.AdminGroups {
+		if strings.TrimSpace(group) == "" {
+			validator.Push(fmt.Errorf("Last login admin group at position %d must not be empty", i+1))
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldValidateLastLoginAdminGroups(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.LastLoginConfiguration{AdminGroups: []string{"support"}}
+
+	ValidateLastLogin(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorOnEmptyLastLoginAdminGroup(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.LastLoginConfiguration{AdminGroups: []string{"support", " "}}
+
+	ValidateLastLogin(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Last login admin group at position 2 must not be empty")
+}
@@ -0,0 +1,27 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ValidateLoginNotification validates the login notification configuration.
+func ValidateLoginNotification(configuration *schema.LoginNotificationConfiguration, validator *schema.StructValidator) {
+	if !configuration.Enabled {
+		if configuration.RequireSecondFactor {
+			validator.Push(fmt.Errorf("Login notification require_second_factor requires the login notification to be enabled"))
+		}
+
+		if configuration.LocationHeader != "" {
+			validator.Push(fmt.Errorf("Login notification location_header requires the login notification to be enabled"))
+		}
+
+		return
+	}
+
+	if strings.ContainsAny(configuration.LocationHeader, " :\t") {
+		validator.Push(fmt.Errorf("Login notification location_header '%s' is not a valid header name", configuration.LocationHeader))
+	}
+}
@@ -0,0 +1,50 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldValidateEnabledLoginNotification(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.LoginNotificationConfiguration{
+		Enabled:             true,
+		RequireSecondFactor: true,
+		LocationHeader:      "CF-IPCountry",
+	}
+
+	ValidateLoginNotification(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorsWhenLoginNotificationOptionsSetButDisabled(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.LoginNotificationConfiguration{
+		RequireSecondFactor: true,
+		LocationHeader:      "CF-IPCountry",
+	}
+
+	ValidateLoginNotification(&config, validator)
+
+	assert.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "Login notification require_second_factor requires the login notification to be enabled")
+	assert.EqualError(t, validator.Errors()[1], "Login notification location_header requires the login notification to be enabled")
+}
+
+func TestShouldRaiseErrorWhenLoginNotificationLocationHeaderInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.LoginNotificationConfiguration{
+		Enabled:        true,
+		LocationHeader: "X-Geo: Country",
+	}
+
+	ValidateLoginNotification(&config, validator)
+
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Login notification location_header 'X-Geo: Country' is not a valid header name")
+}
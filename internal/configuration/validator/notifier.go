@@ -3,6 +3,7 @@ package validator
 import (
 	"errors"
 	"fmt"
+	"net/mail"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -20,6 +21,8 @@ func ValidateNotifier(configuration *schema.NotifierConfiguration, validator *sc
 		return
 	}
 
+	validateCircuitBreaker("notifier", configuration.CircuitBreaker, validator)
+
 	if configuration.FileSystem != nil {
 		if configuration.FileSystem.Filename == "" {
 			validator.Push(fmt.Errorf("Filename of filesystem notifier must not be empty"))
@@ -43,6 +46,13 @@ func ValidateNotifier(configuration *schema.NotifierConfiguration, validator *sc
 
 		if configuration.SMTP.Sender == "" {
 			validator.Push(fmt.Errorf("Sender of SMTP notifier must be provided"))
+		} else if _, err := mail.ParseAddress(configuration.SMTP.Sender); err != nil {
+			validator.Push(fmt.Errorf("Sender of SMTP notifier '%s' is not a valid email address: %s", configuration.SMTP.Sender, err))
+		}
+
+		// The startup check address is used as is as a recipient, it cannot include a display name.
+		if address, err := mail.ParseAddress(configuration.SMTP.StartupCheckAddress); err != nil || address.Address != configuration.SMTP.StartupCheckAddress {
+			validator.Push(fmt.Errorf("Startup check address of SMTP notifier '%s' must be an email address like user@example.com", configuration.SMTP.StartupCheckAddress))
 		}
 
 		if configuration.SMTP.Subject == "" {
@@ -127,6 +127,50 @@ func (suite *NotifierSuite) TestShouldEnsureSenderOfSMTPNotifierAreProvided() {
 	suite.Assert().EqualError(suite.validator.Errors()[0], "Sender of SMTP notifier must be provided")
 }
 
+func (suite *NotifierSuite) TestShouldAcceptSenderWithDisplayName() {
+	suite.configuration.FileSystem = nil
+	suite.configuration.SMTP.Sender = "Authelia <admin@example.com>"
+
+	ValidateNotifier(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *NotifierSuite) TestShouldRaiseErrorWhenSenderIsMalformed() {
+	suite.configuration.FileSystem = nil
+	suite.configuration.SMTP.Sender = "admin@example.com>"
+
+	ValidateNotifier(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().Regexp("^Sender of SMTP notifier 'admin@example.com>' is not a valid email address: mail: ", suite.validator.Errors()[0].Error())
+}
+
+func (suite *NotifierSuite) TestShouldRaiseErrorWhenSenderHasNoDomain() {
+	suite.configuration.FileSystem = nil
+	suite.configuration.SMTP.Sender = "admin"
+
+	ValidateNotifier(&suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().Regexp("^Sender of SMTP notifier 'admin' is not a valid email address: mail: ", suite.validator.Errors()[0].Error())
+}
+
+func (suite *NotifierSuite) TestShouldRaiseErrorWhenStartupCheckAddressIsMalformed() {
+	suite.configuration.FileSystem = nil
+	suite.configuration.SMTP.StartupCheckAddress = "Test <test@example.com>"
+
+	ValidateNotifier(&suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Startup check address of SMTP notifier 'Test <test@example.com>' must be an email address like user@example.com")
+}
+
 // Deprecated: Temporary Test. TODO: Remove in 4.28 (Whole Test).
 func (suite *NotifierSuite) TestShouldReturnDeprecationWarningsFor428() {
 	var disableVerifyCert = true
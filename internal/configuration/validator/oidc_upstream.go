@@ -0,0 +1,107 @@
+package validator
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ValidateOIDCUpstream validates and update the upstream OpenID Connect provider configuration.
+func ValidateOIDCUpstream(configuration *schema.OIDCUpstreamConfiguration, validator *schema.StructValidator) {
+	if configuration.DisplayName == "" {
+		configuration.DisplayName = schema.DefaultOIDCUpstreamConfiguration.DisplayName
+	}
+
+	if configuration.Issuer == "" {
+		validator.Push(fmt.Errorf("OIDC upstream issuer must be provided"))
+	} else if issuer, err := url.Parse(configuration.Issuer); err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		validator.Push(fmt.Errorf("OIDC upstream issuer '%s' must be an absolute https URL", configuration.Issuer))
+	}
+
+	if configuration.ClientID == "" {
+		validator.Push(fmt.Errorf("OIDC upstream client_id must be provided"))
+	}
+
+	if configuration.ClientSecret == "" {
+		validator.Push(fmt.Errorf("OIDC upstream client_secret must be provided"))
+	}
+
+	if configuration.RedirectURI == "" {
+		validator.Push(fmt.Errorf("OIDC upstream redirect_uri must be provided"))
+	} else if redirectURI, err := url.Parse(configuration.RedirectURI); err != nil || redirectURI.Scheme != "https" || redirectURI.Host == "" {
+		validator.Push(fmt.Errorf("OIDC upstream redirect_uri '%s' must be an absolute https URL", configuration.RedirectURI))
+	}
+
+	if len(configuration.Scopes) == 0 {
+		configuration.Scopes = schema.DefaultOIDCUpstreamConfiguration.Scopes
+	} else if !utils.IsStringInSlice("openid", configuration.Scopes) {
+		validator.Push(fmt.Errorf("OIDC upstream scopes must include the openid scope"))
+	}
+
+	if configuration.Timeout == "" {
+		configuration.Timeout = schema.DefaultOIDCUpstreamConfiguration.Timeout
+	} else if _, err := utils.ParseDurationString(configuration.Timeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing OIDC upstream timeout string: %s", err))
+	}
+
+	if configuration.Claims.Username == "" {
+		configuration.Claims.Username = schema.DefaultOIDCUpstreamConfiguration.Claims.Username
+	} else if configuration.Claims.Username != schema.DefaultOIDCUpstreamConfiguration.Claims.Username {
+		validator.PushWarning(fmt.Errorf("OIDC upstream username claim '%s' is not the sub claim, it must be unique and "+
+			"never reassigned to another user by the upstream provider or this user gets the sessions and the second "+
+			"factor devices of the previous one", configuration.Claims.Username))
+	}
+
+	if configuration.Claims.DisplayName == "" {
+		configuration.Claims.DisplayName = schema.DefaultOIDCUpstreamConfiguration.Claims.DisplayName
+	}
+
+	if configuration.Claims.Email == "" {
+		configuration.Claims.Email = schema.DefaultOIDCUpstreamConfiguration.Claims.Email
+	}
+
+	if configuration.Claims.Groups == "" {
+		configuration.Claims.Groups = schema.DefaultOIDCUpstreamConfiguration.Claims.Groups
+	}
+
+	validateOIDCUpstreamGroupAssignments(configuration.GroupAssignments, validator)
+}
+
+func validateOIDCUpstreamGroupAssignments(assignments []schema.OIDCUpstreamGroupAssignmentConfiguration, validator *schema.StructValidator) {
+	usernames := map[string]int{}
+
+	for i, assignment := range assignments {
+		position := i + 1
+
+		switch {
+		case assignment.Username != "" && assignment.UsernamePattern != "":
+			validator.Push(fmt.Errorf("OIDC upstream group assignment at position %d must define either a username or a username_pattern, not both", position))
+		case assignment.Username != "":
+			if previous, ok := usernames[assignment.Username]; ok {
+				validator.Push(fmt.Errorf("OIDC upstream group assignment at position %d conflicts with the one at position %d for username '%s'", position, previous, assignment.Username))
+			} else {
+				usernames[assignment.Username] = position
+			}
+		case assignment.UsernamePattern != "":
+			if _, err := regexp.Compile(assignment.UsernamePattern); err != nil {
+				validator.Push(fmt.Errorf("OIDC upstream group assignment at position %d has an invalid username_pattern '%s': %s", position, assignment.UsernamePattern, err))
+			}
+		default:
+			validator.Push(fmt.Errorf("OIDC upstream group assignment at position %d must define a username or a username_pattern", position))
+		}
+
+		if len(assignment.Groups) == 0 {
+			validator.Push(fmt.Errorf("OIDC upstream group assignment at position %d must assign at least one group", position))
+		}
+
+		for _, group := range assignment.Groups {
+			if group == "" {
+				validator.Push(fmt.Errorf("OIDC upstream group assignment at position %d assigns an empty group", position))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type OIDCUpstream struct {
+	suite.Suite
+	configuration *schema.OIDCUpstreamConfiguration
+	validator     *schema.StructValidator
+}
+
+func (suite *OIDCUpstream) SetupTest() {
+	suite.validator = schema.NewStructValidator()
+	suite.configuration = &schema.OIDCUpstreamConfiguration{
+		Issuer:       "https://idp.example.com",
+		ClientID:     "authelia",
+		ClientSecret: "secret",
+		RedirectURI:  "https://login.example.com/api/oidc-upstream/callback",
+	}
+}
+
+func (suite *OIDCUpstream) TestShouldValidateCompleteConfigurationAndSetDefaults() {
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+
+	suite.Assert().Equal("SSO", suite.configuration.DisplayName)
+	suite.Assert().Equal([]string{"openid", "profile", "email", "groups"}, suite.configuration.Scopes)
+	suite.Assert().Equal("10s", suite.configuration.Timeout)
+	suite.Assert().Equal(schema.DefaultOIDCUpstreamConfiguration.Claims, suite.configuration.Claims)
+}
+
+func (suite *OIDCUpstream) TestShouldRaiseErrorsWhenRequiredValuesMissing() {
+	suite.configuration = &schema.OIDCUpstreamConfiguration{}
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 4)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "OIDC upstream issuer must be provided")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "OIDC upstream client_id must be provided")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "OIDC upstream client_secret must be provided")
+	suite.Assert().EqualError(suite.validator.Errors()[3], "OIDC upstream redirect_uri must be provided")
+}
+
+func (suite *OIDCUpstream) TestShouldWarnWhenUsernameClaimIsNotSubject() {
+	suite.configuration.Claims.Username = "preferred_username"
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasErrors())
+	suite.Require().Len(suite.validator.Warnings(), 1)
+	suite.Assert().EqualError(suite.validator.Warnings()[0], "OIDC upstream username claim 'preferred_username' is not "+
+		"the sub claim, it must be unique and never reassigned to another user by the upstream provider or this user "+
+		"gets the sessions and the second factor devices of the previous one")
+}
+
+func (suite *OIDCUpstream) TestShouldRaiseErrorsWhenURLsAreNotHTTPS() {
+	suite.configuration.Issuer = "http://idp.example.com"
+	suite.configuration.RedirectURI = "/api/oidc-upstream/callback"
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "OIDC upstream issuer 'http://idp.example.com' must be an absolute https URL")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "OIDC upstream redirect_uri '/api/oidc-upstream/callback' must be an absolute https URL")
+}
+
+func (suite *OIDCUpstream) TestShouldRaiseErrorWhenOpenIDScopeMissing() {
+	suite.configuration.Scopes = []string{"profile", "email"}
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "OIDC upstream scopes must include the openid scope")
+}
+
+func (suite *OIDCUpstream) TestShouldRaiseErrorWhenTimeoutInvalid() {
+	suite.configuration.Timeout = "abc"
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Error occurred parsing OIDC upstream timeout string: Could not convert the input string of abc into a duration")
+}
+
+func (suite *OIDCUpstream) TestShouldValidateGroupAssignments() {
+	suite.configuration.GroupAssignments = []schema.OIDCUpstreamGroupAssignmentConfiguration{
+		{Username: "john", Groups: []string{"admins"}},
+		{UsernamePattern: "^svc-.*$", Groups: []string{"services"}},
+	}
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *OIDCUpstream) TestShouldRaiseErrorsWhenGroupAssignmentsConflict() {
+	suite.configuration.GroupAssignments = []schema.OIDCUpstreamGroupAssignmentConfiguration{
+		{Username: "john", Groups: []string{"admins"}},
+		{Username: "john", UsernamePattern: "^j.*$", Groups: []string{"dev"}},
+		{Username: "john", Groups: []string{"dev"}},
+		{UsernamePattern: "^(svc$", Groups: []string{"services"}},
+		{Groups: []string{"dev"}},
+		{Username: "harry"},
+		{Username: "bob", Groups: []string{""}},
+	}
+
+	ValidateOIDCUpstream(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 6)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "OIDC upstream group assignment at position 2 must define either a username or a username_pattern, not both")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "OIDC upstream group assignment at position 3 conflicts with the one at position 1 for username 'john'")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "OIDC upstream group assignment at position 4 has an invalid username_pattern '^(svc$': error parsing regexp: missing closing ): `^(svc$`")
+	suite.Assert().EqualError(suite.validator.Errors()[3], "OIDC upstream group assignment at position 5 must define a username or a username_pattern")
+	suite.Assert().EqualError(suite.validator.Errors()[4], "OIDC upstream group assignment at position 6 must assign at least one group")
+	suite.Assert().EqualError(suite.validator.Errors()[5], "OIDC upstream group assignment at position 7 assigns an empty group")
+}
+
+func TestOIDCUpstream(t *testing.T) {
+	suite.Run(t, new(OIDCUpstream))
+}
@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ValidateRegistration validates the self-service registration configuration.
+func ValidateRegistration(configuration *schema.RegistrationConfiguration, validator *schema.StructValidator) {
+	if !configuration.Enabled {
+		return
+	}
+
+	if len(configuration.AllowedDomains) == 0 {
+		validator.Push(fmt.Errorf("Registration allowed_domains must be provided when the registration is enabled"))
+	}
+
+	for _, domain := range configuration.AllowedDomains {
+		if !isValidRegistrationDomain(domain) {
+			validator.Push(fmt.Errorf("Registration allowed domain '%s' is invalid, it must be a domain like example.com or a wildcard like *.example.com", domain))
+		}
+	}
+
+	if configuration.RequireApproval && len(configuration.AdminGroups) == 0 {
+		validator.Push(fmt.Errorf("Registration admin_groups must be provided when the registration requires an approval"))
+	}
+
+	for i, group := range configuration.Groups {
+		if strings.TrimSpace(group) == "" {
+			validator.Push(fmt.Errorf("The group at position %d of `registration` is empty", i+1))
+		}
+	}
+}
+
+// isValidRegistrationDomain returns true if the domain is a plain domain or a wildcard matching its subdomains.
+func isValidRegistrationDomain(domain string) bool {
+	domain = strings.TrimPrefix(domain, "*.")
+
+	return domain != "" && !strings.ContainsAny(domain, "@*/: ") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// validateRegistrationBackend ensures the accounts can be created in the authentication backend.
+func validateRegistrationBackend(configuration *schema.Configuration, validator *schema.StructValidator) {
+	if configuration.Registration.Enabled && configuration.AuthenticationBackend.File == nil {
+		validator.Push(fmt.Errorf("Registration requires the file authentication backend"))
+	}
+}
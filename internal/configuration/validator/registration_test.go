@@ -0,0 +1,69 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldValidateEnabledRegistration(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.RegistrationConfiguration{
+		Enabled:         true,
+		AllowedDomains:  []string{"partner.com", "*.example.com"},
+		RequireApproval: true,
+		AdminGroups:     []string{"admins"},
+		Groups:          []string{"partners"},
+	}
+
+	ValidateRegistration(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldNotValidateDisabledRegistration(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.RegistrationConfiguration{
+		AllowedDomains:  []string{"invalid@domain"},
+		RequireApproval: true,
+	}
+
+	ValidateRegistration(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorWhenRegistrationAllowedDomainsMissing(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.RegistrationConfiguration{Enabled: true}
+
+	ValidateRegistration(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Registration allowed_domains must be provided when the registration is enabled")
+}
+
+func TestShouldRaiseErrorsWhenRegistrationOptionsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.RegistrationConfiguration{
+		Enabled:         true,
+		AllowedDomains:  []string{"@example.com", "*", "a.*.example.com", "example.com."},
+		RequireApproval: true,
+		Groups:          []string{"partners", ""},
+	}
+
+	ValidateRegistration(&config, validator)
+
+	require.Len(t, validator.Errors(), 6)
+	assert.EqualError(t, validator.Errors()[0], "Registration allowed domain '@example.com' is invalid, it must be a domain like example.com or a wildcard like *.example.com")
+	assert.EqualError(t, validator.Errors()[1], "Registration allowed domain '*' is invalid, it must be a domain like example.com or a wildcard like *.example.com")
+	assert.EqualError(t, validator.Errors()[2], "Registration allowed domain 'a.*.example.com' is invalid, it must be a domain like example.com or a wildcard like *.example.com")
+	assert.EqualError(t, validator.Errors()[3], "Registration allowed domain 'example.com.' is invalid, it must be a domain like example.com or a wildcard like *.example.com")
+	assert.EqualError(t, validator.Errors()[4], "Registration admin_groups must be provided when the registration requires an approval")
+	assert.EqualError(t, validator.Errors()[5], "The group at position 2 of `registration` is empty")
+}
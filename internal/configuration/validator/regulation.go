@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
@@ -30,4 +31,33 @@ func ValidateRegulation(configuration *schema.RegulationConfiguration, validator
 	if findTime > banTime {
 		validator.Push(fmt.Errorf("find_time cannot be greater than ban_time"))
 	}
+
+	validateRegulationDelay(configuration, validator)
+
+	for i, group := range configuration.AdminGroups {
+		if strings.TrimSpace(group) == "" {
+			validator.Push(fmt.Errorf("Regulation admin group at position %d must not be empty", i+1))
+		}
+	}
+}
+
+func validateRegulationDelay(configuration *schema.RegulationConfiguration, validator *schema.StructValidator) {
+	if configuration.DelayIncrement == "" {
+		configuration.DelayIncrement = schema.DefaultRegulationConfiguration.DelayIncrement
+	}
+
+	if configuration.MaxDelay == "" {
+		configuration.MaxDelay = schema.DefaultRegulationConfiguration.MaxDelay
+	}
+
+	if _, err := utils.ParseDurationString(configuration.DelayIncrement); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing regulation delay_increment string: %s", err))
+	}
+
+	maxDelay, err := utils.ParseDurationString(configuration.MaxDelay)
+	if err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing regulation max_delay string: %s", err))
+	} else if maxDelay > regulationMaximumDelay {
+		validator.Push(fmt.Errorf("Regulation max_delay must not be greater than %s", regulationMaximumDelay))
+	}
 }
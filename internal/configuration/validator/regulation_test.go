@@ -57,3 +57,50 @@ func TestShouldRaiseErrorOnBadDurationStrings(t *testing.T) {
 	assert.EqualError(t, validator.Errors()[0], "Error occurred parsing regulation find_time string: Could not convert the input string of a year into a duration")
 	assert.EqualError(t, validator.Errors()[1], "Error occurred parsing regulation ban_time string: Could not convert the input string of forever into a duration")
 }
+
+func TestShouldRaiseErrorOnEmptyAdminGroup(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.AdminGroups = []string{"admins", " "}
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Regulation admin group at position 2 must not be empty")
+}
+
+func TestShouldSetDefaultRegulationDelay(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "0", config.DelayIncrement)
+	assert.Equal(t, "10s", config.MaxDelay)
+}
+
+func TestShouldRaiseErrorOnBadDelayStrings(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.DelayIncrement = "a bit"
+	config.MaxDelay = "a lot"
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "Error occurred parsing regulation delay_increment string: Could not convert the input string of a bit into a duration")
+	assert.EqualError(t, validator.Errors()[1], "Error occurred parsing regulation max_delay string: Could not convert the input string of a lot into a duration")
+}
+
+func TestShouldRaiseErrorWhenMaxDelayIsTooLong(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultRegulationConfig()
+	config.DelayIncrement = "5s"
+	config.MaxDelay = "1m"
+
+	ValidateRegulation(&config, validator)
+
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Regulation max_delay must not be greater than 30s")
+}
@@ -29,6 +29,7 @@ func isSecretKey(value string) (isSecretKey bool) {
 func ValidateSecrets(configuration *schema.Configuration, validator *schema.StructValidator, viper *viper.Viper) {
 	configuration.JWTSecret = getSecretValue(SecretNames["JWTSecret"], validator, viper)
 	configuration.Session.Secret = getSecretValue(SecretNames["SessionSecret"], validator, viper)
+	configuration.Session.EncryptionKey = getSecretValue(SecretNames["SessionEncryptionKey"], validator, viper)
 
 	if configuration.DuoAPI != nil {
 		configuration.DuoAPI.SecretKey = getSecretValue(SecretNames["DUOSecretKey"], validator, viper)
@@ -51,6 +52,8 @@ func ValidateSecrets(configuration *schema.Configuration, validator *schema.Stru
 		configuration.Notifier.SMTP.Password = getSecretValue(SecretNames["SMTPPassword"], validator, viper)
 	}
 
+	configuration.Storage.EncryptionKey = getSecretValue(SecretNames["StorageEncryptionKey"], validator, viper)
+
 	if configuration.Storage.MySQL != nil {
 		configuration.Storage.MySQL.Password = getSecretValue(SecretNames["MySQLPassword"], validator, viper)
 	}
@@ -58,6 +61,10 @@ func ValidateSecrets(configuration *schema.Configuration, validator *schema.Stru
 	if configuration.Storage.PostgreSQL != nil {
 		configuration.Storage.PostgreSQL.Password = getSecretValue(SecretNames["PostgreSQLPassword"], validator, viper)
 	}
+
+	if configuration.OIDCUpstream != nil {
+		configuration.OIDCUpstream.ClientSecret = getSecretValue(SecretNames["OIDCUpstreamSecret"], validator, viper)
+	}
 }
 
 func getSecretValue(name string, validator *schema.StructValidator, viper *viper.Viper) string {
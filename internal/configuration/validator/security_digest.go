@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ValidateSecurityDigest validates the security digest configuration and sets its defaults.
+func ValidateSecurityDigest(configuration *schema.SecurityDigestConfiguration, validator *schema.StructValidator) {
+	if configuration.Interval == "" {
+		configuration.Interval = schema.DefaultSecurityDigestConfiguration.Interval
+	} else if _, err := utils.ParseDurationString(configuration.Interval); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing security digest interval string: %s", err))
+	}
+
+	if configuration.Timeout == "" {
+		configuration.Timeout = schema.DefaultSecurityDigestConfiguration.Timeout
+	} else if _, err := utils.ParseDurationString(configuration.Timeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing security digest timeout string: %s", err))
+	}
+
+	if len(configuration.Recipients) == 0 && configuration.WebhookURL == "" {
+		validator.Push(fmt.Errorf("Security digest requires at least one of recipients or webhook_url"))
+	}
+
+	for _, recipient := range configuration.Recipients {
+		// The recipients are used as is by the notifier, they cannot include a display name.
+		if address, err := mail.ParseAddress(recipient); err != nil || address.Address != recipient {
+			validator.Push(fmt.Errorf("Security digest recipient '%s' must be an email address like user@example.com", recipient))
+		}
+	}
+
+	if configuration.WebhookURL != "" {
+		if webhookURL, err := url.ParseRequestURI(configuration.WebhookURL); err != nil || (webhookURL.Scheme != "https" && webhookURL.Scheme != "http") || webhookURL.Host == "" {
+			validator.Push(fmt.Errorf("Security digest webhook_url '%s' must be an absolute http or https URL", configuration.WebhookURL))
+		}
+	}
+
+	for _, event := range configuration.ImmediateEvents {
+		if !utils.IsStringInSlice(event, validSecurityEvents) {
+			validator.Push(fmt.Errorf("Security digest immediate event '%s' is invalid, it must be one of %s", event, strings.Join(validSecurityEvents, ", ")))
+		}
+	}
+}
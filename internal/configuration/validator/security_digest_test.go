@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldSetDefaultSecurityDigestValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.SecurityDigestConfiguration{
+		Recipients: []string{"ops@example.com"},
+	}
+
+	ValidateSecurityDigest(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, "1d", config.Interval)
+	assert.Equal(t, "5s", config.Timeout)
+}
+
+func TestShouldValidateSecurityDigestWithWebhook(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.SecurityDigestConfiguration{
+		Interval:        "1h",
+		WebhookURL:      "https://hooks.example.com/authelia",
+		ImmediateEvents: []string{"ban", "notification_failure"},
+	}
+
+	ValidateSecurityDigest(&config, validator)
+
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, "1h", config.Interval)
+}
+
+func TestShouldRaiseErrorWhenSecurityDigestHasNoDestination(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.SecurityDigestConfiguration{}
+
+	ValidateSecurityDigest(&config, validator)
+
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Security digest requires at least one of recipients or webhook_url")
+}
+
+func TestShouldRaiseErrorsWhenSecurityDigestOptionsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.SecurityDigestConfiguration{
+		Interval:        "1 hour",
+		Timeout:         "abc",
+		Recipients:      []string{"Ops <ops@example.com>"},
+		WebhookURL:      "hooks.example.com",
+		ImmediateEvents: []string{"ban", "login"},
+	}
+
+	ValidateSecurityDigest(&config, validator)
+
+	assert.Len(t, validator.Errors(), 5)
+	assert.EqualError(t, validator.Errors()[0], "Error occurred parsing security digest interval string: Could not convert the input string of 1 hour into a duration")
+	assert.EqualError(t, validator.Errors()[1], "Error occurred parsing security digest timeout string: Could not convert the input string of abc into a duration")
+	assert.EqualError(t, validator.Errors()[2], "Security digest recipient 'Ops <ops@example.com>' must be an email address like user@example.com")
+	assert.EqualError(t, validator.Errors()[3], "Security digest webhook_url 'hooks.example.com' must be an absolute http or https URL")
+	assert.EqualError(t, validator.Errors()[4], "Security digest immediate event 'login' is invalid, it must be one of ban, authentication_failure, notification_failure")
+}
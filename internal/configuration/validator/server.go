@@ -1,8 +1,14 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
@@ -12,6 +18,15 @@ import (
 var defaultReadBufferSize = 4096
 var defaultWriteBufferSize = 4096
 
+var headerNameRegexp = regexp.MustCompile("^[A-Za-z0-9-]+$")
+
+var verifyEndpointPathRegexp = regexp.MustCompile("^(/[A-Za-z0-9._~-]+)+$")
+
+// basePathRegexp matches the paths made of segments which don't need to be escaped, excluding the dot segments.
+var basePathRegexp = regexp.MustCompile("^(/[A-Za-z0-9_~-][A-Za-z0-9._~-]*)+$")
+
+var validVerifyProfiles = []string{schema.VerifyProfileDefault, schema.VerifyProfileTraefik, schema.VerifyProfileNGINX, schema.VerifyProfileHAProxy}
+
 // ValidateServer checks a server configuration is correct.
 func ValidateServer(configuration *schema.ServerConfiguration, validator *schema.StructValidator) {
 	switch {
@@ -24,6 +39,8 @@ func ValidateServer(configuration *schema.ServerConfiguration, validator *schema
 		configuration.Path = path.Clean("/" + configuration.Path)
 	}
 
+	validateServerBasePath(configuration, validator)
+
 	if configuration.ReadBufferSize == 0 {
 		configuration.ReadBufferSize = defaultReadBufferSize
 	} else if configuration.ReadBufferSize < 0 {
@@ -35,4 +52,280 @@ func ValidateServer(configuration *schema.ServerConfiguration, validator *schema
 	} else if configuration.WriteBufferSize < 0 {
 		validator.Push(fmt.Errorf("server write buffer size must be above 0"))
 	}
+
+	if configuration.ForwardedHops < 0 {
+		validator.Push(fmt.Errorf("server forwarded hops must be 0 or above"))
+	}
+
+	if configuration.ForwardedHops > 0 && len(configuration.TrustedProxies) != 0 {
+		validator.Push(fmt.Errorf("server forwarded hops and trusted proxies must not be configured together"))
+	}
+
+	if configuration.ExternalURL != "" {
+		externalURL, err := url.Parse(configuration.ExternalURL)
+
+		switch {
+		case err != nil || externalURL.Host == "" || (externalURL.Scheme != "https" && !(externalURL.Scheme == schemeHTTP && isLoopbackHost(externalURL.Hostname()))):
+			validator.Push(fmt.Errorf("server external_url '%s' must be an absolute https URL", configuration.ExternalURL))
+		case externalURL.RawQuery != "" || externalURL.Fragment != "":
+			validator.Push(fmt.Errorf("server external_url '%s' must not have a query or a fragment", configuration.ExternalURL))
+		default:
+			configuration.ExternalURL = strings.TrimSuffix(configuration.ExternalURL, "/")
+		}
+	}
+
+	for i, proxy := range configuration.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err == nil {
+			continue
+		}
+
+		ip := net.ParseIP(proxy)
+
+		switch {
+		case ip == nil:
+			validator.Push(fmt.Errorf("server trusted proxy '%s' must be an IP address or a network in CIDR notation", proxy))
+		case ip.To4() != nil:
+			configuration.TrustedProxies[i] = proxy + "/32"
+		default:
+			configuration.TrustedProxies[i] = proxy + "/128"
+		}
+	}
+
+	for _, acceptType := range configuration.APIRequests.AcceptTypes {
+		parts := strings.Split(acceptType, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(acceptType, " ;,") {
+			validator.Push(fmt.Errorf("server api_requests accept type '%s' must be a media type of the form type/subtype", acceptType))
+		}
+	}
+
+	if configuration.RequestID.Enabled {
+		validateServerRequestID(&configuration.RequestID, validator)
+	}
+
+	validateServerCacheControl(&configuration.CacheControl, validator)
+
+	validateServerRequestSampling(&configuration.RequestSampling, validator)
+
+	validateServerVerifyEndpoints(configuration.VerifyEndpoints, validator)
+
+	switch configuration.StartupChecks {
+	case "":
+		configuration.StartupChecks = schema.DefaultServerConfiguration.StartupChecks
+	case schema.StartupChecksFail, schema.StartupChecksWarn:
+	default:
+		validator.Push(fmt.Errorf("server startup checks must be either %s or %s", schema.StartupChecksFail, schema.StartupChecksWarn))
+	}
+}
+
+func validateServerRequestID(configuration *schema.ServerRequestIDConfiguration, validator *schema.StructValidator) {
+	if len(configuration.Headers) == 0 {
+		configuration.Headers = schema.DefaultServerRequestIDConfiguration.Headers
+	}
+
+	if configuration.ResponseHeader == "" {
+		configuration.ResponseHeader = schema.DefaultServerRequestIDConfiguration.ResponseHeader
+	}
+
+	for _, header := range configuration.Headers {
+		if !headerNameRegexp.MatchString(header) {
+			validator.Push(fmt.Errorf("server request_id header '%s' must be a valid header name", header))
+		}
+	}
+
+	if !headerNameRegexp.MatchString(configuration.ResponseHeader) {
+		validator.Push(fmt.Errorf("server request_id response_header '%s' must be a valid header name", configuration.ResponseHeader))
+	}
+}
+
+func validateServerRequestSampling(configuration *schema.ServerRequestSamplingConfiguration, validator *schema.StructValidator) {
+	if configuration.Rate < 0 || configuration.Rate > 1 {
+		validator.Push(fmt.Errorf("server request_sampling rate must be between 0 and 1"))
+	}
+
+	if configuration.MaxPerMinute == 0 {
+		configuration.MaxPerMinute = schema.DefaultServerRequestSamplingConfiguration.MaxPerMinute
+	} else if configuration.MaxPerMinute < 0 {
+		validator.Push(fmt.Errorf("server request_sampling max_per_minute must be above 0"))
+	}
+
+	for _, header := range configuration.RedactedHeaders {
+		if !headerNameRegexp.MatchString(header) {
+			validator.Push(fmt.Errorf("server request_sampling redacted header '%s' must be a valid header name", header))
+		}
+	}
+}
+
+// validateServerCacheControl sets the default sensitive policy and warns when it allows the responses of the portal and
+// the API to be stored by the browsers or intermediaries.
+func validateServerCacheControl(configuration *schema.ServerCacheControlConfiguration, validator *schema.StructValidator) {
+	if configuration.Sensitive == "" {
+		configuration.Sensitive = schema.DefaultServerCacheControlConfiguration.Sensitive
+	}
+
+	if strings.ContainsAny(configuration.Sensitive, "\r\n") {
+		validator.Push(fmt.Errorf("server cache_control sensitive must not contain line breaks"))
+	}
+
+	if strings.ContainsAny(configuration.Static, "\r\n") {
+		validator.Push(fmt.Errorf("server cache_control static must not contain line breaks"))
+	}
+
+	if !strings.Contains(strings.ToLower(configuration.Sensitive), "no-store") {
+		validator.PushWarning(fmt.Errorf("server cache_control sensitive '%s' allows the responses of the portal and the API to be cached, they may contain sensitive information", configuration.Sensitive))
+	}
+}
+
+// validateServerVerifyEndpoints checks the additional verify endpoints have distinct paths which do not collide with
+// the other endpoints of Authelia, and sets their defaults.
+func validateServerVerifyEndpoints(endpoints []schema.ServerVerifyEndpointConfiguration, validator *schema.StructValidator) {
+	paths := make([]string, 0, len(endpoints))
+
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+
+		switch {
+		case !verifyEndpointPathRegexp.MatchString(endpoint.Path):
+			validator.Push(fmt.Errorf("server verify endpoint path '%s' must be an absolute path such as /api/verify/nginx", endpoint.Path))
+		case endpoint.Path == schema.DefaultServerVerifyEndpointConfiguration.Path,
+			strings.HasPrefix(endpoint.Path, "/api/") && !strings.HasPrefix(endpoint.Path, "/api/verify/"),
+			strings.HasPrefix(endpoint.Path, "/static/"):
+			validator.Push(fmt.Errorf("server verify endpoint path '%s' collides with another endpoint of Authelia", endpoint.Path))
+		case utils.IsStringInSlice(endpoint.Path, paths):
+			validator.Push(fmt.Errorf("server verify endpoint path '%s' is defined more than once", endpoint.Path))
+		default:
+			paths = append(paths, endpoint.Path)
+		}
+
+		if endpoint.Profile == "" {
+			endpoint.Profile = schema.DefaultServerVerifyEndpointConfiguration.Profile
+		} else if !utils.IsStringInSlice(endpoint.Profile, validVerifyProfiles) {
+			validator.Push(fmt.Errorf("server verify endpoint '%s' profile '%s' must be one of %s",
+				endpoint.Path, endpoint.Profile, strings.Join(validVerifyProfiles, ", ")))
+		}
+
+		validateServerVerifyEndpointHeaders(endpoint, validator)
+	}
+}
+
+func validateServerVerifyEndpointHeaders(endpoint *schema.ServerVerifyEndpointConfiguration, validator *schema.StructValidator) {
+	defaults := schema.DefaultServerVerifyEndpointConfiguration.Headers
+
+	for _, header := range []struct {
+		name  string
+		value *string
+		def   string
+	}{
+		{"user", &endpoint.Headers.User, defaults.User},
+		{"groups", &endpoint.Headers.Groups, defaults.Groups},
+		{"name", &endpoint.Headers.Name, defaults.Name},
+		{"email", &endpoint.Headers.Email, defaults.Email},
+	} {
+		if *header.value == "" {
+			*header.value = header.def
+		} else if !headerNameRegexp.MatchString(*header.value) {
+			validator.Push(fmt.Errorf("server verify endpoint '%s' %s header '%s' must be a valid header name",
+				endpoint.Path, header.name, *header.value))
+		}
+	}
+}
+
+// validateUnixSocket checks the configuration of a host listening on a unix domain socket.
+func validateUnixSocket(configuration *schema.Configuration, validator *schema.StructValidator) {
+	if socketPath := strings.TrimPrefix(configuration.Host, schema.UnixSocketHostPrefix); !filepath.IsAbs(socketPath) {
+		validator.Push(fmt.Errorf("The unix socket path '%s' of the host must be absolute", socketPath))
+	}
+
+	if configuration.Port != 0 {
+		validator.Push(fmt.Errorf("The port must not be configured when the host is a unix socket"))
+	}
+
+	if configuration.Server.UnixSocketMode == "" {
+		configuration.Server.UnixSocketMode = schema.DefaultServerConfiguration.UnixSocketMode
+	} else if mode, err := strconv.ParseUint(configuration.Server.UnixSocketMode, 8, 32); err != nil || mode > 0777 {
+		validator.Push(fmt.Errorf("server unix_socket_mode '%s' must be an octal file mode such as 0660", configuration.Server.UnixSocketMode))
+	}
+}
+
+// ValidateServerHTTPRedirect checks the HTTP to HTTPS redirect listener is correct and doesn't collide with the main
+// listener.
+func ValidateServerHTTPRedirect(configuration *schema.Configuration, validator *schema.StructValidator) {
+	redirect := configuration.Server.HTTPRedirect
+
+	if strings.HasPrefix(configuration.Host, schema.UnixSocketHostPrefix) {
+		validator.Push(fmt.Errorf("server http_redirect can't be used when the host is a unix socket"))
+		return
+	}
+
+	if redirect.Host == "" {
+		redirect.Host = configuration.Host
+	}
+
+	if redirect.Port == 0 {
+		redirect.Port = schema.DefaultServerHTTPRedirectConfiguration.Port
+	} else if redirect.Port < 0 || redirect.Port > 65535 {
+		validator.Push(fmt.Errorf("server http_redirect port must be between 1 and 65535"))
+	}
+
+	if configuration.TLSCert == "" || configuration.TLSKey == "" {
+		validator.Push(fmt.Errorf("server http_redirect requires the tls_cert and tls_key to be configured"))
+	}
+
+	if redirect.Port == configuration.Port && isListenerHostColliding(redirect.Host, configuration.Host) {
+		validator.Push(fmt.Errorf("server http_redirect listener %s collides with the main listener %s",
+			net.JoinHostPort(redirect.Host, strconv.Itoa(redirect.Port)), net.JoinHostPort(configuration.Host, strconv.Itoa(configuration.Port))))
+	}
+}
+
+// isListenerHostColliding returns true if two listeners on the same port would bind the same address.
+func isListenerHostColliding(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	for _, host := range []string{a, b} {
+		if host == "" {
+			return true
+		}
+
+		if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLoopbackHost returns true if the host is localhost or a loopback IP, allowing a plain http external URL for local
+// development.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateServerBasePath checks the base path the portal is served under, mapping the deprecated path to it.
+func validateServerBasePath(configuration *schema.ServerConfiguration, validator *schema.StructValidator) {
+	// Deprecated. Maps deprecated values to the new ones. TODO: Remove in 4.30 (if block).
+	if configuration.Path != "" {
+		validator.PushWarning(errors.New("DEPRECATED: server `path` option has been replaced by `server.base_path` (will be removed in 4.30.0)"))
+
+		// The path is only prefixed by a forward slash once it has been validated.
+		if configuration.BasePath == "" && strings.HasPrefix(configuration.Path, "/") {
+			configuration.BasePath = configuration.Path
+		}
+	}
+
+	switch {
+	case configuration.BasePath == "":
+	case !strings.HasPrefix(configuration.BasePath, "/"):
+		validator.Push(fmt.Errorf("server base_path '%s' must start with a forward slash", configuration.BasePath))
+	case strings.HasSuffix(configuration.BasePath, "/"):
+		validator.Push(fmt.Errorf("server base_path '%s' must not end with a forward slash", configuration.BasePath))
+	case !basePathRegexp.MatchString(configuration.BasePath):
+		validator.Push(fmt.Errorf("server base_path '%s' must only contain segments made of alpha numeric characters, '.', '_', '~' and '-' such as /auth/portal", configuration.BasePath))
+	}
 }
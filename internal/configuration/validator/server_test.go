@@ -16,6 +16,89 @@ func TestShouldSetDefaultConfig(t *testing.T) {
 	require.Len(t, validator.Errors(), 0)
 	assert.Equal(t, defaultReadBufferSize, config.ReadBufferSize)
 	assert.Equal(t, defaultWriteBufferSize, config.WriteBufferSize)
+	assert.Equal(t, schema.StartupChecksFail, config.StartupChecks)
+}
+
+func TestShouldRaiseOnNegativeForwardedHops(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		ForwardedHops: -1,
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server forwarded hops must be 0 or above")
+}
+
+func TestShouldRaiseOnForwardedHopsWithTrustedProxies(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		ForwardedHops:  1,
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server forwarded hops and trusted proxies must not be configured together")
+}
+
+func TestShouldNormalizeExternalURLAndTrustedProxies(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		ExternalURL:    "https://login.example.com/auth/",
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "fd00::1"},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+
+	assert.Equal(t, "https://login.example.com/auth", config.ExternalURL)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::1/128"}, config.TrustedProxies)
+}
+
+func TestShouldRaiseOnInvalidExternalURLAndTrustedProxies(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		ExternalURL:    "login.example.com",
+		TrustedProxies: []string{"10.0.0.0/33", "proxy"},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 3)
+
+	assert.EqualError(t, validator.Errors()[0], "server external_url 'login.example.com' must be an absolute https URL")
+	assert.EqualError(t, validator.Errors()[1], "server trusted proxy '10.0.0.0/33' must be an IP address or a network in CIDR notation")
+	assert.EqualError(t, validator.Errors()[2], "server trusted proxy 'proxy' must be an IP address or a network in CIDR notation")
+
+	validator = schema.NewStructValidator()
+	config = schema.ServerConfiguration{ExternalURL: "https://login.example.com/?a=b"}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server external_url 'https://login.example.com/?a=b' must not have a query or a fragment")
+
+	validator = schema.NewStructValidator()
+	config = schema.ServerConfiguration{ExternalURL: "http://login.example.com"}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server external_url 'http://login.example.com' must be an absolute https URL")
+}
+
+func TestShouldRaiseOnInvalidStartupChecks(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		StartupChecks: "ignore",
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server startup checks must be either fail or warn")
 }
 
 func TestShouldParsePathCorrectly(t *testing.T) {
@@ -26,8 +109,65 @@ func TestShouldParsePathCorrectly(t *testing.T) {
 
 	ValidateServer(&config, validator)
 	require.Len(t, validator.Errors(), 0)
+	require.Len(t, validator.Warnings(), 1)
 
 	assert.Equal(t, "/apple", config.Path)
+	assert.Equal(t, "/apple", config.BasePath)
+	assert.EqualError(t, validator.Warnings()[0], "DEPRECATED: server `path` option has been replaced by `server.base_path` (will be removed in 4.30.0)")
+}
+
+func TestShouldValidateBasePath(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		BasePath: "/auth/portal",
+	}
+
+	ValidateServer(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Len(t, validator.Warnings(), 0)
+	assert.Equal(t, "/auth/portal", config.BasePath)
+}
+
+func TestShouldPreferBasePathOverDeprecatedPath(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		Path:     "apple",
+		BasePath: "/auth/portal",
+	}
+
+	ValidateServer(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Len(t, validator.Warnings(), 1)
+	assert.Equal(t, "/auth/portal", config.BasePath)
+}
+
+func TestShouldRaiseOnInvalidBasePath(t *testing.T) {
+	testCases := []struct {
+		basePath, expected string
+	}{
+		{"auth", "server base_path 'auth' must start with a forward slash"},
+		{"/", "server base_path '/' must not end with a forward slash"},
+		{"/auth/", "server base_path '/auth/' must not end with a forward slash"},
+		{"/auth//portal", "server base_path '/auth//portal' must only contain segments made of alpha numeric characters, '.', '_', '~' and '-' such as /auth/portal"},
+		{"/auth/../portal", "server base_path '/auth/../portal' must only contain segments made of alpha numeric characters, '.', '_', '~' and '-' such as /auth/portal"},
+		{"/auth portal", "server base_path '/auth portal' must only contain segments made of alpha numeric characters, '.', '_', '~' and '-' such as /auth/portal"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.basePath, func(t *testing.T) {
+			validator := schema.NewStructValidator()
+			config := schema.ServerConfiguration{
+				BasePath: tc.basePath,
+			}
+
+			ValidateServer(&config, validator)
+
+			require.Len(t, validator.Errors(), 1)
+			assert.EqualError(t, validator.Errors()[0], tc.expected)
+		})
+	}
 }
 
 func TestShouldRaiseOnNegativeValues(t *testing.T) {
@@ -61,3 +201,236 @@ func TestShouldRaiseOnForwardSlashInPath(t *testing.T) {
 	assert.Len(t, validator.Errors(), 1)
 	assert.Error(t, validator.Errors()[0], "server path must not contain any forward slashes")
 }
+
+func TestShouldRaiseOnInvalidAPIRequestsAcceptTypes(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		APIRequests: schema.ServerAPIRequestsConfiguration{
+			AcceptTypes: []string{"application/json", "json", "application/json; charset=utf-8"},
+		},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 2)
+
+	assert.EqualError(t, validator.Errors()[0], "server api_requests accept type 'json' must be a media type of the form type/subtype")
+	assert.EqualError(t, validator.Errors()[1], "server api_requests accept type 'application/json; charset=utf-8' must be a media type of the form type/subtype")
+}
+
+func TestShouldSetDefaultRequestIDHeaders(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		RequestID: schema.ServerRequestIDConfiguration{Enabled: true},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+
+	assert.Equal(t, []string{"X-Request-ID", "X-Correlation-ID"}, config.RequestID.Headers)
+	assert.Equal(t, "X-Request-ID", config.RequestID.ResponseHeader)
+}
+
+func TestShouldRaiseOnInvalidRequestIDHeaders(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		RequestID: schema.ServerRequestIDConfiguration{
+			Enabled:        true,
+			Headers:        []string{"X-Trace-ID", "X Request ID"},
+			ResponseHeader: "X-Request-ID:",
+		},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 2)
+
+	assert.EqualError(t, validator.Errors()[0], "server request_id header 'X Request ID' must be a valid header name")
+	assert.EqualError(t, validator.Errors()[1], "server request_id response_header 'X-Request-ID:' must be a valid header name")
+}
+
+func TestShouldSetDefaultCacheControlPolicy(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+	require.Len(t, validator.Warnings(), 0)
+
+	assert.Equal(t, "no-store", config.CacheControl.Sensitive)
+	assert.Equal(t, "", config.CacheControl.Static)
+}
+
+func TestShouldWarnWhenSensitiveResponsesMayBeCached(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		CacheControl: schema.ServerCacheControlConfiguration{
+			Sensitive: "private, max-age=60",
+			Static:    "public, max-age=86400",
+		},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+	require.Len(t, validator.Warnings(), 1)
+
+	assert.EqualError(t, validator.Warnings()[0], "server cache_control sensitive 'private, max-age=60' allows the responses of the portal and the API to be cached, they may contain sensitive information")
+}
+
+func TestShouldRaiseOnCacheControlPolicyWithLineBreaks(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		CacheControl: schema.ServerCacheControlConfiguration{
+			Sensitive: "no-store\r\nSet-Cookie: a=b",
+			Static:    "public\n",
+		},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 2)
+
+	assert.EqualError(t, validator.Errors()[0], "server cache_control sensitive must not contain line breaks")
+	assert.EqualError(t, validator.Errors()[1], "server cache_control static must not contain line breaks")
+}
+
+func TestShouldSetDefaultRequestSamplingMaxPerMinute(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		RequestSampling: schema.ServerRequestSamplingConfiguration{Rate: 0.01},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+
+	assert.Equal(t, 60, config.RequestSampling.MaxPerMinute)
+}
+
+func TestShouldRaiseOnInvalidRequestSampling(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		RequestSampling: schema.ServerRequestSamplingConfiguration{
+			Rate:            1.5,
+			MaxPerMinute:    -1,
+			RedactedHeaders: []string{"X-Api-Key", "X Secret"},
+		},
+	}
+
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 3)
+
+	assert.EqualError(t, validator.Errors()[0], "server request_sampling rate must be between 0 and 1")
+	assert.EqualError(t, validator.Errors()[1], "server request_sampling max_per_minute must be above 0")
+	assert.EqualError(t, validator.Errors()[2], "server request_sampling redacted header 'X Secret' must be a valid header name")
+}
+
+func newHTTPRedirectConfig() schema.Configuration {
+	config := schema.Configuration{
+		Host:    "0.0.0.0",
+		Port:    443,
+		TLSCert: "/config/cert.pem",
+		TLSKey:  "/config/key.pem",
+	}
+	config.Server.HTTPRedirect = &schema.ServerHTTPRedirectConfiguration{}
+
+	return config
+}
+
+func TestShouldSetDefaultHTTPRedirectConfig(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newHTTPRedirectConfig()
+
+	ValidateServerHTTPRedirect(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+
+	assert.Equal(t, "0.0.0.0", config.Server.HTTPRedirect.Host)
+	assert.Equal(t, 80, config.Server.HTTPRedirect.Port)
+}
+
+func TestShouldRaiseOnHTTPRedirectWithoutTLS(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newHTTPRedirectConfig()
+	config.TLSKey = ""
+
+	ValidateServerHTTPRedirect(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server http_redirect requires the tls_cert and tls_key to be configured")
+}
+
+func TestShouldRaiseOnHTTPRedirectInvalidPort(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newHTTPRedirectConfig()
+	config.Server.HTTPRedirect.Port = 70000
+
+	ValidateServerHTTPRedirect(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server http_redirect port must be between 1 and 65535")
+}
+
+func TestShouldRaiseOnHTTPRedirectCollidingWithMainListener(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newHTTPRedirectConfig()
+	config.Server.HTTPRedirect.Host = "192.168.1.10"
+	config.Server.HTTPRedirect.Port = 443
+
+	ValidateServerHTTPRedirect(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], "server http_redirect listener 192.168.1.10:443 collides with the main listener 0.0.0.0:443")
+}
+
+func TestShouldNotRaiseOnHTTPRedirectOnDistinctAddressSamePort(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newHTTPRedirectConfig()
+	config.Host = "192.168.1.10"
+	config.Server.HTTPRedirect.Host = "192.168.1.11"
+	config.Server.HTTPRedirect.Port = 443
+
+	ValidateServerHTTPRedirect(&config, validator)
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldSetDefaultVerifyEndpointProfileAndHeaders(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		VerifyEndpoints: []schema.ServerVerifyEndpointConfiguration{
+			{Path: "/api/verify/nginx", Profile: "nginx", Headers: schema.ServerVerifyEndpointHeadersConfiguration{User: "X-Forwarded-User"}},
+			{Path: "/authz/traefik"},
+		},
+	}
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, schema.VerifyProfileNGINX, config.VerifyEndpoints[0].Profile)
+	assert.Equal(t, schema.ServerVerifyEndpointHeadersConfiguration{
+		User:   "X-Forwarded-User",
+		Groups: "Remote-Groups",
+		Name:   "Remote-Name",
+		Email:  "Remote-Email",
+	}, config.VerifyEndpoints[0].Headers)
+	assert.Equal(t, schema.VerifyProfileDefault, config.VerifyEndpoints[1].Profile)
+	assert.Equal(t, schema.DefaultServerVerifyEndpointConfiguration.Headers, config.VerifyEndpoints[1].Headers)
+}
+
+func TestShouldRaiseOnInvalidVerifyEndpoints(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		VerifyEndpoints: []schema.ServerVerifyEndpointConfiguration{
+			{Path: "api/verify/nginx"},
+			{Path: "/api/verify"},
+			{Path: "/api/firstfactor"},
+			{Path: "/api/verify/haproxy", Profile: "apache"},
+			{Path: "/api/verify/haproxy", Headers: schema.ServerVerifyEndpointHeadersConfiguration{Groups: "Remote Groups"}},
+		},
+	}
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 6)
+	assert.EqualError(t, validator.Errors()[0], "server verify endpoint path 'api/verify/nginx' must be an absolute path such as /api/verify/nginx")
+	assert.EqualError(t, validator.Errors()[1], "server verify endpoint path '/api/verify' collides with another endpoint of Authelia")
+	assert.EqualError(t, validator.Errors()[2], "server verify endpoint path '/api/firstfactor' collides with another endpoint of Authelia")
+	assert.EqualError(t, validator.Errors()[3], "server verify endpoint '/api/verify/haproxy' profile 'apache' must be one of default, traefik, nginx, haproxy")
+	assert.EqualError(t, validator.Errors()[4], "server verify endpoint path '/api/verify/haproxy' is defined more than once")
+	assert.EqualError(t, validator.Errors()[5], "server verify endpoint '/api/verify/haproxy' groups header 'Remote Groups' must be a valid header name")
+}
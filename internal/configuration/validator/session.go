@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
@@ -43,12 +44,47 @@ func validateSession(configuration *schema.SessionConfiguration, validator *sche
 		validator.Push(fmt.Errorf("Error occurred parsing session inactivity string: %s", err))
 	}
 
+	validateSessionInactivityGrace(configuration, validator)
+
 	if configuration.RememberMeDuration == "" {
 		configuration.RememberMeDuration = schema.DefaultSessionConfiguration.RememberMeDuration // 1 month
 	} else if _, err := utils.ParseDurationString(configuration.RememberMeDuration); err != nil {
 		validator.Push(fmt.Errorf("Error occurred parsing session remember_me_duration string: %s", err))
 	}
 
+	if configuration.MaxSessionsPerUser < 0 {
+		validator.Push(errors.New("The session max_sessions_per_user must be 0 or above"))
+	}
+
+	switch {
+	case configuration.Memory.MaxSessions == 0:
+		configuration.Memory.MaxSessions = schema.DefaultSessionConfiguration.Memory.MaxSessions
+	case configuration.Memory.MaxSessions < 0:
+		validator.Push(errors.New("The session memory max_sessions must be above 0"))
+	}
+
+	switch configuration.SecondFactorChange {
+	case "":
+		configuration.SecondFactorChange = schema.DefaultSessionConfiguration.SecondFactorChange
+	case schema.SecondFactorChangeDowngrade, schema.SecondFactorChangeDestroy, schema.SecondFactorChangeIgnore:
+		break
+	default:
+		validator.Push(fmt.Errorf("The session second_factor_change must be one of '%s', '%s' or '%s' but it is configured as '%s'",
+			schema.SecondFactorChangeDowngrade, schema.SecondFactorChangeDestroy, schema.SecondFactorChangeIgnore, configuration.SecondFactorChange))
+	}
+
+	switch configuration.DomainCheck {
+	case "":
+		configuration.DomainCheck = schema.DefaultSessionConfiguration.DomainCheck
+	case schema.SessionDomainCheckError, schema.SessionDomainCheckWarn, schema.SessionDomainCheckDisable:
+		break
+	default:
+		validator.Push(fmt.Errorf("The session domain_check must be one of '%s', '%s' or '%s' but it is configured as '%s'",
+			schema.SessionDomainCheckError, schema.SessionDomainCheckWarn, schema.SessionDomainCheckDisable, configuration.DomainCheck))
+	}
+
+	validateSessionEncryptionKeys(configuration, validator)
+
 	if configuration.Domain == "" {
 		validator.Push(errors.New("Set domain of the session object"))
 	}
@@ -58,6 +94,68 @@ func validateSession(configuration *schema.SessionConfiguration, validator *sche
 	}
 }
 
+// validateSessionInactivityGrace ensures the inactivity grace period is only set along with the downgrade policy so that
+// keeping the inactive sessions at one factor is always an explicit choice.
+func validateSessionInactivityGrace(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
+	switch configuration.InactivityGracePolicy {
+	case "":
+		configuration.InactivityGracePolicy = schema.DefaultSessionConfiguration.InactivityGracePolicy
+	case schema.SessionInactivityGracePolicyDestroy, schema.SessionInactivityGracePolicyDowngrade:
+		break
+	default:
+		validator.Push(fmt.Errorf("The session inactivity_grace_policy must be one of '%s' or '%s' but it is configured as '%s'",
+			schema.SessionInactivityGracePolicyDestroy, schema.SessionInactivityGracePolicyDowngrade, configuration.InactivityGracePolicy))
+
+		return
+	}
+
+	var gracePeriod time.Duration
+
+	if configuration.InactivityGracePeriod != "" {
+		duration, err := utils.ParseDurationString(configuration.InactivityGracePeriod)
+		if err != nil {
+			validator.Push(fmt.Errorf("Error occurred parsing session inactivity_grace_period string: %s", err))
+			return
+		}
+
+		gracePeriod = duration
+	}
+
+	switch {
+	case gracePeriod != 0 && configuration.InactivityGracePolicy != schema.SessionInactivityGracePolicyDowngrade:
+		validator.Push(fmt.Errorf("The session inactivity_grace_period requires the inactivity_grace_policy to be '%s'",
+			schema.SessionInactivityGracePolicyDowngrade))
+	case gracePeriod == 0 && configuration.InactivityGracePolicy == schema.SessionInactivityGracePolicyDowngrade:
+		validator.Push(fmt.Errorf("The session inactivity_grace_policy '%s' requires an inactivity_grace_period",
+			schema.SessionInactivityGracePolicyDowngrade))
+	}
+}
+
+func validateSessionEncryptionKeys(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
+	if configuration.EncryptionKey == "" {
+		if len(configuration.PreviousEncryptionKeys) != 0 {
+			validator.Push(errors.New("The session previous_encryption_keys can only be set along with an encryption_key"))
+		}
+
+		return
+	}
+
+	if len(configuration.EncryptionKey) < sessionEncryptionKeyMinLength {
+		validator.Push(fmt.Errorf(errFmtSessionEncryptionKeyTooShort, "encryption_key", sessionEncryptionKeyMinLength))
+	}
+
+	for _, key := range configuration.PreviousEncryptionKeys {
+		if len(key) < sessionEncryptionKeyMinLength {
+			validator.Push(fmt.Errorf(errFmtSessionEncryptionKeyTooShort, "previous_encryption_keys", sessionEncryptionKeyMinLength))
+			break
+		}
+	}
+
+	if configuration.Redis == nil {
+		validator.PushWarning(errors.New("The session encryption_key has no effect unless the redis session provider is used"))
+	}
+}
+
 func validateRedis(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
 	if configuration.Redis.Host == "" {
 		validator.Push(fmt.Errorf(errFmtSessionRedisHostRequired, "redis"))
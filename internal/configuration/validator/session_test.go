@@ -395,6 +395,208 @@ func TestShouldRaiseErrorWhenBadInactivityAndExpirationSet(t *testing.T) {
 	assert.EqualError(t, validator.Errors()[1], "Error occurred parsing session inactivity string: Could not convert the input string of -1 into a duration")
 }
 
+func TestShouldRaiseErrorWhenBadInactivityGracePeriodSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.InactivityGracePeriod = testBadTimer
+	config.InactivityGracePolicy = schema.SessionInactivityGracePolicyDowngrade
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "Error occurred parsing session inactivity_grace_period string: Could not convert the input string of -1 into a duration")
+}
+
+func TestShouldSetDefaultInactivityGracePolicy(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.SessionInactivityGracePolicyDestroy, config.InactivityGracePolicy)
+}
+
+func TestShouldRaiseErrorWhenBadInactivityGracePolicySet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.InactivityGracePolicy = "remember"
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session inactivity_grace_policy must be one of 'destroy' or 'downgrade' but it is configured as 'remember'")
+}
+
+func TestShouldRaiseErrorWhenInactivityGracePeriodSetWithoutDowngradePolicy(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.InactivityGracePeriod = "1m"
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session inactivity_grace_period requires the inactivity_grace_policy to be 'downgrade'")
+
+	validator = schema.NewStructValidator()
+	config = newDefaultSessionConfig()
+	config.InactivityGracePolicy = schema.SessionInactivityGracePolicyDowngrade
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session inactivity_grace_policy 'downgrade' requires an inactivity_grace_period")
+
+	validator = schema.NewStructValidator()
+	config.InactivityGracePeriod = "1m"
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorWhenNegativeMaxSessionsPerUserSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.MaxSessionsPerUser = -1
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session max_sessions_per_user must be 0 or above")
+}
+
+func TestShouldSetDefaultMemoryMaxSessions(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, 10000, config.Memory.MaxSessions)
+}
+
+func TestShouldRaiseErrorWhenNegativeMemoryMaxSessionsSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Memory.MaxSessions = -1
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session memory max_sessions must be above 0")
+}
+
+func TestShouldSetDefaultSecondFactorChange(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.SecondFactorChangeDowngrade, config.SecondFactorChange)
+}
+
+func TestShouldRaiseErrorWhenBadSecondFactorChangeSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.SecondFactorChange = "logout"
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session second_factor_change must be one of 'downgrade', 'destroy' or 'ignore' but it is configured as 'logout'")
+}
+
+func TestShouldSetDefaultDomainCheck(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.SessionDomainCheckWarn, config.DomainCheck)
+}
+
+func TestShouldRaiseErrorWhenBadDomainCheckSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.DomainCheck = "fail"
+
+	ValidateSession(&config, validator)
+
+	assert.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session domain_check must be one of 'error', 'warn' or 'disable' but it is configured as 'fail'")
+}
+
+func TestShouldValidateSessionEncryptionKeys(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.EncryptionKey = "a_very_long_session_encryption_key"
+	config.PreviousEncryptionKeys = []string{"an_old_very_long_session_encryption_key"}
+	config.Redis = &schema.RedisSessionConfiguration{
+		Host: "redis.localhost",
+		Port: 6379,
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorWhenSessionEncryptionKeysTooShort(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.EncryptionKey = "short"
+	config.PreviousEncryptionKeys = []string{"a_very_long_session_encryption_key", "short"}
+	config.Redis = &schema.RedisSessionConfiguration{
+		Host: "redis.localhost",
+		Port: 6379,
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "The session encryption_key must be at least 32 characters long")
+	assert.EqualError(t, validator.Errors()[1], "The session previous_encryption_keys must be at least 32 characters long")
+}
+
+func TestShouldRaiseErrorWhenPreviousSessionEncryptionKeysSetWithoutEncryptionKey(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.PreviousEncryptionKeys = []string{"a_very_long_session_encryption_key"}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The session previous_encryption_keys can only be set along with an encryption_key")
+}
+
+func TestShouldRaiseWarningWhenSessionEncryptionKeySetWithoutRedis(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.EncryptionKey = "a_very_long_session_encryption_key"
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasErrors())
+	require.Len(t, validator.Warnings(), 1)
+	assert.EqualError(t, validator.Warnings()[0], "The session encryption_key has no effect unless the redis session provider is used")
+}
+
 func TestShouldRaiseErrorWhenBadRememberMeDurationSet(t *testing.T) {
 	validator := schema.NewStructValidator()
 	config := newDefaultSessionConfig()
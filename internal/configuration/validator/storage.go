@@ -2,12 +2,14 @@ package validator
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // ValidateStorage validates storage configuration.
-func ValidateStorage(configuration schema.StorageConfiguration, validator *schema.StructValidator) {
+func ValidateStorage(configuration *schema.StorageConfiguration, validator *schema.StructValidator) {
 	if configuration.Local == nil && configuration.MySQL == nil && configuration.PostgreSQL == nil {
 		validator.Push(errors.New("A storage configuration must be provided. It could be 'local', 'mysql' or 'postgres'"))
 	}
@@ -20,6 +22,42 @@ func ValidateStorage(configuration schema.StorageConfiguration, validator *schem
 	case configuration.Local != nil:
 		validateLocalStorageConfiguration(configuration.Local, validator)
 	}
+
+	if configuration.Pruning != nil {
+		validateStoragePruningConfiguration(configuration.Pruning, validator)
+	}
+
+	validateStorageEncryptionKeys(configuration, validator)
+
+	switch configuration.FailureMode {
+	case "":
+		configuration.FailureMode = schema.StorageFailureModeClosed
+	case schema.StorageFailureModeClosed, schema.StorageFailureModeOpen:
+	default:
+		validator.Push(fmt.Errorf("storage failure_mode must be either '%s' or '%s' but it is '%s'",
+			schema.StorageFailureModeClosed, schema.StorageFailureModeOpen, configuration.FailureMode))
+	}
+}
+
+func validateStorageEncryptionKeys(configuration *schema.StorageConfiguration, validator *schema.StructValidator) {
+	if configuration.EncryptionKey == "" {
+		if len(configuration.PreviousEncryptionKeys) != 0 {
+			validator.Push(errors.New("The storage previous_encryption_keys can only be set along with an encryption_key"))
+		}
+
+		return
+	}
+
+	if len(configuration.EncryptionKey) < storageEncryptionKeyMinLength {
+		validator.Push(fmt.Errorf(errFmtStorageEncryptionKeyTooShort, "encryption_key", storageEncryptionKeyMinLength))
+	}
+
+	for _, key := range configuration.PreviousEncryptionKeys {
+		if len(key) < storageEncryptionKeyMinLength {
+			validator.Push(fmt.Errorf(errFmtStorageEncryptionKeyTooShort, "previous_encryption_keys", storageEncryptionKeyMinLength))
+			break
+		}
+	}
 }
 
 func validateSQLConfiguration(configuration *schema.SQLStorageConfiguration, validator *schema.StructValidator) {
@@ -30,6 +68,29 @@ func validateSQLConfiguration(configuration *schema.SQLStorageConfiguration, val
 	if configuration.Database == "" {
 		validator.Push(errors.New("the SQL database must be provided"))
 	}
+
+	validateSQLConnectionPool(configuration, validator)
+}
+
+func validateSQLConnectionPool(configuration *schema.SQLStorageConfiguration, validator *schema.StructValidator) {
+	if configuration.MaxOpenConns < 0 {
+		validator.Push(errors.New("the SQL max_open_conns must be 0 or above"))
+	}
+
+	if configuration.MaxIdleConns < 0 {
+		validator.Push(errors.New("the SQL max_idle_conns must be 0 or above"))
+	}
+
+	if configuration.MaxOpenConns > 0 && configuration.MaxIdleConns > configuration.MaxOpenConns {
+		validator.Push(fmt.Errorf("the SQL max_idle_conns (%d) must not be greater than max_open_conns (%d)",
+			configuration.MaxIdleConns, configuration.MaxOpenConns))
+	}
+
+	if configuration.ConnMaxLifetime != "" {
+		if _, err := utils.ParseDurationString(configuration.ConnMaxLifetime); err != nil {
+			validator.Push(fmt.Errorf("Error occurred parsing SQL conn_max_lifetime string: %s", err))
+		}
+	}
 }
 
 func validatePostgreSQLConfiguration(configuration *schema.PostgreSQLStorageConfiguration, validator *schema.StructValidator) {
@@ -49,4 +110,58 @@ func validateLocalStorageConfiguration(configuration *schema.LocalStorageConfigu
 	if configuration.Path == "" {
 		validator.Push(errors.New("A file path must be provided with key 'path'"))
 	}
+
+	if configuration.BusyTimeout == "" {
+		configuration.BusyTimeout = schema.DefaultLocalStorageConfiguration.BusyTimeout
+	} else if _, err := utils.ParseDurationString(configuration.BusyTimeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing storage local busy_timeout string: %s", err))
+	}
+}
+
+func validateStoragePruningConfiguration(configuration *schema.StoragePruningConfiguration, validator *schema.StructValidator) {
+	if configuration.Interval == "" {
+		configuration.Interval = schema.DefaultStoragePruningConfiguration.Interval
+	} else if _, err := utils.ParseDurationString(configuration.Interval); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing storage pruning interval string: %s", err))
+	}
+
+	if configuration.AuthenticationLogsRetention == "" {
+		configuration.AuthenticationLogsRetention = schema.DefaultStoragePruningConfiguration.AuthenticationLogsRetention
+	} else if _, err := utils.ParseDurationString(configuration.AuthenticationLogsRetention); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing storage pruning authentication_logs_retention string: %s", err))
+	}
+
+	if configuration.LoginDevicesRetention == "" {
+		configuration.LoginDevicesRetention = schema.DefaultStoragePruningConfiguration.LoginDevicesRetention
+	} else if _, err := utils.ParseDurationString(configuration.LoginDevicesRetention); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing storage pruning login_devices_retention string: %s", err))
+	}
+
+	if configuration.BatchSize == 0 {
+		configuration.BatchSize = schema.DefaultStoragePruningConfiguration.BatchSize
+	} else if configuration.BatchSize < 0 {
+		validator.Push(errors.New("storage pruning batch_size must be above 0"))
+	}
+}
+
+// validateStoragePruningRetention ensures the authentication logs are kept long enough for the regulation to work.
+func validateStoragePruningRetention(pruning *schema.StoragePruningConfiguration, regulation *schema.RegulationConfiguration, validator *schema.StructValidator) {
+	if pruning == nil || regulation == nil {
+		return
+	}
+
+	retention, err := utils.ParseDurationString(pruning.AuthenticationLogsRetention)
+	if err != nil {
+		return
+	}
+
+	findTime, err := utils.ParseDurationString(regulation.FindTime)
+	if err != nil {
+		return
+	}
+
+	if retention < findTime {
+		validator.Push(fmt.Errorf("storage pruning authentication_logs_retention (%s) must not be shorter than the regulation find_time (%s)",
+			pruning.AuthenticationLogsRetention, regulation.FindTime))
+	}
 }
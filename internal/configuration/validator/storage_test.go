@@ -19,12 +19,18 @@ func (suite *StorageSuite) SetupTest() {
 	suite.configuration.Local = &schema.LocalStorageConfiguration{
 		Path: "/this/is/a/path",
 	}
+	suite.configuration.MySQL = nil
+	suite.configuration.PostgreSQL = nil
+	suite.configuration.Pruning = nil
+	suite.configuration.FailureMode = ""
+	suite.configuration.EncryptionKey = ""
+	suite.configuration.PreviousEncryptionKeys = nil
 }
 
 func (suite *StorageSuite) TestShouldValidateOneStorageIsConfigured() {
 	suite.configuration.Local = nil
 
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Require().Len(suite.validator.Errors(), 1)
@@ -34,7 +40,7 @@ func (suite *StorageSuite) TestShouldValidateOneStorageIsConfigured() {
 func (suite *StorageSuite) TestShouldValidateLocalPathIsProvided() {
 	suite.configuration.Local.Path = ""
 
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Require().Len(suite.validator.Errors(), 1)
@@ -44,15 +50,90 @@ func (suite *StorageSuite) TestShouldValidateLocalPathIsProvided() {
 	suite.validator.Clear()
 	suite.configuration.Local.Path = "/myapth"
 
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Assert().False(suite.validator.HasErrors())
 }
 
+func (suite *StorageSuite) TestShouldSetDefaultLocalBusyTimeout() {
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+	suite.Assert().Equal("5s", suite.configuration.Local.BusyTimeout)
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorOnInvalidLocalBusyTimeout() {
+	suite.configuration.Local.BusyTimeout = testBadTimer
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Error occurred parsing storage local busy_timeout string: Could not convert the input string of -1 into a duration")
+}
+
+func (suite *StorageSuite) TestShouldValidateSQLConnectionPool() {
+	suite.configuration.MySQL = &schema.MySQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{
+			Username:        "myuser",
+			Password:        "pass",
+			Database:        "database",
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: "1h",
+		},
+	}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorsOnInvalidSQLConnectionPool() {
+	suite.configuration.PostgreSQL = &schema.PostgreSQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{
+			Username:        "myuser",
+			Password:        "pass",
+			Database:        "database",
+			MaxOpenConns:    -1,
+			MaxIdleConns:    -1,
+			ConnMaxLifetime: testBadTimer,
+		},
+	}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 3)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "the SQL max_open_conns must be 0 or above")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "the SQL max_idle_conns must be 0 or above")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Error occurred parsing SQL conn_max_lifetime string: Could not convert the input string of -1 into a duration")
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorWhenSQLMaxIdleConnsGreaterThanMaxOpenConns() {
+	suite.configuration.MySQL = &schema.MySQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{
+			Username:     "myuser",
+			Password:     "pass",
+			Database:     "database",
+			MaxOpenConns: 5,
+			MaxIdleConns: 10,
+		},
+	}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "the SQL max_idle_conns (10) must not be greater than max_open_conns (5)")
+}
+
 func (suite *StorageSuite) TestShouldValidateSQLUsernamePasswordAndDatabaseAreProvided() {
 	suite.configuration.MySQL = &schema.MySQLStorageConfiguration{}
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Require().Len(suite.validator.Errors(), 2)
 	suite.Assert().EqualError(suite.validator.Errors()[0], "the SQL username and password must be provided")
@@ -66,7 +147,7 @@ func (suite *StorageSuite) TestShouldValidateSQLUsernamePasswordAndDatabaseArePr
 			Database: "database",
 		},
 	}
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Assert().False(suite.validator.HasErrors())
@@ -81,7 +162,7 @@ func (suite *StorageSuite) TestShouldValidatePostgresSSLModeIsDisableByDefault()
 		},
 	}
 
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Assert().False(suite.validator.HasErrors())
@@ -99,13 +180,99 @@ func (suite *StorageSuite) TestShouldValidatePostgresSSLModeMustBeValid() {
 		SSLMode: "unknown",
 	}
 
-	ValidateStorage(suite.configuration, suite.validator)
+	ValidateStorage(&suite.configuration, suite.validator)
 
 	suite.Assert().False(suite.validator.HasWarnings())
 	suite.Require().Len(suite.validator.Errors(), 1)
 	suite.Assert().EqualError(suite.validator.Errors()[0], "SSL mode must be 'disable', 'require', 'verify-ca', or 'verify-full'")
 }
 
+func (suite *StorageSuite) TestShouldSetDefaultPruningValues() {
+	suite.configuration.Pruning = &schema.StoragePruningConfiguration{}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+	suite.Assert().Equal("0", suite.configuration.Pruning.Interval)
+	suite.Assert().Equal("1M", suite.configuration.Pruning.AuthenticationLogsRetention)
+	suite.Assert().Equal(1000, suite.configuration.Pruning.BatchSize)
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorOnInvalidPruningValues() {
+	suite.configuration.Pruning = &schema.StoragePruningConfiguration{
+		Interval:                    "-1",
+		AuthenticationLogsRetention: "abc",
+		BatchSize:                   -1,
+	}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 3)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Error occurred parsing storage pruning interval string: Could not convert the input string of -1 into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Error occurred parsing storage pruning authentication_logs_retention string: Could not convert the input string of abc into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "storage pruning batch_size must be above 0")
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorWhenRetentionIsShorterThanRegulationFindTime() {
+	pruning := &schema.StoragePruningConfiguration{AuthenticationLogsRetention: "1m"}
+	regulation := &schema.RegulationConfiguration{FindTime: "2m"}
+
+	validateStoragePruningRetention(pruning, regulation, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "storage pruning authentication_logs_retention (1m) must not be shorter than the regulation find_time (2m)")
+}
+
+func (suite *StorageSuite) TestShouldSetDefaultFailureMode() {
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+	suite.Assert().Equal(schema.StorageFailureModeClosed, suite.configuration.FailureMode)
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorOnInvalidFailureMode() {
+	suite.configuration.FailureMode = "fail_maybe"
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "storage failure_mode must be either 'fail_closed' or 'fail_open' but it is 'fail_maybe'")
+}
+
+func (suite *StorageSuite) TestShouldValidateEncryptionKeys() {
+	suite.configuration.EncryptionKey = "a_very_long_storage_encryption_key"
+	suite.configuration.PreviousEncryptionKeys = []string{"an_old_very_long_storage_encryption_key"}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorWhenEncryptionKeysTooShort() {
+	suite.configuration.EncryptionKey = "short"
+	suite.configuration.PreviousEncryptionKeys = []string{"an_old_very_long_storage_encryption_key", "short"}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 2)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "The storage encryption_key must be at least 32 characters long")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "The storage previous_encryption_keys must be at least 32 characters long")
+}
+
+func (suite *StorageSuite) TestShouldRaiseErrorWhenPreviousEncryptionKeysSetWithoutEncryptionKey() {
+	suite.configuration.PreviousEncryptionKeys = []string{"an_old_very_long_storage_encryption_key"}
+
+	ValidateStorage(&suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "The storage previous_encryption_keys can only be set along with an encryption_key")
+}
+
 func TestShouldRunStorageSuite(t *testing.T) {
 	suite.Run(t, new(StorageSuite))
 }
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -23,4 +24,35 @@ func ValidateTOTP(configuration *schema.TOTPConfiguration, validator *schema.Str
 	} else if *configuration.Skew < 0 {
 		validator.Push(fmt.Errorf("TOTP Skew must be 0 or more"))
 	}
+
+	validateTOTPDomainIssuers(configuration.DomainIssuers, validator)
+}
+
+// validateTOTPDomainIssuers checks each domain issuer defines a distinct domain and an issuer which can be embedded in
+// the otpauth URI, the domains are lower cased.
+func validateTOTPDomainIssuers(domainIssuers []schema.TOTPDomainIssuerConfiguration, validator *schema.StructValidator) {
+	domains := make(map[string]bool, len(domainIssuers))
+
+	for i, domainIssuer := range domainIssuers {
+		if domainIssuer.Domain == "" {
+			validator.Push(fmt.Errorf("TOTP domain issuer at position %d must define a domain", i+1))
+			continue
+		}
+
+		domain := strings.ToLower(domainIssuer.Domain)
+		domainIssuers[i].Domain = domain
+
+		if domains[domain] {
+			validator.Push(fmt.Errorf("TOTP domain issuer for domain '%s' is defined more than once", domain))
+		}
+
+		domains[domain] = true
+
+		switch {
+		case strings.TrimSpace(domainIssuer.Issuer) == "":
+			validator.Push(fmt.Errorf("TOTP domain issuer for domain '%s' must define an issuer", domain))
+		case strings.Contains(domainIssuer.Issuer, ":"):
+			validator.Push(fmt.Errorf("TOTP domain issuer '%s' for domain '%s' must not contain a colon", domainIssuer.Issuer, domain))
+		}
+	}
 }
@@ -34,3 +34,38 @@ func TestShouldRaiseErrorWhenInvalidTOTPMinimumValues(t *testing.T) {
 	assert.EqualError(t, validator.Errors()[0], "TOTP Period must be 1 or more")
 	assert.EqualError(t, validator.Errors()[1], "TOTP Skew must be 0 or more")
 }
+
+func TestShouldValidateTOTPDomainIssuers(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.TOTPConfiguration{
+		DomainIssuers: []schema.TOTPDomainIssuerConfiguration{
+			{Domain: "Brand-A.example.com", Issuer: "Brand A"},
+			{Domain: "brand-b.example.com", Issuer: "Brand B"},
+		},
+	}
+
+	ValidateTOTP(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "brand-a.example.com", config.DomainIssuers[0].Domain)
+}
+
+func TestShouldRaiseErrorWhenInvalidTOTPDomainIssuers(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.TOTPConfiguration{
+		DomainIssuers: []schema.TOTPDomainIssuerConfiguration{
+			{Issuer: "Brand A"},
+			{Domain: "brand-a.example.com", Issuer: " "},
+			{Domain: "Brand-A.example.com", Issuer: "Brand A"},
+			{Domain: "brand-b.example.com", Issuer: "Brand: B"},
+		},
+	}
+
+	ValidateTOTP(&config, validator)
+
+	require.Len(t, validator.Errors(), 4)
+	assert.EqualError(t, validator.Errors()[0], "TOTP domain issuer at position 1 must define a domain")
+	assert.EqualError(t, validator.Errors()[1], "TOTP domain issuer for domain 'brand-a.example.com' must define an issuer")
+	assert.EqualError(t, validator.Errors()[2], "TOTP domain issuer for domain 'brand-a.example.com' is defined more than once")
+	assert.EqualError(t, validator.Errors()[3], "TOTP domain issuer 'Brand: B' for domain 'brand-b.example.com' must not contain a colon")
+}
@@ -0,0 +1,82 @@
+package validator
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ValidateTrustedHeader validates and update the trusted header configuration.
+func ValidateTrustedHeader(configuration *schema.TrustedHeaderConfiguration, validator *schema.StructValidator) {
+	if configuration.Header == "" {
+		validator.Push(fmt.Errorf("Trusted header header must be provided"))
+	}
+
+	switch {
+	case configuration.PublicKey != "" && configuration.JWKSURL != "":
+		validator.Push(fmt.Errorf("Trusted header must define either a public_key or a jwks_url, not both"))
+	case configuration.PublicKey != "":
+		if _, err := jwt.ParseRSAPublicKeyFromPEM([]byte(configuration.PublicKey)); err != nil {
+			validator.Push(fmt.Errorf("Trusted header public_key must be a PEM encoded RSA public key: %s", err))
+		}
+	case configuration.JWKSURL != "":
+		if jwksURL, err := url.Parse(configuration.JWKSURL); err != nil || jwksURL.Scheme != "https" || jwksURL.Host == "" {
+			validator.Push(fmt.Errorf("Trusted header jwks_url '%s' must be an absolute https URL", configuration.JWKSURL))
+		}
+	default:
+		validator.Push(fmt.Errorf("Trusted header must define either a public_key or a jwks_url"))
+	}
+
+	if configuration.Audience == "" {
+		validator.PushWarning(fmt.Errorf("Trusted header audience is not set, any token signed by the key will be accepted regardless of the service it was issued for"))
+	}
+
+	if configuration.Timeout == "" {
+		configuration.Timeout = schema.DefaultTrustedHeaderConfiguration.Timeout
+	} else if _, err := utils.ParseDurationString(configuration.Timeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing trusted header timeout string: %s", err))
+	}
+
+	if configuration.LookupTimeout == "" {
+		configuration.LookupTimeout = schema.DefaultTrustedHeaderConfiguration.LookupTimeout
+	} else if _, err := utils.ParseDurationString(configuration.LookupTimeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing trusted header lookup_timeout string: %s", err))
+	}
+
+	switch configuration.FailureMode {
+	case "":
+		configuration.FailureMode = schema.DefaultTrustedHeaderConfiguration.FailureMode
+	case schema.TrustedHeaderFailureModeClosed, schema.TrustedHeaderFailureModeOpen:
+	default:
+		validator.Push(fmt.Errorf("Trusted header failure_mode must be either '%s' or '%s' but it is '%s'",
+			schema.TrustedHeaderFailureModeClosed, schema.TrustedHeaderFailureModeOpen, configuration.FailureMode))
+	}
+
+	if configuration.Claims.Username == "" {
+		configuration.Claims.Username = schema.DefaultTrustedHeaderConfiguration.Claims.Username
+	}
+
+	if configuration.Claims.DisplayName == "" {
+		configuration.Claims.DisplayName = schema.DefaultTrustedHeaderConfiguration.Claims.DisplayName
+	}
+
+	if configuration.Claims.Email == "" {
+		configuration.Claims.Email = schema.DefaultTrustedHeaderConfiguration.Claims.Email
+	}
+
+	if configuration.Claims.Groups == "" {
+		configuration.Claims.Groups = schema.DefaultTrustedHeaderConfiguration.Claims.Groups
+	}
+}
+
+// validateTrustedHeaderProxies checks the trusted proxies are configured when the trusted header is, otherwise any
+// client could forge the header as every request is considered as coming from a trusted proxy.
+func validateTrustedHeaderProxies(configuration *schema.Configuration, validator *schema.StructValidator) {
+	if len(configuration.Server.TrustedProxies) == 0 {
+		validator.Push(fmt.Errorf("The trusted_header requires the server trusted_proxies to be configured"))
+	}
+}
@@ -0,0 +1,130 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+const testTrustedHeaderPublicKey = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAyuoFeeV1xhjMRzLuqF9Z
+NmoWKfVvyRc8ljiih86bVK5S0tH77NlQlC3NsGBGcGg/mVurfVjzeQB9OUnvRVu8
+xP6iawU+2fX8ybYHp1KtJ79GhNCiCnHjx85iXqOVD5N3isfXi/5hlFHunw2kT5bz
+vRkEUX9Qkno9c4DLeW2tirwbKebpfSqafYrTh8s21+pU7HjuAMAhA+XDbSReYwmR
+zwtWI4ObIPAvV2lgfbXlbpNr7+htwDme8SHp65Noaco0gs9h8BkNHVeVhhmM6NJB
+S92zx2vA1QDIZgaeKIYXrdE+cpPo/z12FuQmrc0o8HaPoTOfEAPI5Po2Yh0L57cV
+0QIDAQAB
+-----END PUBLIC KEY-----
+`
+
+type TrustedHeader struct {
+	suite.Suite
+	configuration *schema.TrustedHeaderConfiguration
+	validator     *schema.StructValidator
+}
+
+func (suite *TrustedHeader) SetupTest() {
+	suite.validator = schema.NewStructValidator()
+	suite.configuration = &schema.TrustedHeaderConfiguration{
+		Header:    "X-Mesh-Identity",
+		PublicKey: testTrustedHeaderPublicKey,
+		Audience:  "authelia",
+	}
+}
+
+func (suite *TrustedHeader) TestShouldValidateCompleteConfigurationAndSetDefaults() {
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+
+	suite.Assert().Equal("10s", suite.configuration.Timeout)
+	suite.Assert().Equal("2s", suite.configuration.LookupTimeout)
+	suite.Assert().Equal(schema.TrustedHeaderFailureModeClosed, suite.configuration.FailureMode)
+	suite.Assert().Equal(schema.DefaultTrustedHeaderConfiguration.Claims, suite.configuration.Claims)
+}
+
+func (suite *TrustedHeader) TestShouldRaiseErrorsWhenLookupFallbackInvalid() {
+	suite.configuration.LookupTimeout = "abc"
+	suite.configuration.FailureMode = "ignore"
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 2)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Error occurred parsing trusted header lookup_timeout string: Could not convert the input string of abc into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Trusted header failure_mode must be either 'fail_closed' or 'fail_open' but it is 'ignore'")
+}
+
+func (suite *TrustedHeader) TestShouldValidateJWKSURL() {
+	suite.configuration.PublicKey = ""
+	suite.configuration.JWKSURL = "https://mesh.example.com/jwks"
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *TrustedHeader) TestShouldRaiseErrorsWhenRequiredValuesMissing() {
+	suite.configuration = &schema.TrustedHeaderConfiguration{Audience: "authelia"}
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Trusted header header must be provided")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Trusted header must define either a public_key or a jwks_url")
+}
+
+func (suite *TrustedHeader) TestShouldRaiseErrorWhenBothKeySourcesAreDefined() {
+	suite.configuration.JWKSURL = "https://mesh.example.com/jwks"
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Trusted header must define either a public_key or a jwks_url, not both")
+}
+
+func (suite *TrustedHeader) TestShouldRaiseErrorWhenPublicKeyInvalid() {
+	suite.configuration.PublicKey = "abc"
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Trusted header public_key must be a PEM encoded RSA public key: Invalid Key: Key must be PEM encoded PKCS1 or PKCS8 private key")
+}
+
+func (suite *TrustedHeader) TestShouldRaiseErrorWhenJWKSURLIsNotHTTPS() {
+	suite.configuration.PublicKey = ""
+	suite.configuration.JWKSURL = "http://mesh.example.com/jwks"
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Trusted header jwks_url 'http://mesh.example.com/jwks' must be an absolute https URL")
+}
+
+func (suite *TrustedHeader) TestShouldRaiseErrorWhenTimeoutInvalid() {
+	suite.configuration.Timeout = "abc"
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Error occurred parsing trusted header timeout string: Could not convert the input string of abc into a duration")
+}
+
+func (suite *TrustedHeader) TestShouldWarnWhenAudienceMissing() {
+	suite.configuration.Audience = ""
+
+	ValidateTrustedHeader(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasErrors())
+	suite.Require().Len(suite.validator.Warnings(), 1)
+	suite.Assert().EqualError(suite.validator.Warnings()[0], "Trusted header audience is not set, any token signed by the key will be accepted regardless of the service it was issued for")
+}
+
+func TestTrustedHeader(t *testing.T) {
+	suite.Run(t, new(TrustedHeader))
+}
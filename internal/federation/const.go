@@ -0,0 +1,24 @@
+package federation
+
+import (
+	"errors"
+	"time"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// keySetRetryInterval is the interval during which the key set is not retrieved again after a failed lookup, so that
+// the requests carrying a trusted header don't each wait for the lookup timeout while the key set is unavailable.
+const keySetRetryInterval = 10 * time.Second
+
+var supportedSigningAlgorithms = []string{"RS256", "RS384", "RS512"}
+
+// ErrInvalidIDToken is returned when the ID token returned by the upstream provider can't be trusted.
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// ErrInvalidTrustedHeader is returned when the token of the trusted header can't be trusted.
+var ErrInvalidTrustedHeader = errors.New("invalid trusted header")
+
+// ErrKeySetUnavailable is returned when the token of the trusted header can't be verified because the key set could
+// not be retrieved in time.
+var ErrKeySetUnavailable = errors.New("key set unavailable")
@@ -0,0 +1,371 @@
+package federation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// OIDCUpstreamProvider authenticates users against an upstream OpenID Connect provider using the authorization code
+// flow.
+type OIDCUpstreamProvider struct {
+	configuration    schema.OIDCUpstreamConfiguration
+	client           *http.Client
+	groupAssignments []groupAssignment
+
+	mutex     sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]*rsa.PublicKey
+}
+
+// NewOIDCUpstreamProvider creates a provider for the upstream OpenID Connect provider of the given configuration.
+func NewOIDCUpstreamProvider(configuration schema.OIDCUpstreamConfiguration, certPool *x509.CertPool) *OIDCUpstreamProvider {
+	timeout, _ := utils.ParseDurationString(configuration.Timeout)
+
+	assignments := make([]groupAssignment, 0, len(configuration.GroupAssignments))
+
+	for _, assignment := range configuration.GroupAssignments {
+		ga := groupAssignment{username: assignment.Username, groups: assignment.Groups}
+
+		if assignment.UsernamePattern != "" {
+			ga.pattern = regexp.MustCompile(assignment.UsernamePattern)
+		}
+
+		assignments = append(assignments, ga)
+	}
+
+	return &OIDCUpstreamProvider{
+		configuration:    configuration,
+		groupAssignments: assignments,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12},
+			},
+		},
+	}
+}
+
+// DisplayName returns the name of the provider displayed to the users.
+func (p *OIDCUpstreamProvider) DisplayName() string {
+	return p.configuration.DisplayName
+}
+
+// StartupCheck checks the discovery document of the upstream provider is reachable.
+func (p *OIDCUpstreamProvider) StartupCheck() (bool, error) {
+	if _, err := p.getDiscovery(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AuthCodeURL returns the URL of the upstream provider the user must be redirected to in order to authenticate.
+func (p *OIDCUpstreamProvider) AuthCodeURL(state, nonce string) (string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the authorization endpoint: %w", err)
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.configuration.ClientID)
+	query.Set("redirect_uri", p.configuration.RedirectURI)
+	query.Set("scope", strings.Join(p.configuration.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange exchanges the authorization code for an ID token and returns the identity it asserts.
+func (p *OIDCUpstreamProvider) Exchange(code, nonce string) (*Identity, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.configuration.RedirectURI)
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.configuration.ClientID), url.QueryEscape(p.configuration.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach the token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	token := tokenResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("unable to decode the token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint replied with status %d: %s", resp.StatusCode, token.Error)
+	}
+
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint did not return an ID token")
+	}
+
+	return p.verifyIDToken(token.IDToken, nonce)
+}
+
+func (p *OIDCUpstreamProvider) verifyIDToken(rawIDToken, nonce string) (*Identity, error) {
+	parser := jwt.Parser{ValidMethods: supportedSigningAlgorithms}
+	claims := jwt.MapClaims{}
+
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+
+	if !claims.VerifyIssuer(p.configuration.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+
+	if !utils.IsStringInSlice(p.configuration.ClientID, stringsFromClaim(claims["aud"])) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+
+	// A token having several audiences names the client it was issued to in the azp claim, a token issued to another
+	// client must not be accepted even if it lists this client among its audiences.
+	if azp, ok := claims["azp"]; ok && stringFromClaim(azp) != p.configuration.ClientID {
+		return nil, fmt.Errorf("%w: issued to another client", ErrInvalidIDToken)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing expiration", ErrInvalidIDToken)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("%w: unexpected nonce", ErrInvalidIDToken)
+	}
+
+	identity := &Identity{
+		Username:    stringFromClaim(claims[p.configuration.Claims.Username]),
+		DisplayName: stringFromClaim(claims[p.configuration.Claims.DisplayName]),
+		Emails:      stringsFromClaim(claims[p.configuration.Claims.Email]),
+		Groups:      stringsFromClaim(claims[p.configuration.Claims.Groups]),
+	}
+
+	if identity.Username == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidIDToken, p.configuration.Claims.Username)
+	}
+
+	identity.Groups = p.mergeAssignedGroups(identity.Username, identity.Groups)
+
+	return identity, nil
+}
+
+// mergeAssignedGroups appends the groups locally assigned to the user to the groups asserted by the upstream provider,
+// skipping the ones already present.
+func (p *OIDCUpstreamProvider) mergeAssignedGroups(username string, groups []string) []string {
+	for _, assignment := range p.groupAssignments {
+		if !assignment.matches(username) {
+			continue
+		}
+
+		for _, group := range assignment.groups {
+			if !utils.IsStringInSlice(group, groups) {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return groups
+}
+
+func (p *OIDCUpstreamProvider) getDiscovery() (*discoveryDocument, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	discovery := &discoveryDocument{}
+	if err := p.getJSON(strings.TrimSuffix(p.configuration.Issuer, "/")+discoveryPath, discovery); err != nil {
+		return nil, fmt.Errorf("unable to retrieve the discovery document: %w", err)
+	}
+
+	if discovery.Issuer != p.configuration.Issuer {
+		return nil, fmt.Errorf("discovery document issuer %s does not match the configured issuer %s", discovery.Issuer, p.configuration.Issuer)
+	}
+
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document is missing the authorization, token or jwks endpoint")
+	}
+
+	p.discovery = discovery
+
+	return discovery, nil
+}
+
+// getKey returns the key of the given ID, fetching the key set again when the key is unknown to support key rotation.
+func (p *OIDCUpstreamProvider) getKey(kid string) (*rsa.PublicKey, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	keys, err := getKeySet(p.client, discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = keys
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key %s", kid)
+}
+
+func (p *OIDCUpstreamProvider) getJSON(endpoint string, v interface{}) error {
+	return getJSON(p.client, endpoint, v)
+}
+
+func getJSON(client *http.Client, endpoint string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s replied with status %d", endpoint, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// getKeySet retrieves the RSA signing keys of the key set at the given URL indexed by their ID.
+func getKeySet(client *http.Client, jwksURL string) (map[string]*rsa.PublicKey, error) {
+	keySet := jsonWebKeySet{}
+	if err := getJSON(client, jwksURL, &keySet); err != nil {
+		return nil, fmt.Errorf("unable to retrieve the key set: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := parseRSAPublicKey(jwk)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[jwk.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+func parseRSAPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the modulus of key %s: %w", jwk.KeyID, err)
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the exponent of key %s: %w", jwk.KeyID, err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func stringFromClaim(claim interface{}) string {
+	value, _ := claim.(string)
+	return value
+}
+
+func stringsFromClaim(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// RandomState generates a random value suitable for the state and nonce parameters.
+func RandomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
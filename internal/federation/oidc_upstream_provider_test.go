@@ -0,0 +1,268 @@
+package federation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type OIDCUpstreamProviderSuite struct {
+	suite.Suite
+
+	key      *rsa.PrivateKey
+	server   *httptest.Server
+	provider *OIDCUpstreamProvider
+	claims   jwt.MapClaims
+
+	tokenRequest *http.Request
+	tokenForm    url.Values
+}
+
+func (s *OIDCUpstreamProviderSuite) SetupTest() {
+	var err error
+
+	s.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	mux := http.NewServeMux()
+	s.server = httptest.NewServer(mux)
+
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{
+			Issuer:                s.server.URL,
+			AuthorizationEndpoint: s.server.URL + "/authorize",
+			TokenEndpoint:         s.server.URL + "/token",
+			JWKSURI:               s.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			KeyType: "RSA",
+			KeyID:   "key1",
+			Use:     "sig",
+			N:       base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		s.tokenRequest = r
+		s.tokenForm = r.PostForm
+
+		if r.PostForm.Get("code") != "valid-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_grant"})
+
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, s.claims)
+		token.Header["kid"] = "key1"
+
+		idToken, err := token.SignedString(s.key)
+		s.Require().NoError(err)
+
+		_ = json.NewEncoder(w).Encode(tokenResponse{IDToken: idToken})
+	})
+
+	configuration := schema.DefaultOIDCUpstreamConfiguration
+	configuration.Issuer = s.server.URL
+	configuration.ClientID = "authelia"
+	configuration.ClientSecret = "secret"
+	configuration.RedirectURI = "https://login.example.com/api/oidc-upstream/callback"
+
+	s.provider = NewOIDCUpstreamProvider(configuration, nil)
+
+	s.claims = jwt.MapClaims{
+		"iss":                s.server.URL,
+		"aud":                []string{"authelia", "another"},
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"nonce":              "nonce",
+		"sub":                "john",
+		"preferred_username": "johnny",
+		"name":               "John Doe",
+		"email":              "john@example.com",
+		"groups":             []string{"admins", "dev"},
+	}
+}
+
+func (s *OIDCUpstreamProviderSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldPassStartupCheck() {
+	ok, err := s.provider.StartupCheck()
+
+	s.Assert().NoError(err)
+	s.Assert().True(ok)
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldBuildAuthCodeURL() {
+	authURL, err := s.provider.AuthCodeURL("state", "nonce")
+	s.Require().NoError(err)
+
+	u, err := url.Parse(authURL)
+	s.Require().NoError(err)
+
+	s.Assert().Equal(s.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	s.Assert().Equal("code", u.Query().Get("response_type"))
+	s.Assert().Equal("authelia", u.Query().Get("client_id"))
+	s.Assert().Equal("https://login.example.com/api/oidc-upstream/callback", u.Query().Get("redirect_uri"))
+	s.Assert().Equal("openid profile email groups", u.Query().Get("scope"))
+	s.Assert().Equal("state", u.Query().Get("state"))
+	s.Assert().Equal("nonce", u.Query().Get("nonce"))
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldExchangeCodeForIdentity() {
+	identity, err := s.provider.Exchange("valid-code", "nonce")
+	s.Require().NoError(err)
+
+	s.Assert().Equal(&Identity{
+		Username:    "john",
+		DisplayName: "John Doe",
+		Emails:      []string{"john@example.com"},
+		Groups:      []string{"admins", "dev"},
+	}, identity)
+
+	username, password, ok := s.tokenRequest.BasicAuth()
+	s.Assert().True(ok)
+	s.Assert().Equal("authelia", username)
+	s.Assert().Equal("secret", password)
+	s.Assert().Equal("authorization_code", s.tokenForm.Get("grant_type"))
+	s.Assert().Equal("https://login.example.com/api/oidc-upstream/callback", s.tokenForm.Get("redirect_uri"))
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldFailWhenTokenEndpointRejectsCode() {
+	_, err := s.provider.Exchange("invalid-code", "nonce")
+
+	s.Assert().EqualError(err, "token endpoint replied with status 400: invalid_grant")
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldRejectUnexpectedNonce() {
+	_, err := s.provider.Exchange("valid-code", "another-nonce")
+
+	s.Assert().EqualError(err, "invalid ID token: unexpected nonce")
+	s.Assert().ErrorIs(err, ErrInvalidIDToken)
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldRejectUnexpectedAudience() {
+	s.claims["aud"] = "another"
+
+	_, err := s.provider.Exchange("valid-code", "nonce")
+
+	s.Assert().EqualError(err, "invalid ID token: unexpected audience")
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldAcceptTokenAuthorizedForClient() {
+	s.claims["azp"] = "authelia"
+
+	identity, err := s.provider.Exchange("valid-code", "nonce")
+
+	s.Require().NoError(err)
+	s.Assert().Equal("john", identity.Username)
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldRejectTokenIssuedToAnotherClient() {
+	s.claims["azp"] = "another"
+
+	_, err := s.provider.Exchange("valid-code", "nonce")
+
+	s.Assert().EqualError(err, "invalid ID token: issued to another client")
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldRejectUnexpectedIssuer() {
+	s.claims["iss"] = "https://evil.example.com"
+
+	_, err := s.provider.Exchange("valid-code", "nonce")
+
+	s.Assert().EqualError(err, "invalid ID token: unexpected issuer")
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldRejectExpiredToken() {
+	s.claims["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	_, err := s.provider.Exchange("valid-code", "nonce")
+
+	s.Assert().EqualError(err, "invalid ID token: Token is expired")
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldRejectTokenWithoutUsername() {
+	delete(s.claims, "sub")
+
+	_, err := s.provider.Exchange("valid-code", "nonce")
+
+	s.Assert().EqualError(err, "invalid ID token: missing sub claim")
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldMapCustomClaims() {
+	s.provider.configuration.Claims.Username = "sub"
+	s.provider.configuration.Claims.Groups = "roles"
+	s.claims["sub"] = "jdoe"
+	s.claims["roles"] = "admins"
+
+	identity, err := s.provider.Exchange("valid-code", "nonce")
+	s.Require().NoError(err)
+
+	s.Assert().Equal("jdoe", identity.Username)
+	s.Assert().Equal([]string{"admins"}, identity.Groups)
+}
+
+func (s *OIDCUpstreamProviderSuite) TestShouldMergeLocallyAssignedGroups() {
+	s.provider.configuration.GroupAssignments = []schema.OIDCUpstreamGroupAssignmentConfiguration{
+		{Username: "john", Groups: []string{"admins", "ops"}},
+		{UsernamePattern: "^j", Groups: []string{"j-users", "ops"}},
+		{Username: "harry", Groups: []string{"harry-only"}},
+	}
+	s.provider = NewOIDCUpstreamProvider(s.provider.configuration, nil)
+
+	identity, err := s.provider.Exchange("valid-code", "nonce")
+	s.Require().NoError(err)
+
+	s.Assert().Equal([]string{"admins", "dev", "ops", "j-users"}, identity.Groups)
+}
+
+func TestRunOIDCUpstreamProviderSuite(t *testing.T) {
+	suite.Run(t, new(OIDCUpstreamProviderSuite))
+}
+
+func TestShouldFailStartupCheckWhenIssuerMismatches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{Issuer: "https://idp.example.com"})
+	}))
+	defer server.Close()
+
+	configuration := schema.DefaultOIDCUpstreamConfiguration
+	configuration.Issuer = server.URL
+
+	ok, err := NewOIDCUpstreamProvider(configuration, nil).StartupCheck()
+
+	assert.False(t, ok)
+	assert.EqualError(t, err, "discovery document issuer https://idp.example.com does not match the configured issuer "+server.URL)
+}
+
+func TestShouldGenerateRandomState(t *testing.T) {
+	state1, err := RandomState()
+	require.NoError(t, err)
+
+	state2, err := RandomState()
+	require.NoError(t, err)
+
+	assert.Len(t, state1, 43)
+	assert.NotEqual(t, state1, state2)
+}
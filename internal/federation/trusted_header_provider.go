@@ -0,0 +1,165 @@
+package federation
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// TrustedHeaderProvider verifies the identity asserted by a trusted proxy, such as the sidecar of a service mesh, with
+// a JWT signed by either a static public key or one of the keys of a JWKS.
+type TrustedHeaderProvider struct {
+	configuration schema.TrustedHeaderConfiguration
+	client        *http.Client
+	lookupClient  *http.Client
+	publicKey     *rsa.PublicKey
+
+	mutex          sync.Mutex
+	keys           map[string]*rsa.PublicKey
+	lookupFailedAt time.Time
+}
+
+// NewTrustedHeaderProvider creates a provider verifying the trusted header of the given configuration.
+func NewTrustedHeaderProvider(configuration schema.TrustedHeaderConfiguration, certPool *x509.CertPool) *TrustedHeaderProvider {
+	timeout, _ := utils.ParseDurationString(configuration.Timeout)
+	lookupTimeout, _ := utils.ParseDurationString(configuration.LookupTimeout)
+
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12},
+	}
+
+	provider := &TrustedHeaderProvider{
+		configuration: configuration,
+		client:        &http.Client{Timeout: timeout, Transport: transport},
+		lookupClient:  &http.Client{Timeout: lookupTimeout, Transport: transport},
+	}
+
+	if configuration.PublicKey != "" {
+		// Ignore the error as the public key is checked by the validator.
+		provider.publicKey, _ = jwt.ParseRSAPublicKeyFromPEM([]byte(configuration.PublicKey))
+	}
+
+	return provider
+}
+
+// Header returns the name of the header carrying the token.
+func (p *TrustedHeaderProvider) Header() string {
+	return p.configuration.Header
+}
+
+// StartupCheck checks the key set is reachable when the keys are retrieved from a JWKS.
+func (p *TrustedHeaderProvider) StartupCheck() (bool, error) {
+	if p.configuration.JWKSURL == "" {
+		return true, nil
+	}
+
+	keys, err := getKeySet(p.client, p.configuration.JWKSURL)
+	if err != nil {
+		return false, err
+	}
+
+	p.mutex.Lock()
+	p.keys = keys
+	p.mutex.Unlock()
+
+	return true, nil
+}
+
+// Verify verifies the signature, the expiration, the issuer and the audience of the token and returns the identity it
+// asserts. The token may be prefixed by the Bearer scheme.
+func (p *TrustedHeaderProvider) Verify(rawToken string) (*Identity, error) {
+	rawToken = strings.TrimSpace(rawToken)
+
+	if len(rawToken) > 7 && strings.EqualFold(rawToken[:7], "Bearer ") {
+		rawToken = strings.TrimSpace(rawToken[7:])
+	}
+
+	parser := jwt.Parser{ValidMethods: supportedSigningAlgorithms}
+	claims := jwt.MapClaims{}
+
+	_, err := parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if p.publicKey != nil {
+			return p.publicKey, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+
+		return p.getKey(kid)
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && errors.Is(validationErr.Inner, ErrKeySetUnavailable) {
+			return nil, validationErr.Inner
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTrustedHeader, err)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing expiration", ErrInvalidTrustedHeader)
+	}
+
+	if p.configuration.Issuer != "" && !claims.VerifyIssuer(p.configuration.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidTrustedHeader)
+	}
+
+	if p.configuration.Audience != "" && !utils.IsStringInSlice(p.configuration.Audience, stringsFromClaim(claims["aud"])) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidTrustedHeader)
+	}
+
+	identity := &Identity{
+		Username:    stringFromClaim(claims[p.configuration.Claims.Username]),
+		DisplayName: stringFromClaim(claims[p.configuration.Claims.DisplayName]),
+		Emails:      stringsFromClaim(claims[p.configuration.Claims.Email]),
+		Groups:      stringsFromClaim(claims[p.configuration.Claims.Groups]),
+	}
+
+	if identity.Username == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidTrustedHeader, p.configuration.Claims.Username)
+	}
+
+	return identity, nil
+}
+
+// getKey returns the key of the given ID, fetching the key set again when the key is unknown to support key rotation.
+// The key set is fetched within the lookup timeout and not fetched again for a while after a failed lookup.
+func (p *TrustedHeaderProvider) getKey(kid string) (*rsa.PublicKey, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if !p.lookupFailedAt.IsZero() && time.Since(p.lookupFailedAt) < keySetRetryInterval {
+		return nil, fmt.Errorf("%w: the last lookup failed less than %s ago", ErrKeySetUnavailable, keySetRetryInterval)
+	}
+
+	keys, err := getKeySet(p.lookupClient, p.configuration.JWKSURL)
+	if err != nil {
+		p.lookupFailedAt = time.Now()
+
+		return nil, fmt.Errorf("%w: %s", ErrKeySetUnavailable, err)
+	}
+
+	p.keys = keys
+	p.lookupFailedAt = time.Time{}
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key %s", kid)
+}
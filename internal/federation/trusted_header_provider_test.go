@@ -0,0 +1,211 @@
+package federation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type TrustedHeaderProviderSuite struct {
+	suite.Suite
+
+	key           *rsa.PrivateKey
+	configuration schema.TrustedHeaderConfiguration
+	claims        jwt.MapClaims
+}
+
+func (s *TrustedHeaderProviderSuite) SetupTest() {
+	var err error
+
+	s.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	der, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	s.Require().NoError(err)
+
+	s.configuration = schema.DefaultTrustedHeaderConfiguration
+	s.configuration.Header = "X-Mesh-Identity"
+	s.configuration.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	s.configuration.Issuer = "https://mesh.example.com"
+	s.configuration.Audience = "authelia"
+
+	s.claims = jwt.MapClaims{
+		"iss":    "https://mesh.example.com",
+		"aud":    "authelia",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+		"sub":    "john",
+		"name":   "John Doe",
+		"email":  "john@example.com",
+		"groups": []string{"admins", "dev"},
+	}
+}
+
+func (s *TrustedHeaderProviderSuite) sign(key *rsa.PrivateKey, kid string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, s.claims)
+
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	s.Require().NoError(err)
+
+	return signed
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldVerifyTokenSignedByPublicKey() {
+	identity, err := NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(s.key, ""))
+	s.Require().NoError(err)
+
+	s.Assert().Equal(&Identity{
+		Username:    "john",
+		DisplayName: "John Doe",
+		Emails:      []string{"john@example.com"},
+		Groups:      []string{"admins", "dev"},
+	}, identity)
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldVerifyBearerToken() {
+	identity, err := NewTrustedHeaderProvider(s.configuration, nil).Verify("Bearer " + s.sign(s.key, ""))
+	s.Require().NoError(err)
+
+	s.Assert().Equal("john", identity.Username)
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldVerifyTokenSignedByKeyOfJWKS() {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		_ = json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			KeyType: "RSA",
+			KeyID:   "key1",
+			N:       base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	s.configuration.PublicKey = ""
+	s.configuration.JWKSURL = server.URL
+
+	provider := NewTrustedHeaderProvider(s.configuration, nil)
+
+	ok, err := provider.StartupCheck()
+	s.Require().NoError(err)
+	s.Require().True(ok)
+
+	identity, err := provider.Verify(s.sign(s.key, "key1"))
+	s.Require().NoError(err)
+	s.Assert().Equal("john", identity.Username)
+
+	_, err = provider.Verify(s.sign(s.key, "key2"))
+	s.Assert().EqualError(err, "invalid trusted header: unknown key key2")
+	s.Assert().Equal(2, requests)
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldFailFastWhenKeySetLookupTimesOut() {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	s.configuration.PublicKey = ""
+	s.configuration.JWKSURL = server.URL
+
+	provider := NewTrustedHeaderProvider(s.configuration, nil)
+	provider.lookupClient.Timeout = 50 * time.Millisecond
+
+	_, err := provider.Verify(s.sign(s.key, "key1"))
+	s.Require().Error(err)
+	s.Assert().ErrorIs(err, ErrKeySetUnavailable)
+	s.Assert().Contains(err.Error(), "Client.Timeout exceeded")
+
+	start := time.Now()
+
+	_, err = provider.Verify(s.sign(s.key, "key1"))
+	s.Assert().EqualError(err, "key set unavailable: the last lookup failed less than 10s ago")
+	s.Assert().Less(int64(time.Since(start)), int64(50*time.Millisecond))
+	s.Assert().Equal(1, requests)
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectTokenSignedByAnotherKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	_, err = NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(key, ""))
+
+	s.Assert().EqualError(err, "invalid trusted header: crypto/rsa: verification error")
+	s.Assert().ErrorIs(err, ErrInvalidTrustedHeader)
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectUnsignedToken() {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, s.claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	s.Require().NoError(err)
+
+	_, err = NewTrustedHeaderProvider(s.configuration, nil).Verify(token)
+
+	s.Assert().EqualError(err, "invalid trusted header: signing method none is invalid")
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectExpiredToken() {
+	s.claims["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	_, err := NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(s.key, ""))
+
+	s.Assert().EqualError(err, "invalid trusted header: Token is expired")
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectTokenWithoutExpiration() {
+	delete(s.claims, "exp")
+
+	_, err := NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(s.key, ""))
+
+	s.Assert().EqualError(err, "invalid trusted header: missing expiration")
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectUnexpectedIssuer() {
+	s.claims["iss"] = "https://evil.example.com"
+
+	_, err := NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(s.key, ""))
+
+	s.Assert().EqualError(err, "invalid trusted header: unexpected issuer")
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectUnexpectedAudience() {
+	s.claims["aud"] = []string{"another"}
+
+	_, err := NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(s.key, ""))
+
+	s.Assert().EqualError(err, "invalid trusted header: unexpected audience")
+}
+
+func (s *TrustedHeaderProviderSuite) TestShouldRejectTokenWithoutUsername() {
+	delete(s.claims, "sub")
+
+	_, err := NewTrustedHeaderProvider(s.configuration, nil).Verify(s.sign(s.key, ""))
+
+	s.Assert().EqualError(err, "invalid trusted header: missing sub claim")
+}
+
+func TestRunTrustedHeaderProviderSuite(t *testing.T) {
+	suite.Run(t, new(TrustedHeaderProviderSuite))
+}
@@ -0,0 +1,54 @@
+package federation
+
+import (
+	"regexp"
+)
+
+// Identity is the identity of a user authenticated by an upstream provider.
+type Identity struct {
+	Username    string
+	DisplayName string
+	Emails      []string
+	Groups      []string
+}
+
+// groupAssignment is a group assignment of the configuration with its username pattern compiled.
+type groupAssignment struct {
+	username string
+	pattern  *regexp.Regexp
+	groups   []string
+}
+
+func (a groupAssignment) matches(username string) bool {
+	if a.pattern != nil {
+		return a.pattern.MatchString(username)
+	}
+
+	return a.username == username
+}
+
+// discoveryDocument is the subset of the OpenID Connect discovery document used by Authelia.
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a key of a JSON Web Key Set, only RSA keys being supported.
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+	Error   string `json:"error"`
+}
@@ -1,5 +1,7 @@
 package handlers
 
+import "time"
+
 // TOTPRegistrationAction is the string representation of the action for which the token has been produced.
 const TOTPRegistrationAction = "RegisterTOTPDevice"
 
@@ -9,6 +11,12 @@ const U2FRegistrationAction = "RegisterU2FDevice"
 // ResetPasswordAction is the string representation of the action for which the token has been produced.
 const ResetPasswordAction = "ResetPassword"
 
+// RegistrationAction is the string representation of the action for which the token has been produced.
+const RegistrationAction = "RegisterAccount"
+
+// EmailVerificationAction is the string representation of the action for which the token has been produced.
+const EmailVerificationAction = "VerifyEmail"
+
 const authPrefix = "Basic "
 
 // ProxyAuthorizationHeader is the basic-auth HTTP header Authelia utilises.
@@ -20,10 +28,25 @@ const AuthorizationHeader = "Authorization"
 // SessionUsernameHeader is used as additional protection to validate a user for things like pam_exec.
 const SessionUsernameHeader = "Session-Username"
 
-const remoteUserHeader = "Remote-User"
-const remoteNameHeader = "Remote-Name"
-const remoteEmailHeader = "Remote-Email"
-const remoteGroupsHeader = "Remote-Groups"
+const headerXRequestedWith = "X-Requested-With"
+
+// The authentication methods of the last logins other than the second factor methods.
+const (
+	lastLoginMethodPassword      = "password"
+	lastLoginMethodOIDCUpstream  = "oidc_upstream"
+	lastLoginMethodTrustedHeader = "trusted_header"
+)
+
+const breakGlassAuthenticateHeader = "WWW-Authenticate"
+const breakGlassAuthenticateChallenge = "Basic realm=\"Authelia break-glass\""
+
+// The limits of the break-glass attempts, each check of the password costing at least 64 MiB of memory.
+const (
+	breakGlassMaxFailures      = 5
+	breakGlassFailuresWindow   = 5 * time.Minute
+	breakGlassMaxTrackedIPs    = 10000
+	breakGlassConcurrentChecks = 1
+)
 
 var protoHostSeparator = []byte("://")
 
@@ -43,6 +66,18 @@ const unableToRegisterOneTimePasswordMessage = "Unable to set up one-time passwo
 const unableToRegisterSecurityKeyMessage = "Unable to register your security key."
 const unableToResetPasswordMessage = "Unable to reset your password."
 const mfaValidationFailedMessage = "Authentication failed, please retry later."
+const passwordReusedMessage = "Your new password was used recently, please choose another one."
+const loginNotificationTitle = "New login to your account"
+const emailNotVerifiedMessage = "Please verify your email address using the link which has been sent to you."
+const passwordChangedTooRecentlyMessage = "Your password was changed too recently, please retry later."
+const unableToRegisterMessage = "Unable to register your account."
+const registrationNotAllowedMessage = "The registration is not allowed for this email address."
+const registrationApprovedTitle = "Your account has been approved"
+
+// oidcUpstreamFlowLifespan is the time an authentication against the upstream provider can stay pending before being
+// discarded, and oidcUpstreamMaxPendingFlows the number of such authentications kept in the session at most.
+const oidcUpstreamFlowLifespan = 10 * time.Minute
+const oidcUpstreamMaxPendingFlows = 10
 
 const ldapPasswordComplexityCode = "0000052D."
 
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// withDefaultGroups returns the groups of a user merged with the default groups every authenticated user belongs to.
+// The default groups of the authentication backend are only merged when the user was retrieved from that backend.
+func withDefaultGroups(ctx *middlewares.AutheliaCtx, groups []string, fromBackend bool) []string {
+	configuration := ctx.Configuration.AuthenticationBackend
+
+	defaults := configuration.DefaultGroups
+
+	if fromBackend {
+		switch {
+		case configuration.File != nil:
+			defaults = append(append([]string{}, configuration.File.DefaultGroups...), defaults...)
+		case configuration.Ldap != nil:
+			defaults = append(append([]string{}, configuration.Ldap.DefaultGroups...), defaults...)
+		}
+	}
+
+	if len(defaults) == 0 {
+		return groups
+	}
+
+	merged := make([]string, len(groups), len(groups)+len(defaults))
+	copy(merged, groups)
+
+	for _, group := range defaults {
+		if !utils.IsStringInSlice(group, merged) {
+			merged = append(merged, group)
+		}
+	}
+
+	return merged
+}
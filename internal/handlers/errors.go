@@ -11,3 +11,4 @@ const UnauthorizedError = "You're not authorized."
 
 var errMissingXForwardedHost = errors.New("Missing header X-Forwarded-Host")
 var errMissingXForwardedProto = errors.New("Missing header X-Forwarded-Proto")
+var errMissingXOriginalURL = errors.New("Missing header X-Original-URL")
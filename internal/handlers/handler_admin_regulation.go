@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// adminRegulationResetRequestBody is the body of the request resetting the regulation of a user.
+type adminRegulationResetRequestBody struct {
+	Username string `json:"username" valid:"required"`
+}
+
+// adminRegulationResetResponseBody is the body of the response to the reset of the regulation of a user.
+type adminRegulationResetResponseBody struct {
+	// The number of failed authentication attempts which have been removed.
+	Removed int `json:"removed"`
+}
+
+// isRegulationAdmin returns true if the user is authenticated with two factors and member of one of the regulation admin
+// groups.
+func isRegulationAdmin(ctx *middlewares.AutheliaCtx, userSession session.UserSession) bool {
+	return isTwoFactorAdmin(userSession, ctx.Configuration.Regulation.AdminGroups)
+}
+
+// isTwoFactorAdmin returns true if the user is authenticated with two factors and member of one of the given groups.
+func isTwoFactorAdmin(userSession session.UserSession, adminGroups []string) bool {
+	if userSession.AuthenticationLevel < authentication.TwoFactor {
+		return false
+	}
+
+	for _, group := range userSession.Groups {
+		if utils.IsStringInSlice(group, adminGroups) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AdminRegulationResetPost lifts the ban of a user by removing its failed authentication attempts. Only the users
+// authenticated with two factors and member of one of the regulation admin groups are allowed to do so.
+func AdminRegulationResetPost(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if !isRegulationAdmin(ctx, userSession) {
+		ctx.Logger.Warnf("User %s is not allowed to reset the regulation of other users", userSession.Username)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	var body adminRegulationResetRequestBody
+
+	if err := ctx.ParseBody(&body); err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return
+	}
+
+	count, err := ctx.Providers.Regulator.Reset(body.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to reset the regulation of user %s: %s", body.Username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Infof("Regulation of user %s reset by %s from %s, %d failed authentication attempt(s) removed",
+		body.Username, userSession.Username, ctx.RemoteIP(), count)
+
+	if err = ctx.SetJSONBody(adminRegulationResetResponseBody{Removed: count}); err != nil {
+		ctx.Logger.Errorf("Unable to set the response body: %s", err)
+	}
+}
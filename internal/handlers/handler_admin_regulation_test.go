@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type AdminRegulationSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *AdminRegulationSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Configuration.Regulation = &schema.RegulationConfiguration{AdminGroups: []string{"support"}}
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = "harry"
+	userSession.Groups = []string{"dev", "support"}
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.Ctx.Request.SetBodyString("{\"username\": \"john\"}")
+}
+
+func (s *AdminRegulationSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *AdminRegulationSuite) TestShouldResetRegulationOfUser() {
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteFailedAuthenticationLogs(gomock.Eq(testUsername)).
+		Return(3, nil)
+
+	AdminRegulationResetPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), adminRegulationResetResponseBody{Removed: 3})
+	s.Assert().Equal("Regulation of user john reset by harry from 0.0.0.0, 3 failed authentication attempt(s) removed",
+		s.mock.Hook.LastEntry().Message)
+}
+
+func (s *AdminRegulationSuite) TestShouldForbidUsersNotMemberOfAdminGroups() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Groups = []string{"dev"}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	AdminRegulationResetPost(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("User harry is not allowed to reset the regulation of other users", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *AdminRegulationSuite) TestShouldForbidUsersAuthenticatedWithOneFactor() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.AuthenticationLevel = authentication.OneFactor
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	AdminRegulationResetPost(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *AdminRegulationSuite) TestShouldFailWhenUsernameIsMissing() {
+	s.mock.Ctx.Request.SetBodyString("{}")
+
+	AdminRegulationResetPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+}
+
+func (s *AdminRegulationSuite) TestShouldFailWhenStorageFails() {
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteFailedAuthenticationLogs(gomock.Eq(testUsername)).
+		Return(0, fmt.Errorf("failed"))
+
+	AdminRegulationResetPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("Unable to reset the regulation of user john: Unable to remove the failed authentication attempts: failed",
+		s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunAdminRegulationSuite(t *testing.T) {
+	suite.Run(t, new(AdminRegulationSuite))
+}
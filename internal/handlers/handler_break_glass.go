@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/middlewares"
+)
+
+// breakGlassCheck is the result of the check of a provider returned to the break-glass user.
+type breakGlassCheck struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type providerWithStartupCheck interface {
+	StartupCheck() (bool, error)
+}
+
+// breakGlassLimiter limits the attempts of each client IP and the number of concurrent checks of the password since
+// the endpoint is neither authenticated nor protected by the regulation.
+type breakGlassLimiter struct {
+	semaphore chan struct{}
+
+	mutex    sync.Mutex
+	failures map[string]breakGlassFailures
+}
+
+type breakGlassFailures struct {
+	count int
+	since time.Time
+}
+
+func newBreakGlassLimiter() *breakGlassLimiter {
+	return &breakGlassLimiter{
+		semaphore: make(chan struct{}, breakGlassConcurrentChecks),
+		failures:  map[string]breakGlassFailures{},
+	}
+}
+
+// IsLimited returns true if the client IP failed too many attempts within the window.
+func (l *breakGlassLimiter) IsLimited(ip string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	failures, ok := l.failures[ip]
+
+	return ok && now.Before(failures.since.Add(breakGlassFailuresWindow)) && failures.count >= breakGlassMaxFailures
+}
+
+// Fail records a failed attempt of the client IP. The expired entries are evicted when too many IPs are tracked, all
+// of them being forgotten if they are all still valid.
+func (l *breakGlassLimiter) Fail(ip string, now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	failures, ok := l.failures[ip]
+	if ok && now.Before(failures.since.Add(breakGlassFailuresWindow)) {
+		failures.count++
+		l.failures[ip] = failures
+
+		return
+	}
+
+	if !ok && len(l.failures) >= breakGlassMaxTrackedIPs {
+		for key, entry := range l.failures {
+			if !now.Before(entry.since.Add(breakGlassFailuresWindow)) {
+				delete(l.failures, key)
+			}
+		}
+
+		if len(l.failures) >= breakGlassMaxTrackedIPs {
+			l.failures = map[string]breakGlassFailures{}
+		}
+	}
+
+	l.failures[ip] = breakGlassFailures{count: 1, since: now}
+}
+
+// TryAcquire reserves one of the concurrent checks of the password, false if they are all in progress.
+func (l *breakGlassLimiter) TryAcquire() bool {
+	select {
+	case l.semaphore <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release releases a check reserved with TryAcquire.
+func (l *breakGlassLimiter) Release() {
+	<-l.semaphore
+}
+
+// BreakGlassDiagnosticsGet returns the handler returning the health of each provider to the break-glass user. The user
+// authenticates with basic auth against the credential defined in the configuration so that neither the
+// authentication backend, the storage nor the session provider is required. The attempts are limited per client IP
+// and restricted to the configured networks if any.
+func BreakGlassDiagnosticsGet(configuration schema.BreakGlassConfiguration) middlewares.RequestHandler {
+	limiter := newBreakGlassLimiter()
+
+	return func(ctx *middlewares.AutheliaCtx) {
+		breakGlassDiagnosticsGet(ctx, configuration, limiter)
+	}
+}
+
+func breakGlassDiagnosticsGet(ctx *middlewares.AutheliaCtx, configuration schema.BreakGlassConfiguration, limiter *breakGlassLimiter) {
+	remoteIP := ctx.RemoteIP()
+
+	if !isBreakGlassNetwork(configuration.Networks, remoteIP) {
+		ctx.Logger.Warnf("Break-glass access attempt from %s outside of the allowed networks", remoteIP)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	username, password, err := parseBasicAuth(AuthorizationHeader, string(ctx.Request.Header.Peek(AuthorizationHeader)))
+	if err != nil {
+		ctx.Logger.Warnf("Break-glass access attempt from %s without valid credentials: %s", remoteIP, err)
+		replyBreakGlassUnauthorized(ctx)
+
+		return
+	}
+
+	if limiter.IsLimited(remoteIP.String(), ctx.Clock.Now()) {
+		ctx.Logger.Warnf("Break-glass access attempt of user %s from %s refused after too many failures", username, remoteIP)
+		ctx.RequestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+
+		return
+	}
+
+	if !limiter.TryAcquire() {
+		ctx.Logger.Warnf("Break-glass access attempt of user %s from %s refused while another one is checked", username, remoteIP)
+		ctx.RequestCtx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+
+		return
+	}
+
+	ok := checkBreakGlassCredential(ctx, configuration, username, password)
+
+	limiter.Release()
+
+	if !ok {
+		limiter.Fail(remoteIP.String(), ctx.Clock.Now())
+
+		ctx.Logger.Warnf("Break-glass access attempt of user %s from %s failed", username, remoteIP)
+		replyBreakGlassUnauthorized(ctx)
+
+		return
+	}
+
+	ctx.Logger.Warnf("BREAK-GLASS ACCESS: user %s accessed the diagnostics from %s", username, remoteIP)
+
+	checks := []breakGlassCheck{
+		doBreakGlassCheck("storage", ctx.Providers.StorageProvider),
+		doBreakGlassCheck("authentication backend", ctx.Providers.UserProvider),
+		doBreakGlassCheck("notifier", ctx.Providers.Notifier),
+	}
+
+	if ctx.Providers.OIDCUpstream != nil {
+		checks = append(checks, doBreakGlassCheck("OIDC upstream provider", ctx.Providers.OIDCUpstream))
+	}
+
+	if ctx.Providers.TrustedHeader != nil {
+		checks = append(checks, doBreakGlassCheck("trusted header", ctx.Providers.TrustedHeader))
+	}
+
+	if err = ctx.SetJSONBody(checks); err != nil {
+		ctx.Logger.Errorf("Unable to set the response body: %s", err)
+	}
+}
+
+// checkBreakGlassCredential returns true if the username and the password match the break-glass credential. The
+// password is always checked to avoid disclosing the username through the response time.
+func checkBreakGlassCredential(ctx *middlewares.AutheliaCtx, configuration schema.BreakGlassConfiguration, username, password string) bool {
+	ok, err := authentication.CheckPassword(password, configuration.Password)
+	if err != nil {
+		ctx.Logger.Errorf("Unable to check the break-glass password: %s", err)
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(username), []byte(configuration.Username)) == 1 && ok
+}
+
+// isBreakGlassNetwork returns true if the IP is within one of the networks, or if no network is configured.
+func isBreakGlassNetwork(networks []string, ip net.IP) bool {
+	if len(networks) == 0 {
+		return true
+	}
+
+	for _, network := range networks {
+		if _, ipNet, err := net.ParseCIDR(network); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replyBreakGlassUnauthorized replies 401 and asks the client for basic auth credentials.
+func replyBreakGlassUnauthorized(ctx *middlewares.AutheliaCtx) {
+	ctx.ReplyUnauthorized()
+	ctx.Response.Header.Set(breakGlassAuthenticateHeader, breakGlassAuthenticateChallenge)
+}
+
+func doBreakGlassCheck(name string, provider providerWithStartupCheck) breakGlassCheck {
+	check := breakGlassCheck{Name: name}
+
+	if provider == nil {
+		check.Error = "the provider is not configured"
+		return check
+	}
+
+	if _, err := provider.StartupCheck(); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	check.Healthy = true
+
+	return check
+}
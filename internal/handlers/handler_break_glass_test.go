@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type BreakGlassSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *BreakGlassSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+
+	hash, err := authentication.HashPassword("password", "", authentication.HashingAlgorithmArgon2id, 1, 1024, 1, 32, 16)
+	s.Require().NoError(err)
+
+	s.mock.Ctx.Configuration.BreakGlass = &schema.BreakGlassConfiguration{Username: "breakglass", Password: hash}
+}
+
+func (s *BreakGlassSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *BreakGlassSuite) setCredentials(username, password string) {
+	s.mock.Ctx.Request.Header.Set(AuthorizationHeader,
+		authPrefix+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+}
+
+func (s *BreakGlassSuite) TestShouldReturnHealthOfProvidersWhenBackendsAreDown() {
+	s.setCredentials("breakglass", "password")
+
+	s.mock.StorageProviderMock.EXPECT().StartupCheck().Return(false, fmt.Errorf("connection refused"))
+	s.mock.UserProviderMock.EXPECT().StartupCheck().Return(false, fmt.Errorf("LDAP server unreachable"))
+	s.mock.NotifierMock.EXPECT().StartupCheck().Return(true, nil)
+
+	BreakGlassDiagnosticsGet(*s.mock.Ctx.Configuration.BreakGlass)(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), []breakGlassCheck{
+		{Name: "storage", Error: "connection refused"},
+		{Name: "authentication backend", Error: "LDAP server unreachable"},
+		{Name: "notifier", Healthy: true},
+	})
+	s.Assert().Equal("BREAK-GLASS ACCESS: user breakglass accessed the diagnostics from 0.0.0.0", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *BreakGlassSuite) TestShouldRejectWrongPassword() {
+	s.setCredentials("breakglass", "wrong")
+
+	BreakGlassDiagnosticsGet(*s.mock.Ctx.Configuration.BreakGlass)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Basic realm=\"Authelia break-glass\"", string(s.mock.Ctx.Response.Header.Peek("WWW-Authenticate")))
+	s.Assert().Equal("Break-glass access attempt of user breakglass from 0.0.0.0 failed", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *BreakGlassSuite) TestShouldRejectWrongUsername() {
+	s.setCredentials("john", "password")
+
+	BreakGlassDiagnosticsGet(*s.mock.Ctx.Configuration.BreakGlass)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Break-glass access attempt of user john from 0.0.0.0 failed", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *BreakGlassSuite) TestShouldRejectMissingCredentials() {
+	BreakGlassDiagnosticsGet(*s.mock.Ctx.Configuration.BreakGlass)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Break-glass access attempt from 0.0.0.0 without valid credentials: Basic prefix not found in Authorization header",
+		s.mock.Hook.LastEntry().Message)
+}
+
+func (s *BreakGlassSuite) TestShouldLimitFailedAttemptsOfClient() {
+	handler := BreakGlassDiagnosticsGet(*s.mock.Ctx.Configuration.BreakGlass)
+
+	s.setCredentials("breakglass", "wrong")
+
+	for i := 0; i < breakGlassMaxFailures; i++ {
+		handler(s.mock.Ctx)
+		s.Require().Equal(401, s.mock.Ctx.Response.StatusCode())
+	}
+
+	// The right password is not even checked until the window is over.
+	s.setCredentials("breakglass", "password")
+
+	handler(s.mock.Ctx)
+
+	s.Assert().Equal(429, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Break-glass access attempt of user breakglass from 0.0.0.0 refused after too many failures", s.mock.Hook.LastEntry().Message)
+
+	s.mock.Clock.Set(s.mock.Clock.Now().Add(breakGlassFailuresWindow))
+
+	s.mock.StorageProviderMock.EXPECT().StartupCheck().Return(true, nil)
+	s.mock.UserProviderMock.EXPECT().StartupCheck().Return(true, nil)
+	s.mock.NotifierMock.EXPECT().StartupCheck().Return(true, nil)
+
+	s.mock.Ctx.Response.Reset()
+	handler(s.mock.Ctx)
+
+	s.Assert().Equal(200, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *BreakGlassSuite) TestShouldRefuseAttemptWhileAnotherOneIsChecked() {
+	limiter := newBreakGlassLimiter()
+	s.Require().True(limiter.TryAcquire())
+
+	s.setCredentials("breakglass", "password")
+
+	breakGlassDiagnosticsGet(s.mock.Ctx, *s.mock.Ctx.Configuration.BreakGlass, limiter)
+
+	s.Assert().Equal(429, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Break-glass access attempt of user breakglass from 0.0.0.0 refused while another one is checked", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *BreakGlassSuite) TestShouldRejectClientOutsideOfNetworks() {
+	s.mock.Ctx.Configuration.BreakGlass.Networks = []string{"10.0.0.0/8"}
+	s.setCredentials("breakglass", "password")
+
+	BreakGlassDiagnosticsGet(*s.mock.Ctx.Configuration.BreakGlass)(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Break-glass access attempt from 0.0.0.0 outside of the allowed networks", s.mock.Hook.LastEntry().Message)
+}
+
+func TestShouldForgetExpiredBreakGlassFailuresWhenTrackingTooManyIPs(t *testing.T) {
+	limiter := newBreakGlassLimiter()
+	now := time.Now()
+
+	for i := 0; i < breakGlassMaxTrackedIPs; i++ {
+		limiter.Fail(fmt.Sprintf("ip-%d", i), now)
+	}
+
+	limiter.Fail("10.0.0.1", now.Add(breakGlassFailuresWindow))
+
+	assert.Len(t, limiter.failures, 1)
+}
+
+func TestRunBreakGlassSuite(t *testing.T) {
+	suite.Run(t, new(BreakGlassSuite))
+}
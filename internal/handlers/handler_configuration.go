@@ -8,6 +8,7 @@ import (
 // ConfigurationBody the content returned by the configuration endpoint.
 type ConfigurationBody struct {
 	AvailableMethods    MethodList `json:"available_methods"`
+	DefaultMethod       string     `json:"default_method,omitempty"`
 	SecondFactorEnabled bool       `json:"second_factor_enabled"` // whether second factor is enabled or not.
 	TOTPPeriod          int        `json:"totp_period"`
 }
@@ -22,7 +23,9 @@ func ConfigurationGet(ctx *middlewares.AutheliaCtx) {
 		body.AvailableMethods = append(body.AvailableMethods, authentication.Push)
 	}
 
-	body.SecondFactorEnabled = ctx.Providers.Authorizer.IsSecondFactorEnabled()
+	body.DefaultMethod = ctx.Configuration.Default2FAMethod
+
+	body.SecondFactorEnabled = ctx.Providers.Authorizer.IsSecondFactorEnabled() || ctx.GetSession().SecondFactorRequired
 	ctx.Logger.Tracef("Second factor enabled: %v", body.SecondFactorEnabled)
 
 	ctx.Logger.Tracef("Available methods are %s", body.AvailableMethods)
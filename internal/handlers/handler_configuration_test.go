@@ -20,7 +20,7 @@ func (s *SecondFactorAvailableMethodsFixture) SetupTest() {
 	s.mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(schema.AccessControlConfiguration{
 		DefaultPolicy: "deny",
 		Rules:         []schema.ACLRule{},
-	})
+	}, nil)
 }
 
 func (s *SecondFactorAvailableMethodsFixture) TearDownTest() {
@@ -60,6 +60,24 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldServeDefaultMethodsAndMo
 	s.mock.Assert200OK(s.T(), expectedBody)
 }
 
+func (s *SecondFactorAvailableMethodsFixture) TestShouldServeDefaultMethod() {
+	s.mock.Ctx.Configuration = schema.Configuration{
+		Default2FAMethod: "u2f",
+		TOTP: &schema.TOTPConfiguration{
+			Period: schema.DefaultTOTPConfiguration.Period,
+		},
+	}
+	expectedBody := ConfigurationBody{
+		AvailableMethods:    []string{"totp", "u2f"},
+		DefaultMethod:       "u2f",
+		SecondFactorEnabled: false,
+		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
+	}
+
+	ConfigurationGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), expectedBody)
+}
+
 func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsDisabledWhenNoRuleIsSetToTwoFactor() {
 	s.mock.Ctx.Configuration = schema.Configuration{
 		TOTP: &schema.TOTPConfiguration{
@@ -82,7 +100,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsDisab
 				Policy:  "bypass",
 			},
 		},
-	})
+	}, nil)
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
 		AvailableMethods:    []string{"totp", "u2f"},
@@ -113,7 +131,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsEnabl
 				Policy:  "bypass",
 			},
 		},
-	})
+	}, nil)
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
 		AvailableMethods:    []string{"totp", "u2f"},
@@ -144,7 +162,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsEnabl
 				Policy:  "bypass",
 			},
 		},
-	})
+	}, nil)
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
 		AvailableMethods:    []string{"totp", "u2f"},
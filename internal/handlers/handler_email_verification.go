@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+)
+
+var emailVerificationStartArgs = middlewares.IdentityVerificationStartArgs{
+	MailTitle:         "Verify your email address",
+	MailButtonContent: "Verify",
+	TargetEndpoint:    "/email/verify",
+	ActionClaim:       EmailVerificationAction,
+}
+
+// checkEmailVerified returns true if the current email address of the user has been verified. Otherwise a link to
+// verify it is sent to the user. An error is returned if the user has no email address, if the verified address cannot
+// be loaded and the storage failure mode does not tolerate it, or if the link cannot be sent.
+func checkEmailVerified(ctx *middlewares.AutheliaCtx, details *authentication.UserDetails) (bool, error) {
+	if len(details.Emails) == 0 {
+		return false, fmt.Errorf("User %s has no email address to verify", details.Username)
+	}
+
+	verifiedEmail, err := ctx.Providers.StorageProvider.LoadVerifiedEmail(details.Username)
+	if err != nil {
+		if isStorageFailureTolerated(ctx, fmt.Sprintf("email verification of user %s", details.Username), err) {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("Unable to load the verified email address of user %s: %s", details.Username, err)
+	}
+
+	if strings.EqualFold(verifiedEmail, details.Emails[0]) {
+		return true, nil
+	}
+
+	identity := &session.Identity{Username: details.Username, Email: details.Emails[0]}
+
+	if err = middlewares.SendIdentityVerificationEmail(ctx, emailVerificationStartArgs, identity); err != nil {
+		return false, fmt.Errorf("Unable to send the email verification link to user %s: %s", details.Username, err)
+	}
+
+	return false, nil
+}
+
+func emailVerificationIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	details, err := ctx.Providers.UserProvider.GetDetails(username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Error while retrieving details from user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	if len(details.Emails) == 0 {
+		ctx.Error(fmt.Errorf("User %s has no email address to verify", username), operationFailedMessage)
+		return
+	}
+
+	if err = ctx.Providers.StorageProvider.SaveVerifiedEmail(username, details.Emails[0], ctx.Clock.Now()); err != nil {
+		ctx.Error(fmt.Errorf("Unable to save the verified email address of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Debugf("User %s verified their email address %s", username, details.Emails[0])
+
+	ctx.ReplyOK()
+}
+
+// EmailVerificationIdentityFinish the handler marking the email address of the user as verified once they followed
+// the link sent at login.
+var EmailVerificationIdentityFinish = middlewares.IdentityVerificationFinish(
+	middlewares.IdentityVerificationFinishArgs{ActionClaim: EmailVerificationAction}, emailVerificationIdentityFinish)
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type EmailVerificationSuite struct {
+	suite.Suite
+
+	mock    *mocks.MockAutheliaCtx
+	details *authentication.UserDetails
+}
+
+func (s *EmailVerificationSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Configuration.EmailVerification.Enabled = true
+	s.mock.Ctx.Configuration.Server.ExternalURL = "https://login.example.com"
+
+	s.details = &authentication.UserDetails{
+		Username: testUsername,
+		Emails:   []string{"john@example.com"},
+		Groups:   []string{"dev"},
+	}
+}
+
+func (s *EmailVerificationSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *EmailVerificationSuite) TestShouldDenyLoginAndSendLinkWhenEmailNotVerified() {
+	s.mock.UserProviderMock.EXPECT().
+		CheckUserPassword(gomock.Eq(testUsername), gomock.Eq("password")).
+		Return(true, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(s.details, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadVerifiedEmail(gomock.Eq(testUsername)).
+		Return("", nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveIdentityVerificationToken(gomock.Any()).
+		Return(nil)
+
+	var body string
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq("Verify your email address"), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_, _, b, _ string) error {
+			body = b
+			return nil
+		})
+
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john", "password": "password"}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), emailNotVerifiedMessage)
+	s.Assert().Equal("User john has not verified their email address", s.mock.Hook.LastEntry().Message)
+	s.Assert().Contains(body, "https://login.example.com/email/verify?token=")
+	s.Assert().Equal("", s.mock.Ctx.GetSession().Username)
+}
+
+func (s *EmailVerificationSuite) TestShouldAcceptVerifiedEmail() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadVerifiedEmail(gomock.Eq(testUsername)).
+		Return("John@Example.com", nil)
+
+	verified, err := checkEmailVerified(s.mock.Ctx, s.details)
+	s.Require().NoError(err)
+	s.Assert().True(verified)
+}
+
+func (s *EmailVerificationSuite) TestShouldRequireVerificationWhenEmailChanged() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadVerifiedEmail(gomock.Eq(testUsername)).
+		Return("john@old.example.com", nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveIdentityVerificationToken(gomock.Any()).
+		Return(nil)
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq("Verify your email address"), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	verified, err := checkEmailVerified(s.mock.Ctx, s.details)
+	s.Require().NoError(err)
+	s.Assert().False(verified)
+}
+
+func (s *EmailVerificationSuite) TestShouldFailWhenUserHasNoEmail() {
+	s.details.Emails = nil
+
+	_, err := checkEmailVerified(s.mock.Ctx, s.details)
+	s.Assert().EqualError(err, "User john has no email address to verify")
+}
+
+func (s *EmailVerificationSuite) TestShouldFailWhenVerifiedEmailCannotBeLoadedAndFailClosed() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeClosed
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadVerifiedEmail(gomock.Eq(testUsername)).
+		Return("", fmt.Errorf("Failed"))
+
+	_, err := checkEmailVerified(s.mock.Ctx, s.details)
+	s.Assert().EqualError(err, "Unable to load the verified email address of user john: Failed")
+}
+
+func (s *EmailVerificationSuite) TestShouldAcceptLoginWhenVerifiedEmailCannotBeLoadedAndFailOpen() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeOpen
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadVerifiedEmail(gomock.Eq(testUsername)).
+		Return("", fmt.Errorf("Failed"))
+
+	verified, err := checkEmailVerified(s.mock.Ctx, s.details)
+	s.Require().NoError(err)
+	s.Assert().True(verified)
+}
+
+func (s *EmailVerificationSuite) TestShouldSaveVerifiedEmailOnFinish() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(s.details, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveVerifiedEmail(gomock.Eq(testUsername), gomock.Eq("john@example.com"), gomock.Eq(s.mock.Clock.Now())).
+		Return(nil)
+
+	emailVerificationIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *EmailVerificationSuite) TestShouldFailFinishWhenVerifiedEmailCannotBeSaved() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(s.details, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveVerifiedEmail(gomock.Eq(testUsername), gomock.Eq("john@example.com"), gomock.Any()).
+		Return(fmt.Errorf("Failed"))
+
+	emailVerificationIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("Unable to save the verified email address of user john: Failed", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunEmailVerificationSuite(t *testing.T) {
+	suite.Run(t, new(EmailVerificationSuite))
+}
@@ -78,7 +78,9 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			return
 		}
 
-		bannedUntil, err := ctx.Providers.Regulator.Regulate(bodyJSON.Username)
+		// The attempt is reserved before checking the password so that concurrent attempts, possibly handled by other
+		// instances, are regulated as well.
+		reservation, bannedUntil, err := ctx.Providers.Regulator.Reserve(bodyJSON.Username)
 
 		if err != nil {
 			if err == regulation.ErrUserIsBanned {
@@ -86,17 +88,30 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 				return
 			}
 
-			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regulate authentication: %s", err.Error()), authenticationFailedMessage)
+			if !isStorageFailureTolerated(ctx, fmt.Sprintf("regulation of user %s", bodyJSON.Username), err) {
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regulate authentication: %s", err.Error()), authenticationFailedMessage)
+				return
+			}
+		}
+
+		delay, err := ctx.Providers.Regulator.Delay(bodyJSON.Username, reservation)
 
+		if err != nil && !isStorageFailureTolerated(ctx, fmt.Sprintf("throttling of user %s", bodyJSON.Username), err) {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to throttle authentication: %s", err.Error()), authenticationFailedMessage)
 			return
 		}
 
+		if delay > 0 {
+			ctx.Logger.Debugf("Delaying authentication attempt of user %s by %s", bodyJSON.Username, delay)
+			<-ctx.Clock.After(delay)
+		}
+
 		userPasswordOk, err := ctx.Providers.UserProvider.CheckUserPassword(bodyJSON.Username, bodyJSON.Password)
 
 		if err != nil {
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
 
-			if err := ctx.Providers.Regulator.Mark(bodyJSON.Username, false); err != nil {
+			if err := ctx.Providers.Regulator.Confirm(bodyJSON.Username, reservation, false); err != nil {
 				ctx.Logger.Errorf("Unable to mark authentication: %s", err.Error())
 			}
 
@@ -108,7 +123,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		if !userPasswordOk {
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
 
-			if err := ctx.Providers.Regulator.Mark(bodyJSON.Username, false); err != nil {
+			if err := ctx.Providers.Regulator.Confirm(bodyJSON.Username, reservation, false); err != nil {
 				ctx.Logger.Errorf("Unable to mark authentication: %s", err.Error())
 			}
 
@@ -118,16 +133,19 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		}
 
 		ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
-		err = ctx.Providers.Regulator.Mark(bodyJSON.Username, true)
+		err = ctx.Providers.Regulator.Confirm(bodyJSON.Username, reservation, true)
 
-		if err != nil {
+		if err != nil && !isStorageFailureTolerated(ctx, fmt.Sprintf("recording of the authentication of user %s", bodyJSON.Username), err) {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err.Error()), authenticationFailedMessage)
 			return
 		}
 
 		ctx.Logger.Debugf("Credentials validation of user %s is ok", bodyJSON.Username)
 
-		// Reset all values from previous session before regenerating the cookie.
+		// Reset all values from previous session before regenerating the cookie, except the pending authentications
+		// against the upstream provider started from other tabs.
+		pendingFlows := ctx.GetSession().OIDCUpstreamFlows
+
 		err = ctx.SaveSession(session.NewDefaultUserSession())
 
 		if err != nil {
@@ -164,15 +182,44 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 
 		ctx.Logger.Tracef("Details for user %s => groups: %s, emails %s", bodyJSON.Username, userDetails.Groups, userDetails.Emails)
 
+		if ctx.Configuration.EmailVerification.Enabled {
+			verified, err := checkEmailVerified(ctx, userDetails)
+			if err != nil {
+				handleAuthenticationUnauthorized(ctx, err, authenticationFailedMessage)
+				return
+			}
+
+			if !verified {
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("User %s has not verified their email address", bodyJSON.Username), emailNotVerifiedMessage)
+				return
+			}
+		}
+
+		secondFactorRequired := false
+
+		if ctx.Configuration.LoginNotification.Enabled {
+			unrecognised, err := checkLoginDevice(ctx, userDetails.Username, userDetails.Emails)
+			if err != nil {
+				handleAuthenticationUnauthorized(ctx, err, authenticationFailedMessage)
+				return
+			}
+
+			secondFactorRequired = unrecognised && ctx.Configuration.LoginNotification.RequireSecondFactor
+		}
+
 		// And set those information in the new session.
 		userSession := ctx.GetSession()
+		userSession.OIDCUpstreamFlows = pendingFlows
 		userSession.Username = userDetails.Username
 		userSession.DisplayName = userDetails.DisplayName
-		userSession.Groups = userDetails.Groups
+		userSession.Groups = withDefaultGroups(ctx, userDetails.Groups, true)
 		userSession.Emails = userDetails.Emails
+		userSession.Attributes = userDetails.Attributes
 		userSession.AuthenticationLevel = authentication.OneFactor
+		userSession.FirstFactorAuthnTimestamp = ctx.Clock.Now().Unix()
 		userSession.LastActivity = time.Now().Unix()
 		userSession.KeepMeLoggedIn = keepMeLoggedIn
+		userSession.SecondFactorRequired = secondFactorRequired
 		refresh, refreshInterval := getProfileRefreshSettings(ctx.Configuration.AuthenticationBackend)
 
 		if refresh {
@@ -186,8 +233,31 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			return
 		}
 
+		registerUserSession(ctx, userSession.Username)
+		recordLastLogin(ctx, userSession.Username, lastLoginMethodPassword)
+
 		successful = true
 
-		Handle1FAResponse(ctx, bodyJSON.TargetURL, bodyJSON.RequestMethod, userSession.Username, userSession.Groups)
+		if userSession.SecondFactorRequired {
+			ctx.Logger.Debugf("User %s logged in from an unrecognised device and must complete the second factor", userSession.Username)
+			ctx.ReplyOK()
+
+			return
+		}
+
+		Handle1FAResponse(ctx, bodyJSON.TargetURL, bodyJSON.RequestMethod, userSession.Username, userSession.Groups, userSession.Attributes)
+	}
+}
+
+// registerUserSession registers the session of the user who just authenticated and logs the revocation of their
+// oldest sessions exceeding the limit of sessions per user.
+func registerUserSession(ctx *middlewares.AutheliaCtx, username string) {
+	revoked, err := ctx.Providers.SessionProvider.RegisterUserSession(ctx.RequestCtx, username)
+
+	if err != nil {
+		ctx.Logger.Errorf("Unable to revoke the oldest sessions of user %s: %s", username, err)
+	} else if revoked > 0 {
+		ctx.Logger.Infof("Revoked %d oldest session(s) of user %s exceeding the limit of %d concurrent sessions",
+			revoked, username, ctx.Providers.SessionProvider.MaxSessionsPerUser)
 	}
 }
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"fmt"
+	"regexp"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
@@ -15,6 +17,8 @@ import (
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/mocks"
 	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/session"
 )
 
 type FirstFactorSuite struct {
@@ -98,6 +102,41 @@ func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCrede
 	FirstFactorPost(0, false)(s.mock.Ctx)
 }
 
+func (s *FirstFactorSuite) TestShouldReplyIdenticallyToUnknownUserAndWrongPassword() {
+	wrongPassword := mocks.NewMockAutheliaCtx(s.T())
+	defer wrongPassword.Close()
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("unknown"), gomock.Eq("hello")).
+		Return(false, authentication.ErrUserNotFound)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	wrongPassword.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, nil)
+
+	wrongPassword.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{"username": "unknown", "password": "hello"}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	wrongPassword.Ctx.Request.SetBodyString(`{"username": "test", "password": "hello"}`)
+	FirstFactorPost(0, false)(wrongPassword.Ctx)
+
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+	s.Assert().Equal(wrongPassword.Ctx.Response.StatusCode(), s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal(wrongPassword.Ctx.Response.Body(), s.mock.Ctx.Response.Body())
+}
+
 func (s *FirstFactorSuite) TestShouldFailIfUserProviderGetDetailsFail() {
 	s.mock.UserProviderMock.
 		EXPECT().
@@ -228,6 +267,140 @@ func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeUnchecked() {
 	assert.Equal(s.T(), []string{"dev", "admins"}, session.Groups)
 }
 
+func (s *FirstFactorSuite) TestShouldRegenerateSessionForPreventingSessionFixation() {
+	s.mock.Ctx.Clock = &s.mock.Clock
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, nil)
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		GetDetails(gomock.Eq("test")).
+		Return(&authentication.UserDetails{
+			Username: "test",
+			Emails:   []string{"test@example.com"},
+			Groups:   []string{"dev", "admins"},
+		}, nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.OIDCUpstreamFlows = map[string]*session.OIDCUpstreamSession{
+		"state": {State: "state", Nonce: "nonce", StartedAt: s.mock.Clock.Now().Unix()},
+	}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	r := regexp.MustCompile("^authelia_session=(.*); path=")
+	res := r.FindAllStringSubmatch(string(s.mock.Ctx.Response.Header.PeekCookie("authelia_session")), -1)
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"requestMethod": "GET",
+		"keepMeLoggedIn": false
+	}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	s.Assert().Equal(200, s.mock.Ctx.Response.StatusCode())
+	s.Assert().NotEqual(
+		res[0][1],
+		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
+
+	// The pending authentications against the upstream provider survive the regeneration.
+	userSession = s.mock.Ctx.GetSession()
+	s.Assert().Equal(authentication.OneFactor, userSession.AuthenticationLevel)
+	s.Assert().Equal(s.mock.Clock.Now().Unix(), userSession.FirstFactorAuthnTimestamp)
+	s.Require().Contains(userSession.OIDCUpstreamFlows, "state")
+	s.Assert().Equal("nonce", userSession.OIDCUpstreamFlows["state"].Nonce)
+}
+
+func (s *FirstFactorSuite) TestShouldFailIfRegulationFailsAndStorageFailsClosed() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeClosed
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.DefaultRegulationConfiguration, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("test"), gomock.Any()).
+		Return(nil, fmt.Errorf("Failed"))
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": false
+	}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), "Unable to regulate authentication: Unable to load the authentication logs: Failed", s.mock.Hook.LastEntry().Message)
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+}
+
+func (s *FirstFactorSuite) TestShouldAuthenticateIfStorageFailsOpen() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeOpen
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.DefaultRegulationConfiguration, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("test"), gomock.Any()).
+		Return(nil, fmt.Errorf("Failed"))
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		ConfirmAuthenticationLog(gomock.Eq("test"), gomock.Any(), gomock.Eq(true)).
+		Return(fmt.Errorf("Failed"))
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		GetDetails(gomock.Eq("test")).
+		Return(&authentication.UserDetails{
+			Username: "test",
+			Emails:   []string{"test@example.com"},
+			Groups:   []string{"dev", "admins"},
+		}, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": false
+	}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	assert.Equal(s.T(), authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+
+	var warnings []string
+
+	for _, entry := range s.mock.Hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+
+	assert.Equal(s.T(), []string{
+		"Storage backend failure tolerated by the fail_open mode, regulation of user test is degraded: Unable to load the authentication logs: Failed",
+		"Storage backend failure tolerated by the fail_open mode, recording of the authentication of user test is degraded: Failed",
+	}, warnings)
+}
+
 func (s *FirstFactorSuite) TestShouldSaveUsernameFromAuthenticationBackendInSession() {
 	s.mock.UserProviderMock.
 		EXPECT().
@@ -272,6 +445,43 @@ func (s *FirstFactorSuite) TestShouldSaveUsernameFromAuthenticationBackendInSess
 	assert.Equal(s.T(), []string{"dev", "admins"}, session.Groups)
 }
 
+func (s *FirstFactorSuite) TestShouldMergeDefaultGroupsInSession() {
+	s.mock.Ctx.Configuration.AuthenticationBackend.DefaultGroups = []string{"authenticated", "dev"}
+	s.mock.Ctx.Configuration.AuthenticationBackend.File = &schema.FileAuthenticationBackendConfiguration{
+		DefaultGroups: []string{"local"},
+	}
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, nil)
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		GetDetails(gomock.Eq("test")).
+		Return(&authentication.UserDetails{
+			Username: "test",
+			Emails:   []string{"test@example.com"},
+			Groups:   []string{"dev", "admins"},
+		}, nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"requestMethod": "GET",
+		"keepMeLoggedIn": false
+	}`)
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), []string{"dev", "admins", "local", "authenticated"}, s.mock.Ctx.GetSession().Groups)
+}
+
 type FirstFactorRedirectionSuite struct {
 	suite.Suite
 
@@ -289,7 +499,7 @@ func (s *FirstFactorRedirectionSuite) SetupTest() {
 		},
 	}
 	s.mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(
-		s.mock.Ctx.Configuration.AccessControl)
+		s.mock.Ctx.Configuration.AccessControl, nil)
 
 	s.mock.UserProviderMock.
 		EXPECT().
@@ -355,6 +565,28 @@ func (s *FirstFactorRedirectionSuite) TestShouldRedirectToDefaultURLWhenURLIsUns
 	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: "https://default.local"})
 }
 
+// When:
+//   1/ the target url is not in the redirection allowlist
+//   2/ two_factor is disabled (no policy is set to two_factor)
+//   3/ default_redirect_url is provided
+// Then:
+//   the user should be redirected to the default url.
+func (s *FirstFactorRedirectionSuite) TestShouldRedirectToDefaultURLWhenURLIsNotAllowedAndTwoFactorDisabled() {
+	s.mock.Ctx.Configuration.AllowedRedirectionDomains = []string{"*.example.com"}
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"requestMethod": "GET",
+		"keepMeLoggedIn": false,
+		"targetURL": "https://notallowed.local"
+	}`)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	// Respond with 200.
+	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: "https://default.local"})
+}
+
 // When:
 //   1/ two_factor is enabled (default policy)
 // Then:
@@ -362,7 +594,7 @@ func (s *FirstFactorRedirectionSuite) TestShouldRedirectToDefaultURLWhenURLIsUns
 func (s *FirstFactorRedirectionSuite) TestShouldReply200WhenNoTargetURLProvidedAndTwoFactorEnabled() {
 	s.mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(schema.AccessControlConfiguration{
 		DefaultPolicy: "two_factor",
-	})
+	}, nil)
 	s.mock.Ctx.Request.SetBodyString(`{
 		"username": "test",
 		"password": "hello",
@@ -393,10 +625,40 @@ func (s *FirstFactorRedirectionSuite) TestShouldReply200WhenUnsafeTargetURLProvi
 				Policy:  "two_factor",
 			},
 		},
-	})
+	}, nil)
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"requestMethod": "GET",
+		"keepMeLoggedIn": false
+	}`)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	// Respond with 200.
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+// When:
+//   1/ the target url is a deep link protected by two_factor
+// Then:
+//   the user should receive 200 without redirection URL so that the portal asks for the second factor while keeping
+//   the target url.
+func (s *FirstFactorRedirectionSuite) TestShouldReply200WhenTargetURLRequiresStepUpToTwoFactor() {
+	s.mock.Ctx.Configuration.Session.Domain = "example.com"
+	s.mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(schema.AccessControlConfiguration{
+		DefaultPolicy: "deny",
+		Rules: []schema.ACLRule{
+			{
+				Domains: []string{"two-factor.example.com"},
+				Policy:  "two_factor",
+			},
+		},
+	}, nil)
 	s.mock.Ctx.Request.SetBodyString(`{
 		"username": "test",
 		"password": "hello",
+		"targetURL": "https://two-factor.example.com/reports/2021/q1?format=pdf",
 		"requestMethod": "GET",
 		"keepMeLoggedIn": false
 	}`)
@@ -407,6 +669,40 @@ func (s *FirstFactorRedirectionSuite) TestShouldReply200WhenUnsafeTargetURLProvi
 	s.mock.Assert200OK(s.T(), nil)
 }
 
+func (s *FirstFactorRedirectionSuite) TestShouldReply200AndRequireSecondFactorWhenLoginUnrecognised() {
+	s.mock.Ctx.Configuration.LoginNotification.Enabled = true
+	s.mock.Ctx.Configuration.LoginNotification.RequireSecondFactor = true
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLoginDevices(gomock.Eq("test")).
+		Return([]models.LoginDevice{{Username: "test", Fingerprint: "another", IP: "10.0.0.1"}}, nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		SaveLoginDevice(gomock.Any()).
+		Return(nil)
+
+	s.mock.NotifierMock.
+		EXPECT().
+		Send(gomock.Eq("test@example.com"), gomock.Eq(loginNotificationTitle), gomock.Any(), gomock.Eq("")).
+		Return(nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"targetURL": "https://default.local",
+		"requestMethod": "GET",
+		"keepMeLoggedIn": false
+	}`)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	// Respond with 200 without redirecting to the one factor target.
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().True(s.mock.Ctx.GetSession().SecondFactorRequired)
+}
+
 func TestFirstFactorSuite(t *testing.T) {
 	suite.Run(t, new(FirstFactorSuite))
 	suite.Run(t, new(FirstFactorRedirectionSuite))
@@ -2,18 +2,53 @@ package handlers
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/authelia/authelia/internal/middlewares"
 )
 
-// LogoutPost is the handler logging out the user attached to the given cookie.
+// LogoutPost is the handler logging out the user attached to the given cookie. The session is destroyed server-side
+// so that it is invalidated for every domain sharing the session cookie. The user is then redirected either to the
+// target URL provided in the body if it is safe, or to the configured logout redirection URL.
 func LogoutPost(ctx *middlewares.AutheliaCtx) {
+	body := logoutRequestBody{}
+
+	if len(ctx.PostBody()) > 0 {
+		if err := ctx.ParseBody(&body); err != nil {
+			ctx.Error(fmt.Errorf("Unable to parse body during logout: %s", err), operationFailedMessage)
+			return
+		}
+	}
+
 	ctx.Logger.Tracef("Destroy session")
 	err := ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to destroy session during logout: %s", err), operationFailedMessage)
+		return
+	}
+
+	redirectionURL := ctx.Configuration.LogoutRedirectionURL
+
+	if body.TargetURL != "" {
+		targetURL, err := url.ParseRequestURI(body.TargetURL)
+
+		switch {
+		case err != nil:
+			ctx.Logger.Debugf("Unable to parse logout target URL %s: %s", body.TargetURL, err)
+		case isRedirectionSafe(ctx, *targetURL):
+			redirectionURL = body.TargetURL
+		default:
+			ctx.Logger.Debugf("Logout target URL %s is not safe, falling back to the logout redirection URL", body.TargetURL)
+		}
 	}
 
-	ctx.ReplyOK()
+	if redirectionURL == "" {
+		ctx.ReplyOK()
+		return
+	}
+
+	if err := ctx.SetJSONBody(redirectResponse{Redirect: redirectionURL}); err != nil {
+		ctx.Logger.Errorf("Unable to set logout redirection URL in body: %s", err)
+	}
 }
@@ -38,6 +38,40 @@ func (s *LogoutSuite) TestShouldDestroySession() {
 	assert.True(s.T(), strings.HasPrefix(string(b), "authelia_session=;"))
 }
 
+func (s *LogoutSuite) TestShouldReplyOKWithoutRedirectionURL() {
+	LogoutPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *LogoutSuite) TestShouldRedirectToLogoutRedirectionURL() {
+	s.mock.Ctx.Configuration.LogoutRedirectionURL = "https://home.example.com/"
+
+	LogoutPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: "https://home.example.com/"})
+}
+
+func (s *LogoutSuite) TestShouldRedirectToSafeTargetURL() {
+	s.mock.Ctx.Configuration.Session.Domain = "example.com"
+	s.mock.Ctx.Configuration.LogoutRedirectionURL = "https://home.example.com/"
+	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://app.example.com/\"}")
+
+	LogoutPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: "https://app.example.com/"})
+}
+
+func (s *LogoutSuite) TestShouldFallbackToLogoutRedirectionURLWhenTargetURLIsUnsafe() {
+	s.mock.Ctx.Configuration.Session.Domain = "example.com"
+	s.mock.Ctx.Configuration.LogoutRedirectionURL = "https://home.example.com/"
+	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://evil.example.org/\"}")
+
+	LogoutPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: "https://home.example.com/"})
+}
+
 func TestRunLogoutSuite(t *testing.T) {
 	s := new(LogoutSuite)
 	suite.Run(t, s)
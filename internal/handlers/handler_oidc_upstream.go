@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/federation"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// OIDCUpstreamAuthorizeGet redirects the user to the upstream OpenID Connect provider in order to authenticate. The
+// target URL provided in the rd parameter is kept in the session to redirect the user once authenticated.
+func OIDCUpstreamAuthorizeGet(ctx *middlewares.AutheliaCtx) {
+	state, err := federation.RandomState()
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to generate the upstream authentication state: %s", err), operationFailedMessage)
+		return
+	}
+
+	nonce, err := federation.RandomState()
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to generate the upstream authentication nonce: %s", err), operationFailedMessage)
+		return
+	}
+
+	authURL, err := ctx.Providers.OIDCUpstream.AuthCodeURL(state, nonce)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to build the upstream authorization URL: %s", err), operationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+	userSession.OIDCUpstreamFlows = pruneOIDCUpstreamFlows(ctx, userSession.OIDCUpstreamFlows, "")
+	userSession.OIDCUpstreamFlows[state] = &session.OIDCUpstreamSession{
+		State:         state,
+		Nonce:         nonce,
+		TargetURL:     string(ctx.QueryArgs().Peek("rd")),
+		RequestMethod: string(ctx.QueryArgs().Peek("rm")),
+		StartedAt:     ctx.Clock.Now().Unix(),
+	}
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		ctx.Error(fmt.Errorf("Unable to save the upstream authentication state: %s", err), operationFailedMessage)
+		return
+	}
+
+	ctx.Redirect(authURL, fasthttp.StatusFound)
+}
+
+// OIDCUpstreamCallbackGet is the handler the upstream OpenID Connect provider redirects the user to. The authorization
+// code is exchanged for an ID token and the user is considered authenticated with one factor using the identity it
+// asserts.
+func OIDCUpstreamCallbackGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+	state := string(ctx.QueryArgs().Peek("state"))
+
+	if len(userSession.OIDCUpstreamFlows) == 0 {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("No authentication against the upstream provider is pending"), authenticationFailedMessage)
+		return
+	}
+
+	pending, ok := userSession.OIDCUpstreamFlows[state]
+	remaining := pruneOIDCUpstreamFlows(ctx, userSession.OIDCUpstreamFlows, state)
+
+	if ok && ctx.Clock.Now().After(time.Unix(pending.StartedAt, 0).Add(oidcUpstreamFlowLifespan)) {
+		ctx.Logger.Debugf("Upstream authentication with state %s has expired", state)
+
+		ok = false
+	}
+
+	// The flow is completed whatever the outcome of the callback, it is removed from the pending ones.
+	userSession.OIDCUpstreamFlows = remaining
+	if err := ctx.SaveSession(userSession); err != nil {
+		ctx.Logger.Errorf("Unable to save the upstream authentication state: %s", err)
+	}
+
+	if upstreamError := ctx.QueryArgs().Peek("error"); len(upstreamError) != 0 {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Upstream provider replied with error %s: %s", upstreamError, ctx.QueryArgs().Peek("error_description")), authenticationFailedMessage)
+		return
+	}
+
+	if !ok {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Upstream authentication state does not match"), authenticationFailedMessage)
+		return
+	}
+
+	identity, err := ctx.Providers.OIDCUpstream.Exchange(string(ctx.QueryArgs().Peek("code")), pending.Nonce)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to authenticate against the upstream provider: %s", err), authenticationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Debugf("Identity of user %s asserted by the upstream provider", identity.Username)
+
+	if err = ctx.Providers.Regulator.Mark(identity.Username, true); err != nil &&
+		!isStorageFailureTolerated(ctx, fmt.Sprintf("recording of the authentication of user %s", identity.Username), err) {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), authenticationFailedMessage)
+		return
+	}
+
+	// Reset all values from previous session before regenerating the cookie.
+	if err = ctx.SaveSession(session.NewDefaultUserSession()); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to reset the session for user %s: %s", identity.Username, err), authenticationFailedMessage)
+		return
+	}
+
+	if err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regenerate session for user %s: %s", identity.Username, err), authenticationFailedMessage)
+		return
+	}
+
+	userSession = ctx.GetSession()
+	userSession.OIDCUpstreamFlows = remaining
+	userSession.Username = identity.Username
+	userSession.DisplayName = identity.DisplayName
+	userSession.Groups = withDefaultGroups(ctx, identity.Groups, false)
+	userSession.Emails = identity.Emails
+	userSession.AuthenticationLevel = authentication.OneFactor
+	userSession.FirstFactorAuthnTimestamp = ctx.Clock.Now().Unix()
+	userSession.LastActivity = ctx.Clock.Now().Unix()
+	userSession.UpstreamIssuer = ctx.Configuration.OIDCUpstream.Issuer
+
+	if ctx.Configuration.LoginNotification.Enabled {
+		unrecognised, err := checkLoginDevice(ctx, identity.Username, identity.Emails)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, err, authenticationFailedMessage)
+			return
+		}
+
+		userSession.SecondFactorRequired = unrecognised && ctx.Configuration.LoginNotification.RequireSecondFactor
+	}
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to save session of user %s", identity.Username), authenticationFailedMessage)
+		return
+	}
+
+	registerUserSession(ctx, userSession.Username)
+	recordLastLogin(ctx, userSession.Username, lastLoginMethodOIDCUpstream)
+
+	ctx.Redirect(getOIDCUpstreamRedirectionURL(ctx, userSession, pending), fasthttp.StatusFound)
+}
+
+// pruneOIDCUpstreamFlows returns a copy of the pending authentications against the upstream provider without the one
+// matching the completed state and without the expired ones. Only the most recent ones are kept when there are too many.
+func pruneOIDCUpstreamFlows(ctx *middlewares.AutheliaCtx, flows map[string]*session.OIDCUpstreamSession, completed string) map[string]*session.OIDCUpstreamSession {
+	pending := make([]*session.OIDCUpstreamSession, 0, len(flows))
+
+	for state, flow := range flows {
+		if state == completed || ctx.Clock.Now().After(time.Unix(flow.StartedAt, 0).Add(oidcUpstreamFlowLifespan)) {
+			continue
+		}
+
+		pending = append(pending, flow)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].StartedAt > pending[j].StartedAt
+	})
+
+	// Keep room for the flow about to be started.
+	if len(pending) >= oidcUpstreamMaxPendingFlows {
+		pending = pending[:oidcUpstreamMaxPendingFlows-1]
+	}
+
+	pruned := make(map[string]*session.OIDCUpstreamSession, len(pending)+1)
+
+	for _, flow := range pending {
+		pruned[flow.State] = flow
+	}
+
+	return pruned
+}
+
+// getOIDCUpstreamRedirectionURL returns the portal if the target URL or the session requires the second factor, the
+// target URL if it is safe, or the default redirection URL otherwise.
+func getOIDCUpstreamRedirectionURL(ctx *middlewares.AutheliaCtx, userSession session.UserSession, pending *session.OIDCUpstreamSession) string {
+	portalURL := ctx.Configuration.Server.BasePath + "/"
+
+	if userSession.SecondFactorRequired && pending.TargetURL == "" {
+		return portalURL
+	}
+
+	if pending.TargetURL != "" {
+		targetURL, err := url.ParseRequestURI(pending.TargetURL)
+
+		switch {
+		case err != nil || !isRedirectionSafe(ctx, *targetURL):
+			ctx.Logger.Debugf("Redirection URL %s is not safe", pending.TargetURL)
+		case userSession.SecondFactorRequired || ctx.Providers.Authorizer.GetRequiredLevel(
+			authorization.Subject{Username: userSession.Username, Groups: userSession.Groups, IP: ctx.RemoteIP(), Attributes: userSession.Attributes},
+			authorization.NewObject(targetURL, pending.RequestMethod)) == authorization.TwoFactor:
+			query := url.Values{}
+			query.Set("rd", pending.TargetURL)
+
+			if pending.RequestMethod != "" {
+				query.Set("rm", pending.RequestMethod)
+			}
+
+			return portalURL + "?" + query.Encode()
+		default:
+			return pending.TargetURL
+		}
+	}
+
+	if ctx.Configuration.DefaultRedirectionURL != "" {
+		return ctx.Configuration.DefaultRedirectionURL
+	}
+
+	return portalURL
+}
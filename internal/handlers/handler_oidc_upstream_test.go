@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/federation"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/session"
+)
+
+type OIDCUpstreamSuite struct {
+	suite.Suite
+
+	mock   *mocks.MockAutheliaCtx
+	server *httptest.Server
+}
+
+func (s *OIDCUpstreamSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.server.URL,
+			"authorization_endpoint": s.server.URL + "/authorize",
+			"token_endpoint":         s.server.URL + "/token",
+			"jwks_uri":               s.server.URL + "/jwks",
+		})
+	}))
+
+	configuration := schema.DefaultOIDCUpstreamConfiguration
+	configuration.Issuer = s.server.URL
+	configuration.ClientID = "authelia"
+	configuration.ClientSecret = "secret"
+	configuration.RedirectURI = "https://login.example.com/api/oidc-upstream/callback"
+
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Configuration.Session.Domain = "example.com"
+	s.mock.Ctx.Configuration.OIDCUpstream = &configuration
+	s.mock.Ctx.Providers.OIDCUpstream = federation.NewOIDCUpstreamProvider(configuration, nil)
+}
+
+func (s *OIDCUpstreamSuite) TearDownTest() {
+	s.mock.Close()
+	s.server.Close()
+}
+
+func (s *OIDCUpstreamSuite) TestShouldRedirectToUpstreamProviderAndKeepTargetURL() {
+	s.mock.Ctx.QueryArgs().Add("rd", "https://two-factor.example.com/")
+	s.mock.Ctx.QueryArgs().Add("rm", "GET")
+
+	OIDCUpstreamAuthorizeGet(s.mock.Ctx)
+
+	s.Require().Equal(302, s.mock.Ctx.Response.StatusCode())
+
+	location, err := url.Parse(string(s.mock.Ctx.Response.Header.Peek("Location")))
+	s.Require().NoError(err)
+
+	pending := s.mock.Ctx.GetSession().OIDCUpstreamFlows[location.Query().Get("state")]
+	s.Require().NotNil(pending)
+
+	s.Assert().Equal(s.server.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	s.Assert().Equal(pending.State, location.Query().Get("state"))
+	s.Assert().Equal(pending.Nonce, location.Query().Get("nonce"))
+	s.Assert().NotEqual(pending.State, pending.Nonce)
+	s.Assert().Equal("https://two-factor.example.com/", pending.TargetURL)
+	s.Assert().Equal("GET", pending.RequestMethod)
+	s.Assert().Equal(s.mock.Clock.Now().Unix(), pending.StartedAt)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldKeepConcurrentAuthenticationsPending() {
+	s.mock.Ctx.QueryArgs().Add("rd", "https://one-factor.example.com/")
+	OIDCUpstreamAuthorizeGet(s.mock.Ctx)
+
+	s.mock.Ctx.QueryArgs().Set("rd", "https://two-factor.example.com/")
+	OIDCUpstreamAuthorizeGet(s.mock.Ctx)
+
+	flows := s.mock.Ctx.GetSession().OIDCUpstreamFlows
+	s.Require().Len(flows, 2)
+
+	var targetURLs []string
+
+	for state, flow := range flows {
+		s.Assert().Equal(state, flow.State)
+		targetURLs = append(targetURLs, flow.TargetURL)
+	}
+
+	s.Assert().ElementsMatch([]string{"https://one-factor.example.com/", "https://two-factor.example.com/"}, targetURLs)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldOnlyCompleteTheAuthenticationMatchingTheState() {
+	s.setPendingAuthentication()
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.OIDCUpstreamFlows["other-state"] = &session.OIDCUpstreamSession{State: "other-state", Nonce: "other-nonce", StartedAt: s.mock.Clock.Now().Unix()}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.Ctx.QueryArgs().Add("state", "state")
+	s.mock.Ctx.QueryArgs().Add("code", "code")
+
+	OIDCUpstreamCallbackGet(s.mock.Ctx)
+
+	// The token endpoint is not served, the exchange fails but the flow is completed anyway.
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+
+	flows := s.mock.Ctx.GetSession().OIDCUpstreamFlows
+	s.Require().Len(flows, 1)
+	s.Assert().Equal("other-nonce", flows["other-state"].Nonce)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldFailCallbackWhenAuthenticationExpired() {
+	s.setPendingAuthentication()
+	s.mock.Clock.Set(s.mock.Clock.Now().Add(11 * time.Minute))
+
+	s.mock.Ctx.QueryArgs().Add("state", "state")
+	s.mock.Ctx.QueryArgs().Add("code", "code")
+
+	OIDCUpstreamCallbackGet(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+	s.Assert().Equal("Upstream authentication state does not match", s.mock.Hook.LastEntry().Message)
+	s.Assert().Len(s.mock.Ctx.GetSession().OIDCUpstreamFlows, 0)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldDiscardOldestAuthenticationsWhenTooManyArePending() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.OIDCUpstreamFlows = map[string]*session.OIDCUpstreamSession{}
+
+	for i := 0; i < 12; i++ {
+		state := fmt.Sprintf("state-%d", i)
+		userSession.OIDCUpstreamFlows[state] = &session.OIDCUpstreamSession{State: state, StartedAt: s.mock.Clock.Now().Add(time.Duration(i-12) * time.Second).Unix()}
+	}
+
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	OIDCUpstreamAuthorizeGet(s.mock.Ctx)
+
+	flows := s.mock.Ctx.GetSession().OIDCUpstreamFlows
+	s.Require().Len(flows, 10)
+	s.Assert().NotContains(flows, "state-0")
+	s.Assert().NotContains(flows, "state-2")
+	s.Assert().Contains(flows, "state-3")
+	s.Assert().Contains(flows, "state-11")
+}
+
+func (s *OIDCUpstreamSuite) TestShouldFailCallbackWithoutPendingAuthentication() {
+	s.mock.Ctx.QueryArgs().Add("state", "state")
+	s.mock.Ctx.QueryArgs().Add("code", "code")
+
+	OIDCUpstreamCallbackGet(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+	s.Assert().Equal("No authentication against the upstream provider is pending", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldFailCallbackWhenStateMismatches() {
+	s.setPendingAuthentication()
+	s.mock.Ctx.QueryArgs().Add("state", "another-state")
+	s.mock.Ctx.QueryArgs().Add("code", "code")
+
+	OIDCUpstreamCallbackGet(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+	s.Assert().Equal("Upstream authentication state does not match", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal("", s.mock.Ctx.GetSession().Username)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldFailCallbackWhenUpstreamReturnsError() {
+	s.setPendingAuthentication()
+	s.mock.Ctx.QueryArgs().Add("state", "state")
+	s.mock.Ctx.QueryArgs().Add("error", "access_denied")
+	s.mock.Ctx.QueryArgs().Add("error_description", "user cancelled")
+
+	OIDCUpstreamCallbackGet(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+	s.Assert().Equal("Upstream provider replied with error access_denied: user cancelled", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldRedirectToSafeTargetURL() {
+	redirectionURL := getOIDCUpstreamRedirectionURL(s.mock.Ctx, session.UserSession{Username: testUsername},
+		&session.OIDCUpstreamSession{TargetURL: "https://one-factor.example.com/"})
+
+	s.Assert().Equal("https://one-factor.example.com/", redirectionURL)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldRedirectToPortalWhenTargetURLRequiresSecondFactor() {
+	redirectionURL := getOIDCUpstreamRedirectionURL(s.mock.Ctx, session.UserSession{Username: testUsername},
+		&session.OIDCUpstreamSession{TargetURL: "https://two-factor.example.com/", RequestMethod: "GET"})
+
+	s.Assert().Equal("/?rd=https%3A%2F%2Ftwo-factor.example.com%2F&rm=GET", redirectionURL)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldRedirectToDefaultRedirectionURLWhenTargetURLIsUnsafe() {
+	s.mock.Ctx.Configuration.DefaultRedirectionURL = "https://home.example.com/"
+
+	redirectionURL := getOIDCUpstreamRedirectionURL(s.mock.Ctx, session.UserSession{Username: testUsername},
+		&session.OIDCUpstreamSession{TargetURL: "https://evil.com/"})
+
+	s.Assert().Equal("https://home.example.com/", redirectionURL)
+}
+
+func (s *OIDCUpstreamSuite) TestShouldRedirectToPortalWithoutTargetURL() {
+	redirectionURL := getOIDCUpstreamRedirectionURL(s.mock.Ctx, session.UserSession{Username: testUsername},
+		&session.OIDCUpstreamSession{})
+
+	s.Assert().Equal("/", redirectionURL)
+}
+
+func (s *OIDCUpstreamSuite) setPendingAuthentication() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.OIDCUpstreamFlows = map[string]*session.OIDCUpstreamSession{
+		"state": {State: "state", Nonce: "nonce", StartedAt: s.mock.Clock.Now().Unix()},
+	}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func TestRunOIDCUpstreamSuite(t *testing.T) {
+	suite.Run(t, new(OIDCUpstreamSuite))
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pquerna/otp/totp"
 
@@ -27,6 +28,34 @@ func isTokenUserValidFor2FARegistration(ctx *middlewares.AutheliaCtx, username s
 	return ctx.GetSession().Username == username
 }
 
+// getTOTPIssuer returns the issuer of the most specific domain issuer matching the host the portal is served from, or
+// the global issuer if none matches.
+func getTOTPIssuer(ctx *middlewares.AutheliaCtx) string {
+	issuer := ctx.Configuration.TOTP.Issuer
+
+	if len(ctx.Configuration.TOTP.DomainIssuers) == 0 {
+		return issuer
+	}
+
+	rootURL, err := ctx.ExternalRootURL()
+	if err != nil {
+		ctx.Logger.Errorf("Unable to determine the domain of the portal, using the global TOTP issuer: %s", err)
+		return issuer
+	}
+
+	host := strings.ToLower(rootURL.Hostname())
+	matched := 0
+
+	for _, domainIssuer := range ctx.Configuration.TOTP.DomainIssuers {
+		if (host == domainIssuer.Domain || strings.HasSuffix(host, "."+domainIssuer.Domain)) && len(domainIssuer.Domain) > matched {
+			issuer = domainIssuer.Issuer
+			matched = len(domainIssuer.Domain)
+		}
+	}
+
+	return issuer
+}
+
 // SecondFactorTOTPIdentityStart the handler for initiating the identity validation.
 var SecondFactorTOTPIdentityStart = middlewares.IdentityVerificationStart(middlewares.IdentityVerificationStartArgs{
 	MailTitle:             "Register your mobile",
@@ -38,7 +67,7 @@ var SecondFactorTOTPIdentityStart = middlewares.IdentityVerificationStart(middle
 
 func secondFactorTOTPIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
 	key, err := totp.Generate(totp.GenerateOpts{
-		Issuer:      ctx.Configuration.TOTP.Issuer,
+		Issuer:      getTOTPIssuer(ctx),
 		AccountName: username,
 		SecretSize:  32,
 		Period:      uint(ctx.Configuration.TOTP.Period),
@@ -49,12 +78,23 @@ func secondFactorTOTPIdentityFinish(ctx *middlewares.AutheliaCtx, username strin
 		return
 	}
 
+	userSession := ctx.GetSession()
+
+	if err = updateSecondFactorVersion(ctx, &userSession); err != nil {
+		ctx.Error(err, unableToRegisterOneTimePasswordMessage)
+		return
+	}
+
 	err = ctx.Providers.StorageProvider.SaveTOTPSecret(username, key.Secret())
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to save TOTP secret in DB: %s", err), unableToRegisterOneTimePasswordMessage)
 		return
 	}
 
+	if err = ctx.SaveSession(userSession); err != nil {
+		ctx.Logger.Errorf("Unable to save the second factor version in the session of user %s: %s", username, err)
+	}
+
 	response := TOTPKeyResponse{
 		OTPAuthURL:   key.URL(),
 		Base32Secret: key.Secret(),
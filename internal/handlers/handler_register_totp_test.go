@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type RegisterTOTPSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *RegisterTOTPSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Configuration.TOTP = &schema.TOTPConfiguration{
+		Issuer: "Authelia",
+		Period: 30,
+		DomainIssuers: []schema.TOTPDomainIssuerConfiguration{
+			{Domain: "brand-a.example.com", Issuer: "Brand A"},
+			{Domain: "brand-b.example.com", Issuer: "Brand B"},
+			{Domain: "staff.brand-b.example.com", Issuer: "Brand B Staff"},
+		},
+	}
+
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *RegisterTOTPSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *RegisterTOTPSuite) TestShouldEmbedDomainIssuerInOTPAuthURL() {
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "login.brand-a.example.com")
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveSecondFactorVersion(gomock.Eq(testUsername), gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveTOTPSecret(gomock.Eq(testUsername), gomock.Any()).
+		Return(nil)
+
+	secondFactorTOTPIdentityFinish(s.mock.Ctx, testUsername)
+
+	var response TOTPKeyResponse
+
+	s.mock.GetResponseData(s.T(), &response)
+
+	otpAuthURL, err := url.Parse(response.OTPAuthURL)
+	s.Require().NoError(err)
+
+	s.Assert().Equal("Brand A", otpAuthURL.Query().Get("issuer"))
+	s.Assert().Equal("/Brand A:john", otpAuthURL.Path)
+}
+
+func (s *RegisterTOTPSuite) TestShouldUseMostSpecificDomainIssuer() {
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "login.staff.brand-b.example.com")
+	s.Assert().Equal("Brand B Staff", getTOTPIssuer(s.mock.Ctx))
+
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "Login.Brand-B.example.com:8443")
+	s.Assert().Equal("Brand B", getTOTPIssuer(s.mock.Ctx))
+}
+
+func (s *RegisterTOTPSuite) TestShouldUseGlobalIssuerWhenNoDomainMatches() {
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+	s.Assert().Equal("Authelia", getTOTPIssuer(s.mock.Ctx))
+
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "login.notbrand-a.example.com")
+	s.Assert().Equal("Authelia", getTOTPIssuer(s.mock.Ctx))
+}
+
+func (s *RegisterTOTPSuite) TestShouldUseDomainOfExternalURL() {
+	s.mock.Ctx.Configuration.Server.ExternalURL = "https://auth.brand-b.example.com"
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "login.brand-a.example.com")
+
+	s.Assert().Equal("Brand B", getTOTPIssuer(s.mock.Ctx))
+}
+
+func TestRunRegisterTOTPSuite(t *testing.T) {
+	suite.Run(t, new(RegisterTOTPSuite))
+}
@@ -24,17 +24,13 @@ var SecondFactorU2FIdentityStart = middlewares.IdentityVerificationStart(middlew
 })
 
 func secondFactorU2FIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
-	if ctx.XForwardedProto() == nil {
-		ctx.Error(errMissingXForwardedProto, operationFailedMessage)
-		return
-	}
-
-	if ctx.XForwardedHost() == nil {
-		ctx.Error(errMissingXForwardedHost, operationFailedMessage)
+	rootURL, err := ctx.ExternalRootURL()
+	if err != nil {
+		ctx.Error(err, operationFailedMessage)
 		return
 	}
 
-	appID := fmt.Sprintf("%s://%s", ctx.XForwardedProto(), ctx.XForwardedHost())
+	appID := fmt.Sprintf("%s://%s", rootURL.Scheme, rootURL.Host)
 	ctx.Logger.Tracef("U2F appID is %s", appID)
 
 	var trustedFacets = []string{appID}
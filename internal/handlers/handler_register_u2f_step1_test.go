@@ -59,8 +59,8 @@ func (s *HandlerRegisterU2FStep1Suite) TestShouldRaiseWhenXForwardedProtoIsMissi
 		Return(true, nil)
 
 	s.mock.StorageProviderMock.EXPECT().
-		RemoveIdentityVerificationToken(gomock.Eq(token)).
-		Return(nil)
+		ConsumeIdentityVerificationToken(gomock.Eq(token)).
+		Return(true, nil)
 
 	SecondFactorU2FIdentityFinish(s.mock.Ctx)
 
@@ -79,8 +79,8 @@ func (s *HandlerRegisterU2FStep1Suite) TestShouldRaiseWhenXForwardedHostIsMissin
 		Return(true, nil)
 
 	s.mock.StorageProviderMock.EXPECT().
-		RemoveIdentityVerificationToken(gomock.Eq(token)).
-		Return(nil)
+		ConsumeIdentityVerificationToken(gomock.Eq(token)).
+		Return(true, nil)
 
 	SecondFactorU2FIdentityFinish(s.mock.Ctx)
 
@@ -44,6 +44,12 @@ func SecondFactorU2FRegister(ctx *middlewares.AutheliaCtx) {
 
 	ctx.Logger.Debugf("Register U2F device for user %s", userSession.Username)
 
+	// The session is saved by the deferred function clearing the challenge.
+	if err = updateSecondFactorVersion(ctx, &userSession); err != nil {
+		ctx.Error(err, unableToRegisterSecurityKeyMessage)
+		return
+	}
+
 	publicKey := elliptic.Marshal(elliptic.P256(), registration.PubKey.X, registration.PubKey.Y)
 	err = ctx.Providers.StorageProvider.SaveU2FDeviceHandle(userSession.Username, registration.KeyHandle, publicKey)
 
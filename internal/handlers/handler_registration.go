@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/templates"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// registrationUsernameRegexp restricts the usernames of the registered accounts to the characters safe in the users
+// database, the access control subjects and the forwarded headers.
+var registrationUsernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,99}$`)
+
+var registrationStartArgs = middlewares.IdentityVerificationStartArgs{
+	MailTitle:         "Confirm your registration",
+	MailButtonContent: "Confirm",
+	TargetEndpoint:    "/registration/verify",
+	ActionClaim:       RegistrationAction,
+}
+
+// registrationRequestBody is the body of the request registering an account.
+type registrationRequestBody struct {
+	Username    string `json:"username" valid:"required"`
+	DisplayName string `json:"displayName" valid:"required"`
+	Email       string `json:"email" valid:"required"`
+	Password    string `json:"password" valid:"required"`
+}
+
+// registrationResponseBody is the body of the response to the verification of the email address of an account.
+type registrationResponseBody struct {
+	ApprovalRequired bool `json:"approvalRequired"`
+}
+
+// adminRegistrationRequestBody is the body of the request approving or rejecting a pending registration.
+type adminRegistrationRequestBody struct {
+	Username string `json:"username" valid:"required"`
+}
+
+// adminRegistrationResponseBody is a registration awaiting the approval of an administrator.
+type adminRegistrationResponseBody struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Email       string `json:"email"`
+	Time        int64  `json:"time"`
+}
+
+// isRegistrationEmailAllowed returns true if the domain of the email address matches one of the allowed domains. A
+// domain prefixed by *. matches its subdomains only.
+func isRegistrationEmailAllowed(email string, allowedDomains []string) bool {
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return false
+	}
+
+	domain := strings.ToLower(email[i+1:])
+
+	for _, allowed := range allowedDomains {
+		allowed = strings.ToLower(allowed)
+
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(domain, allowed[1:]) {
+				return true
+			}
+
+			continue
+		}
+
+		if domain == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RegistrationPost registers a pending account and sends a link to the user to verify their email address. The
+// account is only created once the email address is verified and, if required, the registration approved. In order to
+// prevent user enumeration, the registration of an existing user is silently ignored.
+func RegistrationPost(ctx *middlewares.AutheliaCtx) {
+	var body registrationRequestBody
+
+	if err := ctx.ParseBody(&body); err != nil {
+		ctx.Error(err, unableToRegisterMessage)
+		return
+	}
+
+	if !registrationUsernameRegexp.MatchString(body.Username) {
+		ctx.Error(fmt.Errorf("Username %s is not valid for a registration", body.Username), unableToRegisterMessage)
+		return
+	}
+
+	if address, err := mail.ParseAddress(body.Email); err != nil || address.Address != body.Email {
+		ctx.Error(fmt.Errorf("Email address %s of the registration of user %s is not valid", body.Email, body.Username), unableToRegisterMessage)
+		return
+	}
+
+	if !isRegistrationEmailAllowed(body.Email, ctx.Configuration.Registration.AllowedDomains) {
+		ctx.Error(fmt.Errorf("Email address %s of the registration of user %s is not in an allowed domain", body.Email, body.Username), registrationNotAllowedMessage)
+		return
+	}
+
+	if _, err := ctx.Providers.UserProvider.GetDetails(body.Username); err == nil {
+		ctx.Logger.Infof("Ignoring the registration of user %s who already exists", body.Username)
+		ctx.ReplyOK()
+
+		return
+	}
+
+	pending, err := ctx.Providers.StorageProvider.LoadPendingRegistration(body.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load the pending registration of user %s: %s", body.Username, err), unableToRegisterMessage)
+		return
+	}
+
+	if pending != nil && pending.Verified {
+		ctx.Logger.Infof("Ignoring the registration of user %s whose registration is awaiting an approval", body.Username)
+		ctx.ReplyOK()
+
+		return
+	}
+
+	if pending != nil && isRegistrationLinkValid(ctx, pending) {
+		ctx.Logger.Infof("Ignoring the registration of user %s whose previous registration link is still valid", body.Username)
+		ctx.ReplyOK()
+
+		return
+	}
+
+	hash, err := authentication.HashPasswordWithConfiguration(body.Password, ctx.Configuration.AuthenticationBackend.File.Password)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to hash the password of the registration of user %s: %s", body.Username, err), unableToRegisterMessage)
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.SavePendingRegistration(models.PendingRegistration{
+		Username:    body.Username,
+		DisplayName: body.DisplayName,
+		Email:       body.Email,
+		Hash:        hash,
+		Time:        ctx.Clock.Now(),
+	})
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to save the pending registration of user %s: %s", body.Username, err), unableToRegisterMessage)
+		return
+	}
+
+	identity := &session.Identity{Username: body.Username, Email: body.Email}
+
+	if err = middlewares.SendIdentityVerificationEmail(ctx, registrationStartArgs, identity); err != nil {
+		ctx.Error(fmt.Errorf("Unable to send the registration link to user %s: %s", body.Username, err), unableToRegisterMessage)
+		return
+	}
+
+	ctx.Logger.Infof("Account %s registered from %s, awaiting the verification of its email address", body.Username, ctx.RemoteIP())
+
+	ctx.ReplyOK()
+}
+
+// isRegistrationLinkValid returns true if the link sent for the pending registration may not be expired yet. The link
+// only carries the username, so the pending registration must not be replaced until then, otherwise it would verify the
+// email address of the new registration instead. The token expiration having a resolution of one second, the link is
+// considered valid one more second.
+func isRegistrationLinkValid(ctx *middlewares.AutheliaCtx, registration *models.PendingRegistration) bool {
+	// Ignore the error as the duration is checked by the validator.
+	lifetime, _ := utils.ParseDurationString(ctx.Configuration.IdentityVerification.TokenLifetime)
+
+	return !ctx.Clock.Now().After(registration.Time.Add(lifetime + time.Second))
+}
+
+func registrationIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	registration, err := ctx.Providers.StorageProvider.LoadPendingRegistration(username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load the pending registration of user %s: %s", username, err), unableToRegisterMessage)
+		return
+	}
+
+	if registration == nil {
+		ctx.Error(fmt.Errorf("No registration of user %s is pending", username), unableToRegisterMessage)
+		return
+	}
+
+	if ctx.Configuration.Registration.RequireApproval {
+		registration.Verified = true
+
+		if err = ctx.Providers.StorageProvider.SavePendingRegistration(*registration); err != nil {
+			ctx.Error(fmt.Errorf("Unable to save the pending registration of user %s: %s", username, err), unableToRegisterMessage)
+			return
+		}
+
+		ctx.Logger.Infof("User %s verified their email address, their registration is awaiting an approval", username)
+	} else if err = createRegisteredAccount(ctx, registration); err != nil {
+		ctx.Error(err, unableToRegisterMessage)
+		return
+	}
+
+	if err = ctx.SetJSONBody(registrationResponseBody{ApprovalRequired: ctx.Configuration.Registration.RequireApproval}); err != nil {
+		ctx.Logger.Errorf("Unable to set the response body: %s", err)
+	}
+}
+
+// RegistrationIdentityFinish the handler verifying the email address of a registered account once the user followed
+// the link sent at registration.
+var RegistrationIdentityFinish = middlewares.IdentityVerificationFinish(
+	middlewares.IdentityVerificationFinishArgs{ActionClaim: RegistrationAction}, registrationIdentityFinish)
+
+// createRegisteredAccount creates the account of a pending registration in the authentication backend and removes the
+// pending registration.
+func createRegisteredAccount(ctx *middlewares.AutheliaCtx, registration *models.PendingRegistration) error {
+	err := ctx.Providers.UserProvider.AddUser(authentication.UserDetails{
+		Username:    registration.Username,
+		DisplayName: registration.DisplayName,
+		Emails:      []string{registration.Email},
+		Groups:      ctx.Configuration.Registration.Groups,
+	}, registration.Hash)
+	if err != nil {
+		return fmt.Errorf("Unable to create the account of user %s: %s", registration.Username, err)
+	}
+
+	if err = ctx.Providers.StorageProvider.DeletePendingRegistration(registration.Username); err != nil {
+		ctx.Logger.Errorf("Unable to delete the pending registration of user %s: %s", registration.Username, err)
+	}
+
+	// The user verified the email address of the account while registering.
+	if err = ctx.Providers.StorageProvider.SaveVerifiedEmail(registration.Username, registration.Email, ctx.Clock.Now()); err != nil {
+		ctx.Logger.Errorf("Unable to save the verified email address of user %s: %s", registration.Username, err)
+	}
+
+	ctx.Logger.Infof("Account of user %s created", registration.Username)
+
+	return nil
+}
+
+// isRegistrationAdmin returns true if the user is authenticated with two factors and member of one of the registration
+// admin groups.
+func isRegistrationAdmin(ctx *middlewares.AutheliaCtx, userSession session.UserSession) bool {
+	if userSession.AuthenticationLevel < authentication.TwoFactor {
+		return false
+	}
+
+	for _, group := range userSession.Groups {
+		for _, adminGroup := range ctx.Configuration.Registration.AdminGroups {
+			if group == adminGroup {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AdminRegistrationsGet lists the registrations awaiting an approval. Only the users authenticated with two factors
+// and member of one of the registration admin groups are allowed to do so.
+func AdminRegistrationsGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if !isRegistrationAdmin(ctx, userSession) {
+		ctx.Logger.Warnf("User %s is not allowed to list the pending registrations", userSession.Username)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	registrations, err := ctx.Providers.StorageProvider.LoadVerifiedPendingRegistrations()
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load the pending registrations: %s", err), operationFailedMessage)
+		return
+	}
+
+	response := make([]adminRegistrationResponseBody, 0, len(registrations))
+
+	for _, registration := range registrations {
+		response = append(response, adminRegistrationResponseBody{
+			Username:    registration.Username,
+			DisplayName: registration.DisplayName,
+			Email:       registration.Email,
+			Time:        registration.Time.Unix(),
+		})
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set the response body: %s", err)
+	}
+}
+
+// AdminRegistrationApprovePost creates the account of a registration awaiting an approval and notifies the user.
+func AdminRegistrationApprovePost(ctx *middlewares.AutheliaCtx) {
+	userSession, registration, ok := loadRegistrationAwaitingApproval(ctx)
+	if !ok {
+		return
+	}
+
+	if err := createRegisteredAccount(ctx, registration); err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Infof("Registration of user %s approved by %s from %s", registration.Username, userSession.Username, ctx.RemoteIP())
+
+	sendRegistrationApprovedNotification(ctx, registration)
+
+	ctx.ReplyOK()
+}
+
+// AdminRegistrationRejectPost removes a registration awaiting an approval.
+func AdminRegistrationRejectPost(ctx *middlewares.AutheliaCtx) {
+	userSession, registration, ok := loadRegistrationAwaitingApproval(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ctx.Providers.StorageProvider.DeletePendingRegistration(registration.Username); err != nil {
+		ctx.Error(fmt.Errorf("Unable to delete the pending registration of user %s: %s", registration.Username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Infof("Registration of user %s rejected by %s from %s", registration.Username, userSession.Username, ctx.RemoteIP())
+
+	ctx.ReplyOK()
+}
+
+// loadRegistrationAwaitingApproval checks the user is a registration admin and loads the registration awaiting an
+// approval designated by the body of the request. It replies to the request and returns false otherwise.
+func loadRegistrationAwaitingApproval(ctx *middlewares.AutheliaCtx) (session.UserSession, *models.PendingRegistration, bool) {
+	userSession := ctx.GetSession()
+
+	if !isRegistrationAdmin(ctx, userSession) {
+		ctx.Logger.Warnf("User %s is not allowed to approve or reject the pending registrations", userSession.Username)
+		ctx.ReplyForbidden()
+
+		return userSession, nil, false
+	}
+
+	var body adminRegistrationRequestBody
+
+	if err := ctx.ParseBody(&body); err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return userSession, nil, false
+	}
+
+	registration, err := ctx.Providers.StorageProvider.LoadPendingRegistration(body.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load the pending registration of user %s: %s", body.Username, err), operationFailedMessage)
+		return userSession, nil, false
+	}
+
+	if registration == nil || !registration.Verified {
+		ctx.Error(fmt.Errorf("No registration of user %s is awaiting an approval", body.Username), operationFailedMessage)
+		return userSession, nil, false
+	}
+
+	return userSession, registration, true
+}
+
+// sendRegistrationApprovedNotification notifies the user their account has been approved. A failure is only logged
+// since the account is created anyway.
+func sendRegistrationApprovedNotification(ctx *middlewares.AutheliaCtx, registration *models.PendingRegistration) {
+	params := map[string]interface{}{
+		"username": registration.Username,
+	}
+
+	if rootURL, err := ctx.ExternalRootURL(); err == nil {
+		params["url"] = rootURL.String()
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := templates.RegistrationApprovedEmailTemplate.Execute(buf, params); err != nil {
+		ctx.Logger.Errorf("Unable to render the registration approval of user %s: %s", registration.Username, err)
+		return
+	}
+
+	if err := ctx.Providers.Notifier.Send(registration.Email, registrationApprovedTitle, buf.String(), ""); err != nil {
+		ctx.Logger.Errorf("Unable to send the registration approval to user %s: %s", registration.Username, err)
+	}
+}
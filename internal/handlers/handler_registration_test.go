@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+)
+
+type RegistrationSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *RegistrationSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Configuration.Server.ExternalURL = "https://login.example.com"
+
+	password := schema.DefaultPasswordSHA512Configuration
+	s.mock.Ctx.Configuration.AuthenticationBackend.File = &schema.FileAuthenticationBackendConfiguration{
+		Password: &password,
+	}
+
+	s.mock.Ctx.Configuration.Registration = schema.RegistrationConfiguration{
+		Enabled:        true,
+		AllowedDomains: []string{"example.com"},
+		AdminGroups:    []string{"support"},
+		Groups:         []string{"users"},
+	}
+
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john", "displayName": "John Doe", "email": "john@example.com", "password": "password"}`)
+}
+
+func (s *RegistrationSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *RegistrationSuite) setAdminSession(groups ...string) {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = "harry"
+	userSession.Groups = groups
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *RegistrationSuite) TestShouldRegisterPendingAccountAndSendLink() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(nil, authentication.ErrUserNotFound)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(nil, nil)
+
+	var registration models.PendingRegistration
+
+	s.mock.StorageProviderMock.EXPECT().
+		SavePendingRegistration(gomock.Any()).
+		DoAndReturn(func(r models.PendingRegistration) error {
+			registration = r
+			return nil
+		})
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveIdentityVerificationToken(gomock.Any()).
+		Return(nil)
+
+	var body string
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq("Confirm your registration"), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_, _, b, _ string) error {
+			body = b
+			return nil
+		})
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Contains(body, "https://login.example.com/registration/verify?token=")
+
+	s.Assert().Equal(testUsername, registration.Username)
+	s.Assert().Equal("John Doe", registration.DisplayName)
+	s.Assert().Equal("john@example.com", registration.Email)
+	s.Assert().False(registration.Verified)
+	s.Assert().Equal(s.mock.Clock.Now(), registration.Time)
+
+	ok, err := authentication.CheckPassword("password", registration.Hash)
+	s.Require().NoError(err)
+	s.Assert().True(ok)
+}
+
+func (s *RegistrationSuite) TestShouldRejectEmailOutsideAllowedDomains() {
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john", "displayName": "John Doe", "email": "john@evil.com", "password": "password"}`)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), registrationNotAllowedMessage)
+	s.Assert().Equal("Email address john@evil.com of the registration of user john is not in an allowed domain", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldRejectInvalidUsername() {
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john,ou=admins", "displayName": "John Doe", "email": "john@example.com", "password": "password"}`)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterMessage)
+	s.Assert().Equal("Username john,ou=admins is not valid for a registration", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldRejectInvalidEmail() {
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john", "displayName": "John Doe", "email": "John <john@example.com>", "password": "password"}`)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterMessage)
+	s.Assert().Equal("Email address John <john@example.com> of the registration of user john is not valid", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldSilentlyIgnoreExistingUser() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(&authentication.UserDetails{Username: testUsername}, nil)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal("Ignoring the registration of user john who already exists", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldSilentlyIgnoreRegistrationAwaitingApproval() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(nil, authentication.ErrUserNotFound)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Verified: true}, nil)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal("Ignoring the registration of user john whose registration is awaiting an approval", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldSilentlyIgnoreRegistrationWhoseLinkIsStillValid() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(nil, authentication.ErrUserNotFound)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Email: "john@example.com", Time: s.mock.Clock.Now().Add(-5 * time.Minute)}, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john", "displayName": "John Doe", "email": "harry@example.com", "password": "password"}`)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal("Ignoring the registration of user john whose previous registration link is still valid", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldReplaceRegistrationWhoseLinkExpired() {
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(nil, authentication.ErrUserNotFound)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Email: "john@example.com", Time: s.mock.Clock.Now().Add(-6 * time.Minute)}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SavePendingRegistration(gomock.Any()).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveIdentityVerificationToken(gomock.Any()).
+		Return(nil)
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq("Confirm your registration"), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	RegistrationPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *RegistrationSuite) TestShouldCreateAccountOnFinish() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, DisplayName: "John Doe", Email: "john@example.com", Hash: "hash"}, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		AddUser(gomock.Eq(authentication.UserDetails{
+			Username:    testUsername,
+			DisplayName: "John Doe",
+			Emails:      []string{"john@example.com"},
+			Groups:      []string{"users"},
+		}), gomock.Eq("hash")).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeletePendingRegistration(gomock.Eq(testUsername)).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveVerifiedEmail(gomock.Eq(testUsername), gomock.Eq("john@example.com"), gomock.Eq(s.mock.Clock.Now())).
+		Return(nil)
+
+	registrationIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200OK(s.T(), registrationResponseBody{ApprovalRequired: false})
+	s.Assert().Equal("Account of user john created", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldMarkRegistrationVerifiedOnFinishWhenApprovalRequired() {
+	s.mock.Ctx.Configuration.Registration.RequireApproval = true
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Email: "john@example.com"}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SavePendingRegistration(gomock.Eq(models.PendingRegistration{Username: testUsername, Email: "john@example.com", Verified: true})).
+		Return(nil)
+
+	registrationIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200OK(s.T(), registrationResponseBody{ApprovalRequired: true})
+}
+
+func (s *RegistrationSuite) TestShouldFailFinishWhenAccountCannotBeCreated() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Email: "john@example.com", Hash: "hash"}, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		AddUser(gomock.Any(), gomock.Eq("hash")).
+		Return(authentication.ErrUserAlreadyExists)
+
+	registrationIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterMessage)
+	s.Assert().Equal("Unable to create the account of user john: "+authentication.ErrUserAlreadyExists.Error(), s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldListRegistrationsAwaitingApproval() {
+	s.setAdminSession("support")
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadVerifiedPendingRegistrations().
+		Return([]models.PendingRegistration{
+			{Username: testUsername, DisplayName: "John Doe", Email: "john@example.com", Hash: "hash", Verified: true, Time: s.mock.Clock.Now()},
+		}, nil)
+
+	AdminRegistrationsGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), []adminRegistrationResponseBody{
+		{Username: testUsername, DisplayName: "John Doe", Email: "john@example.com", Time: s.mock.Clock.Now().Unix()},
+	})
+}
+
+func (s *RegistrationSuite) TestShouldForbidListingToUsersNotMemberOfAdminGroups() {
+	s.setAdminSession("dev")
+
+	AdminRegistrationsGet(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("User harry is not allowed to list the pending registrations", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldApproveRegistrationAndNotifyUser() {
+	s.setAdminSession("support")
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john"}`)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Email: "john@example.com", Hash: "hash", Verified: true}, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		AddUser(gomock.Any(), gomock.Eq("hash")).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeletePendingRegistration(gomock.Eq(testUsername)).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveVerifiedEmail(gomock.Eq(testUsername), gomock.Eq("john@example.com"), gomock.Any()).
+		Return(nil)
+
+	var body string
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq(registrationApprovedTitle), gomock.Any(), gomock.Eq("")).
+		DoAndReturn(func(_, _, b, _ string) error {
+			body = b
+			return nil
+		})
+
+	AdminRegistrationApprovePost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Contains(body, "Your account john has been approved")
+	s.Assert().Contains(body, "https://login.example.com")
+	s.Assert().Equal("Registration of user john approved by harry from 0.0.0.0", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldNotApproveRegistrationNotVerified() {
+	s.setAdminSession("support")
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john"}`)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername}, nil)
+
+	AdminRegistrationApprovePost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("No registration of user john is awaiting an approval", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *RegistrationSuite) TestShouldRejectRegistration() {
+	s.setAdminSession("support")
+	s.mock.Ctx.Request.SetBodyString(`{"username": "john"}`)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPendingRegistration(gomock.Eq(testUsername)).
+		Return(&models.PendingRegistration{Username: testUsername, Verified: true}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeletePendingRegistration(gomock.Eq(testUsername)).
+		Return(fmt.Errorf("Failed"))
+
+	AdminRegistrationRejectPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("Unable to delete the pending registration of user john: Failed", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunRegistrationSuite(t *testing.T) {
+	suite.Run(t, new(RegistrationSuite))
+}
+
+func TestShouldMatchRegistrationAllowedDomains(t *testing.T) {
+	domains := []string{"example.com", "*.corp.example.com"}
+
+	assert.True(t, isRegistrationEmailAllowed("john@example.com", domains))
+	assert.True(t, isRegistrationEmailAllowed("john@EXAMPLE.com", domains))
+	assert.True(t, isRegistrationEmailAllowed("john@eu.corp.example.com", domains))
+	assert.False(t, isRegistrationEmailAllowed("john@corp.example.com", domains))
+	assert.False(t, isRegistrationEmailAllowed("john@sub.example.com", domains))
+	assert.False(t, isRegistrationEmailAllowed("john@evilexample.com", domains))
+	assert.False(t, isRegistrationEmailAllowed("john", domains))
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/utils"
 )
 
@@ -27,6 +29,15 @@ func ResetPasswordPost(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
+	if ctx.Configuration.AuthenticationBackend.File != nil {
+		var message string
+
+		if message, err = checkPasswordHistory(ctx, *userSession.PasswordResetUsername, requestBody.Password); err != nil {
+			ctx.Error(err, message)
+			return
+		}
+	}
+
 	err = ctx.Providers.UserProvider.UpdatePassword(*userSession.PasswordResetUsername, requestBody.Password)
 
 	if err != nil {
@@ -44,6 +55,10 @@ func ResetPasswordPost(ctx *middlewares.AutheliaCtx) {
 
 	ctx.Logger.Debugf("Password of user %s has been reset", *userSession.PasswordResetUsername)
 
+	if ctx.Configuration.AuthenticationBackend.File != nil {
+		appendPasswordHistory(ctx, *userSession.PasswordResetUsername, requestBody.Password)
+	}
+
 	// Reset the request.
 	userSession.PasswordResetUsername = nil
 	err = ctx.SaveSession(userSession)
@@ -55,3 +70,74 @@ func ResetPasswordPost(ctx *middlewares.AutheliaCtx) {
 
 	ctx.ReplyOK()
 }
+
+// checkPasswordHistory checks the new password of the user is not one of their recent passwords and that their
+// password was not changed too recently. It returns the message to display to the user when the check fails.
+func checkPasswordHistory(ctx *middlewares.AutheliaCtx, username, password string) (message string, err error) {
+	configuration := ctx.Configuration.AuthenticationBackend.File.PasswordHistory
+	minAge, _ := utils.ParseDurationString(configuration.MinAge)
+
+	if configuration.HistorySize == 0 && minAge == 0 {
+		return "", nil
+	}
+
+	limit := configuration.HistorySize
+	if limit == 0 {
+		limit = 1
+	}
+
+	entries, err := ctx.Providers.StorageProvider.LoadLatestPasswordHistory(username, limit)
+	if err != nil {
+		if isStorageFailureTolerated(ctx, fmt.Sprintf("password history check of user %s", username), err) {
+			return "", nil
+		}
+
+		return unableToResetPasswordMessage, fmt.Errorf("Unable to load the password history of user %s: %s", username, err)
+	}
+
+	if minAge != 0 && len(entries) != 0 && ctx.Clock.Now().Before(entries[0].Time.Add(minAge)) {
+		return passwordChangedTooRecentlyMessage, fmt.Errorf("Password of user %s was changed less than %s ago", username, configuration.MinAge)
+	}
+
+	if configuration.HistorySize == 0 {
+		return "", nil
+	}
+
+	if reused, err := ctx.Providers.UserProvider.CheckUserPassword(username, password); err == nil && reused {
+		return passwordReusedMessage, fmt.Errorf("User %s attempted to reuse their current password", username)
+	}
+
+	for _, entry := range entries {
+		if reused, err := authentication.CheckPassword(password, entry.Hash); err == nil && reused {
+			return passwordReusedMessage, fmt.Errorf("User %s attempted to reuse one of their last %d passwords", username, configuration.HistorySize)
+		}
+	}
+
+	return "", nil
+}
+
+// appendPasswordHistory records the new password of the user when the password history is enabled. Failing to do so
+// is only logged since the password has already been changed.
+func appendPasswordHistory(ctx *middlewares.AutheliaCtx, username, password string) {
+	configuration := ctx.Configuration.AuthenticationBackend.File
+	minAge, _ := utils.ParseDurationString(configuration.PasswordHistory.MinAge)
+
+	if configuration.PasswordHistory.HistorySize == 0 && minAge == 0 {
+		return
+	}
+
+	hash, err := authentication.HashPasswordWithConfiguration(password, configuration.Password)
+	if err != nil {
+		ctx.Logger.Errorf("Unable to hash the password of user %s for the password history: %s", username, err)
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.AppendPasswordHistory(models.PasswordHistoryEntry{
+		Username: username,
+		Hash:     hash,
+		Time:     ctx.Clock.Now(),
+	})
+	if err != nil {
+		ctx.Logger.Errorf("Unable to record the password history of user %s: %s", username, err)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+)
+
+type ResetPasswordStep2Suite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *ResetPasswordStep2Suite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+
+	password := schema.DefaultPasswordSHA512Configuration
+	s.mock.Ctx.Configuration.AuthenticationBackend.File = &schema.FileAuthenticationBackendConfiguration{
+		Password: &password,
+		PasswordHistory: schema.PasswordHistoryConfiguration{
+			HistorySize: 3,
+			MinAge:      "1d",
+		},
+	}
+
+	username := testUsername
+	userSession := s.mock.Ctx.GetSession()
+	userSession.PasswordResetUsername = &username
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.Ctx.Request.SetBodyString("{\"password\": \"newpassword\"}")
+}
+
+func (s *ResetPasswordStep2Suite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *ResetPasswordStep2Suite) hash(password string) string {
+	hash, err := authentication.HashPasswordWithConfiguration(password, s.mock.Ctx.Configuration.AuthenticationBackend.File.Password)
+	s.Require().NoError(err)
+
+	return hash
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldResetPasswordAndRecordHistory() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestPasswordHistory(gomock.Eq(testUsername), gomock.Eq(3)).
+		Return([]models.PasswordHistoryEntry{
+			{Username: testUsername, Hash: s.hash("oldpassword"), Time: s.mock.Clock.Now().Add(-48 * time.Hour)},
+		}, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		CheckUserPassword(gomock.Eq(testUsername), gomock.Eq("newpassword")).
+		Return(false, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		UpdatePassword(gomock.Eq(testUsername), gomock.Eq("newpassword")).
+		Return(nil)
+
+	var entry models.PasswordHistoryEntry
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendPasswordHistory(gomock.Any()).
+		DoAndReturn(func(e models.PasswordHistoryEntry) error {
+			entry = e
+			return nil
+		})
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Nil(s.mock.Ctx.GetSession().PasswordResetUsername)
+
+	s.Assert().Equal(testUsername, entry.Username)
+	s.Assert().Equal(s.mock.Clock.Now(), entry.Time)
+
+	ok, err := authentication.CheckPassword("newpassword", entry.Hash)
+	s.Require().NoError(err)
+	s.Assert().True(ok)
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldRejectPasswordFromHistory() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestPasswordHistory(gomock.Eq(testUsername), gomock.Eq(3)).
+		Return([]models.PasswordHistoryEntry{
+			{Username: testUsername, Hash: s.hash("currentpassword"), Time: s.mock.Clock.Now().Add(-48 * time.Hour)},
+			{Username: testUsername, Hash: s.hash("newpassword"), Time: s.mock.Clock.Now().Add(-96 * time.Hour)},
+		}, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		CheckUserPassword(gomock.Eq(testUsername), gomock.Eq("newpassword")).
+		Return(false, nil)
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), passwordReusedMessage)
+	s.Assert().Equal("User john attempted to reuse one of their last 3 passwords", s.mock.Hook.LastEntry().Message)
+	s.Assert().NotNil(s.mock.Ctx.GetSession().PasswordResetUsername)
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldRejectCurrentPassword() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestPasswordHistory(gomock.Eq(testUsername), gomock.Eq(3)).
+		Return(nil, nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		CheckUserPassword(gomock.Eq(testUsername), gomock.Eq("newpassword")).
+		Return(true, nil)
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), passwordReusedMessage)
+	s.Assert().Equal("User john attempted to reuse their current password", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldRejectPasswordChangedTooRecently() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestPasswordHistory(gomock.Eq(testUsername), gomock.Eq(3)).
+		Return([]models.PasswordHistoryEntry{
+			{Username: testUsername, Hash: s.hash("currentpassword"), Time: s.mock.Clock.Now().Add(-time.Hour)},
+		}, nil)
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), passwordChangedTooRecentlyMessage)
+	s.Assert().Equal("Password of user john was changed less than 1d ago", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldNotCheckHistoryWhenDisabled() {
+	s.mock.Ctx.Configuration.AuthenticationBackend.File.PasswordHistory = schema.PasswordHistoryConfiguration{MinAge: "0"}
+
+	s.mock.UserProviderMock.EXPECT().
+		UpdatePassword(gomock.Eq(testUsername), gomock.Eq("newpassword")).
+		Return(nil)
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldSkipHistoryWhenStorageFailsOpen() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeOpen
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestPasswordHistory(gomock.Eq(testUsername), gomock.Eq(3)).
+		Return(nil, fmt.Errorf("Failed"))
+
+	s.mock.UserProviderMock.EXPECT().
+		UpdatePassword(gomock.Eq(testUsername), gomock.Eq("newpassword")).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendPasswordHistory(gomock.Any()).
+		Return(fmt.Errorf("Failed"))
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *ResetPasswordStep2Suite) TestShouldFailWhenHistoryCannotBeLoadedAndStorageFailsClosed() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeClosed
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestPasswordHistory(gomock.Eq(testUsername), gomock.Eq(3)).
+		Return(nil, fmt.Errorf("Failed"))
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToResetPasswordMessage)
+	s.Assert().Equal("Unable to load the password history of user john: Failed", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunResetPasswordStep2Suite(t *testing.T) {
+	suite.Run(t, new(ResetPasswordStep2Suite))
+}
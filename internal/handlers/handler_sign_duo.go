@@ -58,6 +58,11 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 			return
 		}
 
+		if err = loadSecondFactorVersion(ctx, &userSession); err != nil {
+			handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+			return
+		}
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -66,6 +71,8 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		}
 
 		userSession.AuthenticationLevel = authentication.TwoFactor
+		userSession.SecondFactorAuthnTimestamp = ctx.Clock.Now().Unix()
+		userSession.SecondFactorRequired = false
 		err = ctx.SaveSession(userSession)
 
 		if err != nil {
@@ -73,6 +80,8 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 			return
 		}
 
+		recordLastLogin(ctx, userSession.Username, authentication.Push)
+
 		Handle2FAResponse(ctx, requestBody.TargetURL)
 	}
 }
@@ -37,6 +37,11 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 			return
 		}
 
+		if err = loadSecondFactorVersion(ctx, &userSession); err != nil {
+			handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+			return
+		}
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -45,6 +50,8 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 		}
 
 		userSession.AuthenticationLevel = authentication.TwoFactor
+		userSession.SecondFactorAuthnTimestamp = ctx.Clock.Now().Unix()
+		userSession.SecondFactorRequired = false
 		err = ctx.SaveSession(userSession)
 
 		if err != nil {
@@ -52,6 +59,8 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 			return
 		}
 
+		recordLastLogin(ctx, userSession.Username, authentication.TOTP)
+
 		Handle2FAResponse(ctx, bodyJSON.TargetURL)
 	}
 }
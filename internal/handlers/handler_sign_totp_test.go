@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/tstranex/u2f"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/mocks"
 	"github.com/authelia/authelia/internal/session"
 )
@@ -104,6 +105,30 @@ func (s *HandlerSignTOTPSuite) TestShouldRedirectUserToSafeTargetURL() {
 	})
 }
 
+func (s *HandlerSignTOTPSuite) TestShouldRedirectUserToDeepLinkAfterStepUp() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPSecret(gomock.Any()).
+		Return("secret", nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
+		Return(true, nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token:     "abc",
+		TargetURL: "https://mydomain.local/reports/2021/q1?format=pdf&filter=a%2Fb",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), redirectResponse{
+		Redirect: "https://mydomain.local/reports/2021/q1?format=pdf&filter=a%2Fb",
+	})
+}
+
 func (s *HandlerSignTOTPSuite) TestShouldNotRedirectToUnsafeURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
@@ -126,7 +151,36 @@ func (s *HandlerSignTOTPSuite) TestShouldNotRedirectToUnsafeURL() {
 	s.mock.Assert200OK(s.T(), nil)
 }
 
+func (s *HandlerSignTOTPSuite) TestShouldRedirectToDefaultURLWhenURLIsNotAllowed() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPSecret(gomock.Any()).
+		Return("secret", nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
+		Return(true, nil)
+
+	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
+	s.mock.Ctx.Configuration.AllowedRedirectionDomains = []string{"home.example.com"}
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token:     "abc",
+		TargetURL: "https://mydomain.local",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), redirectResponse{
+		Redirect: testRedirectionURL,
+	})
+}
+
 func (s *HandlerSignTOTPSuite) TestShouldRegenerateSessionForPreventingSessionFixation() {
+	s.mock.Ctx.Clock = &s.mock.Clock
+
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
@@ -152,6 +206,34 @@ func (s *HandlerSignTOTPSuite) TestShouldRegenerateSessionForPreventingSessionFi
 	s.Assert().NotEqual(
 		res[0][1],
 		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
+	s.Assert().Equal(s.mock.Clock.Now().Unix(), s.mock.Ctx.GetSession().SecondFactorAuthnTimestamp)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldKeepSecondFactorVersionInSession() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+	s.mock.Ctx.Configuration.Session.SecondFactorChange = schema.SecondFactorChangeDowngrade
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPSecret(gomock.Any()).
+		Return("secret", nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
+		Return(true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadSecondFactorVersion(gomock.Eq(testUsername)).
+		Return(int64(42), nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(int64(42), s.mock.Ctx.GetSession().SecondFactorVersion)
 }
 
 func TestRunHandlerSignTOTPSuite(t *testing.T) {
@@ -13,17 +13,13 @@ import (
 
 // SecondFactorU2FSignGet handler for initiating a signing request.
 func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
-	if ctx.XForwardedProto() == nil {
-		ctx.Error(errMissingXForwardedProto, mfaValidationFailedMessage)
-		return
-	}
-
-	if ctx.XForwardedHost() == nil {
-		ctx.Error(errMissingXForwardedHost, mfaValidationFailedMessage)
+	rootURL, err := ctx.ExternalRootURL()
+	if err != nil {
+		ctx.Error(err, mfaValidationFailedMessage)
 		return
 	}
 
-	appID := fmt.Sprintf("%s://%s", ctx.XForwardedProto(), ctx.XForwardedHost())
+	appID := fmt.Sprintf("%s://%s", rootURL.Scheme, rootURL.Host)
 
 	var trustedFacets = []string{appID}
 	challenge, err := u2f.NewChallenge(appID, trustedFacets)
@@ -40,6 +40,11 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 			return
 		}
 
+		if err = loadSecondFactorVersion(ctx, &userSession); err != nil {
+			handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+			return
+		}
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -48,6 +53,8 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 		}
 
 		userSession.AuthenticationLevel = authentication.TwoFactor
+		userSession.SecondFactorAuthnTimestamp = ctx.Clock.Now().Unix()
+		userSession.SecondFactorRequired = false
 		err = ctx.SaveSession(userSession)
 
 		if err != nil {
@@ -55,6 +62,8 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 			return
 		}
 
+		recordLastLogin(ctx, userSession.Username, authentication.U2F)
+
 		Handle2FAResponse(ctx, requestBody.TargetURL)
 	}
 }
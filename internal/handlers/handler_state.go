@@ -6,7 +6,7 @@ import (
 
 // StateGet is the handler serving the user state.
 func StateGet(ctx *middlewares.AutheliaCtx) {
-	userSession := ctx.GetSession()
+	userSession := establishTrustedHeaderSession(ctx, ctx.GetSession())
 	stateResponse := StateResponse{
 		Username:              userSession.Username,
 		AuthenticationLevel:   userSession.AuthenticationLevel,
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/federation"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// getTrustedHeaderIdentity returns the identity asserted by the trusted header, or nil if the trusted header is not
+// configured or not provided. The header is ignored when it is not sent by one of the trusted proxies.
+func getTrustedHeaderIdentity(ctx *middlewares.AutheliaCtx) (*federation.Identity, error) {
+	if ctx.Providers.TrustedHeader == nil {
+		return nil, nil
+	}
+
+	header := ctx.Providers.TrustedHeader.Header()
+
+	value := ctx.Request.Header.Peek(header)
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	if len(ctx.Configuration.Server.TrustedProxies) == 0 || !ctx.IsFromTrustedProxy() {
+		ctx.Logger.Warnf("Ignoring the %s header sent by %s which is not a trusted proxy", header, ctx.RequestCtx.RemoteIP())
+		return nil, nil
+	}
+
+	identity, err := ctx.Providers.TrustedHeader.Verify(string(value))
+	if err != nil {
+		// The lookup of the key set timing out must not hang or fail every request when the operator prefers to
+		// handle them like the requests without the trusted header.
+		if errors.Is(err, federation.ErrKeySetUnavailable) && ctx.Configuration.TrustedHeader.FailureMode == schema.TrustedHeaderFailureModeOpen {
+			ctx.Logger.Warnf("Ignoring the %s header which can't be verified: %s", header, err)
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("Unable to verify the %s header: %s", header, err)
+	}
+
+	identity.Groups = withDefaultGroups(ctx, identity.Groups, false)
+
+	return identity, nil
+}
+
+// establishTrustedHeaderSession authenticates the anonymous user with one factor using the identity asserted by the
+// trusted header, allowing them to complete the second factor in the portal.
+func establishTrustedHeaderSession(ctx *middlewares.AutheliaCtx, userSession session.UserSession) session.UserSession {
+	if userSession.Username != "" {
+		return userSession
+	}
+
+	identity, err := getTrustedHeaderIdentity(ctx)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return userSession
+	}
+
+	if identity == nil {
+		return userSession
+	}
+
+	if err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx); err != nil {
+		ctx.Logger.Errorf("Unable to regenerate session for user %s: %s", identity.Username, err)
+		return userSession
+	}
+
+	userSession = ctx.GetSession()
+	userSession.Username = identity.Username
+	userSession.DisplayName = identity.DisplayName
+	userSession.Groups = identity.Groups
+	userSession.Emails = identity.Emails
+	userSession.AuthenticationLevel = authentication.OneFactor
+	userSession.FirstFactorAuthnTimestamp = ctx.Clock.Now().Unix()
+	userSession.LastActivity = ctx.Clock.Now().Unix()
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		ctx.Logger.Errorf("Unable to save session of user %s: %s", identity.Username, err)
+		return session.NewDefaultUserSession()
+	}
+
+	ctx.Logger.Debugf("Identity of user %s asserted by the trusted header", identity.Username)
+
+	registerUserSession(ctx, userSession.Username)
+	recordLastLogin(ctx, userSession.Username, lastLoginMethodTrustedHeader)
+
+	return userSession
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/federation"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type TrustedHeaderSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+	key  *rsa.PrivateKey
+}
+
+func (s *TrustedHeaderSuite) SetupTest() {
+	var err error
+
+	s.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+
+	der, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	s.Require().NoError(err)
+
+	configuration := schema.DefaultTrustedHeaderConfiguration
+	configuration.Header = "X-Mesh-Identity"
+	configuration.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	configuration.Audience = "authelia"
+
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Configuration.TrustedHeader = &configuration
+	s.mock.Ctx.Configuration.Server.TrustedProxies = []string{"0.0.0.0/32"}
+	s.mock.Ctx.Providers.TrustedHeader = federation.NewTrustedHeaderProvider(configuration, nil)
+}
+
+func (s *TrustedHeaderSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *TrustedHeaderSuite) setToken(claims jwt.MapClaims) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(s.key)
+	s.Require().NoError(err)
+
+	s.mock.Ctx.Request.Header.Set("X-Mesh-Identity", "Bearer "+token)
+}
+
+func (s *TrustedHeaderSuite) setValidToken() {
+	s.setToken(jwt.MapClaims{
+		"aud":    "authelia",
+		"exp":    time.Now().Add(time.Minute).Unix(),
+		"sub":    testUsername,
+		"email":  "john@example.com",
+		"groups": []string{"dev"},
+	})
+}
+
+func (s *TrustedHeaderSuite) TestShouldAuthorizeOneFactorResourceWithTrustedHeader() {
+	s.setValidToken()
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://one-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(200, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal([]byte(testUsername), s.mock.Ctx.Response.Header.Peek("Remote-User"))
+	s.Assert().Equal([]byte("dev"), s.mock.Ctx.Response.Header.Peek("Remote-Groups"))
+	s.Assert().Equal("", s.mock.Ctx.GetSession().Username)
+}
+
+func (s *TrustedHeaderSuite) TestShouldRedirectToPortalForSecondFactorWithTrustedHeader() {
+	s.setValidToken()
+	s.mock.Ctx.QueryArgs().Add("rd", "https://login.example.com/")
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://two-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(302, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("https://login.example.com/?rd=https%3A%2F%2Ftwo-factor.example.com", string(s.mock.Ctx.Response.Header.Peek("Location")))
+}
+
+func (s *TrustedHeaderSuite) TestShouldRejectInvalidTrustedHeader() {
+	s.setToken(jwt.MapClaims{
+		"aud": "authelia",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+		"sub": testUsername,
+	})
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://one-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Error caught when verifying user authorization: Unable to verify the X-Mesh-Identity header: invalid trusted header: Token is expired",
+		s.mock.Hook.Entries[0].Message)
+}
+
+func (s *TrustedHeaderSuite) useUnavailableKeySet(failureMode string) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	configuration := *s.mock.Ctx.Configuration.TrustedHeader
+	configuration.PublicKey = ""
+	configuration.JWKSURL = server.URL
+	configuration.FailureMode = failureMode
+
+	s.mock.Ctx.Configuration.TrustedHeader = &configuration
+	s.mock.Ctx.Providers.TrustedHeader = federation.NewTrustedHeaderProvider(configuration, nil)
+}
+
+func (s *TrustedHeaderSuite) TestShouldRejectTrustedHeaderWhenKeySetUnavailableAndFailClosed() {
+	s.useUnavailableKeySet(schema.TrustedHeaderFailureModeClosed)
+	s.setValidToken()
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://bypass.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Contains(s.mock.Hook.Entries[0].Message, "Unable to verify the X-Mesh-Identity header: key set unavailable")
+}
+
+func (s *TrustedHeaderSuite) TestShouldIgnoreTrustedHeaderWhenKeySetUnavailableAndFailOpen() {
+	s.useUnavailableKeySet(schema.TrustedHeaderFailureModeOpen)
+	s.setValidToken()
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://bypass.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(200, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Contains(s.mock.Hook.Entries[0].Message, "Ignoring the X-Mesh-Identity header which can't be verified: key set unavailable")
+	s.Assert().Equal([]byte(nil), s.mock.Ctx.Response.Header.Peek("Remote-User"))
+}
+
+func (s *TrustedHeaderSuite) TestShouldIgnoreTrustedHeaderFromUntrustedPeer() {
+	s.mock.Ctx.Configuration.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	s.setValidToken()
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://one-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("Ignoring the X-Mesh-Identity header sent by 0.0.0.0 which is not a trusted proxy", s.mock.Hook.Entries[0].Message)
+}
+
+func (s *TrustedHeaderSuite) TestShouldIgnoreTrustedHeaderWithoutTrustedProxies() {
+	s.mock.Ctx.Configuration.Server.TrustedProxies = nil
+	s.setValidToken()
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://one-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *TrustedHeaderSuite) TestShouldPreferSessionOverTrustedHeader() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = "harry"
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.RefreshTTL = time.Now().Add(5 * time.Minute)
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.setValidToken()
+	s.mock.Ctx.Request.Header.Set("X-Original-URL", "https://two-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(s.mock.Ctx)
+
+	s.Assert().Equal(200, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal([]byte("harry"), s.mock.Ctx.Response.Header.Peek("Remote-User"))
+}
+
+func (s *TrustedHeaderSuite) TestShouldEstablishOneFactorSessionInPortal() {
+	s.setValidToken()
+
+	StateGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), StateResponse{
+		Username:            testUsername,
+		AuthenticationLevel: authentication.OneFactor,
+	})
+
+	userSession := s.mock.Ctx.GetSession()
+	s.Assert().Equal(testUsername, userSession.Username)
+	s.Assert().Equal([]string{"john@example.com"}, userSession.Emails)
+	s.Assert().Equal(authentication.OneFactor, userSession.AuthenticationLevel)
+}
+
+func (s *TrustedHeaderSuite) TestShouldRegenerateSessionForPreventingSessionFixation() {
+	s.Require().NoError(s.mock.Ctx.SaveSession(s.mock.Ctx.GetSession()))
+	s.setValidToken()
+
+	r := regexp.MustCompile("^authelia_session=(.*); path=")
+	res := r.FindAllStringSubmatch(string(s.mock.Ctx.Response.Header.PeekCookie("authelia_session")), -1)
+	s.Require().Len(res, 1)
+
+	StateGet(s.mock.Ctx)
+
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+	s.Assert().NotEqual(
+		res[0][1],
+		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
+}
+
+func (s *TrustedHeaderSuite) TestShouldNotEstablishSessionFromUntrustedPeer() {
+	s.mock.Ctx.Configuration.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	s.setValidToken()
+
+	StateGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), StateResponse{})
+	s.Assert().Equal("", s.mock.Ctx.GetSession().Username)
+}
+
+func TestRunTrustedHeaderSuite(t *testing.T) {
+	suite.Run(t, new(TrustedHeaderSuite))
+}
@@ -13,7 +13,7 @@ import (
 	"github.com/authelia/authelia/internal/utils"
 )
 
-func loadInfo(username string, storageProvider storage.Provider, userInfo *UserInfo, logger *logrus.Entry) []error {
+func loadInfo(username string, storageProvider storage.Provider, defaultMethod string, userInfo *UserInfo, logger *logrus.Entry) []error {
 	var wg sync.WaitGroup
 
 	wg.Add(3)
@@ -31,11 +31,7 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 			return
 		}
 
-		if method == "" {
-			userInfo.Method = authentication.PossibleMethods[0]
-		} else {
-			userInfo.Method = method
-		}
+		userInfo.Method = method
 	}()
 
 	go func() {
@@ -76,24 +72,81 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 
 	wg.Wait()
 
+	if userInfo.HasTOTP {
+		userInfo.EnrolledMethods = append(userInfo.EnrolledMethods, authentication.TOTP)
+	}
+
+	if userInfo.HasU2F {
+		userInfo.EnrolledMethods = append(userInfo.EnrolledMethods, authentication.U2F)
+	}
+
+	if userInfo.Method == "" {
+		userInfo.Method = selectDefaultMethod(userInfo, defaultMethod)
+	}
+
 	return errors
 }
 
-// UserInfoGet get the info related to the user identified by the session.
+// selectDefaultMethod returns the 2FA method to use for a user who has not chosen one yet. If exactly one method is
+// enrolled the user is sent straight to it, otherwise the configured default method is used when there is one.
+func selectDefaultMethod(userInfo *UserInfo, defaultMethod string) string {
+	switch {
+	case userInfo.HasTOTP && !userInfo.HasU2F:
+		return authentication.TOTP
+	case userInfo.HasU2F && !userInfo.HasTOTP:
+		return authentication.U2F
+	case defaultMethod != "":
+		return defaultMethod
+	default:
+		return authentication.PossibleMethods[0]
+	}
+}
+
+// UserInfoGet get the info related to the user identified by the session. The profile of the user is refreshed from the
+// authentication backend beforehand according to the refresh interval, the session of a user who no longer exists in
+// the backend being destroyed.
 func UserInfoGet(ctx *middlewares.AutheliaCtx) {
 	userSession := ctx.GetSession()
 
+	refreshProfile, refreshProfileInterval := getProfileRefreshSettings(ctx.Configuration.AuthenticationBackend)
+	if err := verifySessionHasUpToDateProfile(ctx, &userSession, refreshProfile, refreshProfileInterval); err != nil {
+		if err == authentication.ErrUserNotFound {
+			ctx.Logger.Infof("User %s was not found in the authentication backend, the session is destroyed", userSession.Username)
+
+			if err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx); err != nil {
+				ctx.Logger.Errorf("Unable to destroy user session after provider refresh didn't find the user: %s", err)
+			}
+
+			ctx.ReplyUnauthorized()
+
+			return
+		}
+
+		ctx.Logger.Warnf("Error occurred while attempting to update user details from LDAP: %s", err)
+	}
+
 	userInfo := UserInfo{}
-	errors := loadInfo(userSession.Username, ctx.Providers.StorageProvider, &userInfo, ctx.Logger)
+	errors := loadInfo(userSession.Username, ctx.Providers.StorageProvider, ctx.Configuration.Default2FAMethod, &userInfo, ctx.Logger)
 
 	if len(errors) > 0 {
 		ctx.Error(fmt.Errorf("Unable to load user information"), operationFailedMessage)
 		return
 	}
 
+	userInfo.Username = userSession.Username
 	userInfo.DisplayName = userSession.DisplayName
+	userInfo.Emails = userSession.Emails
+	userInfo.Groups = userSession.Groups
+	userInfo.AuthenticationLevel = userSession.AuthenticationLevel
+
+	lastLogin, err := loadLastLogin(ctx, userSession.Username)
+	if err != nil {
+		ctx.Logger.Errorf("Unable to load the last login of user %s: %s", userSession.Username, err)
+	}
+
+	userInfo.LastLogin = lastLogin
 
-	err := ctx.SetJSONBody(userInfo)
+	err = ctx.SetJSONBody(userInfo)
 	if err != nil {
 		ctx.Logger.Errorf("Unable to set user info response in body: %s", err)
 	}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
@@ -10,7 +11,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/storage"
 )
 
@@ -64,6 +68,11 @@ func setPreferencesExpectations(preferences UserInfo, provider *storage.MockProv
 			LoadTOTPSecret(gomock.Eq("john")).
 			Return("", storage.ErrNoTOTPSecret)
 	}
+
+	provider.
+		EXPECT().
+		LoadLastLogin(gomock.Eq("john")).
+		Return(nil, nil)
 }
 
 func TestMethodSetToU2F(t *testing.T) {
@@ -134,8 +143,155 @@ func (s *FetchSuite) TestShouldGetDefaultPreferenceIfNotInDB() {
 		LoadTOTPSecret(gomock.Eq("john")).
 		Return("", storage.ErrNoTOTPSecret)
 
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLastLogin(gomock.Eq("john")).
+		Return(nil, nil)
+
+	UserInfoGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), UserInfo{Username: testUsername, AuthenticationLevel: 1, Method: "totp"})
+}
+
+func (s *FetchSuite) TestShouldGetEnrolledMethodIfOnlyOneAndNotInDB() {
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadPreferred2FAMethod(gomock.Eq("john")).
+		Return("", nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadU2FDeviceHandle(gomock.Eq("john")).
+		Return([]byte("abc"), []byte("abc"), nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadTOTPSecret(gomock.Eq("john")).
+		Return("", storage.ErrNoTOTPSecret)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLastLogin(gomock.Eq("john")).
+		Return(nil, nil)
+
+	UserInfoGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), UserInfo{Username: testUsername, AuthenticationLevel: 1, Method: "u2f", HasU2F: true,
+		EnrolledMethods: []string{"u2f"}})
+}
+
+func (s *FetchSuite) TestShouldGetConfiguredDefaultMethodIfNotInDB() {
+	s.mock.Ctx.Configuration.Default2FAMethod = "mobile_push"
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadPreferred2FAMethod(gomock.Eq("john")).
+		Return("", nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadU2FDeviceHandle(gomock.Eq("john")).
+		Return([]byte("abc"), []byte("abc"), nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadTOTPSecret(gomock.Eq("john")).
+		Return("secret", nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLastLogin(gomock.Eq("john")).
+		Return(nil, nil)
+
+	UserInfoGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), UserInfo{Username: testUsername, AuthenticationLevel: 1, Method: "mobile_push", HasU2F: true,
+		HasTOTP: true, EnrolledMethods: []string{"totp", "u2f"}})
+}
+
+func (s *FetchSuite) TestShouldReturnIdentityAndRefreshedProfile() {
+	s.mock.Ctx.Configuration.AuthenticationBackend.Ldap = &schema.LDAPAuthenticationBackendConfiguration{}
+	s.mock.Ctx.Configuration.AuthenticationBackend.RefreshInterval = schema.ProfileRefreshAlways
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.DisplayName = "John Doe"
+	userSession.Emails = []string{"john@example.com"}
+	userSession.Groups = []string{"dev"}
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	require.NoError(s.T(), s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		GetDetails(gomock.Eq("john")).
+		Return(&authentication.UserDetails{
+			Username:    "john",
+			DisplayName: "John Doe",
+			Emails:      []string{"john.doe@example.com"},
+			Groups:      []string{"dev", "admins"},
+		}, nil)
+
+	setPreferencesExpectations(UserInfo{Method: "totp", HasTOTP: true}, s.mock.StorageProviderMock)
+
+	UserInfoGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), UserInfo{
+		Username:            testUsername,
+		DisplayName:         "John Doe",
+		Emails:              []string{"john.doe@example.com"},
+		Groups:              []string{"dev", "admins"},
+		AuthenticationLevel: authentication.TwoFactor,
+		Method:              "totp",
+		HasTOTP:             true,
+		EnrolledMethods:     []string{"totp"},
+	})
+}
+
+func (s *FetchSuite) TestShouldDestroySessionWhenUserNotFoundOnRefresh() {
+	s.mock.Ctx.Configuration.AuthenticationBackend.Ldap = &schema.LDAPAuthenticationBackendConfiguration{}
+	s.mock.Ctx.Configuration.AuthenticationBackend.RefreshInterval = schema.ProfileRefreshAlways
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		GetDetails(gomock.Eq("john")).
+		Return(nil, authentication.ErrUserNotFound)
+
+	UserInfoGet(s.mock.Ctx)
+
+	s.Assert().Equal(401, s.mock.Ctx.Response.StatusCode())
+
+	userSession := s.mock.Ctx.GetSession()
+	s.Assert().Equal("", userSession.Username)
+	s.Assert().Equal(authentication.NotAuthenticated, userSession.AuthenticationLevel)
+}
+
+func (s *FetchSuite) TestShouldReturnLastLoginNotSavedYet() {
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadPreferred2FAMethod(gomock.Eq("john")).
+		Return("totp", nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadU2FDeviceHandle(gomock.Eq("john")).
+		Return(nil, nil, storage.ErrNoU2FDeviceHandle)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadTOTPSecret(gomock.Eq("john")).
+		Return("", storage.ErrNoTOTPSecret)
+
+	s.mock.Ctx.Providers.LastLoginRecorder.Record(models.LastLogin{
+		Username: "john",
+		IP:       "192.168.1.10",
+		Method:   "password",
+		Time:     time.Unix(1600000000, 0),
+	})
+
 	UserInfoGet(s.mock.Ctx)
-	s.mock.Assert200OK(s.T(), UserInfo{Method: "totp"})
+
+	userInfo := UserInfo{}
+	s.mock.GetResponseData(s.T(), &userInfo)
+
+	s.Require().NotNil(userInfo.LastLogin)
+	s.Assert().Equal("192.168.1.10", userInfo.LastLogin.IP)
+	s.Assert().Equal("password", userInfo.LastLogin.Method)
+	s.Assert().True(time.Unix(1600000000, 0).Equal(userInfo.LastLogin.Time))
 }
 
 func (s *FetchSuite) TestShouldReturnError500WhenStorageFailsToLoad() {
@@ -3,9 +3,10 @@ package handlers
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"net"
 	"net/url"
+	"reflect"
 	"strings"
 	"time"
 
@@ -14,7 +15,9 @@ import (
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/authorization"
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/federation"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
 	"github.com/authelia/authelia/internal/session"
 	"github.com/authelia/authelia/internal/utils"
 )
@@ -33,18 +36,44 @@ func isSchemeWSS(url *url.URL) bool {
 
 // getOriginalURL extract the URL from the request headers (X-Original-URI or X-Forwarded-* headers).
 func getOriginalURL(ctx *middlewares.AutheliaCtx) (*url.URL, error) {
-	originalURL := ctx.XOriginalURL()
-	if originalURL != nil {
-		url, err := url.ParseRequestURI(string(originalURL))
-		if err != nil {
-			return nil, fmt.Errorf("Unable to parse URL extracted from X-Original-URL header: %v", err)
-		}
+	if ctx.XOriginalURL() != nil {
+		return getXOriginalURL(ctx)
+	}
+
+	return getXForwardedURL(ctx)
+}
+
+// getTargetURL extracts the URL from the request headers the profile of the verify endpoint reads it from.
+func getTargetURL(ctx *middlewares.AutheliaCtx, profile verifyProfile) (*url.URL, error) {
+	switch {
+	case !profile.forwardedHeaders:
+		return getXOriginalURL(ctx)
+	case !profile.originalURL:
+		return getXForwardedURL(ctx)
+	default:
+		return getOriginalURL(ctx)
+	}
+}
 
-		ctx.Logger.Trace("Using X-Original-URL header content as targeted site URL")
+// getXOriginalURL extract the URL from the X-Original-URL header.
+func getXOriginalURL(ctx *middlewares.AutheliaCtx) (*url.URL, error) {
+	originalURL := ctx.XOriginalURL()
+	if originalURL == nil {
+		return nil, errMissingXOriginalURL
+	}
 
-		return url, nil
+	url, err := url.ParseRequestURI(string(originalURL))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse URL extracted from X-Original-URL header: %v", err)
 	}
 
+	ctx.Logger.Trace("Using X-Original-URL header content as targeted site URL")
+
+	return url, nil
+}
+
+// getXForwardedURL extract the URL from the X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-URI headers.
+func getXForwardedURL(ctx *middlewares.AutheliaCtx) (*url.URL, error) {
 	forwardedProto := ctx.XForwardedProto()
 	forwardedHost := ctx.XForwardedHost()
 	forwardedURI := ctx.XForwardedURI()
@@ -96,81 +125,122 @@ func parseBasicAuth(header, auth string) (username, password string, err error)
 	return cs[:s], cs[s+1:], nil
 }
 
-// isTargetURLAuthorized check whether the given user is authorized to access the resource.
+// isTargetURLAuthorized check whether the given user is authorized to access the resource and returns the reason why
+// the access is denied if it is not.
 func isTargetURLAuthorized(authorizer *authorization.Authorizer, targetURL url.URL,
-	username string, userGroups []string, clientIP net.IP, method []byte, authLevel authentication.Level) authorizationMatching {
-	level := authorizer.GetRequiredLevel(
-		authorization.Subject{
-			Username: username,
-			Groups:   userGroups,
-			IP:       clientIP,
-		},
-		authorization.NewObjectRaw(&targetURL, method))
+	subject authorization.Subject, method []byte, authLevel authentication.Level) (authorizationMatching, authorization.DenyReason) {
+	level, reason := authorizer.GetRequiredLevelWithReason(subject, authorization.NewObjectRaw(&targetURL, method))
 
 	switch {
 	case level == authorization.Bypass:
-		return Authorized
-	case level == authorization.Denied && username != "":
+		return Authorized, authorization.DenyReasonNone
+	case level == authorization.Denied && subject.Username != "":
 		// If the user is not anonymous, it means that we went through
 		// all the rules related to that user and knowing who he is we can
 		// deduce the access is forbidden
 		// For anonymous users though, we cannot be sure that she
 		// could not be granted the rights to access the resource. Consequently
 		// for anonymous users we send Unauthorized instead of Forbidden
-		return Forbidden
+		return Forbidden, reason
 	case level == authorization.OneFactor && authLevel >= authentication.OneFactor,
 		level == authorization.TwoFactor && authLevel >= authentication.TwoFactor:
-		return Authorized
+		return Authorized, authorization.DenyReasonNone
+	case subject.Username == "":
+		return NotAuthorized, authorization.DenyReasonNotAuthenticated
 	}
 
-	return NotAuthorized
+	return NotAuthorized, authorization.DenyReasonInsufficientLevel
 }
 
 // verifyBasicAuth verify that the provided username and password are correct and
 // that the user is authorized to target the resource.
-func verifyBasicAuth(header string, auth []byte, targetURL url.URL, ctx *middlewares.AutheliaCtx) (username, name string, groups, emails []string, authLevel authentication.Level, err error) { //nolint:unparam
+func verifyBasicAuth(header string, auth []byte, targetURL url.URL, ctx *middlewares.AutheliaCtx) (username, name string, groups, emails []string, attributes map[string][]string, authLevel authentication.Level, err error) { //nolint:unparam
 	username, password, err := parseBasicAuth(header, string(auth))
 
 	if err != nil {
-		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to parse content of %s header: %s", header, err)
+		return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to parse content of %s header: %s", header, err)
+	}
+
+	// The attempt is regulated as on the portal, the credentials of a banned user are not checked.
+	reservation, bannedUntil, err := ctx.Providers.Regulator.Reserve(username)
+	if err != nil {
+		if err == regulation.ErrUserIsBanned {
+			return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("User %s is banned until %s: %w", username, bannedUntil, err)
+		}
+
+		if !isStorageFailureTolerated(ctx, fmt.Sprintf("regulation of user %s", username), err) {
+			return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to regulate user %s: %s", username, err)
+		}
 	}
 
 	authenticated, err := ctx.Providers.UserProvider.CheckUserPassword(username, password)
 
+	confirmBasicAuthAttempt(ctx, username, reservation, err == nil && authenticated)
+
 	if err != nil {
-		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to check credentials extracted from %s header: %s", header, err)
+		return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to check credentials extracted from %s header: %s", header, err)
 	}
 
 	// If the user is not correctly authenticated, send a 401.
 	if !authenticated {
 		// Request Basic Authentication otherwise
-		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("User %s is not authenticated", username)
+		return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("User %s is not authenticated", username)
 	}
 
 	details, err := ctx.Providers.UserProvider.GetDetails(username)
 
 	if err != nil {
-		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to retrieve details of user %s: %s", username, err)
+		return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to retrieve details of user %s: %s", username, err)
+	}
+
+	return username, details.DisplayName, withDefaultGroups(ctx, details.Groups, true), details.Emails, details.Attributes, authentication.OneFactor, nil
+}
+
+// confirmBasicAuthAttempt records the outcome of a basic auth attempt reserved by the regulator. Nothing is recorded
+// when the regulation is disabled since every request of a basic auth client is an attempt.
+func confirmBasicAuthAttempt(ctx *middlewares.AutheliaCtx, username, reservation string, successful bool) {
+	if reservation == "" {
+		return
 	}
 
-	return username, details.DisplayName, details.Groups, details.Emails, authentication.OneFactor, nil
+	if err := ctx.Providers.Regulator.Confirm(username, reservation, successful); err != nil {
+		ctx.Logger.Errorf("Unable to mark the basic auth attempt of user %s: %s", username, err)
+	}
 }
 
 // setForwardedHeaders set the forwarded User, Groups, Name and Email headers.
-func setForwardedHeaders(headers *fasthttp.ResponseHeader, username, name string, groups, emails []string) {
+func setForwardedHeaders(headers *fasthttp.ResponseHeader, names schema.ServerVerifyEndpointHeadersConfiguration, username, name string, groups, emails []string) {
 	if username != "" {
-		headers.Set(remoteUserHeader, username)
-		headers.Set(remoteGroupsHeader, strings.Join(groups, ","))
-		headers.Set(remoteNameHeader, name)
+		headers.Set(names.User, username)
+		headers.Set(names.Groups, strings.Join(groups, ","))
+		headers.Set(names.Name, name)
 
 		if emails != nil {
-			headers.Set(remoteEmailHeader, emails[0])
+			headers.Set(names.Email, emails[0])
 		} else {
-			headers.Set(remoteEmailHeader, "")
+			headers.Set(names.Email, "")
 		}
 	}
 }
 
+// handleAuthorizedAnonymous logs the access of an anonymous user to a bypassed resource with the configured anonymous
+// identity and forwards this identity to the backend if enabled. No session is established for this identity.
+func handleAuthorizedAnonymous(ctx *middlewares.AutheliaCtx, profile verifyProfile, targetURL *url.URL, method []byte) {
+	identity := ctx.Providers.Authorizer.GetAnonymousIdentity(
+		authorization.Subject{IP: ctx.RemoteIP()},
+		authorization.NewObjectRaw(targetURL, method))
+
+	if identity == "" {
+		return
+	}
+
+	ctx.Logger.Infof("Access to %s granted to anonymous user %s", targetURL.String(), identity)
+
+	if ctx.Configuration.AccessControl.AnonymousIdentityHeaders {
+		setForwardedHeaders(&ctx.Response.Header, profile.headers, identity, "", nil, nil)
+	}
+}
+
 // hasUserBeenInactiveTooLong checks whether the user has been inactive for too long.
 func hasUserBeenInactiveTooLong(ctx *middlewares.AutheliaCtx) (bool, error) { //nolint:unparam
 	maxInactivityPeriod := int64(ctx.Providers.SessionProvider.Inactivity.Seconds())
@@ -191,34 +261,72 @@ func hasUserBeenInactiveTooLong(ctx *middlewares.AutheliaCtx) (bool, error) { //
 	return false, nil
 }
 
+// isUserWithinInactivityGracePeriod checks whether an inactive user is still within the grace period following the
+// inactivity timeout during which the session is downgraded to one factor instead of being destroyed. The grace period
+// only applies when the downgrade policy is explicitly configured.
+func isUserWithinInactivityGracePeriod(ctx *middlewares.AutheliaCtx) bool {
+	if ctx.Configuration.Session.InactivityGracePolicy != schema.SessionInactivityGracePolicyDowngrade {
+		return false
+	}
+
+	gracePeriod := int64(ctx.Providers.SessionProvider.InactivityGracePeriod.Seconds())
+	if gracePeriod == 0 {
+		return false
+	}
+
+	maxInactivityPeriod := int64(ctx.Providers.SessionProvider.Inactivity.Seconds())
+	inactivityPeriod := ctx.Clock.Now().Unix() - ctx.GetSession().LastActivity
+
+	return inactivityPeriod <= maxInactivityPeriod+gracePeriod
+}
+
 // verifySessionCookie verifies if a user is identified by a cookie.
 func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userSession *session.UserSession, refreshProfile bool,
-	refreshProfileInterval time.Duration) (username, name string, groups, emails []string, authLevel authentication.Level, err error) {
+	refreshProfileInterval time.Duration) (username, name string, groups, emails []string, attributes map[string][]string, authLevel authentication.Level, err error) {
 	// No username in the session means the user is anonymous.
 	isUserAnonymous := userSession.Username == ""
 
 	if isUserAnonymous && userSession.AuthenticationLevel != authentication.NotAuthenticated {
-		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("An anonymous user cannot be authenticated. That might be the sign of a compromise")
+		return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("An anonymous user cannot be authenticated. That might be the sign of a compromise")
 	}
 
 	if !userSession.KeepMeLoggedIn && !isUserAnonymous {
 		inactiveLongEnough, err := hasUserBeenInactiveTooLong(ctx)
 		if err != nil {
-			return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to check if user has been inactive for a long time: %s", err)
+			return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to check if user has been inactive for a long time: %s", err)
+		}
+
+		if inactiveLongEnough && userSession.AuthenticationLevel >= authentication.OneFactor &&
+			isUserWithinInactivityGracePeriod(ctx) {
+			ctx.Logger.Debugf("User %s has been inactive for too long but is within the grace period, "+
+				"the session is downgraded to one factor", userSession.Username)
+
+			userSession.AuthenticationLevel = authentication.OneFactor
+			userSession.LastActivity = ctx.Clock.Now().Unix()
+
+			if err = ctx.SaveSession(*userSession); err != nil {
+				return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to downgrade user session after long inactivity: %s", err)
+			}
+
+			inactiveLongEnough = false
 		}
 
 		if inactiveLongEnough {
 			// Destroy the session a new one will be regenerated on next request.
 			err := ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
 			if err != nil {
-				return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to destroy user session after long inactivity: %s", err)
+				return "", "", nil, nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to destroy user session after long inactivity: %s", err)
 			}
 
-			return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, authentication.NotAuthenticated, fmt.Errorf("User %s has been inactive for too long", userSession.Username)
+			return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.Attributes, authentication.NotAuthenticated, fmt.Errorf("User %s has been inactive for too long", userSession.Username)
 		}
 	}
 
-	err = verifySessionHasUpToDateProfile(ctx, targetURL, userSession, refreshProfile, refreshProfileInterval)
+	if err = verifySecondFactorVersion(ctx, userSession); err != nil {
+		return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.Attributes, authentication.NotAuthenticated, err
+	}
+
+	err = verifySessionHasUpToDateProfile(ctx, userSession, refreshProfile, refreshProfileInterval)
 	if err != nil {
 		if err == authentication.ErrUserNotFound {
 			err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
@@ -226,19 +334,44 @@ func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userS
 				ctx.Logger.Error(fmt.Errorf("Unable to destroy user session after provider refresh didn't find the user: %s", err))
 			}
 
-			return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, authentication.NotAuthenticated, err
+			return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.Attributes, authentication.NotAuthenticated, err
 		}
 
 		ctx.Logger.Warnf("Error occurred while attempting to update user details from LDAP: %s", err)
 	}
 
-	return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.AuthenticationLevel, nil
+	if userSession.SecondFactorRequired && userSession.AuthenticationLevel < authentication.TwoFactor {
+		ctx.Logger.Debugf("User %s logged in from an unrecognised device and must complete the second factor", userSession.Username)
+
+		return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.Attributes, authentication.NotAuthenticated, nil
+	}
+
+	return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.Attributes, userSession.AuthenticationLevel, nil
 }
 
-func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, isBasicAuth bool, username string, method []byte) {
+func handleUnauthorized(ctx *middlewares.AutheliaCtx, profile verifyProfile, targetURL *url.URL, isBasicAuth bool, subject authorization.Subject, method []byte) {
 	friendlyUsername := "<anonymous>"
-	if username != "" {
-		friendlyUsername = username
+	if subject.Username != "" {
+		friendlyUsername = subject.Username
+	}
+
+	// Kubernetes ingress controller and Traefik use the rd parameter of the verify
+	// endpoint to provide the URL of the login portal. The target URL of the user
+	// is computed from X-Forwarded-* headers or X-Original-URL.
+	// Other proxies like the NGINX auth_request module do not forward the redirections, they redirect the user by
+	// themselves on a 401 response. The rule matching the request may redirect to its own page instead of the portal.
+	rd := ""
+	if profile.redirect {
+		if rd = getRedirectURL(ctx, targetURL, subject, method); rd == "" {
+			rd = string(ctx.QueryArgs().Peek("rd"))
+		}
+	}
+
+	// Anonymous clients which can't follow the redirection to the portal are challenged for their credentials if the
+	// resource accepts them.
+	if !isBasicAuth && subject.Username == "" && (rd == "" || isAPIRequest(ctx)) &&
+		isBasicAuthAllowed(ctx, targetURL, method) {
+		isBasicAuth = true
 	}
 
 	if isBasicAuth {
@@ -249,10 +382,6 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 		return
 	}
 
-	// Kubernetes ingress controller and Traefik use the rd parameter of the verify
-	// endpoint to provide the URL of the login portal. The target URL of the user
-	// is computed from X-Forwarded-* headers or X-Original-URL.
-	rd := string(ctx.QueryArgs().Peek("rd"))
 	rm := string(method)
 
 	friendlyMethod := "unknown"
@@ -261,13 +390,34 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 		friendlyMethod = rm
 	}
 
-	if rd != "" {
+	switch getDeniedResponse(ctx, targetURL, subject, method) {
+	case schema.DeniedResponseForbidden:
+		ctx.Logger.Infof("Access to %s (method %s) is not authorized to user %s, sending 403 response", targetURL.String(), friendlyMethod, friendlyUsername)
+		ctx.ReplyForbidden()
+
+		return
+	case schema.DeniedResponseUnauthorized:
+		ctx.Logger.Infof("Access to %s (method %s) is not authorized to user %s, sending 401 response", targetURL.String(), friendlyMethod, friendlyUsername)
+		ctx.ReplyUnauthorized()
+
+		return
+	}
+
+	if rd != "" && isAPIRequest(ctx) {
+		ctx.Logger.Infof("Access to %s (method %s) is not authorized to user %s, sending 401 response to API request", targetURL.String(), friendlyMethod, friendlyUsername)
+		ctx.ReplyUnauthorized()
+	} else if rd != "" {
 		redirectionURL := ""
 
+		separator := "?"
+		if strings.Contains(rd, "?") {
+			separator = "&"
+		}
+
 		if rm != "" {
-			redirectionURL = fmt.Sprintf("%s?rd=%s&rm=%s", rd, url.QueryEscape(targetURL.String()), rm)
+			redirectionURL = fmt.Sprintf("%s%srd=%s&rm=%s", rd, separator, url.QueryEscape(targetURL.String()), rm)
 		} else {
-			redirectionURL = fmt.Sprintf("%s?rd=%s", rd, url.QueryEscape(targetURL.String()))
+			redirectionURL = fmt.Sprintf("%s%srd=%s", rd, separator, url.QueryEscape(targetURL.String()))
 		}
 
 		ctx.Logger.Infof("Access to %s (method %s) is not authorized to user %s, redirecting to %s", targetURL.String(), friendlyMethod, friendlyUsername, redirectionURL)
@@ -279,6 +429,51 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 	}
 }
 
+// logDenyReason adds the reason why the access is denied to the logs of the request.
+func logDenyReason(ctx *middlewares.AutheliaCtx, reason authorization.DenyReason) {
+	ctx.Logger = ctx.Logger.WithField("deny_reason", string(reason))
+}
+
+// setDenyReasonHeader sets the reason why the access is denied in the configured header of the response so that the
+// proxy can log it as well. It must be called once the response is written since replying with an error resets it.
+func setDenyReasonHeader(ctx *middlewares.AutheliaCtx, reason authorization.DenyReason) {
+	if header := ctx.Configuration.AccessControl.DenyReasonHeader; header != "" {
+		ctx.Response.Header.Set(header, string(reason))
+	}
+}
+
+// isAPIRequest returns true if the request has been made by a script rather than a browser navigation according to
+// the X-Requested-With and Accept headers, such requests expecting a 401 instead of a redirection to the portal.
+func isAPIRequest(ctx *middlewares.AutheliaCtx) bool {
+	config := ctx.Configuration.Server.APIRequests
+
+	if config.RequestedWith && strings.EqualFold(string(ctx.Request.Header.Peek(headerXRequestedWith)), "XMLHttpRequest") {
+		return true
+	}
+
+	if len(config.AcceptTypes) == 0 {
+		return false
+	}
+
+	isAPI := false
+
+	for _, mediaType := range strings.Split(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept)), ",") {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0]))
+
+		if mediaType == "text/html" {
+			return false
+		}
+
+		for _, acceptType := range config.AcceptTypes {
+			if strings.EqualFold(mediaType, acceptType) {
+				isAPI = true
+			}
+		}
+	}
+
+	return isAPI
+}
+
 func updateActivityTimestamp(ctx *middlewares.AutheliaCtx, isBasicAuth bool, username string) error {
 	if isBasicAuth || username == "" {
 		return nil
@@ -344,13 +539,13 @@ func generateVerifySessionHasUpToDateProfileTraceLogs(ctx *middlewares.AutheliaC
 	}
 }
 
-func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userSession *session.UserSession,
+func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, userSession *session.UserSession,
 	refreshProfile bool, refreshProfileInterval time.Duration) error {
 	// TODO: Add a check for LDAP password changes based on a time format attribute.
 	// See https://docs.authelia.com/security/threat-model.html#potential-future-guarantees
 	ctx.Logger.Tracef("Checking if we need check the authentication backend for an updated profile for %s.", userSession.Username)
 
-	if !refreshProfile || userSession.Username == "" || targetURL == nil {
+	if !refreshProfile || userSession.Username == "" || userSession.UpstreamIssuer != "" {
 		return nil
 	}
 
@@ -365,11 +560,14 @@ func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *ur
 		return err
 	}
 
+	details.Groups = withDefaultGroups(ctx, details.Groups, true)
+
 	emailsDiff := utils.IsStringSlicesDifferent(userSession.Emails, details.Emails)
 	groupsDiff := utils.IsStringSlicesDifferent(userSession.Groups, details.Groups)
 	nameDiff := userSession.DisplayName != details.DisplayName
+	attributesDiff := !reflect.DeepEqual(userSession.Attributes, details.Attributes)
 
-	if !groupsDiff && !emailsDiff && !nameDiff {
+	if !groupsDiff && !emailsDiff && !nameDiff && !attributesDiff {
 		ctx.Logger.Tracef("Updated profile not detected for %s.", userSession.Username)
 		// Only update TTL if the user has a interval set.
 		// We get to this check when there were no changes.
@@ -388,6 +586,7 @@ func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *ur
 		userSession.Emails = details.Emails
 		userSession.Groups = details.Groups
 		userSession.DisplayName = details.DisplayName
+		userSession.Attributes = details.Attributes
 
 		// Only update TTL if the user has a interval set.
 		if refreshProfileInterval != schema.RefreshIntervalAlways {
@@ -421,7 +620,7 @@ func getProfileRefreshSettings(cfg schema.AuthenticationBackendConfiguration) (r
 	return refresh, refreshInterval
 }
 
-func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile bool, refreshProfileInterval time.Duration) (isBasicAuth bool, username, name string, groups, emails []string, authLevel authentication.Level, err error) {
+func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile bool, refreshProfileInterval time.Duration) (isBasicAuth, isTrustedHeader bool, username, name string, groups, emails []string, attributes map[string][]string, authLevel authentication.Level, err error) {
 	authHeader := ProxyAuthorizationHeader
 	if bytes.Equal(ctx.QueryArgs().Peek("auth"), []byte("basic")) {
 		authHeader = AuthorizationHeader
@@ -436,13 +635,20 @@ func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile
 		return
 	}
 
+	if !isBasicAuth && isBasicAuthAllowed(ctx, targetURL, ctx.XForwardedMethod()) {
+		if value := ctx.Request.Header.Peek(AuthorizationHeader); bytes.HasPrefix(value, []byte(authPrefix)) {
+			authHeader, authValue = AuthorizationHeader, value
+			isBasicAuth = true
+		}
+	}
+
 	if isBasicAuth {
-		username, name, groups, emails, authLevel, err = verifyBasicAuth(authHeader, authValue, *targetURL, ctx)
+		username, name, groups, emails, attributes, authLevel, err = verifyBasicAuth(authHeader, authValue, *targetURL, ctx)
 		return
 	}
 
 	userSession := ctx.GetSession()
-	username, name, groups, emails, authLevel, err = verifySessionCookie(ctx, targetURL, &userSession, refreshProfile, refreshProfileInterval)
+	username, name, groups, emails, attributes, authLevel, err = verifySessionCookie(ctx, targetURL, &userSession, refreshProfile, refreshProfileInterval)
 
 	sessionUsername := ctx.Request.Header.Peek(SessionUsernameHeader)
 	if sessionUsername != nil && !strings.EqualFold(string(sessionUsername), username) {
@@ -457,18 +663,111 @@ func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile
 		}
 
 		err = fmt.Errorf("Could not match user %s to their %s header with a value of %s when visiting %s", username, SessionUsernameHeader, sessionUsername, targetURL.String())
+
+		return
+	}
+
+	// Anonymous users are authenticated with one factor for this request only by the identity asserted by the
+	// trusted header, if any.
+	if err == nil && username == "" {
+		var identity *federation.Identity
+
+		if identity, err = getTrustedHeaderIdentity(ctx); identity != nil {
+			return false, true, identity.Username, identity.DisplayName, identity.Groups, identity.Emails, nil, authentication.OneFactor, nil
+		}
 	}
 
 	return
 }
 
+// isAuthenticationStale returns true if the authentication of the session is older than the maximum age required by the
+// rule matching the request. The session is then downgraded so that the user authenticates again: the second factor is
+// asked again for the two_factor rules, and the session is destroyed for the one_factor rules.
+func isAuthenticationStale(ctx *middlewares.AutheliaCtx, targetURL *url.URL, subject authorization.Subject, method []byte) (bool, error) {
+	maxAge, level := ctx.Providers.Authorizer.GetFreshAuthentication(subject, authorization.NewObjectRaw(targetURL, method))
+	if maxAge == 0 {
+		return false, nil
+	}
+
+	username := subject.Username
+
+	userSession := ctx.GetSession()
+
+	authenticatedAt := userSession.FirstFactorAuthnTimestamp
+	if level == authorization.TwoFactor {
+		authenticatedAt = userSession.SecondFactorAuthnTimestamp
+	}
+
+	if ctx.Clock.Now().Sub(time.Unix(authenticatedAt, 0)) <= maxAge {
+		return false, nil
+	}
+
+	if level == authorization.TwoFactor {
+		ctx.Logger.Infof("The second factor of user %s is older than %s required to access %s, the session is downgraded to one factor",
+			username, maxAge, targetURL.String())
+
+		userSession.AuthenticationLevel = authentication.OneFactor
+
+		return true, ctx.SaveSession(userSession)
+	}
+
+	ctx.Logger.Infof("The authentication of user %s is older than %s required to access %s, the session is destroyed",
+		username, maxAge, targetURL.String())
+
+	return true, ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
+}
+
+// isBasicAuthAllowed returns true if the resource accepts HTTP Basic credentials in the Authorization header.
+func isBasicAuthAllowed(ctx *middlewares.AutheliaCtx, targetURL *url.URL, method []byte) bool {
+	return ctx.Providers.Authorizer.IsBasicAuthAllowed(
+		authorization.Subject{IP: ctx.RemoteIP()},
+		authorization.NewObjectRaw(targetURL, method))
+}
+
+// getDeniedResponse returns the response to send to the user denied access to the target URL according to the access
+// control rules.
+func getDeniedResponse(ctx *middlewares.AutheliaCtx, targetURL *url.URL, subject authorization.Subject, method []byte) string {
+	return ctx.Providers.Authorizer.GetDeniedResponse(subject, authorization.NewObjectRaw(targetURL, method))
+}
+
+// getRedirectURL returns the URL the rule matching the request redirects the denied users to instead of the portal, if
+// any.
+func getRedirectURL(ctx *middlewares.AutheliaCtx, targetURL *url.URL, subject authorization.Subject, method []byte) string {
+	return ctx.Providers.Authorizer.GetRedirectURL(subject, authorization.NewObjectRaw(targetURL, method))
+}
+
+// newVerifyProfile returns the profile of the given verify endpoint.
+func newVerifyProfile(endpoint schema.ServerVerifyEndpointConfiguration) verifyProfile {
+	profile := verifyProfile{headers: endpoint.Headers}
+
+	switch endpoint.Profile {
+	case schema.VerifyProfileTraefik:
+		profile.forwardedHeaders, profile.redirect = true, true
+	case schema.VerifyProfileNGINX:
+		profile.originalURL = true
+	case schema.VerifyProfileHAProxy:
+		profile.forwardedHeaders = true
+	default:
+		profile.originalURL, profile.forwardedHeaders, profile.redirect = true, true, true
+	}
+
+	return profile
+}
+
 // VerifyGet returns the handler verifying if a request is allowed to go through.
 func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.RequestHandler {
+	return VerifyEndpointGet(cfg, schema.DefaultServerVerifyEndpointConfiguration)
+}
+
+// VerifyEndpointGet returns the handler verifying if a request is allowed to go through for the given verify endpoint,
+// the request being read and replied to as expected by the proxy of the endpoint profile.
+func VerifyEndpointGet(cfg schema.AuthenticationBackendConfiguration, endpoint schema.ServerVerifyEndpointConfiguration) middlewares.RequestHandler {
 	refreshProfile, refreshProfileInterval := getProfileRefreshSettings(cfg)
+	profile := newVerifyProfile(endpoint)
 
 	return func(ctx *middlewares.AutheliaCtx) {
 		ctx.Logger.Tracef("Headers=%s", ctx.Request.Header.String())
-		targetURL, err := getOriginalURL(ctx)
+		targetURL, err := getTargetURL(ctx, profile)
 
 		if err != nil {
 			ctx.Error(fmt.Errorf("Unable to parse target URL: %s", err), operationFailedMessage)
@@ -491,11 +790,19 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 			return
 		}
 
-		isBasicAuth, username, name, groups, emails, authLevel, err := verifyAuth(ctx, targetURL, refreshProfile, refreshProfileInterval)
+		isBasicAuth, isTrustedHeader, username, name, groups, emails, attributes, authLevel, err := verifyAuth(ctx, targetURL, refreshProfile, refreshProfileInterval)
 
 		method := ctx.XForwardedMethod()
+		subject := authorization.Subject{Username: username, Groups: groups, IP: ctx.RemoteIP(), Attributes: attributes}
 
 		if err != nil {
+			reason := authorization.DenyReasonAuthenticationFailed
+			if errors.Is(err, regulation.ErrUserIsBanned) {
+				reason = authorization.DenyReasonBanned
+			}
+
+			logDenyReason(ctx, reason)
+
 			ctx.Logger.Error(fmt.Sprintf("Error caught when verifying user authorization: %s", err))
 
 			if err := updateActivityTimestamp(ctx, isBasicAuth, username); err != nil {
@@ -503,25 +810,47 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 				return
 			}
 
-			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method)
+			handleUnauthorized(ctx, profile, targetURL, isBasicAuth, subject, method)
+			setDenyReasonHeader(ctx, reason)
 
 			return
 		}
 
-		authorized := isTargetURLAuthorized(ctx.Providers.Authorizer, *targetURL, username,
-			groups, ctx.RemoteIP(), method, authLevel)
+		authorized, reason := isTargetURLAuthorized(ctx.Providers.Authorizer, *targetURL, subject, method, authLevel)
+
+		// The credentials of basic auth and the identity asserted by the trusted header are verified on each request.
+		if authorized == Authorized && username != "" && !isBasicAuth && !isTrustedHeader {
+			stale, err := isAuthenticationStale(ctx, targetURL, subject, method)
+			if err != nil {
+				ctx.Error(fmt.Errorf("Unable to require a fresh authentication: %s", err), operationFailedMessage)
+				return
+			}
+
+			if stale {
+				authorized, reason = NotAuthorized, authorization.DenyReasonStaleAuthentication
+			}
+		}
 
 		switch authorized {
 		case Forbidden:
+			logDenyReason(ctx, reason)
 			ctx.Logger.Infof("Access to %s is forbidden to user %s", targetURL.String(), username)
 			ctx.ReplyForbidden()
+			setDenyReasonHeader(ctx, reason)
 		case NotAuthorized:
-			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method)
+			logDenyReason(ctx, reason)
+			handleUnauthorized(ctx, profile, targetURL, isBasicAuth, subject, method)
+			setDenyReasonHeader(ctx, reason)
 		case Authorized:
-			setForwardedHeaders(&ctx.Response.Header, username, name, groups, emails)
+			if username == "" {
+				handleAuthorizedAnonymous(ctx, profile, targetURL, method)
+			} else {
+				setForwardedHeaders(&ctx.Response.Header, profile.headers, username, name, groups, emails)
+			}
 		}
 
-		if err := updateActivityTimestamp(ctx, isBasicAuth, username); err != nil {
+		// The identity asserted by the trusted header is not bound to the session.
+		if err := updateActivityTimestamp(ctx, isBasicAuth || isTrustedHeader, username); err != nil {
 			ctx.Error(fmt.Errorf("Unable to update last activity: %s", err), operationFailedMessage)
 		}
 	}
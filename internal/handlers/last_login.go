@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+)
+
+// recordLastLogin records the successful authentication of the user with the given method. The login is written to the
+// storage in the background so that it doesn't slow the authentication down.
+func recordLastLogin(ctx *middlewares.AutheliaCtx, username, method string) {
+	ctx.Providers.LastLoginRecorder.Record(models.LastLogin{
+		Username: username,
+		IP:       ctx.RemoteIP().String(),
+		Method:   method,
+		Time:     ctx.Clock.Now(),
+	})
+}
+
+// loadLastLogin loads the last successful authentication of the user, nil if they never authenticated.
+func loadLastLogin(ctx *middlewares.AutheliaCtx, username string) (*LastLoginInfo, error) {
+	login, err := ctx.Providers.LastLoginRecorder.Load(username)
+	if err != nil || login == nil {
+		return nil, err
+	}
+
+	return &LastLoginInfo{
+		Username: login.Username,
+		Time:     login.Time.UTC(),
+		IP:       login.IP,
+		Method:   login.Method,
+	}, nil
+}
+
+// AdminLastLoginGet returns the last successful authentication of the user given in the username query parameter to
+// help investigating the use of an account, null if the user never logged in. Only the users authenticated with two
+// factors and member of one of the last login admin groups are allowed to do so.
+func AdminLastLoginGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if !isTwoFactorAdmin(userSession, ctx.Configuration.LastLogin.AdminGroups) {
+		ctx.Logger.Warnf("User %s is not allowed to retrieve the last login of other users", userSession.Username)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	username := string(ctx.QueryArgs().Peek("username"))
+	if username == "" {
+		ctx.Error(fmt.Errorf("No username provided to retrieve the last login"), operationFailedMessage)
+		return
+	}
+
+	lastLogin, err := loadLastLogin(ctx, username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load the last login of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	if err = ctx.SetJSONBody(lastLogin); err != nil {
+		ctx.Logger.Errorf("Unable to set the response body: %s", err)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+)
+
+type LastLoginSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *LastLoginSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Clock.Set(time.Unix(1600000000, 0))
+	s.mock.Ctx.Configuration.LastLogin.AdminGroups = []string{"support"}
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = "harry"
+	userSession.Groups = []string{"dev", "support"}
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.Ctx.Request.SetRequestURI("/api/admin/users/last-login?username=john")
+}
+
+func (s *LastLoginSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *LastLoginSuite) TestShouldRecordLastLoginWithoutWritingIt() {
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-For", "192.168.1.10")
+
+	recordLastLogin(s.mock.Ctx, testUsername, authentication.TOTP)
+
+	login, err := s.mock.Ctx.Providers.LastLoginRecorder.Load(testUsername)
+	s.Require().NoError(err)
+	s.Assert().Equal(&models.LastLogin{
+		Username: testUsername,
+		IP:       "192.168.1.10",
+		Method:   authentication.TOTP,
+		Time:     time.Unix(1600000000, 0),
+	}, login)
+}
+
+func (s *LastLoginSuite) TestShouldReturnLastLoginOfUser() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLastLogin(gomock.Eq(testUsername)).
+		Return(&models.LastLogin{Username: testUsername, IP: "192.168.1.10", Method: lastLoginMethodPassword, Time: time.Unix(1500000000, 0)}, nil)
+
+	AdminLastLoginGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), LastLoginInfo{
+		Username: testUsername,
+		IP:       "192.168.1.10",
+		Method:   lastLoginMethodPassword,
+		Time:     time.Unix(1500000000, 0).UTC(),
+	})
+}
+
+func (s *LastLoginSuite) TestShouldReturnNullWhenUserNeverLoggedIn() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLastLogin(gomock.Eq(testUsername)).
+		Return(nil, nil)
+
+	AdminLastLoginGet(s.mock.Ctx)
+
+	s.Assert().Equal(200, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("{\"status\":\"OK\",\"data\":null}", string(s.mock.Ctx.Response.Body()))
+}
+
+func (s *LastLoginSuite) TestShouldForbidUsersNotMemberOfAdminGroups() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Groups = []string{"dev"}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	AdminLastLoginGet(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("User harry is not allowed to retrieve the last login of other users", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *LastLoginSuite) TestShouldForbidRegulationAdmins() {
+	s.mock.Ctx.Configuration.Regulation = &schema.RegulationConfiguration{AdminGroups: []string{"dev"}}
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Groups = []string{"dev"}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	AdminLastLoginGet(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *LastLoginSuite) TestShouldFailWhenUsernameIsMissing() {
+	s.mock.Ctx.Request.SetRequestURI("/api/admin/users/last-login")
+
+	AdminLastLoginGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("No username provided to retrieve the last login", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *LastLoginSuite) TestShouldFailWhenStorageFails() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLastLogin(gomock.Eq(testUsername)).
+		Return(nil, fmt.Errorf("database is down"))
+
+	AdminLastLoginGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("Unable to load the last login of user john: database is down", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunLastLoginSuite(t *testing.T) {
+	suite.Run(t, new(LastLoginSuite))
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/templates"
+)
+
+// loginDeviceFingerprint returns the fingerprint of the device identified by the given user agent.
+func loginDeviceFingerprint(userAgent []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(userAgent))
+}
+
+// checkLoginDevice records the device and IP address the user just logged in from, refreshing the time of the known
+// ones so that they are kept by the pruning, and notifies the user by email when either of them was never seen before. It returns true if the login was not recognised. The first login recorded for a
+// user is always considered recognised since there is nothing to compare it to. An error is returned if the devices
+// cannot be loaded and the storage failure mode does not tolerate it.
+func checkLoginDevice(ctx *middlewares.AutheliaCtx, username string, emails []string) (unrecognised bool, err error) {
+	devices, err := ctx.Providers.StorageProvider.LoadLoginDevices(username)
+	if err != nil {
+		if isStorageFailureTolerated(ctx, fmt.Sprintf("login notification of user %s", username), err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Unable to load the login devices of user %s: %s", username, err)
+	}
+
+	device := models.LoginDevice{
+		Username:    username,
+		Fingerprint: loginDeviceFingerprint(ctx.UserAgent()),
+		IP:          ctx.RemoteIP().String(),
+		Time:        ctx.Clock.Now(),
+	}
+
+	var knownDevice, knownIP bool
+
+	for _, d := range devices {
+		knownDevice = knownDevice || d.Fingerprint == device.Fingerprint
+		knownIP = knownIP || d.IP == device.IP
+	}
+
+	if err = ctx.Providers.StorageProvider.SaveLoginDevice(device); err != nil {
+		ctx.Logger.Errorf("Unable to record the login device of user %s: %s", username, err)
+	}
+
+	if len(devices) == 0 || (knownDevice && knownIP) {
+		return false, nil
+	}
+
+	ctx.Logger.Infof("User %s logged in from an unrecognised device or IP address %s", username, device.IP)
+
+	sendLoginNotification(ctx, username, emails, device)
+
+	return true, nil
+}
+
+// sendLoginNotification notifies the user by email that they logged in from an unrecognised device or IP address.
+func sendLoginNotification(ctx *middlewares.AutheliaCtx, username string, emails []string, device models.LoginDevice) {
+	if len(emails) == 0 {
+		ctx.Logger.Warnf("Unable to notify user %s of a login from an unrecognised device since they have no email address", username)
+		return
+	}
+
+	var location string
+
+	if ctx.Configuration.LoginNotification.LocationHeader != "" {
+		location = string(ctx.Request.Header.Peek(ctx.Configuration.LoginNotification.LocationHeader))
+	}
+
+	params := map[string]interface{}{
+		"time":      device.Time.UTC().Format(time.RFC1123),
+		"ip":        device.IP,
+		"location":  location,
+		"userAgent": string(ctx.UserAgent()),
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := templates.LoginNotificationEmailTemplate.Execute(buf, params); err != nil {
+		ctx.Logger.Errorf("Unable to render the login notification of user %s: %s", username, err)
+		return
+	}
+
+	if err := ctx.Providers.Notifier.Send(emails[0], loginNotificationTitle, buf.String(), ""); err != nil {
+		ctx.Logger.Errorf("Unable to send the login notification to user %s: %s", username, err)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+)
+
+type LoginNotificationSuite struct {
+	suite.Suite
+
+	mock        *mocks.MockAutheliaCtx
+	fingerprint string
+}
+
+func (s *LoginNotificationSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Configuration.LoginNotification.Enabled = true
+	s.mock.Ctx.Configuration.LoginNotification.LocationHeader = "CF-IPCountry"
+
+	s.mock.Ctx.Request.Header.SetUserAgent("Mozilla/5.0")
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	s.mock.Ctx.Request.Header.Set("CF-IPCountry", "FR")
+
+	s.fingerprint = loginDeviceFingerprint([]byte("Mozilla/5.0"))
+}
+
+func (s *LoginNotificationSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *LoginNotificationSuite) TestShouldNotNotifyOnFirstRecordedLogin() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLoginDevices(gomock.Eq(testUsername)).
+		Return(nil, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveLoginDevice(gomock.Eq(models.LoginDevice{
+			Username:    testUsername,
+			Fingerprint: s.fingerprint,
+			IP:          "192.168.0.1",
+			Time:        s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	unrecognised, err := checkLoginDevice(s.mock.Ctx, testUsername, []string{"john@example.com"})
+	s.Require().NoError(err)
+	s.Assert().False(unrecognised)
+}
+
+func (s *LoginNotificationSuite) TestShouldNotNotifyOnKnownDeviceAndIP() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLoginDevices(gomock.Eq(testUsername)).
+		Return([]models.LoginDevice{
+			{Username: testUsername, Fingerprint: s.fingerprint, IP: "192.168.0.1"},
+		}, nil)
+
+	// The known device is saved again to refresh its time.
+	s.mock.StorageProviderMock.EXPECT().
+		SaveLoginDevice(gomock.Eq(models.LoginDevice{
+			Username:    testUsername,
+			Fingerprint: s.fingerprint,
+			IP:          "192.168.0.1",
+			Time:        s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	unrecognised, err := checkLoginDevice(s.mock.Ctx, testUsername, []string{"john@example.com"})
+	s.Require().NoError(err)
+	s.Assert().False(unrecognised)
+}
+
+func (s *LoginNotificationSuite) TestShouldNotNotifyOnKnownDeviceAndIPSeenSeparately() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLoginDevices(gomock.Eq(testUsername)).
+		Return([]models.LoginDevice{
+			{Username: testUsername, Fingerprint: s.fingerprint, IP: "192.168.0.2"},
+			{Username: testUsername, Fingerprint: "another", IP: "192.168.0.1"},
+		}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveLoginDevice(gomock.Any()).
+		Return(nil)
+
+	unrecognised, err := checkLoginDevice(s.mock.Ctx, testUsername, []string{"john@example.com"})
+	s.Require().NoError(err)
+	s.Assert().False(unrecognised)
+}
+
+func (s *LoginNotificationSuite) TestShouldNotifyOnUnknownIP() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLoginDevices(gomock.Eq(testUsername)).
+		Return([]models.LoginDevice{
+			{Username: testUsername, Fingerprint: s.fingerprint, IP: "192.168.0.2"},
+		}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveLoginDevice(gomock.Any()).
+		Return(nil)
+
+	var body string
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq(loginNotificationTitle), gomock.Any(), gomock.Eq("")).
+		DoAndReturn(func(_, _, b, _ string) error {
+			body = b
+			return nil
+		})
+
+	unrecognised, err := checkLoginDevice(s.mock.Ctx, testUsername, []string{"john@example.com"})
+	s.Require().NoError(err)
+	s.Assert().True(unrecognised)
+
+	s.Assert().Contains(body, "IP address: 192.168.0.1")
+	s.Assert().Contains(body, "Approximate location: FR")
+	s.Assert().Contains(body, "Device: Mozilla/5.0")
+	s.Assert().Contains(body, "Time: Sun, 03 Feb 2013 00:00:00 UTC")
+}
+
+func (s *LoginNotificationSuite) TestShouldFailWhenDevicesCannotBeLoadedAndFailClosed() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeClosed
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLoginDevices(gomock.Eq(testUsername)).
+		Return(nil, fmt.Errorf("Failed"))
+
+	_, err := checkLoginDevice(s.mock.Ctx, testUsername, []string{"john@example.com"})
+	s.Assert().EqualError(err, "Unable to load the login devices of user john: Failed")
+}
+
+func (s *LoginNotificationSuite) TestShouldNotFailWhenDevicesCannotBeLoadedAndFailOpen() {
+	s.mock.Ctx.Configuration.Storage.FailureMode = schema.StorageFailureModeOpen
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLoginDevices(gomock.Eq(testUsername)).
+		Return(nil, fmt.Errorf("Failed"))
+
+	unrecognised, err := checkLoginDevice(s.mock.Ctx, testUsername, []string{"john@example.com"})
+	s.Require().NoError(err)
+	s.Assert().False(unrecognised)
+	s.Assert().Equal("Storage backend failure tolerated by the fail_open mode, login notification of user john is degraded: Failed", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunLoginNotificationSuite(t *testing.T) {
+	suite.Run(t, new(LoginNotificationSuite))
+}
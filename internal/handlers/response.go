@@ -12,7 +12,8 @@ import (
 )
 
 // Handle1FAResponse handle the redirection upon 1FA authentication.
-func Handle1FAResponse(ctx *middlewares.AutheliaCtx, targetURI, requestMethod string, username string, groups []string) {
+func Handle1FAResponse(ctx *middlewares.AutheliaCtx, targetURI, requestMethod string, username string, groups []string,
+	attributes map[string][]string) {
 	if targetURI == "" {
 		if !ctx.Providers.Authorizer.IsSecondFactorEnabled() && ctx.Configuration.DefaultRedirectionURL != "" {
 			err := ctx.SetJSONBody(redirectResponse{Redirect: ctx.Configuration.DefaultRedirectionURL})
@@ -34,9 +35,10 @@ func Handle1FAResponse(ctx *middlewares.AutheliaCtx, targetURI, requestMethod st
 
 	requiredLevel := ctx.Providers.Authorizer.GetRequiredLevel(
 		authorization.Subject{
-			Username: username,
-			Groups:   groups,
-			IP:       ctx.RemoteIP(),
+			Username:   username,
+			Groups:     groups,
+			IP:         ctx.RemoteIP(),
+			Attributes: attributes,
 		},
 		authorization.NewObject(targetURL, requestMethod))
 
@@ -49,9 +51,9 @@ func Handle1FAResponse(ctx *middlewares.AutheliaCtx, targetURI, requestMethod st
 		return
 	}
 
-	safeRedirection := utils.IsRedirectionSafe(*targetURL, ctx.Configuration.Session.Domain)
+	if !isRedirectionSafe(ctx, *targetURL) {
+		ctx.Logger.Debugf("Redirection URL %s is not safe", targetURI)
 
-	if !safeRedirection {
 		if !ctx.Providers.Authorizer.IsSecondFactorEnabled() && ctx.Configuration.DefaultRedirectionURL != "" {
 			err := ctx.SetJSONBody(redirectResponse{Redirect: ctx.Configuration.DefaultRedirectionURL})
 			if err != nil {
@@ -96,16 +98,29 @@ func Handle2FAResponse(ctx *middlewares.AutheliaCtx, targetURI string) {
 		return
 	}
 
-	if targetURL != nil && utils.IsRedirectionSafe(*targetURL, ctx.Configuration.Session.Domain) {
+	if targetURL != nil && isRedirectionSafe(ctx, *targetURL) {
 		err := ctx.SetJSONBody(redirectResponse{Redirect: targetURI})
 		if err != nil {
 			ctx.Logger.Errorf("Unable to set redirection URL in body: %s", err)
 		}
+	} else if ctx.Configuration.DefaultRedirectionURL != "" {
+		ctx.Logger.Debugf("Redirection URL %s is not safe, falling back to the default redirection URL", targetURI)
+
+		err := ctx.SetJSONBody(redirectResponse{Redirect: ctx.Configuration.DefaultRedirectionURL})
+		if err != nil {
+			ctx.Logger.Errorf("Unable to set default redirection URL in body: %s", err)
+		}
 	} else {
 		ctx.ReplyOK()
 	}
 }
 
+// isRedirectionSafe determines if a target URL is under the protected domain and matches the redirection allowlist.
+func isRedirectionSafe(ctx *middlewares.AutheliaCtx, targetURL url.URL) bool {
+	return utils.IsRedirectionSafe(targetURL, ctx.Configuration.Session.Domain) &&
+		utils.IsRedirectionAllowed(targetURL, ctx.Configuration.AllowedRedirectionDomains)
+}
+
 // handleAuthenticationUnauthorized provides harmonized response codes for 1FA.
 func handleAuthenticationUnauthorized(ctx *middlewares.AutheliaCtx, err error, message string) {
 	ctx.SetStatusCode(fasthttp.StatusUnauthorized)
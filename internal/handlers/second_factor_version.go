@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// isSecondFactorChangeChecked returns true if the elevated sessions are checked against the version of the second
+// factor devices of their user.
+func isSecondFactorChangeChecked(ctx *middlewares.AutheliaCtx) bool {
+	switch ctx.Configuration.Session.SecondFactorChange {
+	case schema.SecondFactorChangeDowngrade, schema.SecondFactorChangeDestroy:
+		return true
+	default:
+		return false
+	}
+}
+
+// updateSecondFactorVersion records a change of the second factor devices of the user of the session. The session
+// performing the change is updated with the new version so that only the other sessions lose their elevation, the
+// caller is responsible for saving it.
+func updateSecondFactorVersion(ctx *middlewares.AutheliaCtx, userSession *session.UserSession) error {
+	version := ctx.Clock.Now().UnixNano()
+
+	if err := ctx.Providers.StorageProvider.SaveSecondFactorVersion(userSession.Username, version); err != nil {
+		return fmt.Errorf("Unable to save the second factor version of user %s: %s", userSession.Username, err)
+	}
+
+	userSession.SecondFactorVersion = version
+
+	return nil
+}
+
+// loadSecondFactorVersion keeps the current version of the second factor devices of the user in the session about to
+// be elevated.
+func loadSecondFactorVersion(ctx *middlewares.AutheliaCtx, userSession *session.UserSession) error {
+	if !isSecondFactorChangeChecked(ctx) {
+		return nil
+	}
+
+	version, err := ctx.Providers.StorageProvider.LoadSecondFactorVersion(userSession.Username)
+	if err != nil {
+		return fmt.Errorf("Unable to load the second factor version of user %s: %s", userSession.Username, err)
+	}
+
+	userSession.SecondFactorVersion = version
+
+	return nil
+}
+
+// verifySecondFactorVersion downgrades or destroys an elevated session if the second factor devices of its user
+// changed since it was elevated. An error is returned if the session must not be used any longer.
+func verifySecondFactorVersion(ctx *middlewares.AutheliaCtx, userSession *session.UserSession) error {
+	if !isSecondFactorChangeChecked(ctx) || userSession.AuthenticationLevel < authentication.TwoFactor {
+		return nil
+	}
+
+	version, err := ctx.Providers.StorageProvider.LoadSecondFactorVersion(userSession.Username)
+	if err != nil {
+		if isStorageFailureTolerated(ctx, fmt.Sprintf("verification of the second factor devices of user %s", userSession.Username), err) {
+			return nil
+		}
+
+		return fmt.Errorf("Unable to load the second factor version of user %s: %s", userSession.Username, err)
+	}
+
+	if version == userSession.SecondFactorVersion {
+		return nil
+	}
+
+	if ctx.Configuration.Session.SecondFactorChange == schema.SecondFactorChangeDestroy {
+		if err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx); err != nil {
+			return fmt.Errorf("Unable to destroy user session after the second factor devices changed: %s", err)
+		}
+
+		return fmt.Errorf("Second factor devices of user %s changed since the session completed the second factor, the session is destroyed", userSession.Username)
+	}
+
+	ctx.Logger.Infof("Second factor devices of user %s changed since the session completed the second factor, "+
+		"the session is downgraded to one factor", userSession.Username)
+
+	userSession.AuthenticationLevel = authentication.OneFactor
+
+	if err = ctx.SaveSession(*userSession); err != nil {
+		return fmt.Errorf("Unable to downgrade user session after the second factor devices changed: %s", err)
+	}
+
+	return nil
+}
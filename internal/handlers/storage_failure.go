@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/middlewares"
+)
+
+// isStorageFailureTolerated returns true if the operation can go on in a degraded mode despite the failure of the
+// storage backend, i.e. the storage failure mode is fail_open. The degradation is logged so that operators can notice it.
+func isStorageFailureTolerated(ctx *middlewares.AutheliaCtx, operation string, err error) bool {
+	if ctx.Configuration.Storage.FailureMode != schema.StorageFailureModeOpen {
+		return false
+	}
+
+	ctx.Logger.Warnf("Storage backend failure tolerated by the fail_open mode, %s is degraded: %s", operation, err)
+
+	return true
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
 )
 
 // MethodList is the list of available methods.
@@ -11,11 +14,35 @@ type MethodList = []string
 
 type authorizationMatching int
 
+// verifyProfile is how a verify endpoint reads the target URL from the proxy requests and replies to them.
+type verifyProfile struct {
+	// Whether the target URL is read from the X-Original-URL header.
+	originalURL bool
+	// Whether the target URL is read from the X-Forwarded-* headers.
+	forwardedHeaders bool
+	// Whether unauthorized users are redirected to the portal given in the rd parameter.
+	redirect bool
+	// The names of the headers forwarding the identity of the user.
+	headers schema.ServerVerifyEndpointHeadersConfiguration
+}
+
 // UserInfo is the model of user info and second factor preferences.
 type UserInfo struct {
+	// The users username.
+	Username string `json:"username"`
+
 	// The users display name.
 	DisplayName string `json:"display_name"`
 
+	// The users emails.
+	Emails []string `json:"emails"`
+
+	// The groups the user belongs to.
+	Groups []string `json:"groups"`
+
+	// The authentication level of the current session.
+	AuthenticationLevel authentication.Level `json:"authentication_level"`
+
 	// The preferred 2FA method.
 	Method string `json:"method" valid:"required"`
 
@@ -24,6 +51,20 @@ type UserInfo struct {
 
 	// True if a TOTP device has been registered.
 	HasTOTP bool `json:"has_totp" valid:"required"`
+
+	// The 2FA methods the user has registered a device for.
+	EnrolledMethods []string `json:"enrolled_methods"`
+
+	// The last successful authentication of the user.
+	LastLogin *LastLoginInfo `json:"last_login,omitempty"`
+}
+
+// LastLoginInfo is the last successful authentication of a user.
+type LastLoginInfo struct {
+	Username string    `json:"username"`
+	Time     time.Time `json:"time"`
+	IP       string    `json:"ip"`
+	Method   string    `json:"method"`
 }
 
 // signTOTPRequestBody model of the request body received by TOTP authentication endpoint.
@@ -53,6 +94,11 @@ type firstFactorRequestBody struct {
 	// TODO(c.michaud): add required validation once the above PR is merged.
 }
 
+// logoutRequestBody represents the optional JSON body received by the logout endpoint.
+type logoutRequestBody struct {
+	TargetURL string `json:"targetURL"`
+}
+
 // redirectResponse represent the response sent by the first factor endpoint
 // when a redirection URL has been provided.
 type redirectResponse struct {
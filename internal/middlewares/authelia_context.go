@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -11,17 +12,24 @@ import (
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
 	"github.com/authelia/authelia/internal/session"
 	"github.com/authelia/authelia/internal/utils"
 )
 
 // NewRequestLogger create a new request logger for the given request.
 func NewRequestLogger(ctx *AutheliaCtx) *logrus.Entry {
-	return logrus.WithFields(logrus.Fields{
+	fields := logrus.Fields{
 		"method":    string(ctx.Method()),
 		"path":      string(ctx.Path()),
 		"remote_ip": ctx.RemoteIP().String(),
-	})
+	}
+
+	if requestID, ok := ctx.UserValue(requestIDUserValueKey).(string); ok {
+		fields["request_id"] = requestID
+	}
+
+	return logrus.WithFields(fields)
 }
 
 // NewAutheliaCtx instantiate an AutheliaCtx out of a RequestCtx.
@@ -112,6 +120,54 @@ func (c *AutheliaCtx) XOriginalURL() []byte {
 	return c.RequestCtx.Request.Header.Peek(xOriginalURLHeader)
 }
 
+// ExternalRootURL returns the URL of the portal as seen by the users. The server external_url is used when configured.
+// Otherwise the URL is built from the X-Forwarded-Proto and X-Forwarded-Host headers when the request comes from a
+// trusted proxy, or from the scheme and the Host header of the request itself when it does not.
+func (c *AutheliaCtx) ExternalRootURL() (*url.URL, error) {
+	if c.Configuration.Server.ExternalURL != "" {
+		return url.Parse(c.Configuration.Server.ExternalURL)
+	}
+
+	if !c.IsFromTrustedProxy() {
+		scheme := "http"
+		if c.IsTLS() {
+			scheme = "https"
+		}
+
+		return &url.URL{Scheme: scheme, Host: string(c.Host()), Path: c.Configuration.Server.BasePath}, nil
+	}
+
+	if c.XForwardedProto() == nil {
+		return nil, errMissingXForwardedProto
+	}
+
+	if c.XForwardedHost() == nil {
+		return nil, errMissingXForwardedHost
+	}
+
+	return &url.URL{Scheme: string(c.XForwardedProto()), Host: string(c.XForwardedHost()), Path: c.Configuration.Server.BasePath}, nil
+}
+
+// IsFromTrustedProxy returns true if the request has been sent by one of the trusted proxies, or if no trusted proxy is
+// configured in which case any peer is trusted.
+func (c *AutheliaCtx) IsFromTrustedProxy() bool {
+	if len(c.Configuration.Server.TrustedProxies) == 0 {
+		return true
+	}
+
+	return c.isTrustedProxy(c.RequestCtx.RemoteIP())
+}
+
+func (c *AutheliaCtx) isTrustedProxy(ip net.IP) bool {
+	for _, proxy := range c.Configuration.Server.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetSession return the user session. Any update will be saved in cache.
 func (c *AutheliaCtx) GetSession() session.UserSession {
 	userSession, err := c.Providers.SessionProvider.GetSession(c.RequestCtx)
@@ -168,15 +224,58 @@ func (c *AutheliaCtx) SetJSONBody(value interface{}) error {
 	return nil
 }
 
-// RemoteIP return the remote IP taking X-Forwarded-For header into account if provided.
+// RemoteIP return the remote IP taking X-Forwarded-For header into account if provided by a trusted proxy. As for the
+// X-Forwarded-Host header, every peer is trusted when no trusted proxy is configured.
+// When a number of forwarded hops is configured, the entry added by the outermost trusted proxy is used, i.e. the Nth
+// entry from the right. If the header has fewer entries than hops the request did not go through the expected proxies
+// and the IP of the peer is used.
+// When trusted proxies are configured, the rightmost entry which is not a trusted proxy is used, or the leftmost one if
+// they all are. Otherwise the leftmost entry, which can be set by the client, is used.
+// The IP of the peer is used when the entry is not a valid IP.
 func (c *AutheliaCtx) RemoteIP() net.IP {
 	XForwardedFor := c.Request.Header.Peek("X-Forwarded-For")
-	if XForwardedFor != nil {
-		ips := strings.Split(string(XForwardedFor), ",")
+	if XForwardedFor == nil || !c.IsFromTrustedProxy() {
+		return c.RequestCtx.RemoteIP()
+	}
+
+	ips := strings.Split(string(XForwardedFor), ",")
 
-		if len(ips) > 0 {
-			return net.ParseIP(strings.Trim(ips[0], " "))
+	switch {
+	case c.Configuration.Server.ForwardedHops > 0:
+		hops := c.Configuration.Server.ForwardedHops
+		if hops > len(ips) {
+			return c.RequestCtx.RemoteIP()
 		}
+
+		return c.parseForwardedIP(ips[len(ips)-hops])
+	case len(c.Configuration.Server.TrustedProxies) != 0:
+		for i := len(ips) - 1; i > 0; i-- {
+			ip := net.ParseIP(strings.Trim(ips[i], " "))
+
+			switch {
+			case ip == nil:
+				return c.RequestCtx.RemoteIP()
+			case !c.isTrustedProxy(ip):
+				return ip
+			}
+		}
+
+		return c.parseForwardedIP(ips[0])
+	default:
+		xForwardedForDeprecationOnce.Do(func() {
+			logging.Logger().Warn("DEPRECATED: The client IP is taken from the leftmost entry of the X-Forwarded-For " +
+				"header which can be set by the client since neither server forwarded_hops nor trusted_proxies is " +
+				"configured, configure one of them as this default will be removed in a future version")
+		})
+
+		return c.parseForwardedIP(ips[0])
+	}
+}
+
+// parseForwardedIP parses an entry of the X-Forwarded-For header, returning the IP of the peer if it is not a valid IP.
+func (c *AutheliaCtx) parseForwardedIP(entry string) net.IP {
+	if ip := net.ParseIP(strings.Trim(entry, " ")); ip != nil {
+		return ip
 	}
 
 	return c.RequestCtx.RemoteIP()
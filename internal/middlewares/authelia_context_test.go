@@ -5,6 +5,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
@@ -33,3 +34,112 @@ func TestShouldCallNextWithAutheliaCtx(t *testing.T) {
 
 	assert.True(t, nextCalled)
 }
+
+func TestShouldGetRemoteIPFromXForwardedFor(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2, 3.3.3.3")
+
+	assert.Equal(t, "1.1.1.1", mock.Ctx.RemoteIP().String())
+}
+
+func TestShouldGetRemoteIPOfPeerWhenXForwardedForIsInvalid(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-For", "unknown, 2.2.2.2")
+	assert.Equal(t, "0.0.0.0", mock.Ctx.RemoteIP().String())
+
+	mock.Ctx.Configuration.Server.ForwardedHops = 1
+	mock.Ctx.Request.Header.Set("X-Forwarded-For", "1.1.1.1, unknown")
+	assert.Equal(t, "0.0.0.0", mock.Ctx.RemoteIP().String())
+
+	mock.Ctx.Configuration.Server.ForwardedHops = 0
+	mock.Ctx.Configuration.Server.TrustedProxies = []string{"0.0.0.0/32"}
+	assert.Equal(t, "0.0.0.0", mock.Ctx.RemoteIP().String())
+}
+
+func TestShouldGetRemoteIPFromXForwardedForOfTrustedProxy(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.Server.TrustedProxies = []string{"0.0.0.0/32", "10.0.0.0/8"}
+	mock.Ctx.Request.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2, 10.0.0.1")
+
+	// The entries added by the trusted proxies are skipped, the ones on their left can be set by the client.
+	assert.Equal(t, "2.2.2.2", mock.Ctx.RemoteIP().String())
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-For", "10.0.0.2, 10.0.0.1")
+	assert.Equal(t, "10.0.0.2", mock.Ctx.RemoteIP().String())
+
+	mock.Ctx.Configuration.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	assert.Equal(t, "0.0.0.0", mock.Ctx.RemoteIP().String())
+}
+
+func TestShouldGetRemoteIPFromXForwardedForWithForwardedHops(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2, 3.3.3.3")
+
+	mock.Ctx.Configuration.Server.ForwardedHops = 1
+	assert.Equal(t, "3.3.3.3", mock.Ctx.RemoteIP().String())
+
+	mock.Ctx.Configuration.Server.ForwardedHops = 2
+	assert.Equal(t, "2.2.2.2", mock.Ctx.RemoteIP().String())
+
+	// Less entries than hops means the request did not go through the expected proxies, the peer is used.
+	mock.Ctx.Configuration.Server.ForwardedHops = 4
+	assert.Equal(t, "0.0.0.0", mock.Ctx.RemoteIP().String())
+}
+
+func TestShouldGetExternalRootURLFromForwardedHeaders(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.Server.BasePath = "/auth"
+	mock.Ctx.Request.SetHost("authelia.internal")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+
+	rootURL, err := mock.Ctx.ExternalRootURL()
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.example.com/auth", rootURL.String())
+
+	mock.Ctx.Configuration.Server.TrustedProxies = []string{"0.0.0.0/32"}
+
+	rootURL, err = mock.Ctx.ExternalRootURL()
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.example.com/auth", rootURL.String())
+}
+
+func TestShouldIgnoreForwardedHeadersFromUntrustedProxy(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	mock.Ctx.Request.SetHost("authelia.internal")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Host", "evil.example.com")
+
+	assert.False(t, mock.Ctx.IsFromTrustedProxy())
+
+	rootURL, err := mock.Ctx.ExternalRootURL()
+	require.NoError(t, err)
+	assert.Equal(t, "http://authelia.internal", rootURL.String())
+}
+
+func TestShouldPreferConfiguredExternalURL(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.Server.BasePath = "/auth"
+	mock.Ctx.Configuration.Server.ExternalURL = "https://login.example.com/authelia"
+	mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "http")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Host", "cdn-origin.example.com")
+
+	rootURL, err := mock.Ctx.ExternalRootURL()
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.example.com/authelia", rootURL.String())
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CacheControlMiddleware sets the given Cache-Control policy on the responses, along with the Pragma header understood
+// by HTTP/1.0 caches when the policy forbids caching. An empty policy removes both headers so that the responses can be
+// cached according to the defaults of the browsers and intermediaries. The handlers can still override the policy.
+func CacheControlMiddleware(policy string) func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	lowerPolicy := strings.ToLower(policy)
+	noCache := strings.Contains(lowerPolicy, "no-store") || strings.Contains(lowerPolicy, "no-cache")
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if policy == "" {
+				ctx.Response.Header.Del(cacheControlHeader)
+			} else {
+				ctx.Response.Header.Set(cacheControlHeader, policy)
+			}
+
+			if noCache {
+				ctx.Response.Header.Set(pragmaHeader, "no-cache")
+			} else {
+				ctx.Response.Header.Del(pragmaHeader)
+			}
+
+			next(ctx)
+		}
+	}
+}
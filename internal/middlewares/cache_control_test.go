@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestShouldSetNoStorePolicy(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+
+	CacheControlMiddleware("no-store")(func(ctx *fasthttp.RequestCtx) {})(ctx)
+
+	assert.Equal(t, "no-store", string(ctx.Response.Header.Peek("Cache-Control")))
+	assert.Equal(t, "no-cache", string(ctx.Response.Header.Peek("Pragma")))
+}
+
+func TestShouldOverrideSensitivePolicyWithStaticPolicy(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+
+	static := CacheControlMiddleware("public, max-age=86400")(func(ctx *fasthttp.RequestCtx) {})
+	CacheControlMiddleware("no-store")(static)(ctx)
+
+	assert.Equal(t, "public, max-age=86400", string(ctx.Response.Header.Peek("Cache-Control")))
+	assert.Nil(t, ctx.Response.Header.Peek("Pragma"))
+}
+
+func TestShouldRemovePolicyWhenEmpty(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+
+	static := CacheControlMiddleware("")(func(ctx *fasthttp.RequestCtx) {})
+	CacheControlMiddleware("no-store")(static)(ctx)
+
+	assert.Nil(t, ctx.Response.Header.Peek("Cache-Control"))
+	assert.Nil(t, ctx.Response.Header.Peek("Pragma"))
+}
+
+func TestShouldLetHandlerOverridePolicy(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+
+	CacheControlMiddleware("no-store")(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("Cache-Control", "private, max-age=60")
+	})(ctx)
+
+	assert.Equal(t, "private, max-age=60", string(ctx.Response.Header.Peek("Cache-Control")))
+}
@@ -1,5 +1,9 @@
 package middlewares
 
+import (
+	"sync"
+)
+
 const jwtIssuer = "Authelia"
 
 const xForwardedProtoHeader = "X-Forwarded-Proto"
@@ -9,10 +13,26 @@ const xForwardedURIHeader = "X-Forwarded-URI"
 
 const xOriginalURLHeader = "X-Original-URL"
 
+const cacheControlHeader = "Cache-Control"
+const pragmaHeader = "Pragma"
+
+const requestIDUserValueKey = "request_id"
+
 const applicationJSONContentType = "application/json"
 
+// xForwardedForDeprecationOnce logs the deprecation of the X-Forwarded-For header trusted from any peer only once.
+var xForwardedForDeprecationOnce sync.Once
+
 var okMessageBytes = []byte("{\"status\":\"OK\"}")
 
 const operationFailedMessage = "Operation failed"
 const identityVerificationTokenAlreadyUsedMessage = "The identity verification token has already been used"
 const identityVerificationTokenHasExpiredMessage = "The identity verification token has expired"
+
+const redactedValue = "REDACTED"
+
+// redactedSampledHeaders are the lower case names of the headers whose values are never logged by the request sampling.
+var redactedSampledHeaders = []string{"authorization", "proxy-authorization", "cookie", "set-cookie"}
+
+// redactedSampledQueryArgs are the query arguments whose values are never logged by the request sampling.
+var redactedSampledQueryArgs = []string{"token", "code", "state"}
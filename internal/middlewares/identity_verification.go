@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 
+	"github.com/authelia/authelia/internal/session"
 	"github.com/authelia/authelia/internal/templates"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // IdentityVerificationStart the handler for initiating the identity validation process.
@@ -28,88 +29,87 @@ func IdentityVerificationStart(args IdentityVerificationStartArgs) RequestHandle
 			return
 		}
 
-		// Create the claim with the action to sign it.
-		claims := &IdentityVerificationClaim{
-			jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(5 * time.Minute).Unix(),
-				Issuer:    jwtIssuer,
-			},
-			args.ActionClaim,
-			identity.Username,
-		}
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		ss, err := token.SignedString([]byte(ctx.Configuration.JWTSecret))
-
-		if err != nil {
+		if err = SendIdentityVerificationEmail(ctx, args, identity); err != nil {
 			ctx.Error(err, operationFailedMessage)
 			return
 		}
 
-		err = ctx.Providers.StorageProvider.SaveIdentityVerificationToken(ss)
-		if err != nil {
-			ctx.Error(err, operationFailedMessage)
-			return
-		}
+		ctx.ReplyOK()
+	}
+}
 
-		if ctx.XForwardedProto() == nil {
-			ctx.Error(errMissingXForwardedProto, operationFailedMessage)
-			return
-		}
+// SendIdentityVerificationEmail signs a token for the action of the given arguments, stores it and sends the link
+// containing it to the email address of the identity.
+func SendIdentityVerificationEmail(ctx *AutheliaCtx, args IdentityVerificationStartArgs, identity *session.Identity) error {
+	lifetime, err := utils.ParseDurationString(ctx.Configuration.IdentityVerification.TokenLifetime)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the identity verification token lifetime: %s", err)
+	}
 
-		if ctx.XForwardedHost() == nil {
-			ctx.Error(errMissingXForwardedHost, operationFailedMessage)
-			return
-		}
+	// Create the claim with the action to sign it.
+	claims := &IdentityVerificationClaim{
+		jwt.StandardClaims{
+			ExpiresAt: ctx.Clock.Now().Add(lifetime).Unix(),
+			Issuer:    jwtIssuer,
+		},
+		args.ActionClaim,
+		identity.Username,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString([]byte(ctx.Configuration.JWTSecret))
 
-		link := fmt.Sprintf("%s://%s%s%s?token=%s", ctx.XForwardedProto(),
-			ctx.XForwardedHost(), ctx.Configuration.Server.Path, args.TargetEndpoint, ss)
+	if err != nil {
+		return err
+	}
 
-		bufHTML := new(bytes.Buffer)
+	err = ctx.Providers.StorageProvider.SaveIdentityVerificationToken(ss)
+	if err != nil {
+		return err
+	}
 
-		disableHTML := false
-		if ctx.Configuration.Notifier != nil && ctx.Configuration.Notifier.SMTP != nil {
-			disableHTML = ctx.Configuration.Notifier.SMTP.DisableHTMLEmails
-		}
+	rootURL, err := ctx.ExternalRootURL()
+	if err != nil {
+		return err
+	}
 
-		if !disableHTML {
-			htmlParams := map[string]interface{}{
-				"title":  args.MailTitle,
-				"url":    link,
-				"button": args.MailButtonContent,
-			}
+	link := fmt.Sprintf("%s%s?token=%s", rootURL, args.TargetEndpoint, ss)
 
-			err = templates.HTMLEmailTemplate.Execute(bufHTML, htmlParams)
+	bufHTML := new(bytes.Buffer)
 
-			if err != nil {
-				ctx.Error(err, operationFailedMessage)
-				return
-			}
-		}
+	disableHTML := false
+	if ctx.Configuration.Notifier != nil && ctx.Configuration.Notifier.SMTP != nil {
+		disableHTML = ctx.Configuration.Notifier.SMTP.DisableHTMLEmails
+	}
 
-		bufText := new(bytes.Buffer)
-		textParams := map[string]interface{}{
-			"url": link,
+	if !disableHTML {
+		htmlParams := map[string]interface{}{
+			"title":  args.MailTitle,
+			"url":    link,
+			"button": args.MailButtonContent,
 		}
 
-		err = templates.PlainTextEmailTemplate.Execute(bufText, textParams)
+		err = templates.HTMLEmailTemplate.Execute(bufHTML, htmlParams)
 
 		if err != nil {
-			ctx.Error(err, operationFailedMessage)
-			return
+			return err
 		}
+	}
 
-		ctx.Logger.Debugf("Sending an email to user %s (%s) to confirm identity for registering a device.",
-			identity.Username, identity.Email)
+	bufText := new(bytes.Buffer)
+	textParams := map[string]interface{}{
+		"url": link,
+	}
 
-		err = ctx.Providers.Notifier.Send(identity.Email, args.MailTitle, bufText.String(), bufHTML.String())
+	err = templates.PlainTextEmailTemplate.Execute(bufText, textParams)
 
-		if err != nil {
-			ctx.Error(err, operationFailedMessage)
-			return
-		}
-
-		ctx.ReplyOK()
+	if err != nil {
+		return err
 	}
+
+	ctx.Logger.Debugf("Sending an email to user %s (%s) to confirm their identity for action %s.",
+		identity.Username, identity.Email, args.ActionClaim)
+
+	return ctx.Providers.Notifier.Send(identity.Email, args.MailTitle, bufText.String(), bufHTML.String())
 }
 
 // IdentityVerificationFinish the middleware for finishing the identity validation process.
@@ -187,13 +187,18 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 			return
 		}
 
-		// TODO(c.michaud): find a way to garbage collect unused tokens.
-		err = ctx.Providers.StorageProvider.RemoveIdentityVerificationToken(finishBody.Token)
+		// The token is consumed atomically so that it can't be used by two concurrent requests.
+		consumed, err := ctx.Providers.StorageProvider.ConsumeIdentityVerificationToken(finishBody.Token)
 		if err != nil {
 			ctx.Error(err, operationFailedMessage)
 			return
 		}
 
+		if !consumed {
+			ctx.Error(fmt.Errorf("Token has been consumed by a concurrent request"), identityVerificationTokenAlreadyUsedMessage)
+			return
+		}
+
 		next(ctx, claims.Username)
 	}
 }
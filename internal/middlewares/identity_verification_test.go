@@ -8,6 +8,7 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/authelia/authelia/internal/middlewares"
@@ -124,6 +125,64 @@ func TestShouldFailWhenXForwardedHostHeaderIsMissing(t *testing.T) {
 	assert.Equal(t, "Missing header X-Forwarded-Host", mock.Hook.LastEntry().Message)
 }
 
+func TestShouldUseExternalURLInIdentityVerificationLink(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.JWTSecret = testJWTSecret
+	mock.Ctx.Configuration.Server.ExternalURL = "https://login.example.com"
+
+	mock.StorageProviderMock.EXPECT().
+		SaveIdentityVerificationToken(gomock.Any()).
+		Return(nil)
+
+	mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq("Title"), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_, _, body, _ string) error {
+			assert.Contains(t, body, "https://login.example.com/target?token=")
+			return nil
+		})
+
+	args := newArgs(defaultRetriever)
+	middlewares.IdentityVerificationStart(args)(mock.Ctx)
+
+	assert.Equal(t, 200, mock.Ctx.Response.StatusCode())
+}
+
+func TestShouldIssueTokenWithConfiguredLifetime(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Clock = &mock.Clock
+	mock.Ctx.Configuration.JWTSecret = testJWTSecret
+	mock.Ctx.Configuration.IdentityVerification.TokenLifetime = "1h"
+	mock.Ctx.Request.Header.Add("X-Forwarded-Proto", "http")
+	mock.Ctx.Request.Header.Add("X-Forwarded-Host", "host")
+
+	var token string
+
+	mock.StorageProviderMock.EXPECT().
+		SaveIdentityVerificationToken(gomock.Any()).
+		DoAndReturn(func(t string) error {
+			token = t
+			return nil
+		})
+
+	mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq("Title"), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	middlewares.IdentityVerificationStart(newArgs(defaultRetriever))(mock.Ctx)
+
+	assert.Equal(t, 200, mock.Ctx.Response.StatusCode())
+
+	claims := &middlewares.IdentityVerificationClaim{}
+	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	require.NoError(t, err)
+
+	assert.Equal(t, mock.Clock.Now().Add(time.Hour).Unix(), claims.ExpiresAt)
+}
+
 func TestShouldSucceedIdentityVerificationStartProcess(t *testing.T) {
 	mock := mocks.NewMockAutheliaCtx(t)
 
@@ -287,8 +346,8 @@ func (s *IdentityVerificationFinishProcess) TestShouldFailIfTokenCannotBeRemoved
 		Return(true, nil)
 
 	s.mock.StorageProviderMock.EXPECT().
-		RemoveIdentityVerificationToken(gomock.Eq(token)).
-		Return(fmt.Errorf("cannot remove"))
+		ConsumeIdentityVerificationToken(gomock.Eq(token)).
+		Return(false, fmt.Errorf("cannot remove"))
 
 	middlewares.IdentityVerificationFinish(newFinishArgs(), next)(s.mock.Ctx)
 
@@ -306,14 +365,35 @@ func (s *IdentityVerificationFinishProcess) TestShouldReturn200OnFinishComplete(
 		Return(true, nil)
 
 	s.mock.StorageProviderMock.EXPECT().
-		RemoveIdentityVerificationToken(gomock.Eq(token)).
-		Return(nil)
+		ConsumeIdentityVerificationToken(gomock.Eq(token)).
+		Return(true, nil)
 
 	middlewares.IdentityVerificationFinish(newFinishArgs(), next)(s.mock.Ctx)
 
 	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
 }
 
+func (s *IdentityVerificationFinishProcess) TestShouldFailIfTokenIsReplayedConcurrently() {
+	token := createToken(s.mock.Ctx.Configuration.JWTSecret, "john", "EXP_ACTION",
+		time.Now().Add(1*time.Minute))
+	s.mock.Ctx.Request.SetBodyString(fmt.Sprintf("{\"token\":\"%s\"}", token))
+
+	s.mock.StorageProviderMock.EXPECT().
+		FindIdentityVerificationToken(gomock.Eq(token)).
+		Return(true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		ConsumeIdentityVerificationToken(gomock.Eq(token)).
+		Return(false, nil)
+
+	middlewares.IdentityVerificationFinish(newFinishArgs(), func(ctx *middlewares.AutheliaCtx, username string) {
+		s.Fail("the next handler must not be called for a replayed token")
+	})(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), "The identity verification token has already been used")
+	assert.Equal(s.T(), "Token has been consumed by a concurrent request", s.mock.Hook.LastEntry().Message)
+}
+
 func TestRunIdentityVerificationFinish(t *testing.T) {
 	s := new(IdentityVerificationFinishProcess)
 	suite.Run(t, s)
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// requestIDRegexp matches the request IDs provided by the clients which are safe to be logged and echoed back.
+var requestIDRegexp = regexp.MustCompile("^[A-Za-z0-9._~:/+=-]{1,128}$")
+
+// RequestIDMiddleware identifies each request with the request ID provided in one of the configured headers, or a
+// generated one if none is provided. The request ID is added to the fields of the request logger and echoed back in
+// the configured response header.
+func RequestIDMiddleware(configuration schema.ServerRequestIDConfiguration) func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		if !configuration.Enabled {
+			return next
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			requestID := getRequestID(ctx, configuration.Headers)
+
+			ctx.SetUserValue(requestIDUserValueKey, requestID)
+			ctx.Response.Header.Set(configuration.ResponseHeader, requestID)
+
+			next(ctx)
+		}
+	}
+}
+
+// getRequestID returns the first valid request ID provided in the given headers or generates a new one.
+func getRequestID(ctx *fasthttp.RequestCtx, headers []string) string {
+	for _, header := range headers {
+		if requestID := ctx.Request.Header.Peek(header); requestIDRegexp.Match(requestID) {
+			return string(requestID)
+		}
+	}
+
+	b := make([]byte, 16)
+
+	// The request ID only serves the correlation of logs, a failure of the random source leaves it zeroed.
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
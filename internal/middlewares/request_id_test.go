@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newRequestIDConfiguration() schema.ServerRequestIDConfiguration {
+	configuration := schema.DefaultServerRequestIDConfiguration
+	configuration.Enabled = true
+
+	return configuration
+}
+
+func TestShouldHonorIncomingRequestID(t *testing.T) {
+	var requestID interface{}
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Correlation-ID", "b7ad6b7169203331")
+
+	RequestIDMiddleware(newRequestIDConfiguration())(func(ctx *fasthttp.RequestCtx) {
+		requestID = NewRequestLogger(&AutheliaCtx{RequestCtx: ctx}).Data["request_id"]
+	})(ctx)
+
+	assert.Equal(t, "b7ad6b7169203331", requestID)
+	assert.Equal(t, "b7ad6b7169203331", string(ctx.Response.Header.Peek("X-Request-ID")))
+}
+
+func TestShouldPreferFirstConfiguredRequestIDHeader(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Request-ID", "request")
+	ctx.Request.Header.Set("X-Correlation-ID", "correlation")
+
+	RequestIDMiddleware(newRequestIDConfiguration())(func(ctx *fasthttp.RequestCtx) {})(ctx)
+
+	assert.Equal(t, "request", string(ctx.Response.Header.Peek("X-Request-ID")))
+}
+
+func TestShouldGenerateRequestIDWhenMissingOrInvalid(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Request-ID", "injected\" level=error msg=\"forged")
+
+	RequestIDMiddleware(newRequestIDConfiguration())(func(ctx *fasthttp.RequestCtx) {})(ctx)
+
+	requestID := string(ctx.Response.Header.Peek("X-Request-ID"))
+
+	assert.Regexp(t, "^[0-9a-f]{32}$", requestID)
+	assert.Equal(t, requestID, ctx.UserValue(requestIDUserValueKey))
+}
+
+func TestShouldNotIdentifyRequestsWhenDisabled(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Request-ID", "request")
+
+	RequestIDMiddleware(schema.DefaultServerRequestIDConfiguration)(func(ctx *fasthttp.RequestCtx) {})(ctx)
+
+	assert.Nil(t, ctx.Response.Header.Peek("X-Request-ID"))
+	assert.NotContains(t, NewRequestLogger(&AutheliaCtx{RequestCtx: ctx}).Data, "request_id")
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// RequestSamplingMiddleware logs the headers of a sample of the requests along with the status and the headers of
+// their responses at the debug level. The values of the headers carrying credentials or cookies and of the query
+// arguments carrying tokens are redacted.
+func RequestSamplingMiddleware(configuration schema.ServerRequestSamplingConfiguration) func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return newRequestSamplingMiddleware(configuration, utils.RealClock{}, rand.Float64) //nolint:gosec // Sampling the logged requests doesn't require a secure random source.
+}
+
+func newRequestSamplingMiddleware(configuration schema.ServerRequestSamplingConfiguration, clock utils.Clock, random func() float64) func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		if configuration.Rate <= 0 {
+			return next
+		}
+
+		sampler := &requestSampler{
+			rate:         configuration.Rate,
+			maxPerMinute: configuration.MaxPerMinute,
+			clock:        clock,
+			random:       random,
+		}
+
+		redacted := append([]string{}, redactedSampledHeaders...)
+		for _, header := range configuration.RedactedHeaders {
+			redacted = append(redacted, strings.ToLower(header))
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if !logging.Logger().IsLevelEnabled(logrus.DebugLevel) || !sampler.sample() {
+				next(ctx)
+				return
+			}
+
+			logger := NewRequestLogger(&AutheliaCtx{RequestCtx: ctx})
+
+			requestHeaders := map[string]string{}
+			ctx.Request.Header.VisitAll(func(key, value []byte) {
+				requestHeaders[string(key)] = redactHeader(string(key), string(value), redacted)
+			})
+
+			logger.Debugf("Sampled request %s %s with headers %s", ctx.Method(), redactedRequestURI(ctx), formatSampledHeaders(requestHeaders))
+
+			next(ctx)
+
+			responseHeaders := map[string]string{}
+			ctx.Response.Header.VisitAll(func(key, value []byte) {
+				responseHeaders[string(key)] = redactHeader(string(key), string(value), redacted)
+			})
+
+			logger.Debugf("Sampled response with status %d and headers %s", ctx.Response.StatusCode(), formatSampledHeaders(responseHeaders))
+		}
+	}
+}
+
+// requestSampler samples the requests at the given rate, at most the given number of requests being sampled per
+// minute.
+type requestSampler struct {
+	rate         float64
+	maxPerMinute int
+	clock        utils.Clock
+	random       func() float64
+
+	mutex  sync.Mutex
+	window time.Time
+	count  int
+}
+
+func (s *requestSampler) sample() bool {
+	if s.random() >= s.rate {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if window := s.clock.Now().Truncate(time.Minute); !window.Equal(s.window) {
+		s.window = window
+		s.count = 0
+	}
+
+	if s.count >= s.maxPerMinute {
+		return false
+	}
+
+	s.count++
+
+	return true
+}
+
+func redactHeader(key, value string, redacted []string) string {
+	if utils.IsStringInSlice(strings.ToLower(key), redacted) {
+		return redactedValue
+	}
+
+	return value
+}
+
+// redactedRequestURI returns the URI of the request with the values of the query arguments carrying tokens redacted.
+func redactedRequestURI(ctx *fasthttp.RequestCtx) string {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+
+	ctx.QueryArgs().CopyTo(args)
+
+	for _, key := range redactedSampledQueryArgs {
+		if args.Has(key) {
+			args.Set(key, redactedValue)
+		}
+	}
+
+	if args.Len() == 0 {
+		return string(ctx.Path())
+	}
+
+	return fmt.Sprintf("%s?%s", ctx.Path(), args.QueryString())
+}
+
+func formatSampledHeaders(headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+
+	for key := range headers {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	formatted := make([]string, len(keys))
+
+	for i, key := range keys {
+		formatted[i] = fmt.Sprintf("%s: %s", key, headers[key])
+	}
+
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
@@ -6,17 +6,36 @@ import (
 	"github.com/valyala/fasthttp"
 )
 
-// StripPathMiddleware strips the first level of a path.
-func StripPathMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
-	return func(ctx *fasthttp.RequestCtx) {
-		uri := ctx.Request.RequestURI()
-		n := bytes.IndexByte(uri[1:], '/')
-
-		if n >= 0 {
-			uri = uri[n+1:]
-			ctx.Request.SetRequestURI(string(uri))
-		}
+// StripPathMiddleware strips the base path the portal is served under from the requests. The requests outside of the
+// base path are answered with a 404 so that every route is only served under the base path.
+func StripPathMiddleware(basePath string) func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	prefix := []byte(basePath)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			uri := ctx.Request.RequestURI()
+
+			if !bytes.HasPrefix(uri, prefix) {
+				ctx.NotFound()
+				return
+			}
 
-		next(ctx)
+			rest := uri[len(prefix):]
+
+			switch {
+			case len(rest) == 0:
+				ctx.Request.SetRequestURI("/")
+			case rest[0] == '/':
+				ctx.Request.SetRequestURIBytes(rest)
+			case rest[0] == '?':
+				ctx.Request.SetRequestURI("/" + string(rest))
+			default:
+				// The base path is only a prefix of the first segments, i.e. /authelia for the base path /auth.
+				ctx.NotFound()
+				return
+			}
+
+			next(ctx)
+		}
 	}
 }
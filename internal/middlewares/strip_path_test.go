@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestShouldStripBasePath(t *testing.T) {
+	testCases := []struct {
+		uri, expected string
+	}{
+		{"/auth/portal", "/"},
+		{"/auth/portal/", "/"},
+		{"/auth/portal?rd=https%3A%2F%2Fhome.example.com", "/?rd=https%3A%2F%2Fhome.example.com"},
+		{"/auth/portal/api/health", "/api/health"},
+		{"/auth/portal/static/js/main.js", "/static/js/main.js"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(tc.uri)
+
+			var uri string
+
+			StripPathMiddleware("/auth/portal")(func(ctx *fasthttp.RequestCtx) {
+				uri = string(ctx.Request.RequestURI())
+			})(ctx)
+
+			assert.Equal(t, tc.expected, uri)
+		})
+	}
+}
+
+func TestShouldNotServeRequestsOutsideOfBasePath(t *testing.T) {
+	for _, uri := range []string{"/api/health", "/auth/api/health", "/auth/portals/api/health", "/other/portal/api/health"} {
+		t.Run(uri, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(uri)
+
+			called := false
+
+			StripPathMiddleware("/auth/portal")(func(ctx *fasthttp.RequestCtx) {
+				called = true
+			})(ctx)
+
+			assert.False(t, called)
+			assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+		})
+	}
+}
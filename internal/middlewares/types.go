@@ -8,6 +8,7 @@ import (
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/authorization"
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/federation"
 	"github.com/authelia/authelia/internal/notification"
 	"github.com/authelia/authelia/internal/regulation"
 	"github.com/authelia/authelia/internal/session"
@@ -35,6 +36,11 @@ type Providers struct {
 	UserProvider    authentication.UserProvider
 	StorageProvider storage.Provider
 	Notifier        notification.Notifier
+
+	LastLoginRecorder *storage.LastLoginRecorder
+
+	OIDCUpstream  *federation.OIDCUpstreamProvider
+	TrustedHeader *federation.TrustedHeaderProvider
 }
 
 // RequestHandler represents an Authelia request handler.
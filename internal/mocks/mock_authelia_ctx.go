@@ -69,6 +69,7 @@ func NewMockAutheliaCtx(t *testing.T) *MockAutheliaCtx {
 	configuration := schema.Configuration{}
 	configuration.Session.RememberMeDuration = schema.DefaultSessionConfiguration.RememberMeDuration
 	configuration.Session.Name = "authelia_session"
+	configuration.IdentityVerification = schema.DefaultIdentityVerificationConfiguration
 	configuration.AccessControl.DefaultPolicy = "deny"
 	configuration.AccessControl.Rules = []schema.ACLRule{{
 		Domains: []string{"bypass.example.com"},
@@ -100,12 +101,13 @@ func NewMockAutheliaCtx(t *testing.T) *MockAutheliaCtx {
 
 	mockAuthelia.StorageProviderMock = storage.NewMockProvider(mockAuthelia.Ctrl)
 	providers.StorageProvider = mockAuthelia.StorageProviderMock
+	providers.LastLoginRecorder = storage.NewLastLoginRecorder(providers.StorageProvider, &mockAuthelia.Clock)
 
 	mockAuthelia.NotifierMock = NewMockNotifier(mockAuthelia.Ctrl)
 	providers.Notifier = mockAuthelia.NotifierMock
 
 	providers.Authorizer = authorization.NewAuthorizer(
-		configuration.AccessControl)
+		configuration.AccessControl, nil)
 
 	providers.SessionProvider = session.NewProvider(
 		configuration.Session, nil)
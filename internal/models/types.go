@@ -10,4 +10,70 @@ type AuthenticationAttempt struct {
 	Successful bool
 	// The time of the attempt.
 	Time time.Time
+	// The reservation of an attempt whose outcome is not known yet, it is recorded as failed until it is confirmed.
+	Reservation string
+}
+
+// PasswordHistoryEntry represent a password previously set by a user.
+type PasswordHistoryEntry struct {
+	// The user who set the password.
+	Username string
+	// The salted hash of the password.
+	Hash string
+	// The time the password was set.
+	Time time.Time
+}
+
+// PendingRegistration represent an account registered by a user which is not created yet.
+type PendingRegistration struct {
+	// The username of the account.
+	Username string
+	// The display name of the account.
+	DisplayName string
+	// The email address of the account.
+	Email string
+	// The salted hash of the password of the account.
+	Hash string
+	// Verified true once the user verified their email address.
+	Verified bool
+	// The time the account was registered.
+	Time time.Time
+}
+
+// LastLogin represent the last successful authentication of a user.
+type LastLogin struct {
+	// The user who authenticated.
+	Username string
+	// The IP address the user authenticated from.
+	IP string
+	// The authentication method, i.e. password, totp, u2f, mobile_push, oidc_upstream or trusted_header.
+	Method string
+	// The time of the authentication.
+	Time time.Time
+}
+
+// LoginDevice represent a device and IP address a user successfully logged in from.
+type LoginDevice struct {
+	// The user who logged in.
+	Username string
+	// The fingerprint of the device derived from its user agent.
+	Fingerprint string
+	// The IP address the user logged in from.
+	IP string
+	// The time the device was first seen with this IP address.
+	Time time.Time
+}
+
+// SecondFactorEnrollment represent the second factor devices and preferences of a user.
+type SecondFactorEnrollment struct {
+	// The enrolled user.
+	Username string
+	// The preferred second factor method of the user, empty if they never chose one.
+	PreferredMethod string
+	// The TOTP secret of the user in clear, empty if they did not register a TOTP device.
+	TOTPSecret string
+	// The key handle of the U2F device of the user, nil if they did not register a U2F device.
+	U2FKeyHandle []byte
+	// The public key of the U2F device of the user, nil if they did not register a U2F device.
+	U2FPublicKey []byte
 }
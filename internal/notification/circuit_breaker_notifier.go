@@ -0,0 +1,32 @@
+package notification
+
+import (
+	"github.com/authelia/authelia/internal/circuitbreaker"
+)
+
+// CircuitBreakerNotifier a notifier sending the notifications through a circuit breaker in order to fail fast while
+// the underlying notifier is unavailable.
+type CircuitBreakerNotifier struct {
+	notifier Notifier
+	breaker  *circuitbreaker.CircuitBreaker
+}
+
+// NewCircuitBreakerNotifier create a CircuitBreakerNotifier wrapping the given notifier.
+func NewCircuitBreakerNotifier(notifier Notifier, breaker *circuitbreaker.CircuitBreaker) *CircuitBreakerNotifier {
+	return &CircuitBreakerNotifier{
+		notifier: notifier,
+		breaker:  breaker,
+	}
+}
+
+// StartupCheck checks the underlying notifier, the result is not recorded by the circuit breaker.
+func (n *CircuitBreakerNotifier) StartupCheck() (bool, error) {
+	return n.notifier.StartupCheck()
+}
+
+// Send a notification with the underlying notifier unless the circuit breaker is open.
+func (n *CircuitBreakerNotifier) Send(recipient, subject, body, htmlBody string) error {
+	return n.breaker.Execute(func() error {
+		return n.notifier.Send(recipient, subject, body, htmlBody)
+	})
+}
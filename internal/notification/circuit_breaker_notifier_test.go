@@ -0,0 +1,42 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/circuitbreaker"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+type failingNotifier struct {
+	sent int
+}
+
+func (n *failingNotifier) StartupCheck() (bool, error) {
+	return true, nil
+}
+
+func (n *failingNotifier) Send(recipient, subject, body, htmlBody string) error {
+	n.sent++
+	return errors.New("connection refused")
+}
+
+func TestShouldFailFastWhenNotifierCircuitBreakerIsOpen(t *testing.T) {
+	underlying := &failingNotifier{}
+	notifier := NewCircuitBreakerNotifier(underlying, circuitbreaker.NewCircuitBreaker("notifier",
+		&schema.CircuitBreakerConfiguration{FailureThreshold: 1, Cooldown: "1m"}, utils.RealClock{}))
+
+	assert.EqualError(t, notifier.Send("john@example.com", "subject", "body", ""), "connection refused")
+
+	err := notifier.Send("john@example.com", "subject", "body", "")
+	assert.EqualError(t, err, "notifier is unavailable: circuit breaker is open")
+	assert.Equal(t, 1, underlying.sent)
+
+	ok, err := notifier.StartupCheck()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+}
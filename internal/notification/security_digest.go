@@ -0,0 +1,316 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// securityDigestTopOffenders is the number of subjects with the most events listed for each type of event.
+const securityDigestTopOffenders = 5
+
+// securityDigestMaxSubjects is the number of distinct subjects counted for each type of event, the events of the other
+// subjects only being counted in the total so that the memory used is bounded.
+const securityDigestMaxSubjects = 10000
+
+const securityDigestTitle = "Security digest"
+const securityAlertTitle = "Security alert"
+
+// securityEventTypes are the types of the security events in the order they are reported.
+var securityEventTypes = []string{
+	schema.SecurityEventBan,
+	schema.SecurityEventAuthenticationFailure,
+	schema.SecurityEventNotificationFailure,
+}
+
+// SecurityEvent is an operational security event, such as a ban or a failed notification.
+type SecurityEvent struct {
+	Type    string    `json:"type"`
+	Subject string    `json:"subject"`
+	Detail  string    `json:"detail,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// SecurityDigestReport summarizes the security events of a period.
+type SecurityDigestReport struct {
+	From   time.Time              `json:"from"`
+	To     time.Time              `json:"to"`
+	Events []SecurityEventSummary `json:"events"`
+}
+
+// SecurityEventSummary is the number of events of a type and the subjects with the most events.
+type SecurityEventSummary struct {
+	Type         string                  `json:"type"`
+	Count        int                     `json:"count"`
+	TopOffenders []SecurityEventOffender `json:"top_offenders"`
+}
+
+// SecurityEventOffender is the number of events of a subject.
+type SecurityEventOffender struct {
+	Subject string `json:"subject"`
+	Count   int    `json:"count"`
+}
+
+type securityDigestWebhookBody struct {
+	Kind   string                `json:"kind"`
+	Digest *SecurityDigestReport `json:"digest,omitempty"`
+	Event  *SecurityEvent        `json:"event,omitempty"`
+}
+
+// SecurityDigest batches the security events into a digest sent periodically to the administrators by email and/or to
+// a webhook instead of alerting them of each event. The events of the immediate types are alerted on as well.
+type SecurityDigest struct {
+	notifier   Notifier
+	recipients []string
+	webhookURL string
+	client     *http.Client
+	interval   time.Duration
+	immediate  []string
+	clock      utils.Clock
+	log        *logrus.Logger
+
+	mutex    sync.Mutex
+	since    time.Time
+	totals   map[string]int
+	subjects map[string]map[string]int
+}
+
+// NewSecurityDigest creates a digest of the given configuration sending the emails with the given notifier.
+func NewSecurityDigest(configuration schema.SecurityDigestConfiguration, notifier Notifier, certPool *x509.CertPool, clock utils.Clock) *SecurityDigest {
+	// Ignore the errors as the durations are checked by the validator.
+	interval, _ := utils.ParseDurationString(configuration.Interval)
+	timeout, _ := utils.ParseDurationString(configuration.Timeout)
+
+	return &SecurityDigest{
+		notifier:   notifier,
+		recipients: configuration.Recipients,
+		webhookURL: configuration.WebhookURL,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12},
+			},
+		},
+		interval:  interval,
+		immediate: configuration.ImmediateEvents,
+		clock:     clock,
+		log:       logging.Logger(),
+		since:     clock.Now(),
+		totals:    map[string]int{},
+		subjects:  map[string]map[string]int{},
+	}
+}
+
+// Record records a security event in the next digest and alerts on it right away if its type is immediate.
+func (d *SecurityDigest) Record(event SecurityEvent) {
+	if event.Time.IsZero() {
+		event.Time = d.clock.Now()
+	}
+
+	d.mutex.Lock()
+
+	d.totals[event.Type]++
+
+	subjects, ok := d.subjects[event.Type]
+	if !ok {
+		subjects = map[string]int{}
+		d.subjects[event.Type] = subjects
+	}
+
+	if _, ok = subjects[event.Subject]; ok || len(subjects) < securityDigestMaxSubjects {
+		subjects[event.Subject]++
+	}
+
+	d.mutex.Unlock()
+
+	if utils.IsStringInSlice(event.Type, d.immediate) {
+		go d.alert(event)
+	}
+}
+
+// AuthenticationFailed records a failed authentication attempt of a user.
+func (d *SecurityDigest) AuthenticationFailed(username string) {
+	d.Record(SecurityEvent{Type: schema.SecurityEventAuthenticationFailure, Subject: username})
+}
+
+// UserBanned records the ban of a user by the regulation.
+func (d *SecurityDigest) UserBanned(username string, bannedUntil time.Time) {
+	d.Record(SecurityEvent{
+		Type:    schema.SecurityEventBan,
+		Subject: username,
+		Detail:  fmt.Sprintf("banned until %s", bannedUntil.Format(rfc5322DateTimeLayout)),
+	})
+}
+
+// Notifier returns a notifier sending the notifications with the given one and recording its failures in the digest.
+func (d *SecurityDigest) Notifier(notifier Notifier) Notifier {
+	return &securityDigestNotifier{notifier: notifier, digest: d}
+}
+
+// Flush sends the digest of the events recorded since the last flush and returns it. Nothing is sent and nil is
+// returned if no event was recorded. The events are not recorded again if the digest can't be sent.
+func (d *SecurityDigest) Flush() (*SecurityDigestReport, error) {
+	d.mutex.Lock()
+	report := &SecurityDigestReport{From: d.since, To: d.clock.Now()}
+	totals, subjects := d.totals, d.subjects
+	d.since, d.totals, d.subjects = report.To, map[string]int{}, map[string]map[string]int{}
+	d.mutex.Unlock()
+
+	for _, eventType := range securityEventTypes {
+		if totals[eventType] == 0 {
+			continue
+		}
+
+		report.Events = append(report.Events, SecurityEventSummary{
+			Type:         eventType,
+			Count:        totals[eventType],
+			TopOffenders: topOffenders(subjects[eventType]),
+		})
+	}
+
+	if len(report.Events) == 0 {
+		return nil, nil
+	}
+
+	return report, d.send(securityDigestTitle, formatSecurityDigestReport(report),
+		securityDigestWebhookBody{Kind: "digest", Digest: report})
+}
+
+// Start sends the digests periodically in the background.
+func (d *SecurityDigest) Start() {
+	go func() {
+		for {
+			<-d.clock.After(d.interval)
+
+			if _, err := d.Flush(); err != nil {
+				d.log.Errorf("Unable to send the security digest: %s", err)
+			}
+		}
+	}()
+}
+
+func (d *SecurityDigest) alert(event SecurityEvent) {
+	body := fmt.Sprintf("Security event %s of %s at %s.\n", event.Type, event.Subject, event.Time.Format(rfc5322DateTimeLayout))
+	if event.Detail != "" {
+		body += fmt.Sprintf("\n%s\n", event.Detail)
+	}
+
+	if err := d.send(securityAlertTitle, body, securityDigestWebhookBody{Kind: "alert", Event: &event}); err != nil {
+		d.log.Errorf("Unable to send the security alert of the %s event of %s: %s", event.Type, event.Subject, err)
+	}
+}
+
+// send sends the text to the recipients and the payload to the webhook, returning the errors of all the attempts.
+func (d *SecurityDigest) send(title, body string, payload securityDigestWebhookBody) error {
+	var errs []string
+
+	for _, recipient := range d.recipients {
+		if err := d.notifier.Send(recipient, title, body, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("email to %s: %s", recipient, err))
+		}
+	}
+
+	if d.webhookURL != "" {
+		if err := d.post(payload); err != nil {
+			errs = append(errs, fmt.Sprintf("webhook: %s", err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+func (d *SecurityDigest) post(payload securityDigestWebhookBody) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.client.Post(d.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook replied with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// topOffenders returns the subjects with the most events, the subjects with as many events being sorted by name.
+func topOffenders(subjects map[string]int) []SecurityEventOffender {
+	offenders := make([]SecurityEventOffender, 0, len(subjects))
+	for subject, count := range subjects {
+		offenders = append(offenders, SecurityEventOffender{Subject: subject, Count: count})
+	}
+
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Count != offenders[j].Count {
+			return offenders[i].Count > offenders[j].Count
+		}
+
+		return offenders[i].Subject < offenders[j].Subject
+	})
+
+	if len(offenders) > securityDigestTopOffenders {
+		offenders = offenders[:securityDigestTopOffenders]
+	}
+
+	return offenders
+}
+
+func formatSecurityDigestReport(report *SecurityDigestReport) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Security events from %s to %s:\n", report.From.Format(rfc5322DateTimeLayout), report.To.Format(rfc5322DateTimeLayout))
+
+	for _, summary := range report.Events {
+		fmt.Fprintf(&b, "\n%s: %d\n", summary.Type, summary.Count)
+
+		for _, offender := range summary.TopOffenders {
+			fmt.Fprintf(&b, "  %s: %d\n", offender.Subject, offender.Count)
+		}
+	}
+
+	return b.String()
+}
+
+// securityDigestNotifier records the failures of the notifier it wraps in the digest.
+type securityDigestNotifier struct {
+	notifier Notifier
+	digest   *SecurityDigest
+}
+
+// StartupCheck checks the underlying notifier, a failure is not recorded in the digest.
+func (n *securityDigestNotifier) StartupCheck() (bool, error) {
+	return n.notifier.StartupCheck()
+}
+
+// Send a notification with the underlying notifier, recording the failure in the digest.
+func (n *securityDigestNotifier) Send(recipient, subject, body, htmlBody string) error {
+	err := n.notifier.Send(recipient, subject, body, htmlBody)
+	if err != nil {
+		n.digest.Record(SecurityEvent{Type: schema.SecurityEventNotificationFailure, Subject: recipient, Detail: err.Error()})
+	}
+
+	return err
+}
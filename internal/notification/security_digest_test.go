@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+type sentNotification struct {
+	recipient, subject, body string
+}
+
+type recordingNotifier struct {
+	mutex sync.Mutex
+	sent  []sentNotification
+}
+
+func (n *recordingNotifier) StartupCheck() (bool, error) {
+	return true, nil
+}
+
+func (n *recordingNotifier) Send(recipient, subject, body, htmlBody string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.sent = append(n.sent, sentNotification{recipient, subject, body})
+
+	return nil
+}
+
+func TestShouldSendSecurityDigestWithCountsAndTopOffenders(t *testing.T) {
+	notifier := &recordingNotifier{}
+	digest := NewSecurityDigest(schema.SecurityDigestConfiguration{
+		Interval:   "1d",
+		Timeout:    "5s",
+		Recipients: []string{"ops@example.com"},
+	}, notifier, nil, utils.RealClock{})
+
+	for _, username := range []string{"john", "bob", "john", "alice", "john", "bob", "carol", "dave", "eve"} {
+		digest.AuthenticationFailed(username)
+	}
+
+	digest.UserBanned("john", time.Now().Add(5*time.Minute))
+
+	report, err := digest.Flush()
+	require.NoError(t, err)
+	require.NotNil(t, report)
+
+	require.Len(t, report.Events, 2)
+	assert.Equal(t, SecurityEventSummary{
+		Type:         schema.SecurityEventBan,
+		Count:        1,
+		TopOffenders: []SecurityEventOffender{{Subject: "john", Count: 1}},
+	}, report.Events[0])
+	assert.Equal(t, SecurityEventSummary{
+		Type:  schema.SecurityEventAuthenticationFailure,
+		Count: 9,
+		TopOffenders: []SecurityEventOffender{
+			{Subject: "john", Count: 3},
+			{Subject: "bob", Count: 2},
+			{Subject: "alice", Count: 1},
+			{Subject: "carol", Count: 1},
+			{Subject: "dave", Count: 1},
+		},
+	}, report.Events[1])
+
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, "ops@example.com", notifier.sent[0].recipient)
+	assert.Equal(t, "Security digest", notifier.sent[0].subject)
+	assert.Contains(t, notifier.sent[0].body, "\nban: 1\n  john: 1\n")
+	assert.Contains(t, notifier.sent[0].body, "\nauthentication_failure: 9\n  john: 3\n  bob: 2\n")
+
+	// The events are only reported once and no empty digest is sent.
+	report, err = digest.Flush()
+	assert.NoError(t, err)
+	assert.Nil(t, report)
+	assert.Len(t, notifier.sent, 1)
+}
+
+func TestShouldPostSecurityDigestAndAlertsToWebhook(t *testing.T) {
+	bodies := make(chan securityDigestWebhookBody, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body securityDigestWebhookBody
+
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		bodies <- body
+	}))
+	defer server.Close()
+
+	digest := NewSecurityDigest(schema.SecurityDigestConfiguration{
+		Interval:        "1d",
+		Timeout:         "5s",
+		WebhookURL:      server.URL,
+		ImmediateEvents: []string{schema.SecurityEventBan},
+	}, &recordingNotifier{}, nil, utils.RealClock{})
+
+	digest.AuthenticationFailed("john")
+	digest.UserBanned("john", time.Now().Add(5*time.Minute))
+
+	select {
+	case body := <-bodies:
+		assert.Equal(t, "alert", body.Kind)
+		require.NotNil(t, body.Event)
+		assert.Equal(t, schema.SecurityEventBan, body.Event.Type)
+		assert.Equal(t, "john", body.Event.Subject)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the ban was not alerted")
+	}
+
+	_, err := digest.Flush()
+	require.NoError(t, err)
+
+	body := <-bodies
+	assert.Equal(t, "digest", body.Kind)
+	require.NotNil(t, body.Digest)
+	assert.Len(t, body.Digest.Events, 2)
+}
+
+func TestShouldReturnErrorWhenSecurityDigestWebhookFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	digest := NewSecurityDigest(schema.SecurityDigestConfiguration{
+		Interval:   "1d",
+		Timeout:    "5s",
+		WebhookURL: server.URL,
+	}, &recordingNotifier{}, nil, utils.RealClock{})
+
+	digest.AuthenticationFailed("john")
+
+	_, err := digest.Flush()
+	assert.EqualError(t, err, "webhook: webhook replied with status 500")
+}
+
+func TestShouldRecordNotificationFailuresInSecurityDigest(t *testing.T) {
+	underlying := &failingNotifier{}
+	digest := NewSecurityDigest(schema.SecurityDigestConfiguration{
+		Interval:   "1d",
+		Timeout:    "5s",
+		Recipients: []string{"ops@example.com"},
+	}, &recordingNotifier{}, nil, utils.RealClock{})
+
+	notifier := digest.Notifier(underlying)
+
+	assert.EqualError(t, notifier.Send("john@example.com", "subject", "body", ""), "connection refused")
+
+	report, err := digest.Flush()
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, []SecurityEventSummary{{
+		Type:         schema.SecurityEventNotificationFailure,
+		Count:        1,
+		TopOffenders: []SecurityEventOffender{{Subject: "john@example.com", Count: 1}},
+	}}, report.Events)
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"net/mail"
 	"net/smtp"
 	"strings"
 	"time"
@@ -19,6 +20,7 @@ type SMTPNotifier struct {
 	username            string
 	password            string
 	sender              string
+	envelopeSender      string
 	identifier          string
 	host                string
 	port                int
@@ -36,6 +38,7 @@ func NewSMTPNotifier(configuration schema.SMTPNotifierConfiguration, certPool *x
 		username:            configuration.Username,
 		password:            configuration.Password,
 		sender:              configuration.Sender,
+		envelopeSender:      configuration.Sender,
 		identifier:          configuration.Identifier,
 		host:                configuration.Host,
 		port:                configuration.Port,
@@ -46,6 +49,11 @@ func NewSMTPNotifier(configuration schema.SMTPNotifierConfiguration, certPool *x
 		tlsConfig:           utils.NewTLSConfig(configuration.TLS, tls.VersionTLS12, certPool),
 	}
 
+	// The sender may include a display name which is only suitable for the From header.
+	if address, err := mail.ParseAddress(configuration.Sender); err == nil {
+		notifier.envelopeSender = address.Address
+	}
+
 	return notifier
 }
 
@@ -250,7 +258,7 @@ func (n *SMTPNotifier) StartupCheck() (bool, error) {
 		return false, err
 	}
 
-	if err := n.client.Mail(n.sender); err != nil {
+	if err := n.client.Mail(n.envelopeSender); err != nil {
 		return false, err
 	}
 
@@ -291,7 +299,7 @@ func (n *SMTPNotifier) Send(recipient, title, body, htmlBody string) error {
 	}
 
 	// Set the sender and recipient first.
-	if err := n.client.Mail(n.sender); err != nil {
+	if err := n.client.Mail(n.envelopeSender); err != nil {
 		logger.Debugf("Notifier SMTP failed while sending MAIL FROM (using sender) with error: %s", err)
 		return err
 	}
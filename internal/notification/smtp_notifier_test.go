@@ -55,3 +55,15 @@ func TestShouldConfigureSMTPNotifierWithServerNameOverrideAndDefaultTLS12(t *tes
 	assert.False(t, notifier.tlsConfig.InsecureSkipVerify)
 	assert.Equal(t, "smtp.example.com:25", notifier.address)
 }
+
+func TestShouldUseAddressOfSenderWithDisplayNameAsEnvelopeSender(t *testing.T) {
+	notifier := NewSMTPNotifier(schema.SMTPNotifierConfiguration{
+		Host:   "smtp.example.com",
+		Port:   25,
+		Sender: "Authelia <admin@example.com>",
+		TLS:    schema.DefaultSMTPNotifierConfiguration.TLS,
+	}, nil)
+
+	assert.Equal(t, "Authelia <admin@example.com>", notifier.sender)
+	assert.Equal(t, "admin@example.com", notifier.envelopeSender)
+}
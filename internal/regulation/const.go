@@ -4,3 +4,6 @@ import "fmt"
 
 // ErrUserIsBanned user is banned error message.
 var ErrUserIsBanned = fmt.Errorf("User is banned")
+
+// reservationBytes is the number of random bytes identifying a reserved authentication attempt.
+const reservationBytes = 24
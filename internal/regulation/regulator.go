@@ -1,6 +1,8 @@
 package regulation
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"time"
 
@@ -30,6 +32,18 @@ func NewRegulator(configuration *schema.RegulationConfiguration, provider storag
 			panic(fmt.Errorf("find_time cannot be greater than ban_time"))
 		}
 
+		if configuration.DelayIncrement != "" {
+			regulator.delayIncrement, err = utils.ParseDurationString(configuration.DelayIncrement)
+			if err != nil {
+				panic(err)
+			}
+
+			regulator.maxDelay, err = utils.ParseDurationString(configuration.MaxDelay)
+			if err != nil {
+				panic(err)
+			}
+		}
+
 		// Set regulator enabled only if MaxRetries is not 0.
 		regulator.enabled = configuration.MaxRetries > 0
 		regulator.maxRetries = configuration.MaxRetries
@@ -40,20 +54,164 @@ func NewRegulator(configuration *schema.RegulationConfiguration, provider storag
 	return regulator
 }
 
+// SetObserver sets the observer notified of the failed authentication attempts and of the bans they cause.
+func (r *Regulator) SetObserver(observer Observer) {
+	r.observer = observer
+}
+
 // Mark mark an authentication attempt.
 // We split Mark and Regulate in order to avoid timing attacks.
 func (r *Regulator) Mark(username string, successful bool) error {
-	return r.storageProvider.AppendAuthenticationLog(models.AuthenticationAttempt{
+	err := r.storageProvider.AppendAuthenticationLog(models.AuthenticationAttempt{
 		Username:   username,
 		Successful: successful,
 		Time:       r.clock.Now(),
 	})
+	if err != nil {
+		return err
+	}
+
+	if !successful {
+		r.observeFailure(username)
+	}
+
+	return nil
+}
+
+// observeFailure notifies the observer of a failed attempt and, if this attempt bans the user, of the ban.
+func (r *Regulator) observeFailure(username string) {
+	if r.observer == nil {
+		return
+	}
+
+	r.observer.AuthenticationFailed(username)
+
+	if bannedUntil, err := r.Regulate(username); err == ErrUserIsBanned {
+		r.observer.UserBanned(username, bannedUntil)
+	}
+}
+
+// Reset lifts the ban of a user by removing its failed authentication attempts. The number of removed attempts is
+// returned.
+func (r *Regulator) Reset(username string) (int, error) {
+	count, err := r.storageProvider.DeleteFailedAuthenticationLogs(username)
+	if err != nil {
+		return count, fmt.Errorf("Unable to remove the failed authentication attempts: %s", err)
+	}
+
+	return count, nil
+}
+
+// Reserve records an authentication attempt of a user as failed before its outcome is known and regulates the user
+// taking into account the attempts reserved concurrently by other instances sharing the storage, so no more than the
+// maximum number of retries can be attempted within the find time even by concurrent requests. The reservation must be
+// given to Confirm once the outcome is known. If the user is banned, the attempt is removed and ErrUserIsBanned is
+// returned along with the time until when the user is banned. No attempt is reserved if the regulation is disabled.
+func (r *Regulator) Reserve(username string) (reservation string, bannedUntil time.Time, err error) {
+	if !r.enabled {
+		return "", time.Time{}, nil
+	}
+
+	reservation, err = newReservation()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = r.storageProvider.AppendAuthenticationLog(models.AuthenticationAttempt{
+		Username:    username,
+		Successful:  false,
+		Time:        r.clock.Now(),
+		Reservation: reservation,
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("Unable to reserve the authentication attempt: %s", err)
+	}
+
+	bannedUntil, err = r.regulate(username, reservation)
+	if err == ErrUserIsBanned {
+		// The attempt is not made, it must not extend the ban.
+		if deleteErr := r.storageProvider.DeleteAuthenticationLog(username, reservation); deleteErr != nil {
+			return "", bannedUntil, fmt.Errorf("Unable to remove the reserved authentication attempt: %s", deleteErr)
+		}
+
+		return "", bannedUntil, err
+	}
+
+	return reservation, bannedUntil, err
+}
+
+// newReservation returns a random identifier for a reserved authentication attempt, unique across the instances.
+func newReservation() (string, error) {
+	b := make([]byte, reservationBytes)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Unable to generate the reservation of the authentication attempt: %s", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Confirm records the outcome of an authentication attempt reserved with Reserve. If the reservation is empty because
+// it failed, the attempt is marked instead.
+func (r *Regulator) Confirm(username, reservation string, successful bool) error {
+	if reservation == "" {
+		return r.Mark(username, successful)
+	}
+
+	if err := r.storageProvider.ConfirmAuthenticationLog(username, reservation, successful); err != nil {
+		return err
+	}
+
+	if !successful {
+		r.observeFailure(username)
+	}
+
+	return nil
+}
+
+// Delay returns how long the authentication attempt of a user must be delayed: the delay increment for each failed
+// attempt made within the find time since the last successful one, up to the maximum delay. The attempt with the given
+// reservation, if any, is not counted.
+func (r *Regulator) Delay(username, reservation string) (time.Duration, error) {
+	if r.delayIncrement <= 0 || r.maxDelay <= 0 {
+		return 0, nil
+	}
+
+	attempts, err := r.storageProvider.LoadLatestAuthenticationLogs(username, r.clock.Now().Add(-r.findTime))
+	if err != nil {
+		return 0, fmt.Errorf("Unable to load the authentication logs: %s", err)
+	}
+
+	delay := time.Duration(0)
+
+	for _, attempt := range attempts {
+		if reservation != "" && attempt.Reservation == reservation {
+			continue
+		}
+
+		if attempt.Successful {
+			break
+		}
+
+		delay += r.delayIncrement
+
+		if delay >= r.maxDelay {
+			return r.maxDelay, nil
+		}
+	}
+
+	return delay, nil
 }
 
 // Regulate regulate the authentication attempts for a given user.
 // This method returns ErrUserIsBanned if the user is banned along with the time until when
 // the user is banned.
 func (r *Regulator) Regulate(username string) (time.Time, error) {
+	return r.regulate(username, "")
+}
+
+// regulate regulates the authentication attempts of a user ignoring the attempt with the given reservation, if any.
+func (r *Regulator) regulate(username, reservation string) (time.Time, error) {
 	// If there is regulation configuration, no regulation applies.
 	if !r.enabled {
 		return time.Time{}, nil
@@ -65,12 +223,16 @@ func (r *Regulator) Regulate(username string) (time.Time, error) {
 	attempts, err := r.storageProvider.LoadLatestAuthenticationLogs(username, now.Add(-r.banTime))
 
 	if err != nil {
-		return time.Time{}, nil
+		return time.Time{}, fmt.Errorf("Unable to load the authentication logs: %s", err)
 	}
 
 	latestFailedAttempts := make([]models.AuthenticationAttempt, 0, r.maxRetries)
 
 	for _, attempt := range attempts {
+		if reservation != "" && attempt.Reservation == reservation {
+			continue
+		}
+
 		if attempt.Successful || len(latestFailedAttempts) >= r.maxRetries {
 			// We stop appending failed attempts once we find the first successful attempts or we reach
 			// the configured number of retries, meaning the user is already banned.
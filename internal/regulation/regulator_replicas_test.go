@@ -0,0 +1,126 @@
+package regulation_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// sharedStorage is an in-memory storage of the authentication logs shared by several regulators, as a SQL database
+// is shared by several instances of Authelia.
+type sharedStorage struct {
+	storage.Provider
+
+	mutex    sync.Mutex
+	attempts []models.AuthenticationAttempt
+}
+
+func (s *sharedStorage) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempts = append(s.attempts, attempt)
+
+	return nil
+}
+
+func (s *sharedStorage) LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var attempts []models.AuthenticationAttempt
+
+	// The clock is frozen so the latest attempts are the last appended ones.
+	for i := len(s.attempts) - 1; i >= 0; i-- {
+		if s.attempts[i].Username == username && s.attempts[i].Time.After(fromDate) {
+			attempts = append(attempts, s.attempts[i])
+		}
+	}
+
+	return attempts, nil
+}
+
+func (s *sharedStorage) ConfirmAuthenticationLog(username, reservation string, successful bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.attempts {
+		if s.attempts[i].Username == username && s.attempts[i].Reservation == reservation {
+			s.attempts[i].Successful = successful
+			s.attempts[i].Reservation = ""
+		}
+	}
+
+	return nil
+}
+
+func (s *sharedStorage) DeleteAuthenticationLog(username, reservation string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.attempts {
+		if s.attempts[i].Username == username && s.attempts[i].Reservation == reservation {
+			s.attempts = append(s.attempts[:i], s.attempts[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func TestShouldNotAllowMoreThanMaxRetriesAcrossReplicas(t *testing.T) {
+	configuration := schema.RegulationConfiguration{
+		MaxRetries: 3,
+		FindTime:   "2m",
+		BanTime:    "5m",
+	}
+
+	clock := &mocks.TestingClock{}
+	clock.Set(time.Now())
+
+	provider := &sharedStorage{}
+	replicas := []*regulation.Regulator{
+		regulation.NewRegulator(&configuration, provider, clock),
+		regulation.NewRegulator(&configuration, provider, clock),
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		allowed int
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(regulator *regulation.Regulator) {
+			defer wg.Done()
+
+			reservation, _, err := regulator.Reserve("john")
+			if err != nil {
+				assert.Equal(t, regulation.ErrUserIsBanned, err)
+				return
+			}
+
+			mutex.Lock()
+			allowed++
+			mutex.Unlock()
+
+			// Every password is wrong.
+			assert.NoError(t, regulator.Confirm("john", reservation, false))
+		}(replicas[i%len(replicas)])
+	}
+
+	wg.Wait()
+
+	assert.LessOrEqual(t, allowed, configuration.MaxRetries)
+	assert.Len(t, provider.attempts, allowed)
+}
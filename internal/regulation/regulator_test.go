@@ -1,6 +1,7 @@
 package regulation_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -32,6 +33,7 @@ func (s *RegulatorSuite) SetupTest() {
 		MaxRetries: 3,
 		BanTime:    "180",
 		FindTime:   "30",
+		MaxDelay:   "10s",
 	}
 	s.clock.Set(time.Now())
 }
@@ -59,6 +61,17 @@ func (s *RegulatorSuite) TestShouldNotThrowWhenUserIsLegitimate() {
 	assert.NoError(s.T(), err)
 }
 
+func (s *RegulatorSuite) TestShouldReturnErrorWhenAuthenticationLogsCannotBeLoaded() {
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		Return(nil, fmt.Errorf("connection refused"))
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Regulate("john")
+	assert.EqualError(s.T(), err, "Unable to load the authentication logs: connection refused")
+}
+
 // This test checks the case in which a user failed to authenticate many times but always
 // with a certain amount of time larger than FindTime. Meaning the user should not be banned.
 func (s *RegulatorSuite) TestShouldNotThrowWhenFailedAuthenticationNotInFindTime() {
@@ -251,6 +264,273 @@ func (s *RegulatorSuite) TestShouldCheckRegulationHasBeenResetOnSuccessfulAttemp
 	assert.NoError(s.T(), err)
 }
 
+func (s *RegulatorSuite) TestShouldResetUserByRemovingFailedAttempts() {
+	s.storageMock.EXPECT().
+		DeleteFailedAuthenticationLogs(gomock.Eq("john")).
+		Return(3, nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	count, err := regulator.Reset("john")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, count)
+}
+
+func (s *RegulatorSuite) TestShouldFailToResetUserWhenStorageFails() {
+	s.storageMock.EXPECT().
+		DeleteFailedAuthenticationLogs(gomock.Eq("john")).
+		Return(0, fmt.Errorf("failed"))
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Reset("john")
+	assert.EqualError(s.T(), err, "Unable to remove the failed authentication attempts: failed")
+}
+
+func (s *RegulatorSuite) TestShouldReserveAndConfirmAttempt() {
+	var reserved models.AuthenticationAttempt
+
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		DoAndReturn(func(attempt models.AuthenticationAttempt) error {
+			reserved = attempt
+			return nil
+		})
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		DoAndReturn(func(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+			return []models.AuthenticationAttempt{
+				reserved,
+				{Username: "john", Successful: false, Time: s.clock.Now().Add(-10 * time.Second)},
+				{Username: "john", Successful: false, Time: s.clock.Now().Add(-20 * time.Second)},
+			}, nil
+		})
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	reservation, _, err := regulator.Reserve("john")
+	s.Require().NoError(err)
+
+	s.Assert().Equal(reservation, reserved.Reservation)
+	s.Assert().False(reserved.Successful)
+	s.Assert().Equal(s.clock.Now(), reserved.Time)
+
+	s.storageMock.EXPECT().
+		ConfirmAuthenticationLog(gomock.Eq("john"), gomock.Eq(reservation), gomock.Eq(true)).
+		Return(nil)
+
+	s.Assert().NoError(regulator.Confirm("john", reservation, true))
+}
+
+func (s *RegulatorSuite) TestShouldRemoveReservationWhenUserIsBanned() {
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		Return([]models.AuthenticationAttempt{
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-10 * time.Second)},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-20 * time.Second)},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-25 * time.Second)},
+		}, nil)
+
+	s.storageMock.EXPECT().
+		DeleteAuthenticationLog(gomock.Eq("john"), gomock.Any()).
+		Return(nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	reservation, bannedUntil, err := regulator.Reserve("john")
+
+	s.Assert().Equal(regulation.ErrUserIsBanned, err)
+	s.Assert().Equal("", reservation)
+	s.Assert().Equal(s.clock.Now().Add(-10*time.Second).Add(180*time.Second), bannedUntil)
+}
+
+func (s *RegulatorSuite) TestShouldReturnErrorWhenAttemptCannotBeReserved() {
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(fmt.Errorf("connection refused"))
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	reservation, _, err := regulator.Reserve("john")
+
+	s.Assert().EqualError(err, "Unable to reserve the authentication attempt: connection refused")
+	s.Assert().Equal("", reservation)
+}
+
+func (s *RegulatorSuite) TestShouldMarkAttemptWhenConfirmingWithoutReservation() {
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   "john",
+			Successful: false,
+			Time:       s.clock.Now(),
+		})).
+		Return(nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	s.Assert().NoError(regulator.Confirm("john", "", false))
+}
+
+type recordingObserver struct {
+	failures []string
+	bans     map[string]time.Time
+}
+
+func (o *recordingObserver) AuthenticationFailed(username string) {
+	o.failures = append(o.failures, username)
+}
+
+func (o *recordingObserver) UserBanned(username string, bannedUntil time.Time) {
+	if o.bans == nil {
+		o.bans = map[string]time.Time{}
+	}
+
+	o.bans[username] = bannedUntil
+}
+
+func (s *RegulatorSuite) TestShouldNotifyObserverOfFailedAttemptBanningUser() {
+	s.storageMock.EXPECT().
+		ConfirmAuthenticationLog(gomock.Eq("john"), gomock.Eq("reservation"), gomock.Eq(false)).
+		Return(nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		Return([]models.AuthenticationAttempt{
+			{Username: "john", Successful: false, Time: s.clock.Now()},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-10 * time.Second)},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-20 * time.Second)},
+		}, nil)
+
+	observer := &recordingObserver{}
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+	regulator.SetObserver(observer)
+
+	s.Require().NoError(regulator.Confirm("john", "reservation", false))
+
+	s.Assert().Equal([]string{"john"}, observer.failures)
+	s.Assert().Equal(map[string]time.Time{"john": s.clock.Now().Add(180 * time.Second)}, observer.bans)
+}
+
+func (s *RegulatorSuite) TestShouldNotifyObserverOfFailedAttemptNotBanningUser() {
+	s.storageMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		Return([]models.AuthenticationAttempt{
+			{Username: "john", Successful: false, Time: s.clock.Now()},
+		}, nil)
+
+	observer := &recordingObserver{}
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+	regulator.SetObserver(observer)
+
+	s.Require().NoError(regulator.Mark("john", false))
+
+	s.Assert().Equal([]string{"john"}, observer.failures)
+	s.Assert().Len(observer.bans, 0)
+}
+
+func (s *RegulatorSuite) TestShouldNotNotifyObserverOfSuccessfulAttempt() {
+	s.storageMock.EXPECT().
+		ConfirmAuthenticationLog(gomock.Eq("john"), gomock.Eq("reservation"), gomock.Eq(true)).
+		Return(nil)
+
+	observer := &recordingObserver{}
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+	regulator.SetObserver(observer)
+
+	s.Require().NoError(regulator.Confirm("john", "reservation", true))
+
+	s.Assert().Len(observer.failures, 0)
+	s.Assert().Len(observer.bans, 0)
+}
+
+func (s *RegulatorSuite) TestShouldNotReserveAttemptWhenRegulationIsDisabled() {
+	s.configuration.MaxRetries = 0
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	reservation, _, err := regulator.Reserve("john")
+
+	s.Assert().NoError(err)
+	s.Assert().Equal("", reservation)
+}
+
+func (s *RegulatorSuite) TestShouldIncreaseDelayWithFailedAttempts() {
+	s.configuration.DelayIncrement = "1s"
+	s.configuration.MaxDelay = "3s"
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	var attemptsInDB []models.AuthenticationAttempt
+
+	for _, expected := range []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		s.storageMock.EXPECT().
+			LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Eq(s.clock.Now().Add(-30*time.Second))).
+			Return(attemptsInDB, nil)
+
+		delay, err := regulator.Delay("john", "")
+		s.Require().NoError(err)
+		s.Assert().Equal(expected, delay)
+
+		attemptsInDB = append([]models.AuthenticationAttempt{
+			{Username: "john", Successful: false, Time: s.clock.Now()},
+		}, attemptsInDB...)
+	}
+}
+
+func (s *RegulatorSuite) TestShouldResetDelayOnSuccessfulAttempt() {
+	s.configuration.DelayIncrement = "1s"
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		Return([]models.AuthenticationAttempt{
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-5 * time.Second), Reservation: "pending"},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-10 * time.Second)},
+			{Username: "john", Successful: true, Time: s.clock.Now().Add(-15 * time.Second)},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-20 * time.Second)},
+			{Username: "john", Successful: false, Time: s.clock.Now().Add(-25 * time.Second)},
+		}, nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	delay, err := regulator.Delay("john", "pending")
+
+	s.Require().NoError(err)
+	s.Assert().Equal(time.Second, delay)
+}
+
+func (s *RegulatorSuite) TestShouldReturnErrorWhenDelayCannotBeComputed() {
+	s.configuration.DelayIncrement = "1s"
+
+	s.storageMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("john"), gomock.Any()).
+		Return(nil, fmt.Errorf("connection refused"))
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	_, err := regulator.Delay("john", "")
+	s.Assert().EqualError(err, "Unable to load the authentication logs: connection refused")
+}
+
+func (s *RegulatorSuite) TestShouldNotDelayWhenDelayIsDisabled() {
+	s.configuration.DelayIncrement = "0"
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+
+	delay, err := regulator.Delay("john", "")
+
+	s.Require().NoError(err)
+	s.Assert().Equal(time.Duration(0), delay)
+}
+
 func TestRunRegulatorSuite(t *testing.T) {
 	s := new(RegulatorSuite)
 	suite.Run(t, s)
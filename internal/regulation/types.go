@@ -17,8 +17,21 @@ type Regulator struct {
 	findTime time.Duration
 	// If a user has been banned, this duration is the timelapse during which the user is banned.
 	banTime time.Duration
+	// Each failed attempt since the last successful one delays the next attempts by this duration.
+	delayIncrement time.Duration
+	// The maximum delay of an attempt.
+	maxDelay time.Duration
 
 	storageProvider storage.Provider
 
+	// The observer notified of the failed attempts and of the bans, if any.
+	observer Observer
+
 	clock utils.Clock
 }
+
+// Observer is notified of the failed authentication attempts and of the bans they cause.
+type Observer interface {
+	AuthenticationFailed(username string)
+	UserBanned(username string, bannedUntil time.Time)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// newHTTPRedirectHandler creates a handler permanently redirecting requests to the same host, path and query over
+// HTTPS on the given port.
+func newHTTPRedirectHandler(httpsPort int) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		hostname := string(ctx.Host())
+		if host, _, err := net.SplitHostPort(hostname); err == nil {
+			hostname = host
+		}
+
+		hostname = strings.Trim(hostname, "[]")
+
+		if httpsPort == 443 {
+			if strings.Contains(hostname, ":") {
+				hostname = "[" + hostname + "]"
+			}
+		} else {
+			hostname = net.JoinHostPort(hostname, strconv.Itoa(httpsPort))
+		}
+
+		ctx.Response.Header.Set(fasthttp.HeaderLocation, "https://"+hostname+string(ctx.RequestURI()))
+		ctx.SetStatusCode(fasthttp.StatusMovedPermanently)
+	}
+}
+
+// startHTTPRedirectServer starts the listener redirecting HTTP requests to the main HTTPS listener.
+func startHTTPRedirectServer(configuration schema.Configuration, logger *logrus.Logger) {
+	addrPattern := net.JoinHostPort(configuration.Server.HTTPRedirect.Host, strconv.Itoa(configuration.Server.HTTPRedirect.Port))
+
+	listener, err := net.Listen("tcp", addrPattern)
+	if err != nil {
+		logger.Fatalf("Error initializing HTTP redirect listener: %s", err)
+	}
+
+	server := &fasthttp.Server{
+		ErrorHandler:          autheliaErrorHandler,
+		Handler:               newHTTPRedirectHandler(configuration.Port),
+		NoDefaultServerHeader: true,
+	}
+
+	logger.Infof("Authelia is redirecting HTTP connections on %s to HTTPS", addrPattern)
+	logger.Fatal(server.Serve(listener))
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestShouldRedirectToHTTPSPreservingPathAndQuery(t *testing.T) {
+	testCases := []struct {
+		name      string
+		host      string
+		uri       string
+		httpsPort int
+		expected  string
+	}{
+		{"DefaultPort", "auth.example.com", "/api/state?a=b", 443, "https://auth.example.com/api/state?a=b"},
+		{"StripHTTPPort", "auth.example.com:80", "/", 443, "https://auth.example.com/"},
+		{"CustomPort", "auth.example.com:8080", "/authelia/?rd=x", 9091, "https://auth.example.com:9091/authelia/?rd=x"},
+		{"IPv6", "[::1]:80", "/", 443, "https://[::1]/"},
+		{"IPv6CustomPort", "[::1]", "/", 9091, "https://[::1]:9091/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(tc.uri)
+			ctx.Request.Header.SetHost(tc.host)
+
+			newHTTPRedirectHandler(tc.httpsPort)(ctx)
+
+			assert.Equal(t, fasthttp.StatusMovedPermanently, ctx.Response.StatusCode())
+			assert.Equal(t, tc.expected, string(ctx.Response.Header.Peek(fasthttp.HeaderLocation)))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// newListener creates the listener of the main server, either on a TCP port or on a unix domain socket if the host
+// has the unix: prefix. The address the listener is bound to is returned for logging purposes.
+func newListener(configuration schema.Configuration) (listener net.Listener, address string, err error) {
+	if !strings.HasPrefix(configuration.Host, schema.UnixSocketHostPrefix) {
+		address = net.JoinHostPort(configuration.Host, strconv.Itoa(configuration.Port))
+		listener, err = net.Listen("tcp", address)
+
+		return listener, address, err
+	}
+
+	address = strings.TrimPrefix(configuration.Host, schema.UnixSocketHostPrefix)
+
+	// Remove the socket left behind by a previous instance which did not shut down cleanly.
+	if err = os.Remove(address); err != nil && !os.IsNotExist(err) {
+		return nil, address, err
+	}
+
+	// Skip error check since the validator checks it.
+	mode, _ := strconv.ParseUint(configuration.Server.UnixSocketMode, 8, 32)
+
+	if listener, err = net.Listen("unix", address); err != nil {
+		return nil, address, err
+	}
+
+	// The socket is removed when the listener is closed.
+	if err = os.Chmod(address, os.FileMode(mode)); err != nil {
+		_ = listener.Close()
+		return nil, address, err
+	}
+
+	return listener, address, nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldListenOnUnixSocketAndRemoveItOnClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authelia-socket")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "authelia.sock")
+
+	// A socket left behind by a previous instance.
+	require.NoError(t, ioutil.WriteFile(socketPath, nil, 0600))
+
+	configuration := schema.Configuration{Host: "unix:" + socketPath}
+	configuration.Server.UnixSocketMode = "0660"
+
+	listener, address, err := newListener(configuration)
+	require.NoError(t, err)
+
+	assert.Equal(t, socketPath, address)
+	assert.Equal(t, "unix", listener.Addr().Network())
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, os.ModeSocket, info.Mode()&os.ModeSocket)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	require.NoError(t, listener.Close())
+
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestShouldListenOnTCPPort(t *testing.T) {
+	listener, address, err := newListener(schema.Configuration{Host: "127.0.0.1", Port: 0})
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	assert.Equal(t, "127.0.0.1:0", address)
+	assert.Equal(t, "tcp", listener.Addr().Network())
+}
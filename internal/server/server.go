@@ -4,15 +4,17 @@ import (
 	"embed"
 	"io/fs"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 
 	duoapi "github.com/duosecurity/duo_api_golang"
 	"github.com/fasthttp/router"
+	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/expvarhandler"
 	"github.com/valyala/fasthttp/fasthttpadaptor"
@@ -33,15 +35,21 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	logger := logging.Logger()
 	autheliaMiddleware := middlewares.AutheliaMiddleware(configuration, providers)
 	rememberMe := strconv.FormatBool(configuration.Session.RememberMeDuration != "0")
+
+	oidcUpstream := ""
+	if configuration.OIDCUpstream != nil {
+		oidcUpstream = configuration.OIDCUpstream.DisplayName
+	}
 	resetPassword := strconv.FormatBool(!configuration.AuthenticationBackend.DisableResetPassword)
 
 	embeddedPath, _ := fs.Sub(assets, "public_html")
 	embeddedFS := fasthttpadaptor.NewFastHTTPHandler(http.FileServer(http.FS(embeddedPath)))
+	staticFS := middlewares.CacheControlMiddleware(configuration.Server.CacheControl.Static)(embeddedFS)
 	rootFiles := []string{"favicon.ico", "manifest.json", "robots.txt"}
 
-	serveIndexHandler := ServeTemplatedFile(embeddedAssets, indexFile, configuration.Server.Path, rememberMe, resetPassword, configuration.Session.Name, configuration.Theme)
-	serveSwaggerHandler := ServeTemplatedFile(swaggerAssets, indexFile, configuration.Server.Path, rememberMe, resetPassword, configuration.Session.Name, configuration.Theme)
-	serveSwaggerAPIHandler := ServeTemplatedFile(swaggerAssets, apiFile, configuration.Server.Path, rememberMe, resetPassword, configuration.Session.Name, configuration.Theme)
+	serveIndexHandler := ServeTemplatedFile(embeddedAssets, indexFile, configuration.Server.BasePath, rememberMe, resetPassword, oidcUpstream, configuration.Session.Name, configuration.Theme)
+	serveSwaggerHandler := ServeTemplatedFile(swaggerAssets, indexFile, configuration.Server.BasePath, rememberMe, resetPassword, oidcUpstream, configuration.Session.Name, configuration.Theme)
+	serveSwaggerAPIHandler := ServeTemplatedFile(swaggerAssets, apiFile, configuration.Server.BasePath, rememberMe, resetPassword, oidcUpstream, configuration.Session.Name, configuration.Theme)
 
 	r := router.New()
 	r.GET("/", serveIndexHandler)
@@ -49,10 +57,10 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	r.GET("/api/"+apiFile, serveSwaggerAPIHandler)
 
 	for _, f := range rootFiles {
-		r.GET("/"+f, embeddedFS)
+		r.GET("/"+f, staticFS)
 	}
 
-	r.GET("/static/{filepath:*}", embeddedFS)
+	r.GET("/static/{filepath:*}", staticFS)
 	r.ANY("/api/{filepath:*}", embeddedFS)
 
 	r.GET("/api/health", autheliaMiddleware(handlers.HealthGet))
@@ -64,9 +72,20 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	r.GET("/api/verify", autheliaMiddleware(handlers.VerifyGet(configuration.AuthenticationBackend)))
 	r.HEAD("/api/verify", autheliaMiddleware(handlers.VerifyGet(configuration.AuthenticationBackend)))
 
+	for _, endpoint := range configuration.Server.VerifyEndpoints {
+		r.GET(endpoint.Path, autheliaMiddleware(handlers.VerifyEndpointGet(configuration.AuthenticationBackend, endpoint)))
+		r.HEAD(endpoint.Path, autheliaMiddleware(handlers.VerifyEndpointGet(configuration.AuthenticationBackend, endpoint)))
+	}
+
 	r.POST("/api/firstfactor", autheliaMiddleware(handlers.FirstFactorPost(1000, true)))
 	r.POST("/api/logout", autheliaMiddleware(handlers.LogoutPost))
 
+	// Configure the upstream OpenID Connect provider endpoints only if configuration exists.
+	if configuration.OIDCUpstream != nil {
+		r.GET("/api/oidc-upstream/authorize", autheliaMiddleware(handlers.OIDCUpstreamAuthorizeGet))
+		r.GET("/api/oidc-upstream/callback", autheliaMiddleware(handlers.OIDCUpstreamCallbackGet))
+	}
+
 	// Only register endpoints if forgot password is not disabled.
 	if !configuration.AuthenticationBackend.DisableResetPassword {
 		// Password reset related endpoints.
@@ -78,6 +97,42 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 			handlers.ResetPasswordPost))
 	}
 
+	// Only register the email verification endpoint if the verification is required.
+	if configuration.EmailVerification.Enabled {
+		r.POST("/api/email/verify/identity/finish", autheliaMiddleware(
+			handlers.EmailVerificationIdentityFinish))
+	}
+
+	// Only register the registration endpoints if the registration of accounts is enabled.
+	if configuration.Registration.Enabled {
+		r.POST("/api/registration", autheliaMiddleware(handlers.RegistrationPost))
+		r.POST("/api/registration/identity/finish", autheliaMiddleware(handlers.RegistrationIdentityFinish))
+
+		r.GET("/api/admin/registrations", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.AdminRegistrationsGet)))
+		r.POST("/api/admin/registrations/approve", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.AdminRegistrationApprovePost)))
+		r.POST("/api/admin/registrations/reject", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.AdminRegistrationRejectPost)))
+	}
+
+	// Only register the admin endpoint if some users are allowed to reset the regulation.
+	if len(configuration.Regulation.AdminGroups) != 0 {
+		r.POST("/api/admin/regulation/reset", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.AdminRegulationResetPost)))
+	}
+
+	// Only register the admin endpoint if some users are allowed to investigate the logins of other users.
+	if len(configuration.LastLogin.AdminGroups) != 0 {
+		r.GET("/api/admin/users/last-login", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.AdminLastLoginGet)))
+	}
+
+	// Only register the break-glass diagnostics if the break-glass credential is configured.
+	if configuration.BreakGlass != nil {
+		r.GET("/api/admin/break-glass/diagnostics", autheliaMiddleware(handlers.BreakGlassDiagnosticsGet(*configuration.BreakGlass)))
+	}
+
 	// Information about the user.
 	r.GET("/api/user/info", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.UserInfoGet)))
@@ -137,9 +192,18 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 
 	r.NotFound = serveIndexHandler
 
-	handler := middlewares.LogRequestMiddleware(r.Handler)
-	if configuration.Server.Path != "" {
-		handler = middlewares.StripPathMiddleware(handler)
+	// The responses of the portal and the API are considered sensitive unless the static assets policy overrides it.
+	handler := middlewares.CacheControlMiddleware(configuration.Server.CacheControl.Sensitive)(r.Handler)
+	// The token of the trusted header is as sensitive as the credentials.
+	sampling := configuration.Server.RequestSampling
+	if configuration.TrustedHeader != nil {
+		sampling.RedactedHeaders = append(append([]string{}, sampling.RedactedHeaders...), configuration.TrustedHeader.Header)
+	}
+
+	handler = middlewares.RequestSamplingMiddleware(sampling)(handler)
+	handler = middlewares.RequestIDMiddleware(configuration.Server.RequestID)(middlewares.LogRequestMiddleware(handler))
+	if configuration.Server.BasePath != "" {
+		handler = middlewares.StripPathMiddleware(configuration.Server.BasePath)(handler)
 	}
 
 	server := &fasthttp.Server{
@@ -150,13 +214,13 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 		WriteBufferSize:       configuration.Server.WriteBufferSize,
 	}
 
-	addrPattern := net.JoinHostPort(configuration.Host, strconv.Itoa(configuration.Port))
-
-	listener, err := net.Listen("tcp", addrPattern)
+	listener, addrPattern, err := newListener(configuration)
 	if err != nil {
 		logger.Fatalf("Error initializing listener: %s", err)
 	}
 
+	go shutdownOnSignal(server, logger)
+
 	if configuration.AuthenticationBackend.File != nil && configuration.AuthenticationBackend.File.Password.Algorithm == "argon2id" && runtime.GOOS == "linux" {
 		f, err := ioutil.ReadFile("/sys/fs/cgroup/memory/memory.limit_in_bytes")
 		if err != nil {
@@ -174,10 +238,33 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	}
 
 	if configuration.TLSCert != "" && configuration.TLSKey != "" {
-		logger.Infof("Authelia is listening for TLS connections on %s%s", addrPattern, configuration.Server.Path)
-		logger.Fatal(server.ServeTLS(listener, configuration.TLSCert, configuration.TLSKey))
+		if configuration.Server.HTTPRedirect != nil {
+			go startHTTPRedirectServer(configuration, logger)
+		}
+
+		logger.Infof("Authelia is listening for TLS connections on %s%s", addrPattern, configuration.Server.BasePath)
+		err = server.ServeTLS(listener, configuration.TLSCert, configuration.TLSKey)
 	} else {
-		logger.Infof("Authelia is listening for non-TLS connections on %s%s", addrPattern, configuration.Server.Path)
-		logger.Fatal(server.Serve(listener))
+		logger.Infof("Authelia is listening for non-TLS connections on %s%s", addrPattern, configuration.Server.BasePath)
+		err = server.Serve(listener)
+	}
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// shutdownOnSignal gracefully shuts the server down when the process is interrupted or terminated, closing the listener
+// which removes the unix domain socket if the server listens on one.
+func shutdownOnSignal(server *fasthttp.Server, logger *logrus.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	<-signals
+
+	logger.Info("Authelia is shutting down")
+
+	if err := server.Shutdown(); err != nil {
+		logger.Errorf("Error shutting down the server: %s", err)
 	}
 }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/middlewares"
+)
+
+// ProviderWithStartupCheck represents a provider able to check it is working correctly at startup.
+type ProviderWithStartupCheck interface {
+	StartupCheck() (bool, error)
+}
+
+// DoStartupChecks runs the startup checks of the storage, authentication backend, notifier and upstream OpenID Connect
+// provider if configured, logs the result of each of them and returns the names of the components which failed.
+func DoStartupChecks(configuration schema.Configuration, providers middlewares.Providers) (failures []string) {
+	logger := logging.Logger()
+
+	if !doStartupCheck(logger, "storage", providers.StorageProvider) {
+		failures = append(failures, "storage")
+	}
+
+	if !doStartupCheck(logger, "authentication backend", providers.UserProvider) {
+		failures = append(failures, "authentication backend")
+	}
+
+	if configuration.Notifier != nil && configuration.Notifier.DisableStartupCheck {
+		logger.Debug("Startup check of the notifier is disabled")
+	} else if !doStartupCheck(logger, "notifier", providers.Notifier) {
+		failures = append(failures, "notifier")
+	}
+
+	if providers.OIDCUpstream != nil && !doStartupCheck(logger, "OIDC upstream provider", providers.OIDCUpstream) {
+		failures = append(failures, "OIDC upstream provider")
+	}
+
+	if providers.TrustedHeader != nil && !doStartupCheck(logger, "trusted header", providers.TrustedHeader) {
+		failures = append(failures, "trusted header")
+	}
+
+	return failures
+}
+
+func doStartupCheck(logger *logrus.Logger, name string, provider ProviderWithStartupCheck) bool {
+	if provider == nil {
+		logger.Errorf("Startup check of the %s failed: the provider is not configured", name)
+		return false
+	}
+
+	if _, err := provider.StartupCheck(); err != nil {
+		logger.Errorf("Startup check of the %s failed: %s", name, err)
+		return false
+	}
+
+	logger.Infof("Startup check of the %s passed", name)
+
+	return true
+}
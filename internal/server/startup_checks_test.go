@@ -0,0 +1,112 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+func newStartupCheckProviders(ctrl *gomock.Controller) (middlewares.Providers, *storage.MockProvider, *mocks.MockUserProvider, *mocks.MockNotifier) {
+	storageProvider := storage.NewMockProvider(ctrl)
+	userProvider := mocks.NewMockUserProvider(ctrl)
+	notifier := mocks.NewMockNotifier(ctrl)
+
+	return middlewares.Providers{
+		StorageProvider: storageProvider,
+		UserProvider:    userProvider,
+		Notifier:        notifier,
+	}, storageProvider, userProvider, notifier
+}
+
+func TestShouldPassAllStartupChecks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers, storageProvider, userProvider, notifier := newStartupCheckProviders(ctrl)
+
+	storageProvider.EXPECT().StartupCheck().Return(true, nil)
+	userProvider.EXPECT().StartupCheck().Return(true, nil)
+	notifier.EXPECT().StartupCheck().Return(true, nil)
+
+	failures := DoStartupChecks(schema.Configuration{}, providers)
+	assert.Len(t, failures, 0)
+}
+
+func TestShouldReportStorageStartupCheckFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers, storageProvider, userProvider, notifier := newStartupCheckProviders(ctrl)
+
+	storageProvider.EXPECT().StartupCheck().Return(false, errors.New("connection refused"))
+	userProvider.EXPECT().StartupCheck().Return(true, nil)
+	notifier.EXPECT().StartupCheck().Return(true, nil)
+
+	failures := DoStartupChecks(schema.Configuration{}, providers)
+	assert.Equal(t, []string{"storage"}, failures)
+}
+
+func TestShouldReportAuthenticationBackendStartupCheckFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers, storageProvider, userProvider, notifier := newStartupCheckProviders(ctrl)
+
+	storageProvider.EXPECT().StartupCheck().Return(true, nil)
+	userProvider.EXPECT().StartupCheck().Return(false, errors.New("invalid credentials"))
+	notifier.EXPECT().StartupCheck().Return(true, nil)
+
+	failures := DoStartupChecks(schema.Configuration{}, providers)
+	assert.Equal(t, []string{"authentication backend"}, failures)
+}
+
+func TestShouldReportNotifierStartupCheckFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers, storageProvider, userProvider, notifier := newStartupCheckProviders(ctrl)
+
+	storageProvider.EXPECT().StartupCheck().Return(true, nil)
+	userProvider.EXPECT().StartupCheck().Return(true, nil)
+	notifier.EXPECT().StartupCheck().Return(false, errors.New("smtp server unreachable"))
+
+	failures := DoStartupChecks(schema.Configuration{}, providers)
+	assert.Equal(t, []string{"notifier"}, failures)
+}
+
+func TestShouldSkipNotifierStartupCheckWhenDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers, storageProvider, userProvider, _ := newStartupCheckProviders(ctrl)
+
+	storageProvider.EXPECT().StartupCheck().Return(true, nil)
+	userProvider.EXPECT().StartupCheck().Return(true, nil)
+
+	configuration := schema.Configuration{}
+	configuration.Notifier = &schema.NotifierConfiguration{DisableStartupCheck: true}
+
+	failures := DoStartupChecks(configuration, providers)
+	assert.Len(t, failures, 0)
+}
+
+func TestShouldReportMissingProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	providers, storageProvider, _, notifier := newStartupCheckProviders(ctrl)
+	providers.UserProvider = nil
+
+	storageProvider.EXPECT().StartupCheck().Return(true, nil)
+	notifier.EXPECT().StartupCheck().Return(true, nil)
+
+	failures := DoStartupChecks(schema.Configuration{}, providers)
+	assert.Equal(t, []string{"authentication backend"}, failures)
+}
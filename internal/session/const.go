@@ -2,6 +2,9 @@ package session
 
 const userSessionStorerKey = "UserSession"
 
+// userSessionIndexKeyPrefix is the prefix of the keys of the indexes of the sessions of each user in the backend.
+const userSessionIndexKeyPrefix = "user-sessions;"
+
 const testDomain = "example.com"
 const testExpiration = "40"
 const testName = "my_session"
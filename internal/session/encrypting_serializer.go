@@ -11,13 +11,20 @@ import (
 
 // EncryptingSerializer a serializer encrypting the data with AES-GCM with 256-bit keys.
 type EncryptingSerializer struct {
-	key [32]byte
+	key          [32]byte
+	previousKeys [][32]byte
 }
 
-// NewEncryptingSerializer return new encrypt instance.
-func NewEncryptingSerializer(secret string) *EncryptingSerializer {
-	key := sha256.Sum256([]byte(secret))
-	return &EncryptingSerializer{key}
+// NewEncryptingSerializer return new encrypt instance. Sessions are always encrypted with the key derived from the
+// given secret while the previous secrets are only used to decrypt the sessions encrypted before a key rotation.
+func NewEncryptingSerializer(secret string, previousSecrets ...string) *EncryptingSerializer {
+	serializer := &EncryptingSerializer{key: sha256.Sum256([]byte(secret))}
+
+	for _, previousSecret := range previousSecrets {
+		serializer.previousKeys = append(serializer.previousKeys, sha256.Sum256([]byte(previousSecret)))
+	}
+
+	return serializer
 }
 
 // Encode encode and encrypt session.
@@ -47,7 +54,7 @@ func (e *EncryptingSerializer) Decode(dst *session.Dict, src []byte) error {
 
 	dst.Reset()
 
-	decryptedSrc, err := utils.Decrypt(src, &e.key)
+	decryptedSrc, err := e.decrypt(src)
 	if err != nil {
 		// If an error is thrown while decrypting, it's probably an old unencrypted session
 		// so we just unmarshall it without decrypting. It's a way to avoid a breaking change
@@ -65,3 +72,19 @@ func (e *EncryptingSerializer) Decode(dst *session.Dict, src []byte) error {
 
 	return err
 }
+
+// decrypt decrypts the data with the current key, falling back to the previous keys.
+func (e *EncryptingSerializer) decrypt(src []byte) ([]byte, error) {
+	decryptedSrc, err := utils.Decrypt(src, &e.key)
+	if err == nil {
+		return decryptedSrc, nil
+	}
+
+	for i := range e.previousKeys {
+		if decryptedSrc, perr := utils.Decrypt(src, &e.previousKeys[i]); perr == nil {
+			return decryptedSrc, nil
+		}
+	}
+
+	return nil, err
+}
@@ -44,3 +44,40 @@ func TestShouldSupportUnencryptedSessionForBackwardCompatibility(t *testing.T) {
 
 	assert.Equal(t, "value", decodedPayload.Get("key"))
 }
+
+func TestShouldDecryptWithPreviousKeys(t *testing.T) {
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	oldSerializer := NewEncryptingSerializer("an_old_secret")
+	encryptedDst, err := oldSerializer.Encode(payload)
+	require.NoError(t, err)
+
+	serializer := NewEncryptingSerializer("a_new_secret", "another_secret", "an_old_secret")
+
+	decodedPayload := session.Dict{}
+	err = serializer.Decode(&decodedPayload, encryptedDst)
+	require.NoError(t, err)
+
+	assert.Equal(t, "value", decodedPayload.Get("key"))
+
+	// Sessions are encrypted again with the new key only.
+	reencryptedDst, err := serializer.Encode(decodedPayload)
+	require.NoError(t, err)
+
+	err = oldSerializer.Decode(&decodedPayload, reencryptedDst)
+	assert.EqualError(t, err, "Unable to decrypt session: cipher: message authentication failed")
+}
+
+func TestShouldFailToDecryptWithUnknownKey(t *testing.T) {
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	encryptedDst, err := NewEncryptingSerializer("a_secret").Encode(payload)
+	require.NoError(t, err)
+
+	decodedPayload := session.Dict{}
+	err = NewEncryptingSerializer("another_secret", "yet_another_secret").Decode(&decodedPayload, encryptedDst)
+
+	assert.EqualError(t, err, "Unable to decrypt session: cipher: message authentication failed")
+}
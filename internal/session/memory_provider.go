@@ -0,0 +1,185 @@
+package session
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// memoryProvider is an in-memory session store holding at most maxSessions sessions. When the store is full, the
+// least recently used session is evicted to make room for a new one. A limit of 0 means unlimited.
+type memoryProvider struct {
+	maxSessions int
+	clock       utils.Clock
+
+	mutex    sync.Mutex
+	sessions map[string]*list.Element
+	// lru holds the sessions ordered from the most recently used to the least recently used.
+	lru *list.List
+}
+
+type memorySession struct {
+	id        string
+	data      []byte
+	expiresAt time.Time
+	lastUsed  time.Time
+}
+
+func newMemoryProvider(maxSessions int, clock utils.Clock) *memoryProvider {
+	return &memoryProvider{
+		maxSessions: maxSessions,
+		clock:       clock,
+		sessions:    map[string]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+// Get returns the data of the session, nil if the session does not exist or expired.
+func (p *memoryProvider) Get(id []byte) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	element, ok := p.sessions[string(id)]
+	if !ok {
+		return nil, nil
+	}
+
+	session := element.Value.(*memorySession)
+
+	if p.isExpired(session) {
+		p.remove(element)
+		return nil, nil
+	}
+
+	session.lastUsed = p.clock.Now()
+	p.lru.MoveToFront(element)
+
+	return session.data, nil
+}
+
+// Save stores the data of the session, evicting the least recently used session if the store is full.
+func (p *memoryProvider) Save(id, data []byte, expiration time.Duration) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.set(string(id), data, expiration)
+
+	return nil
+}
+
+// Destroy removes the session.
+func (p *memoryProvider) Destroy(id []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if element, ok := p.sessions[string(id)]; ok {
+		p.remove(element)
+	}
+
+	return nil
+}
+
+// Regenerate moves the data of the session to a new ID.
+func (p *memoryProvider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var data []byte
+
+	if element, ok := p.sessions[string(id)]; ok {
+		if session := element.Value.(*memorySession); !p.isExpired(session) {
+			data = session.data
+		}
+
+		p.remove(element)
+	}
+
+	p.set(string(newID), data, expiration)
+
+	return nil
+}
+
+// Count returns the number of sessions in the store, including the expired ones not yet collected.
+func (p *memoryProvider) Count() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.lru.Len()
+}
+
+// NeedGC indicates the expired sessions must be collected periodically.
+func (p *memoryProvider) NeedGC() bool {
+	return true
+}
+
+// GC removes the expired sessions.
+func (p *memoryProvider) GC() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for element := p.lru.Back(); element != nil; {
+		previous := element.Prev()
+
+		if p.isExpired(element.Value.(*memorySession)) {
+			p.remove(element)
+		}
+
+		element = previous
+	}
+
+	return nil
+}
+
+func (p *memoryProvider) set(id string, data []byte, expiration time.Duration) {
+	var expiresAt time.Time
+
+	if expiration > 0 {
+		expiresAt = p.clock.Now().Add(expiration)
+	}
+
+	if element, ok := p.sessions[id]; ok {
+		session := element.Value.(*memorySession)
+		session.data = data
+		session.expiresAt = expiresAt
+		session.lastUsed = p.clock.Now()
+
+		p.lru.MoveToFront(element)
+
+		return
+	}
+
+	if p.maxSessions > 0 && p.lru.Len() >= p.maxSessions {
+		p.evict()
+	}
+
+	p.sessions[id] = p.lru.PushFront(&memorySession{id: id, data: data, expiresAt: expiresAt, lastUsed: p.clock.Now()})
+}
+
+// evict removes the least recently used session.
+func (p *memoryProvider) evict() {
+	element := p.lru.Back()
+	if element == nil {
+		return
+	}
+
+	session := element.Value.(*memorySession)
+
+	if !p.isExpired(session) {
+		logging.Logger().Warnf("The in-memory session store reached its capacity of %d sessions, the session least recently used at %s was evicted",
+			p.maxSessions, session.lastUsed.Format(time.RFC3339))
+	}
+
+	p.remove(element)
+}
+
+func (p *memoryProvider) remove(element *list.Element) {
+	p.lru.Remove(element)
+	delete(p.sessions, element.Value.(*memorySession).id)
+}
+
+func (p *memoryProvider) isExpired(session *memorySession) bool {
+	return !session.expiresAt.IsZero() && !p.clock.Now().Before(session.expiresAt)
+}
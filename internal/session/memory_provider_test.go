@@ -0,0 +1,122 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (c *testClock) Set(now time.Time) {
+	c.now = now
+}
+
+func newTestMemoryProvider(maxSessions int) (*memoryProvider, *testClock) {
+	clock := &testClock{now: time.Now()}
+
+	return newMemoryProvider(maxSessions, clock), clock
+}
+
+func TestShouldSaveAndGetSessionFromMemory(t *testing.T) {
+	provider, _ := newTestMemoryProvider(0)
+
+	require.NoError(t, provider.Save([]byte("id"), []byte("data"), time.Minute))
+
+	data, err := provider.Get([]byte("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	require.NoError(t, provider.Destroy([]byte("id")))
+
+	data, err = provider.Get([]byte("id"))
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	assert.Equal(t, 0, provider.Count())
+}
+
+func TestShouldEvictLeastRecentlyUsedSessionWhenMemoryIsFull(t *testing.T) {
+	provider, clock := newTestMemoryProvider(2)
+
+	require.NoError(t, provider.Save([]byte("first"), []byte("1"), time.Hour))
+	clock.Set(clock.Now().Add(time.Second))
+	require.NoError(t, provider.Save([]byte("second"), []byte("2"), time.Hour))
+	clock.Set(clock.Now().Add(time.Second))
+
+	// Using the first session makes the second one the least recently used.
+	_, err := provider.Get([]byte("first"))
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Save([]byte("third"), []byte("3"), time.Hour))
+
+	assert.Equal(t, 2, provider.Count())
+
+	data, _ := provider.Get([]byte("second"))
+	assert.Nil(t, data)
+
+	data, _ = provider.Get([]byte("first"))
+	assert.Equal(t, []byte("1"), data)
+
+	data, _ = provider.Get([]byte("third"))
+	assert.Equal(t, []byte("3"), data)
+}
+
+func TestShouldNotEvictWhenUpdatingExistingSessionInFullMemory(t *testing.T) {
+	provider, _ := newTestMemoryProvider(1)
+
+	require.NoError(t, provider.Save([]byte("id"), []byte("1"), time.Hour))
+	require.NoError(t, provider.Save([]byte("id"), []byte("2"), time.Hour))
+
+	data, _ := provider.Get([]byte("id"))
+	assert.Equal(t, []byte("2"), data)
+	assert.Equal(t, 1, provider.Count())
+}
+
+func TestShouldExpireSessionsInMemory(t *testing.T) {
+	provider, clock := newTestMemoryProvider(0)
+
+	require.NoError(t, provider.Save([]byte("short"), []byte("1"), time.Minute))
+	require.NoError(t, provider.Save([]byte("long"), []byte("2"), time.Hour))
+	require.NoError(t, provider.Save([]byte("persistent"), []byte("3"), 0))
+
+	clock.Set(clock.Now().Add(2 * time.Minute))
+
+	data, _ := provider.Get([]byte("short"))
+	assert.Nil(t, data)
+
+	clock.Set(clock.Now().Add(2 * time.Hour))
+
+	assert.True(t, provider.NeedGC())
+	require.NoError(t, provider.GC())
+
+	assert.Equal(t, 1, provider.Count())
+
+	data, _ = provider.Get([]byte("persistent"))
+	assert.Equal(t, []byte("3"), data)
+}
+
+func TestShouldRegenerateSessionInMemory(t *testing.T) {
+	provider, _ := newTestMemoryProvider(1)
+
+	require.NoError(t, provider.Save([]byte("old"), []byte("data"), time.Hour))
+	require.NoError(t, provider.Regenerate([]byte("old"), []byte("new"), time.Hour))
+
+	data, _ := provider.Get([]byte("old"))
+	assert.Nil(t, data)
+
+	data, _ = provider.Get([]byte("new"))
+	assert.Equal(t, []byte("data"), data)
+	assert.Equal(t, 1, provider.Count())
+}
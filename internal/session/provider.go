@@ -6,7 +6,6 @@ import (
 	"time"
 
 	fasthttpsession "github.com/fasthttp/session/v2"
-	"github.com/fasthttp/session/v2/providers/memory"
 	"github.com/fasthttp/session/v2/providers/redis"
 	"github.com/valyala/fasthttp"
 
@@ -20,6 +19,14 @@ type Provider struct {
 	sessionHolder *fasthttpsession.Session
 	RememberMe    time.Duration
 	Inactivity    time.Duration
+	// InactivityGracePeriod is the period following the inactivity timeout during which the session is downgraded to
+	// one factor instead of being destroyed.
+	InactivityGracePeriod time.Duration
+	// MaxSessionsPerUser is the maximum number of concurrent sessions of a user, 0 meaning unlimited.
+	MaxSessionsPerUser int
+
+	providerImpl fasthttpsession.Provider
+	index        *userSessionIndex
 }
 
 // NewProvider instantiate a session provider given a configuration.
@@ -45,6 +52,14 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 
 	provider.Inactivity = duration
 
+	duration, err = utils.ParseDurationString(configuration.InactivityGracePeriod)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	provider.InactivityGracePeriod = duration
+	provider.MaxSessionsPerUser = configuration.MaxSessionsPerUser
+
 	var providerImpl fasthttpsession.Provider
 
 	switch {
@@ -59,10 +74,7 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 			logger.Fatal(err)
 		}
 	default:
-		providerImpl, err = memory.New(memory.Config{})
-		if err != nil {
-			logger.Fatal(err)
-		}
+		providerImpl = newMemoryProvider(configuration.Memory.MaxSessions, utils.RealClock{})
 	}
 
 	err = provider.sessionHolder.SetProvider(providerImpl)
@@ -70,6 +82,19 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 		logger.Fatal(err)
 	}
 
+	provider.providerImpl = providerImpl
+
+	// The sessions of the users are only indexed when their number is limited. The index lasts as long as the longest
+	// sessions.
+	if provider.MaxSessionsPerUser > 0 {
+		ttl := providerConfig.config.Expiration
+		if provider.RememberMe > ttl {
+			ttl = provider.RememberMe
+		}
+
+		provider.index = newUserSessionIndex(providerImpl, ttl)
+	}
+
 	return provider
 }
 
@@ -129,15 +154,86 @@ func (p *Provider) SaveSession(ctx *fasthttp.RequestCtx, userSession UserSession
 
 // RegenerateSession regenerate a session ID.
 func (p *Provider) RegenerateSession(ctx *fasthttp.RequestCtx) error {
-	err := p.sessionHolder.Regenerate(ctx)
-	return err
+	store, err := p.sessionHolder.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	id, username := string(store.GetSessionID()), storeUsername(store)
+
+	err = p.sessionHolder.Regenerate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if username == "" {
+		return nil
+	}
+
+	store, err = p.sessionHolder.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.index.Rename(username, id, string(store.GetSessionID()))
 }
 
 // DestroySession destroy a session ID and delete the cookie.
 func (p *Provider) DestroySession(ctx *fasthttp.RequestCtx) error {
+	store, err := p.sessionHolder.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	if username := storeUsername(store); username != "" {
+		if err = p.index.Remove(username, string(store.GetSessionID())); err != nil {
+			return err
+		}
+	}
+
 	return p.sessionHolder.Destroy(ctx)
 }
 
+// RegisterUserSession registers the current session as the newest session of the user and revokes the oldest
+// sessions of this user exceeding the maximum number of sessions per user. It returns the number of revoked sessions.
+func (p *Provider) RegisterUserSession(ctx *fasthttp.RequestCtx, username string) (revoked int, err error) {
+	store, err := p.sessionHolder.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	evicted, err := p.index.Add(username, string(store.GetSessionID()), p.MaxSessionsPerUser)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range evicted {
+		if err = p.providerImpl.Destroy([]byte(id)); err != nil {
+			return revoked, err
+		}
+
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// storeUsername returns the username of the user session of the store, an empty string if it is anonymous.
+func storeUsername(store *fasthttpsession.Store) string {
+	userSessionJSON, ok := store.Get(userSessionStorerKey).([]byte)
+	if !ok {
+		return ""
+	}
+
+	var userSession UserSession
+
+	if err := json.Unmarshal(userSessionJSON, &userSession); err != nil {
+		return ""
+	}
+
+	return userSession.Username
+}
+
 // UpdateExpiration update the expiration of the cookie and session.
 func (p *Provider) UpdateExpiration(ctx *fasthttp.RequestCtx, expiration time.Duration) error {
 	store, err := p.sessionHolder.Get(ctx)
@@ -44,7 +44,7 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 	// If redis configuration is provided, then use the redis provider.
 	switch {
 	case configuration.Redis != nil:
-		serializer := NewEncryptingSerializer(configuration.Secret)
+		serializer := newSessionSerializer(configuration)
 
 		var tlsConfig *tls.Config
 
@@ -122,3 +122,13 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 		providerName,
 	}
 }
+
+// newSessionSerializer creates the serializer encrypting the sessions at rest. The dedicated encryption key is used when
+// configured, in which case the session secret is kept as a previous key so existing sessions remain readable.
+func newSessionSerializer(configuration schema.SessionConfiguration) *EncryptingSerializer {
+	if configuration.EncryptionKey == "" {
+		return NewEncryptingSerializer(configuration.Secret)
+	}
+
+	return NewEncryptingSerializer(configuration.EncryptionKey, append(configuration.PreviousEncryptionKeys, configuration.Secret)...)
+}
@@ -264,3 +264,36 @@ func TestShouldUseEncryptingSerializerWithRedis(t *testing.T) {
 	_, _ = decoded.UnmarshalMsg(decrypted)
 	assert.Equal(t, "value", decoded.Get("key"))
 }
+
+func TestShouldEncryptWithEncryptionKeyAndDecryptWithSecret(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Secret = "abc"
+	configuration.EncryptionKey = "a_very_long_session_encryption_key"
+	configuration.Redis = &schema.RedisSessionConfiguration{
+		Host: "redis.example.com",
+		Port: 6379,
+	}
+	providerConfig := NewProviderConfig(configuration, nil)
+
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	encoded, err := providerConfig.config.EncodeFunc(payload)
+	require.NoError(t, err)
+
+	key := sha256.Sum256([]byte("a_very_long_session_encryption_key"))
+	decrypted, err := utils.Decrypt(encoded, &key)
+	require.NoError(t, err)
+
+	decoded := session.Dict{}
+	_, _ = decoded.UnmarshalMsg(decrypted)
+	assert.Equal(t, "value", decoded.Get("key"))
+
+	// Sessions encrypted with the session secret before the encryption key was configured are still readable.
+	encoded, err = NewEncryptingSerializer("abc").Encode(payload)
+	require.NoError(t, err)
+
+	decoded = session.Dict{}
+	require.NoError(t, providerConfig.config.DecodeFunc(&decoded, encoded))
+	assert.Equal(t, "value", decoded.Get("key"))
+}
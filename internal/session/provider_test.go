@@ -2,6 +2,7 @@ package session
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -80,3 +81,91 @@ func TestShouldDestroySessionAndWipeSessionData(t *testing.T) {
 	assert.Equal(t, "", newUserSession.Username)
 	assert.Equal(t, authentication.NotAuthenticated, newUserSession.AuthenticationLevel)
 }
+
+func TestShouldRevokeOldestSessionsExceedingTheLimit(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.MaxSessionsPerUser = 2
+
+	provider := NewProvider(configuration, nil)
+
+	login := func() *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		session, _ := provider.GetSession(ctx)
+		session.Username = testUsername
+
+		require.NoError(t, provider.SaveSession(ctx, session))
+
+		return ctx
+	}
+
+	first, second, third := login(), login(), login()
+
+	for _, ctx := range []*fasthttp.RequestCtx{first, second} {
+		revoked, err := provider.RegisterUserSession(ctx, testUsername)
+		require.NoError(t, err)
+		assert.Equal(t, 0, revoked)
+	}
+
+	firstID := string(first.Request.Header.Cookie(testName))
+
+	revoked, err := provider.RegisterUserSession(third, testUsername)
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+
+	data, err := provider.providerImpl.Get([]byte(firstID))
+	require.NoError(t, err)
+	assert.Len(t, data, 0)
+
+	// Logging out frees a slot for a new session.
+	require.NoError(t, provider.DestroySession(second))
+
+	revoked, err = provider.RegisterUserSession(login(), testUsername)
+	require.NoError(t, err)
+	assert.Equal(t, 0, revoked)
+}
+
+func TestShouldNotIndexSessionsWhenUnlimited(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+
+	provider := NewProvider(configuration, nil)
+	assert.Nil(t, provider.index)
+
+	ctx := &fasthttp.RequestCtx{}
+	session, _ := provider.GetSession(ctx)
+	session.Username = testUsername
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	revoked, err := provider.RegisterUserSession(ctx, testUsername)
+	require.NoError(t, err)
+	assert.Equal(t, 0, revoked)
+
+	data, err := provider.providerImpl.Get(userSessionIndexKey(testUsername))
+	require.NoError(t, err)
+	assert.Len(t, data, 0)
+}
+
+func TestShouldExpireSessionIndexWithLongestSessions(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = "1h"
+	configuration.RememberMeDuration = "1M"
+	configuration.MaxSessionsPerUser = 1
+
+	provider := NewProvider(configuration, nil)
+	require.NotNil(t, provider.index)
+	assert.Equal(t, provider.RememberMe, provider.index.ttl)
+
+	configuration.RememberMeDuration = "0"
+
+	provider = NewProvider(configuration, nil)
+	require.NotNil(t, provider.index)
+	assert.Equal(t, time.Hour, provider.index.ttl)
+}
@@ -32,6 +32,10 @@ type UserSession struct {
 	Groups []string
 	Emails []string
 
+	// The extra attributes of the user fetched from the authentication backend, the access control rules can have
+	// conditions on them.
+	Attributes map[string][]string
+
 	KeepMeLoggedIn      bool
 	AuthenticationLevel authentication.Level
 	LastActivity        int64
@@ -47,9 +51,38 @@ type UserSession struct {
 	// while doing the query actually updating the password.
 	PasswordResetUsername *string
 
+	// The pending authentications against the upstream OpenID Connect provider keyed by their state, so that the
+	// flows started in parallel from several tabs do not clobber each other.
+	OIDCUpstreamFlows map[string]*OIDCUpstreamSession
+	// The issuer of the upstream provider the user authenticated against, if any. The profile of such users is not
+	// refreshed from the authentication backend.
+	UpstreamIssuer string
+
+	// This boolean is set to true when the user logged in from an unrecognised device or IP address and the second
+	// factor is required before accessing any resource.
+	SecondFactorRequired bool
+
+	// The version of the second factor devices of the user when the session completed the second factor. The session
+	// loses its elevation when the devices change afterwards.
+	SecondFactorVersion int64
+
+	// The times of the last first factor and second factor authentications of the session, checked against the
+	// maximum age of the rules requiring a fresh authentication.
+	FirstFactorAuthnTimestamp  int64
+	SecondFactorAuthnTimestamp int64
+
 	RefreshTTL time.Time
 }
 
+// OIDCUpstreamSession holds the state of an authentication against the upstream OpenID Connect provider.
+type OIDCUpstreamSession struct {
+	State         string
+	Nonce         string
+	TargetURL     string
+	RequestMethod string
+	StartedAt     int64
+}
+
 // Identity identity of the user who is being verified.
 type Identity struct {
 	Username string
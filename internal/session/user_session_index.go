@@ -0,0 +1,150 @@
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	fasthttpsession "github.com/fasthttp/session/v2"
+)
+
+// userSessionIndex keeps track of the session IDs belonging to each user, ordered from the oldest to the newest. The
+// index of each user is stored in the session backend next to the sessions so that it is shared by all the instances
+// of Authelia and survives their restarts. It is updated without lock, concurrent logins of the same user may
+// therefore lose an update in which case the session is only tracked from the next login of the user. A nil index
+// tracks nothing, it is used when the number of sessions per user is unlimited.
+type userSessionIndex struct {
+	provider fasthttpsession.Provider
+	ttl      time.Duration
+}
+
+// newUserSessionIndex creates an index whose entries expire after the ttl following the last update of the index of the
+// user.
+func newUserSessionIndex(provider fasthttpsession.Provider, ttl time.Duration) *userSessionIndex {
+	return &userSessionIndex{provider: provider, ttl: ttl}
+}
+
+// Add registers the session ID as the newest session of the user and returns the IDs of the oldest sessions which
+// exceed the limit, removing them from the index. The sessions which already expired are forgotten. A limit of 0
+// means unlimited.
+func (i *userSessionIndex) Add(username, id string, limit int) (evicted []string, err error) {
+	if i == nil {
+		return nil, nil
+	}
+
+	ids, err := i.load(username)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]string, 0, len(ids)+1)
+
+	for _, sessionID := range ids {
+		if sessionID == id {
+			continue
+		}
+
+		alive, err := i.isAlive(sessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if alive {
+			sessions = append(sessions, sessionID)
+		}
+	}
+
+	sessions = append(sessions, id)
+
+	if limit > 0 && len(sessions) > limit {
+		evicted = sessions[:len(sessions)-limit]
+		sessions = sessions[len(sessions)-limit:]
+	}
+
+	return evicted, i.save(username, sessions)
+}
+
+// Rename replaces a session ID of the user by a new one, keeping its position in the index.
+func (i *userSessionIndex) Rename(username, id, newID string) error {
+	if i == nil {
+		return nil
+	}
+
+	sessions, err := i.load(username)
+	if err != nil {
+		return err
+	}
+
+	for j, sessionID := range sessions {
+		if sessionID == id {
+			sessions[j] = newID
+			return i.save(username, sessions)
+		}
+	}
+
+	return nil
+}
+
+// Remove forgets a session ID of the user.
+func (i *userSessionIndex) Remove(username, id string) error {
+	if i == nil {
+		return nil
+	}
+
+	sessions, err := i.load(username)
+	if err != nil {
+		return err
+	}
+
+	for j, sessionID := range sessions {
+		if sessionID == id {
+			return i.save(username, append(sessions[:j], sessions[j+1:]...))
+		}
+	}
+
+	return nil
+}
+
+func (i *userSessionIndex) isAlive(id string) (bool, error) {
+	data, err := i.provider.Get([]byte(id))
+	if err != nil {
+		return false, err
+	}
+
+	return len(data) != 0, nil
+}
+
+func (i *userSessionIndex) load(username string) (sessions []string, err error) {
+	data, err := i.provider.Get(userSessionIndexKey(username))
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// save stores the index of the user with the ttl of the index so that the index of a user who no longer logs in doesn't
+// remain in the backend forever, it is also pruned from the expired sessions on every login of the user. A session
+// kept alive for longer than the ttl without any login of the user is therefore no longer tracked once the index of
+// the user expires.
+func (i *userSessionIndex) save(username string, sessions []string) error {
+	if len(sessions) == 0 {
+		return i.provider.Destroy(userSessionIndexKey(username))
+	}
+
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+
+	return i.provider.Save(userSessionIndexKey(username), data, i.ttl)
+}
+
+// userSessionIndexKey returns the key of the index of the user in the session backend. The key contains a semicolon
+// which can't be part of a cookie value so that it can't be loaded as a session.
+func userSessionIndexKey(username string) []byte {
+	return []byte(userSessionIndexKeyPrefix + username)
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+func newTestUserSessionIndex(t *testing.T, aliveIDs ...string) (*userSessionIndex, *memoryProvider) {
+	provider := newMemoryProvider(0, utils.RealClock{})
+
+	for _, id := range aliveIDs {
+		require.NoError(t, provider.Save([]byte(id), []byte("data"), 0))
+	}
+
+	return newUserSessionIndex(provider, 0), provider
+}
+
+func loadTestUserSessionIndex(t *testing.T, index *userSessionIndex, username string) []string {
+	sessions, err := index.load(username)
+	require.NoError(t, err)
+
+	return sessions
+}
+
+func TestShouldNotEvictSessionsWhenUnlimited(t *testing.T) {
+	index, _ := newTestUserSessionIndex(t, "a", "b", "c")
+
+	for _, id := range []string{"a", "b", "c"} {
+		evicted, err := index.Add(testUsername, id, 0)
+		require.NoError(t, err)
+		assert.Len(t, evicted, 0)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, loadTestUserSessionIndex(t, index, testUsername))
+}
+
+func TestShouldEvictOldestSessionsBeyondLimit(t *testing.T) {
+	index, _ := newTestUserSessionIndex(t, "a", "b", "c", "d")
+
+	for _, add := range [][2]string{{testUsername, "a"}, {testUsername, "b"}, {"harry", "c"}} {
+		_, err := index.Add(add[0], add[1], 0)
+		require.NoError(t, err)
+	}
+
+	evicted, err := index.Add(testUsername, "d", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, evicted)
+	assert.Equal(t, []string{"d"}, loadTestUserSessionIndex(t, index, testUsername))
+	assert.Equal(t, []string{"c"}, loadTestUserSessionIndex(t, index, "harry"))
+}
+
+func TestShouldForgetExpiredSessions(t *testing.T) {
+	index, provider := newTestUserSessionIndex(t, "a", "b", "c")
+
+	for _, id := range []string{"a", "b"} {
+		_, err := index.Add(testUsername, id, 0)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, provider.Destroy([]byte("a")))
+
+	evicted, err := index.Add(testUsername, "c", 2)
+	require.NoError(t, err)
+
+	assert.Len(t, evicted, 0)
+	assert.Equal(t, []string{"b", "c"}, loadTestUserSessionIndex(t, index, testUsername))
+}
+
+func TestShouldRenameAndRemoveSessions(t *testing.T) {
+	index, provider := newTestUserSessionIndex(t, "a", "b")
+
+	for _, id := range []string{"a", "b"} {
+		_, err := index.Add(testUsername, id, 0)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, index.Rename(testUsername, "a", "z"))
+	assert.Equal(t, []string{"z", "b"}, loadTestUserSessionIndex(t, index, testUsername))
+
+	require.NoError(t, index.Remove(testUsername, "b"))
+	require.NoError(t, index.Remove(testUsername, "z"))
+	assert.Len(t, loadTestUserSessionIndex(t, index, testUsername), 0)
+
+	// The index of the user is removed from the backend once empty.
+	data, err := provider.Get(userSessionIndexKey(testUsername))
+	require.NoError(t, err)
+	assert.Len(t, data, 0)
+}
+
+func TestShouldShareUserSessionIndexThroughBackend(t *testing.T) {
+	index, provider := newTestUserSessionIndex(t, "a", "b")
+
+	_, err := index.Add(testUsername, "a", 0)
+	require.NoError(t, err)
+
+	// Another instance, or this one after a restart, sees the sessions registered by the first one.
+	evicted, err := newUserSessionIndex(provider, 0).Add(testUsername, "b", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, evicted)
+}
+
+func TestShouldExpireUserSessionIndex(t *testing.T) {
+	provider, clock := newTestMemoryProvider(0)
+	index := newUserSessionIndex(provider, time.Hour)
+
+	require.NoError(t, provider.Save([]byte("a"), []byte("data"), 0))
+
+	_, err := index.Add(testUsername, "a", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, loadTestUserSessionIndex(t, index, testUsername))
+
+	clock.Set(clock.Now().Add(2 * time.Hour))
+
+	assert.Len(t, loadTestUserSessionIndex(t, index, testUsername), 0)
+}
+
+func TestShouldNotTrackSessionsWithoutUserSessionIndex(t *testing.T) {
+	var index *userSessionIndex
+
+	evicted, err := index.Add(testUsername, "a", 1)
+	require.NoError(t, err)
+	assert.Len(t, evicted, 0)
+
+	assert.NoError(t, index.Rename(testUsername, "a", "b"))
+	assert.NoError(t, index.Remove(testUsername, "b"))
+}
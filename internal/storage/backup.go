@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+const backupFormat = "authelia-second-factor-backup"
+const backupVersion = 1
+const backupKDFAlgorithm = "argon2id"
+
+// The key derivation settings of the new backups, the ones of an imported backup are read from its header.
+const (
+	backupKDFIterations  = 3
+	backupKDFMemory      = 64 * 1024
+	backupKDFParallelism = 4
+	backupKDFSaltLength  = 16
+
+	// backupKDFMaxMemory bounds the memory, in KiB, used to derive the key of an imported backup.
+	backupKDFMaxMemory = 1024 * 1024
+)
+
+// backupFile is the header of a backup followed by the encrypted backupContent.
+type backupFile struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	KDF        backupKDF `json:"kdf"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// backupKDF is the derivation of the encryption key of a backup from its passphrase.
+type backupKDF struct {
+	Algorithm   string `json:"algorithm"`
+	Salt        []byte `json:"salt"`
+	Iterations  uint32 `json:"iterations"`
+	Memory      uint32 `json:"memory"`
+	Parallelism uint8  `json:"parallelism"`
+}
+
+// backupContent is the content of a backup, the version is repeated to detect a header swapped with another one.
+type backupContent struct {
+	Version     int                `json:"version"`
+	CreatedAt   time.Time          `json:"created_at"`
+	Enrollments []backupEnrollment `json:"enrollments"`
+}
+
+type backupEnrollment struct {
+	Username        string `json:"username"`
+	PreferredMethod string `json:"preferred_method,omitempty"`
+	TOTPSecret      string `json:"totp_secret,omitempty"`
+	U2FKeyHandle    []byte `json:"u2f_key_handle,omitempty"`
+	U2FPublicKey    []byte `json:"u2f_public_key,omitempty"`
+}
+
+// ExportBackup returns the second factor devices and preferences of all the users of the provider in a backup
+// encrypted with a key derived from the passphrase, along with the number of exported users.
+func ExportBackup(provider Provider, passphrase string, now time.Time) ([]byte, int, error) {
+	if passphrase == "" {
+		return nil, 0, ErrNoBackupPassphrase
+	}
+
+	enrollments, err := provider.LoadSecondFactorEnrollments()
+	if err != nil {
+		return nil, 0, fmt.Errorf("Unable to load the second factor enrollments: %w", err)
+	}
+
+	content := backupContent{Version: backupVersion, CreatedAt: now.UTC(), Enrollments: make([]backupEnrollment, 0, len(enrollments))}
+	for _, enrollment := range enrollments {
+		content.Enrollments = append(content.Enrollments, backupEnrollment(enrollment))
+	}
+
+	plaintext, err := json.Marshal(content)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	kdf := backupKDF{
+		Algorithm:   backupKDFAlgorithm,
+		Salt:        make([]byte, backupKDFSaltLength),
+		Iterations:  backupKDFIterations,
+		Memory:      backupKDFMemory,
+		Parallelism: backupKDFParallelism,
+	}
+
+	if _, err = io.ReadFull(rand.Reader, kdf.Salt); err != nil {
+		return nil, 0, err
+	}
+
+	key := kdf.deriveKey(passphrase)
+
+	ciphertext, err := utils.Encrypt(plaintext, &key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := json.MarshalIndent(backupFile{Format: backupFormat, Version: backupVersion, KDF: kdf, Ciphertext: ciphertext}, "", "  ")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, len(enrollments), nil
+}
+
+// ImportBackup restores the second factor devices and preferences of a backup created by ExportBackup with the same
+// passphrase and returns the number of imported users. The backup is entirely checked before anything is saved and it
+// is refused if the provider already holds second factor enrollments unless force is true, in which case the
+// enrollments of the users of the backup are replaced. A new version of the second factor devices of every imported
+// user is recorded so that their sessions elevated with the replaced devices are revoked.
+func ImportBackup(provider Provider, data []byte, passphrase string, force bool, now time.Time) (int, error) {
+	content, err := readBackup(data, passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	if !force {
+		existing, err := provider.LoadSecondFactorEnrollments()
+		if err != nil {
+			return 0, fmt.Errorf("Unable to load the second factor enrollments: %w", err)
+		}
+
+		if len(existing) != 0 {
+			return 0, ErrStorageNotEmpty
+		}
+	}
+
+	enrollments := make([]models.SecondFactorEnrollment, 0, len(content.Enrollments))
+	for _, enrollment := range content.Enrollments {
+		enrollments = append(enrollments, models.SecondFactorEnrollment(enrollment))
+	}
+
+	if err = provider.SaveSecondFactorEnrollments(enrollments); err != nil {
+		return 0, fmt.Errorf("Unable to save the second factor enrollments: %w", err)
+	}
+
+	for _, enrollment := range enrollments {
+		if err = provider.SaveSecondFactorVersion(enrollment.Username, now.UnixNano()); err != nil {
+			return 0, fmt.Errorf("Unable to save the second factor version of user %s: %w", enrollment.Username, err)
+		}
+	}
+
+	return len(enrollments), nil
+}
+
+// readBackup decrypts the backup and checks its content.
+func readBackup(data []byte, passphrase string) (*backupContent, error) {
+	if passphrase == "" {
+		return nil, ErrNoBackupPassphrase
+	}
+
+	var file backupFile
+
+	if err := json.Unmarshal(data, &file); err != nil || file.Format != backupFormat {
+		return nil, ErrInvalidBackup
+	}
+
+	if file.Version != backupVersion {
+		return nil, fmt.Errorf("The backup version %d is not supported, only version %d is", file.Version, backupVersion)
+	}
+
+	if err := file.KDF.validate(); err != nil {
+		return nil, err
+	}
+
+	key := file.KDF.deriveKey(passphrase)
+
+	plaintext, err := utils.Decrypt(file.Ciphertext, &key)
+	if err != nil {
+		return nil, ErrBackupDecryption
+	}
+
+	var content backupContent
+
+	if err = json.Unmarshal(plaintext, &content); err != nil || content.Version != file.Version {
+		return nil, ErrInvalidBackup
+	}
+
+	usernames := map[string]bool{}
+
+	for _, enrollment := range content.Enrollments {
+		if err = enrollment.validate(); err != nil {
+			return nil, err
+		}
+
+		if usernames[enrollment.Username] {
+			return nil, fmt.Errorf("The backup contains several enrollments of user %s", enrollment.Username)
+		}
+
+		usernames[enrollment.Username] = true
+	}
+
+	return &content, nil
+}
+
+func (k backupKDF) validate() error {
+	if k.Algorithm != backupKDFAlgorithm {
+		return fmt.Errorf("The backup key derivation algorithm %s is not supported", k.Algorithm)
+	}
+
+	if len(k.Salt) == 0 || k.Iterations == 0 || k.Parallelism == 0 || k.Memory == 0 || k.Memory > backupKDFMaxMemory {
+		return ErrInvalidBackup
+	}
+
+	return nil
+}
+
+func (k backupKDF) deriveKey(passphrase string) (key [32]byte) {
+	copy(key[:], argon2.IDKey([]byte(passphrase), k.Salt, k.Iterations, k.Memory, k.Parallelism, uint32(len(key))))
+
+	return key
+}
+
+func (e backupEnrollment) validate() error {
+	if e.Username == "" {
+		return fmt.Errorf("The backup contains an enrollment without username")
+	}
+
+	if e.TOTPSecret != "" {
+		if _, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(e.TOTPSecret, "=")); err != nil {
+			return fmt.Errorf("The backup contains an invalid TOTP secret for user %s", e.Username)
+		}
+	}
+
+	if (len(e.U2FKeyHandle) == 0) != (len(e.U2FPublicKey) == 0) {
+		return fmt.Errorf("The backup contains an incomplete U2F device for user %s", e.Username)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/models"
+)
+
+const backupTestPassphrase = "a passphrase for the backup"
+
+func newBackupTestProvider(t *testing.T, encryptionKey string) *SQLiteProvider {
+	dir, err := ioutil.TempDir("", "authelia-backup")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	provider := NewSQLiteProvider(schema.LocalStorageConfiguration{Path: filepath.Join(dir, "db.sqlite3")})
+	provider.cipher = newValueCipher(encryptionKey, nil)
+
+	t.Cleanup(func() { provider.db.Close() })
+
+	return provider
+}
+
+func newBackupTestEnrollments(t *testing.T, provider Provider) {
+	require.NoError(t, provider.SavePreferred2FAMethod("john", "u2f"))
+	require.NoError(t, provider.SaveTOTPSecret("john", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, provider.SaveU2FDeviceHandle("john", []byte("key-handle"), []byte("public-key")))
+	require.NoError(t, provider.SaveTOTPSecret("harry", "KRSXG5CTMVRXEZLU"))
+	require.NoError(t, provider.SavePreferred2FAMethod("bob", "mobile_push"))
+}
+
+func TestShouldRestoreExportedBackup(t *testing.T) {
+	source := newBackupTestProvider(t, "the encryption key of the source storage")
+	newBackupTestEnrollments(t, source)
+
+	data, count, err := ExportBackup(source, backupTestPassphrase, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	assert.NotContains(t, string(data), "JBSWY3DPEHPK3PXP")
+
+	destination := newBackupTestProvider(t, "the encryption key of the destination storage")
+
+	now := time.Now()
+
+	count, err = ImportBackup(destination, data, backupTestPassphrase, false, now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	for _, username := range []string{"bob", "harry", "john"} {
+		version, err := destination.LoadSecondFactorVersion(username)
+		require.NoError(t, err)
+		assert.Equal(t, now.UnixNano(), version)
+	}
+
+	enrollments, err := destination.LoadSecondFactorEnrollments()
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.SecondFactorEnrollment{
+		{Username: "bob", PreferredMethod: "mobile_push"},
+		{Username: "harry", TOTPSecret: "KRSXG5CTMVRXEZLU"},
+		{
+			Username:        "john",
+			PreferredMethod: "u2f",
+			TOTPSecret:      "JBSWY3DPEHPK3PXP",
+			U2FKeyHandle:    []byte("key-handle"),
+			U2FPublicKey:    []byte("public-key"),
+		},
+	}, enrollments)
+
+	// The secrets are encrypted with the key of the destination storage.
+	secrets, err := destination.loadTOTPSecretsAfter("", 10)
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+
+	_, current, err := destination.cipher.decrypt(secrets[0].secret)
+	require.NoError(t, err)
+	assert.True(t, current)
+}
+
+func TestShouldRefuseToImportBackupIntoNonEmptyStorageWithoutForce(t *testing.T) {
+	source := newBackupTestProvider(t, "")
+	newBackupTestEnrollments(t, source)
+
+	data, _, err := ExportBackup(source, backupTestPassphrase, time.Now())
+	require.NoError(t, err)
+
+	destination := newBackupTestProvider(t, "")
+	require.NoError(t, destination.SaveTOTPSecret("john", "GEZDGNBVGY3TQOJQ"))
+
+	_, err = ImportBackup(destination, data, backupTestPassphrase, false, time.Now())
+	assert.Equal(t, ErrStorageNotEmpty, err)
+
+	secret, err := destination.LoadTOTPSecret("john")
+	require.NoError(t, err)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQ", secret)
+
+	count, err := ImportBackup(destination, data, backupTestPassphrase, true, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	secret, err = destination.LoadTOTPSecret("john")
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+}
+
+func TestShouldRefuseBackupWithWrongPassphrase(t *testing.T) {
+	source := newBackupTestProvider(t, "")
+	newBackupTestEnrollments(t, source)
+
+	data, _, err := ExportBackup(source, backupTestPassphrase, time.Now())
+	require.NoError(t, err)
+
+	_, err = ImportBackup(newBackupTestProvider(t, ""), data, "another passphrase", false, time.Now())
+	assert.Equal(t, ErrBackupDecryption, err)
+}
+
+func TestShouldRefuseAlteredBackup(t *testing.T) {
+	source := newBackupTestProvider(t, "")
+	newBackupTestEnrollments(t, source)
+
+	data, _, err := ExportBackup(source, backupTestPassphrase, time.Now())
+	require.NoError(t, err)
+
+	var file backupFile
+
+	require.NoError(t, json.Unmarshal(data, &file))
+
+	file.Ciphertext[len(file.Ciphertext)-1] ^= 0xff
+
+	altered, err := json.Marshal(file)
+	require.NoError(t, err)
+
+	destination := newBackupTestProvider(t, "")
+
+	_, err = ImportBackup(destination, altered, backupTestPassphrase, false, time.Now())
+	assert.Equal(t, ErrBackupDecryption, err)
+
+	enrollments, err := destination.LoadSecondFactorEnrollments()
+	require.NoError(t, err)
+	assert.Len(t, enrollments, 0)
+}
+
+func TestShouldRefuseInvalidBackups(t *testing.T) {
+	_, err := ImportBackup(nil, []byte("not a backup"), backupTestPassphrase, false, time.Now())
+	assert.Equal(t, ErrInvalidBackup, err)
+
+	_, err = ImportBackup(nil, []byte(`{"format":"authelia-second-factor-backup","version":2}`), backupTestPassphrase, false, time.Now())
+	assert.EqualError(t, err, "The backup version 2 is not supported, only version 1 is")
+
+	_, err = ImportBackup(nil, []byte(`{"format":"authelia-second-factor-backup","version":1,"kdf":{"algorithm":"scrypt"}}`), backupTestPassphrase, false, time.Now())
+	assert.EqualError(t, err, "The backup key derivation algorithm scrypt is not supported")
+
+	_, err = ImportBackup(nil, []byte(`{"format":"authelia-second-factor-backup","version":1}`), "", false, time.Now())
+	assert.Equal(t, ErrNoBackupPassphrase, err)
+}
+
+func TestShouldValidateBackupEnrollments(t *testing.T) {
+	assert.NoError(t, backupEnrollment{Username: "john", TOTPSecret: "JBSWY3DPEHPK3PXP"}.validate())
+
+	assert.EqualError(t, backupEnrollment{TOTPSecret: "JBSWY3DPEHPK3PXP"}.validate(),
+		"The backup contains an enrollment without username")
+	assert.EqualError(t, backupEnrollment{Username: "john", TOTPSecret: "not base32!"}.validate(),
+		"The backup contains an invalid TOTP secret for user john")
+	assert.EqualError(t, backupEnrollment{Username: "john", U2FKeyHandle: []byte("key-handle")}.validate(),
+		"The backup contains an incomplete U2F device for user john")
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 )
 
-const storageSchemaCurrentVersion = SchemaVersion(1)
+const storageSchemaCurrentVersion = SchemaVersion(9)
 const storageSchemaUpgradeMessage = "Storage schema upgraded to v"
 const storageSchemaUpgradeErrorText = "storage schema upgrade failed at v"
 
@@ -14,8 +14,17 @@ const identityVerificationTokensTableName = "identity_verification_tokens"
 const totpSecretsTableName = "totp_secrets"
 const u2fDeviceHandlesTableName = "u2f_devices"
 const authenticationLogsTableName = "authentication_logs"
+const passwordHistoryTableName = "password_history"
+const loginDevicesTableName = "login_devices"
+const secondFactorVersionsTableName = "second_factor_versions"
+const verifiedEmailsTableName = "verified_emails"
+const pendingRegistrationsTableName = "pending_registrations"
+const lastLoginsTableName = "last_logins"
 const configTableName = "config"
 
+// loginDevicesLoadLimit is the maximum number of the most recent login devices of a user loaded to recognise a login.
+const loginDevicesLoadLimit = 100
+
 // sqlUpgradeCreateTableStatements is a map of the schema version number, plus a map of the table name and the statement used to create it.
 // The statement is fmt.Sprintf'd with the table name as the first argument.
 var sqlUpgradeCreateTableStatements = map[SchemaVersion]map[string]string{
@@ -27,6 +36,24 @@ var sqlUpgradeCreateTableStatements = map[SchemaVersion]map[string]string{
 		authenticationLogsTableName:         "CREATE TABLE %s (username VARCHAR(100), successful BOOL, time INTEGER)",
 		configTableName:                     "CREATE TABLE %s (category VARCHAR(32) NOT NULL, key_name VARCHAR(32) NOT NULL, value TEXT, PRIMARY KEY (category, key_name))",
 	},
+	SchemaVersion(2): {
+		passwordHistoryTableName: "CREATE TABLE %s (username VARCHAR(100), hash TEXT, time INTEGER)",
+	},
+	SchemaVersion(3): {
+		loginDevicesTableName: "CREATE TABLE %s (username VARCHAR(100), fingerprint VARCHAR(64), ip VARCHAR(45), time INTEGER)",
+	},
+	SchemaVersion(4): {
+		secondFactorVersionsTableName: "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, version BIGINT)",
+	},
+	SchemaVersion(5): {
+		verifiedEmailsTableName: "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, email VARCHAR(255), time INTEGER)",
+	},
+	SchemaVersion(7): {
+		pendingRegistrationsTableName: "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, display_name VARCHAR(255), email VARCHAR(255), hash TEXT, verified BOOL, time INTEGER)",
+	},
+	SchemaVersion(8): {
+		lastLoginsTableName: "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, ip VARCHAR(45), method VARCHAR(32), time INTEGER)",
+	},
 }
 
 // sqlUpgradesCreateTableIndexesStatements is a map of t he schema version number, plus a slice of statements to create all of the indexes.
@@ -34,6 +61,39 @@ var sqlUpgradesCreateTableIndexesStatements = map[SchemaVersion][]string{
 	SchemaVersion(1): {
 		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s (username, time)", authenticationLogsTableName),
 	},
+	SchemaVersion(2): {
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS pwd_usr_time_idx ON %s (username, time)", passwordHistoryTableName),
+	},
+	SchemaVersion(3): {
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS dev_usr_idx ON %s (username)", loginDevicesTableName),
+	},
+}
+
+// sqlUpgradesAlterTableStatements is a map of the schema version number, plus a slice of statements altering the
+// existing tables.
+var sqlUpgradesAlterTableStatements = map[SchemaVersion][]string{
+	SchemaVersion(6): {
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN reservation VARCHAR(64)", authenticationLogsTableName),
+	},
+}
+
+// totpSecretColumnLength is the length of the TOTP secret column, large enough for a secret encrypted at rest.
+const totpSecretColumnLength = 255
+
+// mysqlUpgradesAlterColumnStatements is a map of the schema version number, plus a slice of statements changing the type
+// of existing columns of a MySQL database. SQLite doesn't enforce the length of the columns and doesn't need them.
+var mysqlUpgradesAlterColumnStatements = map[SchemaVersion][]string{
+	SchemaVersion(9): {
+		fmt.Sprintf("ALTER TABLE %s MODIFY secret VARCHAR(%d)", totpSecretsTableName, totpSecretColumnLength),
+	},
+}
+
+// postgresUpgradesAlterColumnStatements is a map of the schema version number, plus a slice of statements changing the
+// type of existing columns of a PostgreSQL database.
+var postgresUpgradesAlterColumnStatements = map[SchemaVersion][]string{
+	SchemaVersion(9): {
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN secret TYPE VARCHAR(%d)", totpSecretsTableName, totpSecretColumnLength),
+	},
 }
 
 const unitTestUser = "john"
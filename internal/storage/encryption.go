@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// encryptedValuePrefix prefixes the values encrypted at rest to tell them apart from the ones stored before an
+// encryption key was configured.
+const encryptedValuePrefix = "$aes256-gcm$"
+
+// valueCipher encrypts the sensitive values at rest with AES-GCM. Values are always encrypted with the key derived from
+// the current encryption key while the previous keys are only used to decrypt the values encrypted before a rotation.
+type valueCipher struct {
+	key          [32]byte
+	previousKeys [][32]byte
+}
+
+// newValueCipher returns the cipher for the given keys or nil if no encryption key is configured.
+func newValueCipher(key string, previousKeys []string) *valueCipher {
+	if key == "" {
+		return nil
+	}
+
+	cipher := &valueCipher{key: sha256.Sum256([]byte(key))}
+
+	for _, previousKey := range previousKeys {
+		cipher.previousKeys = append(cipher.previousKeys, sha256.Sum256([]byte(previousKey)))
+	}
+
+	return cipher
+}
+
+// encrypt returns the value encrypted with the current key, or the value itself if the cipher is nil.
+func (c *valueCipher) encrypt(value string) (string, error) {
+	if c == nil {
+		return value, nil
+	}
+
+	ciphertext, err := utils.Encrypt([]byte(value), &c.key)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decrypt returns the plain value and whether it is stored as expected, that is encrypted with the current key or in
+// clear if no encryption key is configured.
+func (c *valueCipher) decrypt(value string) (plaintext string, current bool, err error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, c == nil, nil
+	}
+
+	if c == nil {
+		return "", false, ErrNoEncryptionKey
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", false, fmt.Errorf("Unable to decode the encrypted value: %w", err)
+	}
+
+	if decrypted, err := utils.Decrypt(ciphertext, &c.key); err == nil {
+		return string(decrypted), true, nil
+	}
+
+	for i := range c.previousKeys {
+		if decrypted, err := utils.Decrypt(ciphertext, &c.previousKeys[i]); err == nil {
+			return string(decrypted), false, nil
+		}
+	}
+
+	return "", false, ErrUnknownEncryptionKey
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldEncryptAndDecryptValueWithCurrentKey(t *testing.T) {
+	cipher := newValueCipher("a_very_long_storage_encryption_key", nil)
+
+	encrypted, err := cipher.encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(encrypted, encryptedValuePrefix))
+	assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+	plaintext, current, err := cipher.decrypt(encrypted)
+	require.NoError(t, err)
+
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+	assert.True(t, current)
+}
+
+func TestShouldDecryptValueWithPreviousKey(t *testing.T) {
+	encrypted, err := newValueCipher("an_old_very_long_storage_encryption_key", nil).encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	cipher := newValueCipher("a_very_long_storage_encryption_key", []string{"an_old_very_long_storage_encryption_key"})
+
+	plaintext, current, err := cipher.decrypt(encrypted)
+	require.NoError(t, err)
+
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+	assert.False(t, current)
+}
+
+func TestShouldFailToDecryptValueWithUnknownKey(t *testing.T) {
+	encrypted, err := newValueCipher("an_old_very_long_storage_encryption_key", nil).encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	_, _, err = newValueCipher("a_very_long_storage_encryption_key", nil).decrypt(encrypted)
+	assert.Equal(t, ErrUnknownEncryptionKey, err)
+
+	_, _, err = (*valueCipher)(nil).decrypt(encrypted)
+	assert.Equal(t, ErrNoEncryptionKey, err)
+}
+
+func TestShouldReadValuesStoredInClear(t *testing.T) {
+	plaintext, current, err := newValueCipher("a_very_long_storage_encryption_key", nil).decrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+	assert.False(t, current)
+
+	var cipher *valueCipher
+
+	encrypted, err := cipher.encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", encrypted)
+
+	plaintext, current, err = cipher.decrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+	assert.True(t, current)
+}
+
+func TestShouldFitEncryptedTOTPSecretInColumn(t *testing.T) {
+	cipher := newValueCipher("a_very_long_storage_encryption_key", nil)
+
+	// The secrets are 32 characters long but the column used to store up to 64 characters in clear.
+	for _, secret := range []string{strings.Repeat("A", 32), strings.Repeat("A", 64)} {
+		encrypted, err := cipher.encrypt(secret)
+		require.NoError(t, err)
+
+		assert.Greater(t, len(encrypted), 64)
+		assert.LessOrEqual(t, len(encrypted), totpSecretColumnLength)
+	}
+}
@@ -8,4 +8,22 @@ var (
 
 	// ErrNoTOTPSecret error thrown when no TOTP secret has been found in DB.
 	ErrNoTOTPSecret = errors.New("No TOTP secret registered")
+
+	// ErrNoEncryptionKey error thrown when an encrypted value is read but no encryption key is configured.
+	ErrNoEncryptionKey = errors.New("The value is encrypted but no storage encryption key is configured")
+
+	// ErrUnknownEncryptionKey error thrown when an encrypted value can be decrypted by none of the configured keys.
+	ErrUnknownEncryptionKey = errors.New("The value is encrypted with a key which is not configured")
+
+	// ErrNoBackupPassphrase error thrown when a backup is exported or imported without passphrase.
+	ErrNoBackupPassphrase = errors.New("A passphrase is required to encrypt and decrypt the backups")
+
+	// ErrInvalidBackup error thrown when the imported file is not a backup or is malformed.
+	ErrInvalidBackup = errors.New("The file is not a valid backup")
+
+	// ErrBackupDecryption error thrown when a backup can't be decrypted, its integrity being checked at the same time.
+	ErrBackupDecryption = errors.New("Unable to decrypt the backup, the passphrase is wrong or the backup is corrupted")
+
+	// ErrStorageNotEmpty error thrown when a backup is imported into a storage already holding second factor enrollments.
+	ErrStorageNotEmpty = errors.New("The storage already holds second factor enrollments")
 )
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// lastLoginFlushInterval is the interval at which the recorded last logins are written to the storage.
+const lastLoginFlushInterval = time.Second
+
+// lastLoginMaxPending bounds the number of users whose last login waits to be written to the storage, so that the
+// memory used by the recorder doesn't grow while the storage is unavailable.
+const lastLoginMaxPending = 10000
+
+// LastLoginRecorder records the last successful authentication of the users without slowing the logins down. The
+// logins are kept in memory, only the most recent one of each user, and written to the storage in batches.
+type LastLoginRecorder struct {
+	provider Provider
+	clock    utils.Clock
+	log      *logrus.Logger
+
+	mutex   sync.Mutex
+	pending map[string]models.LastLogin
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewLastLoginRecorder creates a LastLoginRecorder writing the last logins to the given storage provider.
+func NewLastLoginRecorder(provider Provider, clock utils.Clock) *LastLoginRecorder {
+	return &LastLoginRecorder{
+		provider: provider,
+		clock:    clock,
+		log:      logging.Logger(),
+		pending:  map[string]models.LastLogin{},
+	}
+}
+
+// Record records the last login of a user, it is written to the storage by the next flush. The login is dropped if too
+// many logins of other users are waiting to be written.
+func (r *LastLoginRecorder) Record(login models.LastLogin) {
+	r.mutex.Lock()
+	recorded := r.add(login)
+	r.mutex.Unlock()
+
+	if !recorded {
+		r.log.Warnf("Unable to record the last login of user %s since %d last logins are waiting to be saved",
+			login.Username, lastLoginMaxPending)
+	}
+}
+
+// add adds the login to the pending ones unless they are full, the mutex must be held.
+func (r *LastLoginRecorder) add(login models.LastLogin) bool {
+	if _, ok := r.pending[login.Username]; !ok && len(r.pending) >= lastLoginMaxPending {
+		return false
+	}
+
+	r.pending[login.Username] = login
+
+	return true
+}
+
+// Load load the last login of a user, the one not written to the storage yet if any. It returns nil if the user never
+// logged in.
+func (r *LastLoginRecorder) Load(username string) (*models.LastLogin, error) {
+	r.mutex.Lock()
+	login, ok := r.pending[username]
+	r.mutex.Unlock()
+
+	if ok {
+		return &login, nil
+	}
+
+	return r.provider.LoadLastLogin(username)
+}
+
+// Flush writes the recorded last logins to the storage and returns their number. The logins are recorded again if
+// they can't be written so that they are retried by the next flush, unless a more recent login has been recorded.
+func (r *LastLoginRecorder) Flush() (int, error) {
+	r.mutex.Lock()
+	pending := r.pending
+	r.pending = map[string]models.LastLogin{}
+	r.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	logins := make([]models.LastLogin, 0, len(pending))
+	for _, login := range pending {
+		logins = append(logins, login)
+	}
+
+	if err := r.provider.SaveLastLogins(logins); err != nil {
+		r.mutex.Lock()
+
+		for username, login := range pending {
+			if _, ok := r.pending[username]; !ok {
+				r.add(login)
+			}
+		}
+
+		r.mutex.Unlock()
+
+		return 0, err
+	}
+
+	return len(logins), nil
+}
+
+// Start writes the recorded last logins to the storage in the background until Stop is called.
+func (r *LastLoginRecorder) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-r.clock.After(lastLoginFlushInterval):
+			}
+
+			if _, err := r.Flush(); err != nil {
+				r.log.Errorf("Unable to save the last logins: %s", err)
+			}
+		}
+	}()
+}
+
+// Stop stops the background writes, waiting for the one in progress, and writes the last logins recorded since then so
+// that they are not lost when Authelia shuts down.
+func (r *LastLoginRecorder) Stop() error {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.done
+
+		r.stop = nil
+	}
+
+	_, err := r.Flush()
+
+	return err
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/models"
+)
+
+func TestShouldFlushOnlyTheMostRecentLastLoginOfEachUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+	recorder := NewLastLoginRecorder(provider, fixedClock{now: time.Unix(1600000000, 0)})
+
+	recorder.Record(models.LastLogin{Username: "john", IP: "192.168.1.10", Method: "password", Time: time.Unix(1600000000, 0)})
+	recorder.Record(models.LastLogin{Username: "john", IP: "192.168.1.10", Method: "totp", Time: time.Unix(1600000005, 0)})
+
+	login, err := recorder.Load("john")
+	require.NoError(t, err)
+	assert.Equal(t, "totp", login.Method)
+
+	provider.EXPECT().
+		SaveLastLogins(gomock.Eq([]models.LastLogin{
+			{Username: "john", IP: "192.168.1.10", Method: "totp", Time: time.Unix(1600000005, 0)},
+		})).
+		Return(nil)
+
+	count, err := recorder.Flush()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Nothing is written when no login has been recorded since the last flush.
+	count, err = recorder.Flush()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	provider.EXPECT().
+		LoadLastLogin(gomock.Eq("john")).
+		Return(&models.LastLogin{Username: "john", IP: "192.168.1.10", Method: "totp", Time: time.Unix(1600000005, 0)}, nil)
+
+	login, err = recorder.Load("john")
+	require.NoError(t, err)
+	assert.Equal(t, "totp", login.Method)
+}
+
+func TestShouldRetryLastLoginsWhichCannotBeSaved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+	recorder := NewLastLoginRecorder(provider, fixedClock{now: time.Unix(1600000000, 0)})
+
+	login := models.LastLogin{Username: "john", IP: "192.168.1.10", Method: "password", Time: time.Unix(1600000000, 0)}
+	recorder.Record(login)
+
+	gomock.InOrder(
+		provider.EXPECT().
+			SaveLastLogins(gomock.Eq([]models.LastLogin{login})).
+			Return(errors.New("database is down")),
+		provider.EXPECT().
+			SaveLastLogins(gomock.Eq([]models.LastLogin{login})).
+			Return(nil),
+	)
+
+	count, err := recorder.Flush()
+	assert.EqualError(t, err, "database is down")
+	assert.Equal(t, 0, count)
+
+	count, err = recorder.Flush()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestShouldDropLastLoginsOfNewUsersWhenTooManyArePending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+	recorder := NewLastLoginRecorder(provider, fixedClock{now: time.Unix(1600000000, 0)})
+
+	for i := 0; i < lastLoginMaxPending; i++ {
+		recorder.Record(models.LastLogin{Username: fmt.Sprintf("user%d", i), Method: "password", Time: time.Unix(1600000000, 0)})
+	}
+
+	recorder.Record(models.LastLogin{Username: "john", Method: "password", Time: time.Unix(1600000000, 0)})
+	recorder.Record(models.LastLogin{Username: "user0", Method: "totp", Time: time.Unix(1600000005, 0)})
+
+	assert.Len(t, recorder.pending, lastLoginMaxPending)
+	assert.NotContains(t, recorder.pending, "john")
+
+	// The users already waiting to be saved keep their most recent login.
+	assert.Equal(t, "totp", recorder.pending["user0"].Method)
+}
+
+func TestShouldFlushLastLoginsWhenStopped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+	recorder := NewLastLoginRecorder(provider, fixedClock{now: time.Unix(1600000000, 0)})
+
+	recorder.Start()
+
+	login := models.LastLogin{Username: "john", IP: "192.168.1.10", Method: "password", Time: time.Unix(1600000000, 0)}
+	recorder.Record(login)
+
+	provider.EXPECT().
+		SaveLastLogins(gomock.Eq([]models.LastLogin{login})).
+		Return(nil)
+
+	require.NoError(t, recorder.Stop())
+	assert.Len(t, recorder.pending, 0)
+}
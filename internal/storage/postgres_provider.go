@@ -23,23 +23,56 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 
 			sqlUpgradesCreateTableStatements:        sqlUpgradeCreateTableStatements,
 			sqlUpgradesCreateTableIndexesStatements: sqlUpgradesCreateTableIndexesStatements,
+			sqlUpgradesAlterColumnStatements:        postgresUpgradesAlterColumnStatements,
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=$1", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("INSERT INTO %s (username, second_factor_method) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET second_factor_method=$2", userPreferencesTableName),
+			sqlGetPreferences:               fmt.Sprintf("SELECT username, second_factor_method FROM %s", userPreferencesTableName),
 
 			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=$1)", identityVerificationTokensTableName),
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES ($1)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=$1", identityVerificationTokensTableName),
+			sqlGetIdentityVerificationTokens:          fmt.Sprintf("SELECT token FROM %s", identityVerificationTokensTableName),
 
 			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=$1", totpSecretsTableName),
 			sqlUpsertTOTPSecret:        fmt.Sprintf("INSERT INTO %s (username, secret) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET secret=$2", totpSecretsTableName),
 			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=$1", totpSecretsTableName),
+			sqlGetTOTPSecretsAfter:     fmt.Sprintf("SELECT username, secret FROM %s WHERE username>$1 ORDER BY username ASC LIMIT $2", totpSecretsTableName),
+			sqlGetTOTPSecrets:          fmt.Sprintf("SELECT username, secret FROM %s", totpSecretsTableName),
 
 			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=$1", u2fDeviceHandlesTableName),
 			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("INSERT INTO %s (username, keyHandle, publicKey) VALUES ($1, $2, $3) ON CONFLICT (username) DO UPDATE SET keyHandle=$2, publicKey=$3", u2fDeviceHandlesTableName),
+			sqlGetU2FDeviceHandles:          fmt.Sprintf("SELECT username, keyHandle, publicKey FROM %s", u2fDeviceHandlesTableName),
 
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES ($1, $2, $3)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlInsertAuthenticationLog:         fmt.Sprintf("INSERT INTO %s (username, successful, time, reservation) VALUES ($1, $2, $3, $4)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:     fmt.Sprintf("SELECT successful, time, reservation FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlUpdateReservedAuthenticationLog: fmt.Sprintf("UPDATE %s SET successful=$1, reservation=NULL WHERE username=$2 AND reservation=$3", authenticationLogsTableName),
+			sqlDeleteReservedAuthenticationLog: fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND reservation=$2", authenticationLogsTableName),
+
+			sqlDeleteAuthenticationLogsBefore: fmt.Sprintf("DELETE FROM %s WHERE time<$1", authenticationLogsTableName),
+			sqlDeleteFailedAuthenticationLogs: fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND successful=$2", authenticationLogsTableName),
+
+			sqlInsertPasswordHistory:    fmt.Sprintf("INSERT INTO %s (username, hash, time) VALUES ($1, $2, $3)", passwordHistoryTableName),
+			sqlGetLatestPasswordHistory: fmt.Sprintf("SELECT hash, time FROM %s WHERE username=$1 ORDER BY time DESC LIMIT $2", passwordHistoryTableName),
+
+			sqlInsertLoginDevice:        fmt.Sprintf("INSERT INTO %s (username, fingerprint, ip, time) VALUES ($1, $2, $3, $4)", loginDevicesTableName),
+			sqlUpdateLoginDeviceTime:    fmt.Sprintf("UPDATE %s SET time=$1 WHERE username=$2 AND fingerprint=$3 AND ip=$4", loginDevicesTableName),
+			sqlGetLoginDevices:          fmt.Sprintf("SELECT fingerprint, ip, time FROM %s WHERE username=$1 ORDER BY time DESC LIMIT %d", loginDevicesTableName, loginDevicesLoadLimit),
+			sqlDeleteLoginDevicesBefore: fmt.Sprintf("DELETE FROM %s WHERE time<$1", loginDevicesTableName),
+
+			sqlGetSecondFactorVersion:    fmt.Sprintf("SELECT version FROM %s WHERE username=$1", secondFactorVersionsTableName),
+			sqlUpsertSecondFactorVersion: fmt.Sprintf("INSERT INTO %s (username, version) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET version=$2", secondFactorVersionsTableName),
+
+			sqlGetVerifiedEmail:    fmt.Sprintf("SELECT email FROM %s WHERE username=$1", verifiedEmailsTableName),
+			sqlUpsertVerifiedEmail: fmt.Sprintf("INSERT INTO %s (username, email, time) VALUES ($1, $2, $3) ON CONFLICT (username) DO UPDATE SET email=$2, time=$3", verifiedEmailsTableName),
+
+			sqlGetPendingRegistration:          fmt.Sprintf("SELECT display_name, email, hash, verified, time FROM %s WHERE username=$1", pendingRegistrationsTableName),
+			sqlGetVerifiedPendingRegistrations: fmt.Sprintf("SELECT username, display_name, email, hash, time FROM %s WHERE verified=$1 ORDER BY time ASC", pendingRegistrationsTableName),
+			sqlUpsertPendingRegistration:       fmt.Sprintf("INSERT INTO %s (username, display_name, email, hash, verified, time) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (username) DO UPDATE SET display_name=$2, email=$3, hash=$4, verified=$5, time=$6", pendingRegistrationsTableName),
+			sqlDeletePendingRegistration:       fmt.Sprintf("DELETE FROM %s WHERE username=$1", pendingRegistrationsTableName),
+
+			sqlGetLastLogin:    fmt.Sprintf("SELECT ip, method, time FROM %s WHERE username=$1", lastLoginsTableName),
+			sqlUpsertLastLogin: fmt.Sprintf("INSERT INTO %s (username, ip, method, time) VALUES ($1, $2, $3, $4) ON CONFLICT (username) DO UPDATE SET ip=$2, method=$3, time=$4", lastLoginsTableName),
 
 			sqlGetExistingTables: "SELECT table_name FROM information_schema.tables WHERE table_type='BASE TABLE' AND table_schema='public'",
 
@@ -80,6 +113,8 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 		provider.log.Fatalf("Unable to connect to SQL database: %v", err)
 	}
 
+	configureConnectionPool(db, configuration.SQLStorageConfiguration)
+
 	if err := provider.initialize(db); err != nil {
 		provider.log.Fatalf("Unable to initialize SQL database: %v", err)
 	}
@@ -3,6 +3,7 @@ package storage
 import (
 	"time"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/models"
 )
 
@@ -14,15 +15,73 @@ type Provider interface {
 
 	FindIdentityVerificationToken(token string) (bool, error)
 	SaveIdentityVerificationToken(token string) error
-	RemoveIdentityVerificationToken(token string) error
+	ConsumeIdentityVerificationToken(token string) (bool, error)
 
 	SaveTOTPSecret(username string, secret string) error
 	LoadTOTPSecret(username string) (string, error)
 	DeleteTOTPSecret(username string) error
+	ReencryptTOTPSecrets(batchSize int) (int, error)
 
 	SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error
 	LoadU2FDeviceHandle(username string) (keyHandle []byte, publicKey []byte, err error)
 
+	LoadSecondFactorEnrollments() ([]models.SecondFactorEnrollment, error)
+	SaveSecondFactorEnrollments(enrollments []models.SecondFactorEnrollment) error
+
 	AppendAuthenticationLog(attempt models.AuthenticationAttempt) error
 	LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	ConfirmAuthenticationLog(username string, reservation string, successful bool) error
+	DeleteAuthenticationLog(username string, reservation string) error
+	DeleteFailedAuthenticationLogs(username string) (int, error)
+
+	AppendPasswordHistory(entry models.PasswordHistoryEntry) error
+	LoadLatestPasswordHistory(username string, limit int) ([]models.PasswordHistoryEntry, error)
+
+	SaveLoginDevice(device models.LoginDevice) error
+	LoadLoginDevices(username string) ([]models.LoginDevice, error)
+
+	LoadSecondFactorVersion(username string) (int64, error)
+	SaveSecondFactorVersion(username string, version int64) error
+
+	LoadVerifiedEmail(username string) (string, error)
+	SaveVerifiedEmail(username string, email string, verifiedAt time.Time) error
+
+	LoadPendingRegistration(username string) (*models.PendingRegistration, error)
+	LoadVerifiedPendingRegistrations() ([]models.PendingRegistration, error)
+	SavePendingRegistration(registration models.PendingRegistration) error
+	DeletePendingRegistration(username string) error
+
+	LoadLastLogin(username string) (*models.LastLogin, error)
+	SaveLastLogins(logins []models.LastLogin) error
+
+	PruneIdentityVerificationTokens(isExpired func(token string) bool, batchSize int) (int, error)
+	PruneAuthenticationLogs(before time.Time) (int, error)
+	PruneLoginDevices(before time.Time) (int, error)
+
+	StartupCheck() (bool, error)
+}
+
+// NewProvider creates the storage provider matching the given configuration, it returns nil if no backend is configured.
+func NewProvider(configuration schema.StorageConfiguration) Provider {
+	cipher := newValueCipher(configuration.EncryptionKey, configuration.PreviousEncryptionKeys)
+
+	switch {
+	case configuration.PostgreSQL != nil:
+		provider := NewPostgreSQLProvider(*configuration.PostgreSQL)
+		provider.cipher = cipher
+
+		return provider
+	case configuration.MySQL != nil:
+		provider := NewMySQLProvider(*configuration.MySQL)
+		provider.cipher = cipher
+
+		return provider
+	case configuration.Local != nil:
+		provider := NewSQLiteProvider(*configuration.Local)
+		provider.cipher = cipher
+
+		return provider
+	default:
+		return nil
+	}
 }
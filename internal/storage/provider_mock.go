@@ -94,18 +94,19 @@ func (mr *MockProviderMockRecorder) SaveIdentityVerificationToken(token interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).SaveIdentityVerificationToken), token)
 }
 
-// RemoveIdentityVerificationToken mocks base method
-func (m *MockProvider) RemoveIdentityVerificationToken(token string) error {
+// ConsumeIdentityVerificationToken mocks base method
+func (m *MockProvider) ConsumeIdentityVerificationToken(token string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveIdentityVerificationToken", token)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "ConsumeIdentityVerificationToken", token)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// RemoveIdentityVerificationToken indicates an expected call of RemoveIdentityVerificationToken
-func (mr *MockProviderMockRecorder) RemoveIdentityVerificationToken(token interface{}) *gomock.Call {
+// ConsumeIdentityVerificationToken indicates an expected call of ConsumeIdentityVerificationToken
+func (mr *MockProviderMockRecorder) ConsumeIdentityVerificationToken(token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).RemoveIdentityVerificationToken), token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).ConsumeIdentityVerificationToken), token)
 }
 
 // SaveTOTPSecret mocks base method
@@ -137,6 +138,21 @@ func (mr *MockProviderMockRecorder) LoadTOTPSecret(username interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadTOTPSecret", reflect.TypeOf((*MockProvider)(nil).LoadTOTPSecret), username)
 }
 
+// ReencryptTOTPSecrets mocks base method
+func (m *MockProvider) ReencryptTOTPSecrets(batchSize int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReencryptTOTPSecrets", batchSize)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReencryptTOTPSecrets indicates an expected call of ReencryptTOTPSecrets
+func (mr *MockProviderMockRecorder) ReencryptTOTPSecrets(batchSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReencryptTOTPSecrets", reflect.TypeOf((*MockProvider)(nil).ReencryptTOTPSecrets), batchSize)
+}
+
 // DeleteTOTPSecret mocks base method
 func (m *MockProvider) DeleteTOTPSecret(username string) error {
 	m.ctrl.T.Helper()
@@ -181,6 +197,35 @@ func (mr *MockProviderMockRecorder) LoadU2FDeviceHandle(username interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadU2FDeviceHandle", reflect.TypeOf((*MockProvider)(nil).LoadU2FDeviceHandle), username)
 }
 
+// LoadSecondFactorEnrollments mocks base method
+func (m *MockProvider) LoadSecondFactorEnrollments() ([]models.SecondFactorEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadSecondFactorEnrollments")
+	ret0, _ := ret[0].([]models.SecondFactorEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadSecondFactorEnrollments indicates an expected call of LoadSecondFactorEnrollments
+func (mr *MockProviderMockRecorder) LoadSecondFactorEnrollments() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadSecondFactorEnrollments", reflect.TypeOf((*MockProvider)(nil).LoadSecondFactorEnrollments))
+}
+
+// SaveSecondFactorEnrollments mocks base method
+func (m *MockProvider) SaveSecondFactorEnrollments(enrollments []models.SecondFactorEnrollment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSecondFactorEnrollments", enrollments)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSecondFactorEnrollments indicates an expected call of SaveSecondFactorEnrollments
+func (mr *MockProviderMockRecorder) SaveSecondFactorEnrollments(enrollments interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSecondFactorEnrollments", reflect.TypeOf((*MockProvider)(nil).SaveSecondFactorEnrollments), enrollments)
+}
+
 // AppendAuthenticationLog mocks base method
 func (m *MockProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
 	m.ctrl.T.Helper()
@@ -209,3 +254,309 @@ func (mr *MockProviderMockRecorder) LoadLatestAuthenticationLogs(username, fromD
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadLatestAuthenticationLogs), username, fromDate)
 }
+
+// StartupCheck mocks base method
+func (m *MockProvider) StartupCheck() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartupCheck")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartupCheck indicates an expected call of StartupCheck
+func (mr *MockProviderMockRecorder) StartupCheck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartupCheck", reflect.TypeOf((*MockProvider)(nil).StartupCheck))
+}
+
+// AppendPasswordHistory mocks base method
+func (m *MockProvider) AppendPasswordHistory(entry models.PasswordHistoryEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendPasswordHistory", entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AppendPasswordHistory indicates an expected call of AppendPasswordHistory
+func (mr *MockProviderMockRecorder) AppendPasswordHistory(entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendPasswordHistory", reflect.TypeOf((*MockProvider)(nil).AppendPasswordHistory), entry)
+}
+
+// LoadLatestPasswordHistory mocks base method
+func (m *MockProvider) LoadLatestPasswordHistory(username string, limit int) ([]models.PasswordHistoryEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLatestPasswordHistory", username, limit)
+	ret0, _ := ret[0].([]models.PasswordHistoryEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLatestPasswordHistory indicates an expected call of LoadLatestPasswordHistory
+func (mr *MockProviderMockRecorder) LoadLatestPasswordHistory(username, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestPasswordHistory", reflect.TypeOf((*MockProvider)(nil).LoadLatestPasswordHistory), username, limit)
+}
+
+// SaveLoginDevice mocks base method
+func (m *MockProvider) SaveLoginDevice(device models.LoginDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLoginDevice", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLoginDevice indicates an expected call of SaveLoginDevice
+func (mr *MockProviderMockRecorder) SaveLoginDevice(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLoginDevice", reflect.TypeOf((*MockProvider)(nil).SaveLoginDevice), device)
+}
+
+// LoadLoginDevices mocks base method
+func (m *MockProvider) LoadLoginDevices(username string) ([]models.LoginDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLoginDevices", username)
+	ret0, _ := ret[0].([]models.LoginDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLoginDevices indicates an expected call of LoadLoginDevices
+func (mr *MockProviderMockRecorder) LoadLoginDevices(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLoginDevices", reflect.TypeOf((*MockProvider)(nil).LoadLoginDevices), username)
+}
+
+// LoadSecondFactorVersion mocks base method
+func (m *MockProvider) LoadSecondFactorVersion(username string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadSecondFactorVersion", username)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadSecondFactorVersion indicates an expected call of LoadSecondFactorVersion
+func (mr *MockProviderMockRecorder) LoadSecondFactorVersion(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadSecondFactorVersion", reflect.TypeOf((*MockProvider)(nil).LoadSecondFactorVersion), username)
+}
+
+// SaveSecondFactorVersion mocks base method
+func (m *MockProvider) SaveSecondFactorVersion(username string, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSecondFactorVersion", username, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSecondFactorVersion indicates an expected call of SaveSecondFactorVersion
+func (mr *MockProviderMockRecorder) SaveSecondFactorVersion(username, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSecondFactorVersion", reflect.TypeOf((*MockProvider)(nil).SaveSecondFactorVersion), username, version)
+}
+
+// LoadVerifiedEmail mocks base method
+func (m *MockProvider) LoadVerifiedEmail(username string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadVerifiedEmail", username)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadVerifiedEmail indicates an expected call of LoadVerifiedEmail
+func (mr *MockProviderMockRecorder) LoadVerifiedEmail(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadVerifiedEmail", reflect.TypeOf((*MockProvider)(nil).LoadVerifiedEmail), username)
+}
+
+// SaveVerifiedEmail mocks base method
+func (m *MockProvider) SaveVerifiedEmail(username, email string, verifiedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveVerifiedEmail", username, email, verifiedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveVerifiedEmail indicates an expected call of SaveVerifiedEmail
+func (mr *MockProviderMockRecorder) SaveVerifiedEmail(username, email, verifiedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveVerifiedEmail", reflect.TypeOf((*MockProvider)(nil).SaveVerifiedEmail), username, email, verifiedAt)
+}
+
+// LoadPendingRegistration mocks base method
+func (m *MockProvider) LoadPendingRegistration(username string) (*models.PendingRegistration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadPendingRegistration", username)
+	ret0, _ := ret[0].(*models.PendingRegistration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadPendingRegistration indicates an expected call of LoadPendingRegistration
+func (mr *MockProviderMockRecorder) LoadPendingRegistration(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPendingRegistration", reflect.TypeOf((*MockProvider)(nil).LoadPendingRegistration), username)
+}
+
+// LoadVerifiedPendingRegistrations mocks base method
+func (m *MockProvider) LoadVerifiedPendingRegistrations() ([]models.PendingRegistration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadVerifiedPendingRegistrations")
+	ret0, _ := ret[0].([]models.PendingRegistration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadVerifiedPendingRegistrations indicates an expected call of LoadVerifiedPendingRegistrations
+func (mr *MockProviderMockRecorder) LoadVerifiedPendingRegistrations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadVerifiedPendingRegistrations", reflect.TypeOf((*MockProvider)(nil).LoadVerifiedPendingRegistrations))
+}
+
+// SavePendingRegistration mocks base method
+func (m *MockProvider) SavePendingRegistration(registration models.PendingRegistration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePendingRegistration", registration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePendingRegistration indicates an expected call of SavePendingRegistration
+func (mr *MockProviderMockRecorder) SavePendingRegistration(registration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePendingRegistration", reflect.TypeOf((*MockProvider)(nil).SavePendingRegistration), registration)
+}
+
+// DeletePendingRegistration mocks base method
+func (m *MockProvider) DeletePendingRegistration(username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePendingRegistration", username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePendingRegistration indicates an expected call of DeletePendingRegistration
+func (mr *MockProviderMockRecorder) DeletePendingRegistration(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePendingRegistration", reflect.TypeOf((*MockProvider)(nil).DeletePendingRegistration), username)
+}
+
+// LoadLastLogin mocks base method
+func (m *MockProvider) LoadLastLogin(username string) (*models.LastLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLastLogin", username)
+	ret0, _ := ret[0].(*models.LastLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLastLogin indicates an expected call of LoadLastLogin
+func (mr *MockProviderMockRecorder) LoadLastLogin(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLastLogin", reflect.TypeOf((*MockProvider)(nil).LoadLastLogin), username)
+}
+
+// SaveLastLogins mocks base method
+func (m *MockProvider) SaveLastLogins(logins []models.LastLogin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLastLogins", logins)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveLastLogins indicates an expected call of SaveLastLogins
+func (mr *MockProviderMockRecorder) SaveLastLogins(logins interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLastLogins", reflect.TypeOf((*MockProvider)(nil).SaveLastLogins), logins)
+}
+
+// PruneIdentityVerificationTokens mocks base method
+func (m *MockProvider) PruneIdentityVerificationTokens(isExpired func(string) bool, batchSize int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneIdentityVerificationTokens", isExpired, batchSize)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneIdentityVerificationTokens indicates an expected call of PruneIdentityVerificationTokens
+func (mr *MockProviderMockRecorder) PruneIdentityVerificationTokens(isExpired, batchSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneIdentityVerificationTokens", reflect.TypeOf((*MockProvider)(nil).PruneIdentityVerificationTokens), isExpired, batchSize)
+}
+
+// ConfirmAuthenticationLog mocks base method
+func (m *MockProvider) ConfirmAuthenticationLog(username, reservation string, successful bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmAuthenticationLog", username, reservation, successful)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmAuthenticationLog indicates an expected call of ConfirmAuthenticationLog
+func (mr *MockProviderMockRecorder) ConfirmAuthenticationLog(username, reservation, successful interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmAuthenticationLog", reflect.TypeOf((*MockProvider)(nil).ConfirmAuthenticationLog), username, reservation, successful)
+}
+
+// DeleteAuthenticationLog mocks base method
+func (m *MockProvider) DeleteAuthenticationLog(username, reservation string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthenticationLog", username, reservation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthenticationLog indicates an expected call of DeleteAuthenticationLog
+func (mr *MockProviderMockRecorder) DeleteAuthenticationLog(username, reservation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthenticationLog", reflect.TypeOf((*MockProvider)(nil).DeleteAuthenticationLog), username, reservation)
+}
+
+// DeleteFailedAuthenticationLogs mocks base method
+func (m *MockProvider) DeleteFailedAuthenticationLogs(username string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFailedAuthenticationLogs", username)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFailedAuthenticationLogs indicates an expected call of DeleteFailedAuthenticationLogs
+func (mr *MockProviderMockRecorder) DeleteFailedAuthenticationLogs(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFailedAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).DeleteFailedAuthenticationLogs), username)
+}
+
+// PruneAuthenticationLogs mocks base method
+func (m *MockProvider) PruneAuthenticationLogs(before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneAuthenticationLogs", before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneAuthenticationLogs indicates an expected call of PruneAuthenticationLogs
+func (mr *MockProviderMockRecorder) PruneAuthenticationLogs(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).PruneAuthenticationLogs), before)
+}
+
+// PruneLoginDevices mocks base method
+func (m *MockProvider) PruneLoginDevices(before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneLoginDevices", before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneLoginDevices indicates an expected call of PruneLoginDevices
+func (mr *MockProviderMockRecorder) PruneLoginDevices(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneLoginDevices", reflect.TypeOf((*MockProvider)(nil).PruneLoginDevices), before)
+}
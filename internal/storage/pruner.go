@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// PruneReport holds the number of records removed from each table by a Pruner.
+type PruneReport struct {
+	IdentityVerificationTokens int
+	AuthenticationLogs         int
+	LoginDevices               int
+}
+
+// Pruner removes the expired records from the storage.
+type Pruner struct {
+	provider  Provider
+	clock     utils.Clock
+	log       *logrus.Logger
+	interval  time.Duration
+	retention time.Duration
+	// loginDevicesRetention is how long the login devices are kept after the last login of their user from them.
+	loginDevicesRetention time.Duration
+	batchSize             int
+}
+
+// NewPruner creates a Pruner given a storage provider and a configuration. The default configuration is used if the
+// given configuration is nil.
+func NewPruner(provider Provider, configuration *schema.StoragePruningConfiguration, clock utils.Clock) (*Pruner, error) {
+	if configuration == nil {
+		configuration = &schema.DefaultStoragePruningConfiguration
+	}
+
+	interval, err := utils.ParseDurationString(configuration.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := utils.ParseDurationString(configuration.AuthenticationLogsRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	loginDevicesRetention := schema.DefaultStoragePruningConfiguration.LoginDevicesRetention
+	if configuration.LoginDevicesRetention != "" {
+		loginDevicesRetention = configuration.LoginDevicesRetention
+	}
+
+	loginDevicesRetentionDuration, err := utils.ParseDurationString(loginDevicesRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := configuration.BatchSize
+	if batchSize <= 0 {
+		batchSize = schema.DefaultStoragePruningConfiguration.BatchSize
+	}
+
+	return &Pruner{
+		provider:  provider,
+		clock:     clock,
+		log:       logging.Logger(),
+		interval:  interval,
+		retention: retention,
+
+		loginDevicesRetention: loginDevicesRetentionDuration,
+		batchSize:             batchSize,
+	}, nil
+}
+
+// Prune removes the expired identity verification tokens, the authentication logs older than the retention period and
+// the login devices not used within their retention period.
+func (p *Pruner) Prune() (report PruneReport, err error) {
+	now := p.clock.Now()
+
+	report.IdentityVerificationTokens, err = p.provider.PruneIdentityVerificationTokens(isIdentityVerificationTokenExpired(now), p.batchSize)
+	if err != nil {
+		return report, err
+	}
+
+	report.AuthenticationLogs, err = p.provider.PruneAuthenticationLogs(now.Add(-p.retention))
+	if err != nil {
+		return report, err
+	}
+
+	report.LoginDevices, err = p.provider.PruneLoginDevices(now.Add(-p.loginDevicesRetention))
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// Start runs the pruning in the background at the configured interval. Nothing is done if the interval is 0.
+func (p *Pruner) Start() {
+	if p.interval == 0 {
+		return
+	}
+
+	p.log.Infof("Storage pruning is enabled and runs every %s", p.interval)
+
+	go func() {
+		for {
+			<-p.clock.After(p.interval)
+
+			report, err := p.Prune()
+			if err != nil {
+				p.log.Errorf("Unable to prune the storage: %s", err)
+				continue
+			}
+
+			p.log.Debugf("Storage pruning removed %d identity verification token(s), %d authentication log(s) and %d login device(s)",
+				report.IdentityVerificationTokens, report.AuthenticationLogs, report.LoginDevices)
+		}
+	}()
+}
+
+// isIdentityVerificationTokenExpired returns a function telling whether a token is expired at the given time. Tokens
+// which cannot be parsed are considered expired since they can't be used anyway.
+func isIdentityVerificationTokenExpired(now time.Time) func(token string) bool {
+	return func(token string) bool {
+		claims := &jwt.StandardClaims{}
+
+		if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+			return true
+		}
+
+		return !claims.VerifyExpiresAt(now.Unix(), true)
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c fixedClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func newSignedToken(t *testing.T, expiresAt time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+		ExpiresAt: expiresAt.Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestShouldDetectExpiredIdentityVerificationTokens(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	isExpired := isIdentityVerificationTokenExpired(now)
+
+	assert.False(t, isExpired(newSignedToken(t, now.Add(time.Minute))))
+	assert.True(t, isExpired(newSignedToken(t, now.Add(-time.Minute))))
+	assert.True(t, isExpired("not-a-jwt"))
+}
+
+func TestShouldPruneStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+	clock := fixedClock{now: time.Unix(1600000000, 0)}
+
+	pruner, err := NewPruner(provider, &schema.StoragePruningConfiguration{
+		Interval:                    "1h",
+		AuthenticationLogsRetention: "1d",
+		LoginDevicesRetention:       "1w",
+		BatchSize:                   50,
+	}, clock)
+	require.NoError(t, err)
+
+	provider.EXPECT().PruneIdentityVerificationTokens(gomock.Any(), 50).Return(2, nil)
+	provider.EXPECT().PruneAuthenticationLogs(clock.now.Add(-24*time.Hour)).Return(10, nil)
+	provider.EXPECT().PruneLoginDevices(clock.now.Add(-7*24*time.Hour)).Return(3, nil)
+
+	report, err := pruner.Prune()
+	require.NoError(t, err)
+
+	assert.Equal(t, PruneReport{IdentityVerificationTokens: 2, AuthenticationLogs: 10, LoginDevices: 3}, report)
+}
+
+func TestShouldReturnErrorWhenPruningFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+
+	pruner, err := NewPruner(provider, nil, fixedClock{now: time.Unix(1600000000, 0)})
+	require.NoError(t, err)
+
+	provider.EXPECT().PruneIdentityVerificationTokens(gomock.Any(), 1000).Return(0, errors.New("database is locked"))
+
+	_, err = pruner.Prune()
+	assert.EqualError(t, err, "database is locked")
+}
+
+func TestShouldFailToCreatePrunerWithInvalidConfiguration(t *testing.T) {
+	_, err := NewPruner(nil, &schema.StoragePruningConfiguration{Interval: "-1"}, fixedClock{})
+	assert.EqualError(t, err, "Could not convert the input string of -1 into a duration")
+}
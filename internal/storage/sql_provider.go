@@ -4,10 +4,12 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
 	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/utils"
@@ -19,25 +21,60 @@ type SQLProvider struct {
 	log  *logrus.Logger
 	name string
 
+	cipher *valueCipher
+
 	sqlUpgradesCreateTableStatements        map[SchemaVersion]map[string]string
 	sqlUpgradesCreateTableIndexesStatements map[SchemaVersion][]string
+	sqlUpgradesAlterColumnStatements        map[SchemaVersion][]string
 
 	sqlGetPreferencesByUsername     string
 	sqlUpsertSecondFactorPreference string
+	sqlGetPreferences               string
 
 	sqlTestIdentityVerificationTokenExistence string
 	sqlInsertIdentityVerificationToken        string
 	sqlDeleteIdentityVerificationToken        string
+	sqlGetIdentityVerificationTokens          string
 
 	sqlGetTOTPSecretByUsername string
 	sqlUpsertTOTPSecret        string
 	sqlDeleteTOTPSecret        string
+	sqlGetTOTPSecretsAfter     string
+	sqlGetTOTPSecrets          string
 
 	sqlGetU2FDeviceHandleByUsername string
 	sqlUpsertU2FDeviceHandle        string
+	sqlGetU2FDeviceHandles          string
+
+	sqlInsertAuthenticationLog         string
+	sqlGetLatestAuthenticationLogs     string
+	sqlUpdateReservedAuthenticationLog string
+	sqlDeleteReservedAuthenticationLog string
+
+	sqlDeleteAuthenticationLogsBefore string
+	sqlDeleteFailedAuthenticationLogs string
+
+	sqlInsertPasswordHistory    string
+	sqlGetLatestPasswordHistory string
+
+	sqlInsertLoginDevice        string
+	sqlUpdateLoginDeviceTime    string
+	sqlGetLoginDevices          string
+	sqlDeleteLoginDevicesBefore string
+
+	sqlGetSecondFactorVersion    string
+	sqlUpsertSecondFactorVersion string
 
-	sqlInsertAuthenticationLog     string
-	sqlGetLatestAuthenticationLogs string
+	sqlGetVerifiedEmail    string
+	sqlUpsertVerifiedEmail string
+
+	sqlGetPendingRegistration          string
+	sqlGetVerifiedPendingRegistrations string
+	sqlUpsertPendingRegistration       string
+	sqlDeletePendingRegistration       string
+
+	sqlGetLastLogin    string
+	sqlUpsertLastLogin string
 
 	sqlGetExistingTables string
 
@@ -45,6 +82,25 @@ type SQLProvider struct {
 	sqlConfigGetValue string
 }
 
+// configureConnectionPool applies the connection pool settings to the database, the settings set to 0 are left to the
+// defaults of the database/sql package.
+func configureConnectionPool(db *sql.DB, configuration schema.SQLStorageConfiguration) {
+	if configuration.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(configuration.MaxOpenConns)
+	}
+
+	if configuration.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(configuration.MaxIdleConns)
+	}
+
+	if configuration.ConnMaxLifetime != "" {
+		// Ignore the error as it is handled by the validator.
+		if lifetime, _ := utils.ParseDurationString(configuration.ConnMaxLifetime); lifetime > 0 {
+			db.SetConnMaxLifetime(lifetime)
+		}
+	}
+}
+
 func (p *SQLProvider) initialize(db *sql.DB) error {
 	p.db = db
 	p.log = logging.Logger()
@@ -111,6 +167,62 @@ func (p *SQLProvider) upgrade() error {
 				return p.handleUpgradeFailure(tx, 1, err)
 			}
 
+			fallthrough
+		case 1:
+			err := p.upgradeSchemaToVersion002(tx, tables)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 2, err)
+			}
+
+			fallthrough
+		case 2:
+			err := p.upgradeSchemaToVersion003(tx, tables)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 3, err)
+			}
+
+			fallthrough
+		case 3:
+			err := p.upgradeSchemaToVersion004(tx, tables)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 4, err)
+			}
+
+			fallthrough
+		case 4:
+			err := p.upgradeSchemaToVersion005(tx, tables)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 5, err)
+			}
+
+			fallthrough
+		case 5:
+			err := p.upgradeSchemaToVersion006(tx)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 6, err)
+			}
+
+			fallthrough
+		case 6:
+			err := p.upgradeSchemaToVersion007(tx, tables)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 7, err)
+			}
+
+			fallthrough
+		case 7:
+			err := p.upgradeSchemaToVersion008(tx, tables)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 8, err)
+			}
+
+			fallthrough
+		case 8:
+			err := p.upgradeSchemaToVersion009(tx)
+			if err != nil {
+				return p.handleUpgradeFailure(tx, 9, err)
+			}
+
 			fallthrough
 		default:
 			err := tx.Commit()
@@ -181,15 +293,31 @@ func (p *SQLProvider) SaveIdentityVerificationToken(token string) error {
 	return err
 }
 
-// RemoveIdentityVerificationToken remove an identity verification token from the database.
-func (p *SQLProvider) RemoveIdentityVerificationToken(token string) error {
-	_, err := p.db.Exec(p.sqlDeleteIdentityVerificationToken, token)
-	return err
+// ConsumeIdentityVerificationToken remove an identity verification token from the database. It returns false if the
+// token was not in the database anymore, i.e. it has been consumed concurrently.
+func (p *SQLProvider) ConsumeIdentityVerificationToken(token string) (bool, error) {
+	result, err := p.db.Exec(p.sqlDeleteIdentityVerificationToken, token)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
 }
 
 // SaveTOTPSecret save a TOTP secret of a given user in the database.
 func (p *SQLProvider) SaveTOTPSecret(username string, secret string) error {
-	_, err := p.db.Exec(p.sqlUpsertTOTPSecret, username, secret)
+	secret, err := p.cipher.encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("Unable to encrypt the TOTP secret: %w", err)
+	}
+
+	_, err = p.db.Exec(p.sqlUpsertTOTPSecret, username, secret)
+
 	return err
 }
 
@@ -204,9 +332,81 @@ func (p *SQLProvider) LoadTOTPSecret(username string) (string, error) {
 		return "", err
 	}
 
+	secret, _, err := p.cipher.decrypt(secret)
+	if err != nil {
+		return "", fmt.Errorf("Unable to decrypt the TOTP secret: %w", err)
+	}
+
 	return secret, nil
 }
 
+// ReencryptTOTPSecrets encrypts the TOTP secrets which are stored in clear or encrypted with a previous key with the
+// current encryption key, by batches of at most batchSize secrets. It returns the number of re-encrypted secrets.
+func (p *SQLProvider) ReencryptTOTPSecrets(batchSize int) (int, error) {
+	if p.cipher == nil {
+		return 0, ErrNoEncryptionKey
+	}
+
+	var (
+		count int
+		after string
+	)
+
+	for {
+		secrets, err := p.loadTOTPSecretsAfter(after, batchSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, secret := range secrets {
+			plaintext, current, err := p.cipher.decrypt(secret.secret)
+			if err != nil {
+				return count, fmt.Errorf("Unable to decrypt the TOTP secret of user %s: %w", secret.username, err)
+			}
+
+			if current {
+				continue
+			}
+
+			if err = p.SaveTOTPSecret(secret.username, plaintext); err != nil {
+				return count, err
+			}
+
+			count++
+		}
+
+		if len(secrets) < batchSize {
+			return count, nil
+		}
+
+		after = secrets[len(secrets)-1].username
+	}
+}
+
+// loadTOTPSecretsAfter loads at most limit TOTP secrets, as stored, of the users following the given username.
+func (p *SQLProvider) loadTOTPSecretsAfter(username string, limit int) ([]storedTOTPSecret, error) {
+	rows, err := p.db.Query(p.sqlGetTOTPSecretsAfter, username, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	secrets := make([]storedTOTPSecret, 0, limit)
+
+	for rows.Next() {
+		var secret storedTOTPSecret
+
+		if err = rows.Scan(&secret.username, &secret.secret); err != nil {
+			return nil, err
+		}
+
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, rows.Err()
+}
+
 // DeleteTOTPSecret delete a TOTP secret from the database given a username.
 func (p *SQLProvider) DeleteTOTPSecret(username string) error {
 	_, err := p.db.Exec(p.sqlDeleteTOTPSecret, username)
@@ -249,9 +449,173 @@ func (p *SQLProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, erro
 	return keyHandle, publicKey, nil
 }
 
+// LoadSecondFactorEnrollments load the second factor devices and preferences of all the users, with the TOTP secrets
+// in clear, sorted by username.
+func (p *SQLProvider) LoadSecondFactorEnrollments() ([]models.SecondFactorEnrollment, error) {
+	enrollments := map[string]*models.SecondFactorEnrollment{}
+
+	enrollment := func(username string) *models.SecondFactorEnrollment {
+		if _, ok := enrollments[username]; !ok {
+			enrollments[username] = &models.SecondFactorEnrollment{Username: username}
+		}
+
+		return enrollments[username]
+	}
+
+	err := p.scanAll(p.sqlGetPreferences, func(rows *sql.Rows) error {
+		var username, method string
+
+		if err := rows.Scan(&username, &method); err != nil {
+			return err
+		}
+
+		enrollment(username).PreferredMethod = method
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.scanAll(p.sqlGetTOTPSecrets, func(rows *sql.Rows) error {
+		var username, secret string
+
+		if err := rows.Scan(&username, &secret); err != nil {
+			return err
+		}
+
+		plaintext, _, err := p.cipher.decrypt(secret)
+		if err != nil {
+			return fmt.Errorf("Unable to decrypt the TOTP secret of user %s: %w", username, err)
+		}
+
+		enrollment(username).TOTPSecret = plaintext
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.scanAll(p.sqlGetU2FDeviceHandles, func(rows *sql.Rows) error {
+		var username, keyHandleBase64, publicKeyBase64 string
+
+		if err := rows.Scan(&username, &keyHandleBase64, &publicKeyBase64); err != nil {
+			return err
+		}
+
+		keyHandle, err := base64.StdEncoding.DecodeString(keyHandleBase64)
+		if err != nil {
+			return fmt.Errorf("Unable to decode the U2F key handle of user %s: %w", username, err)
+		}
+
+		publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+		if err != nil {
+			return fmt.Errorf("Unable to decode the U2F public key of user %s: %w", username, err)
+		}
+
+		enrollment(username).U2FKeyHandle, enrollment(username).U2FPublicKey = keyHandle, publicKey
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SecondFactorEnrollment, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		result = append(result, *enrollment)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+
+	return result, nil
+}
+
+// SaveSecondFactorEnrollments save the second factor devices and preferences of several users in a single
+// transaction, replacing the existing ones. The empty devices and preferences of an enrollment are left untouched.
+func (p *SQLProvider) SaveSecondFactorEnrollments(enrollments []models.SecondFactorEnrollment) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, enrollment := range enrollments {
+		if err = p.saveSecondFactorEnrollment(tx, enrollment); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *SQLProvider) saveSecondFactorEnrollment(tx transaction, enrollment models.SecondFactorEnrollment) error {
+	if enrollment.PreferredMethod != "" {
+		if _, err := tx.Exec(p.sqlUpsertSecondFactorPreference, enrollment.Username, enrollment.PreferredMethod); err != nil {
+			return err
+		}
+	}
+
+	if enrollment.TOTPSecret != "" {
+		secret, err := p.cipher.encrypt(enrollment.TOTPSecret)
+		if err != nil {
+			return fmt.Errorf("Unable to encrypt the TOTP secret: %w", err)
+		}
+
+		if _, err = tx.Exec(p.sqlUpsertTOTPSecret, enrollment.Username, secret); err != nil {
+			return err
+		}
+	}
+
+	if len(enrollment.U2FKeyHandle) != 0 {
+		_, err := tx.Exec(p.sqlUpsertU2FDeviceHandle, enrollment.Username,
+			base64.StdEncoding.EncodeToString(enrollment.U2FKeyHandle),
+			base64.StdEncoding.EncodeToString(enrollment.U2FPublicKey))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanAll runs the query and calls scan for each of the returned rows.
+func (p *SQLProvider) scanAll(query string, scan func(rows *sql.Rows) error) error {
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 // AppendAuthenticationLog append a mark to the authentication log.
 func (p *SQLProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
-	_, err := p.db.Exec(p.sqlInsertAuthenticationLog, attempt.Username, attempt.Successful, attempt.Time.Unix())
+	reservation := sql.NullString{String: attempt.Reservation, Valid: attempt.Reservation != ""}
+
+	_, err := p.db.Exec(p.sqlInsertAuthenticationLog, attempt.Username, attempt.Successful, attempt.Time.Unix(), reservation)
+
+	return err
+}
+
+// ConfirmAuthenticationLog records the outcome of the reserved authentication attempt of a user.
+func (p *SQLProvider) ConfirmAuthenticationLog(username string, reservation string, successful bool) error {
+	_, err := p.db.Exec(p.sqlUpdateReservedAuthenticationLog, successful, username, reservation)
+	return err
+}
+
+// DeleteAuthenticationLog removes the reserved authentication attempt of a user.
+func (p *SQLProvider) DeleteAuthenticationLog(username string, reservation string) error {
+	_, err := p.db.Exec(p.sqlDeleteReservedAuthenticationLog, username, reservation)
 	return err
 }
 
@@ -268,11 +632,14 @@ func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate tim
 	attempts := make([]models.AuthenticationAttempt, 0, 10)
 
 	for rows.Next() {
+		var reservation sql.NullString
+
 		attempt := models.AuthenticationAttempt{
 			Username: username,
 		}
-		err = rows.Scan(&attempt.Successful, &t)
+		err = rows.Scan(&attempt.Successful, &t, &reservation)
 		attempt.Time = time.Unix(t, 0)
+		attempt.Reservation = reservation.String
 
 		if err != nil {
 			return nil, err
@@ -283,3 +650,334 @@ func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate tim
 
 	return attempts, nil
 }
+
+// AppendPasswordHistory records a password set by a user.
+func (p *SQLProvider) AppendPasswordHistory(entry models.PasswordHistoryEntry) error {
+	_, err := p.db.Exec(p.sqlInsertPasswordHistory, entry.Username, entry.Hash, entry.Time.Unix())
+	return err
+}
+
+// LoadLatestPasswordHistory retrieve the last passwords set by a user, the most recent first.
+func (p *SQLProvider) LoadLatestPasswordHistory(username string, limit int) ([]models.PasswordHistoryEntry, error) {
+	rows, err := p.db.Query(p.sqlGetLatestPasswordHistory, username, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.PasswordHistoryEntry, 0, limit)
+
+	for rows.Next() {
+		var t int64
+
+		entry := models.PasswordHistoryEntry{
+			Username: username,
+		}
+
+		if err = rows.Scan(&entry.Hash, &t); err != nil {
+			return nil, err
+		}
+
+		entry.Time = time.Unix(t, 0)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// SaveLoginDevice records a device and IP address a user successfully logged in from, updating the time of the last
+// login if the user already logged in from them so that they are kept by the pruning.
+func (p *SQLProvider) SaveLoginDevice(device models.LoginDevice) error {
+	result, err := p.db.Exec(p.sqlUpdateLoginDeviceTime, device.Time.Unix(), device.Username, device.Fingerprint, device.IP)
+	if err != nil {
+		return err
+	}
+
+	if updated, err := result.RowsAffected(); err != nil || updated != 0 {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlInsertLoginDevice, device.Username, device.Fingerprint, device.IP, device.Time.Unix())
+
+	return err
+}
+
+// LoadLoginDevices retrieve the most recent devices and IP addresses a user previously logged in from.
+func (p *SQLProvider) LoadLoginDevices(username string) ([]models.LoginDevice, error) {
+	rows, err := p.db.Query(p.sqlGetLoginDevices, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]models.LoginDevice, 0)
+
+	for rows.Next() {
+		var t int64
+
+		device := models.LoginDevice{
+			Username: username,
+		}
+
+		if err = rows.Scan(&device.Fingerprint, &device.IP, &t); err != nil {
+			return nil, err
+		}
+
+		device.Time = time.Unix(t, 0)
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// LoadSecondFactorVersion load the version of the second factor devices of a user, 0 if they never changed.
+func (p *SQLProvider) LoadSecondFactorVersion(username string) (int64, error) {
+	var version int64
+	if err := p.db.QueryRow(p.sqlGetSecondFactorVersion, username).Scan(&version); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// SaveSecondFactorVersion save the version of the second factor devices of a user.
+func (p *SQLProvider) SaveSecondFactorVersion(username string, version int64) error {
+	_, err := p.db.Exec(p.sqlUpsertSecondFactorVersion, username, version)
+	return err
+}
+
+// LoadVerifiedEmail load the email address the user last verified, an empty string if they never verified one.
+func (p *SQLProvider) LoadVerifiedEmail(username string) (string, error) {
+	var email string
+	if err := p.db.QueryRow(p.sqlGetVerifiedEmail, username).Scan(&email); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return email, nil
+}
+
+// SaveVerifiedEmail save the email address a user has just verified.
+func (p *SQLProvider) SaveVerifiedEmail(username string, email string, verifiedAt time.Time) error {
+	_, err := p.db.Exec(p.sqlUpsertVerifiedEmail, username, email, verifiedAt.Unix())
+	return err
+}
+
+// LoadPendingRegistration load the pending registration of an account, nil if there is none.
+func (p *SQLProvider) LoadPendingRegistration(username string) (*models.PendingRegistration, error) {
+	var t int64
+
+	registration := models.PendingRegistration{Username: username}
+
+	err := p.db.QueryRow(p.sqlGetPendingRegistration, username).
+		Scan(&registration.DisplayName, &registration.Email, &registration.Hash, &registration.Verified, &t)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	registration.Time = time.Unix(t, 0)
+
+	return &registration, nil
+}
+
+// LoadVerifiedPendingRegistrations load the pending registrations whose email address has been verified, i.e. the
+// ones awaiting an approval, from the oldest to the most recent.
+func (p *SQLProvider) LoadVerifiedPendingRegistrations() ([]models.PendingRegistration, error) {
+	rows, err := p.db.Query(p.sqlGetVerifiedPendingRegistrations, true)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	registrations := make([]models.PendingRegistration, 0)
+
+	for rows.Next() {
+		var t int64
+
+		registration := models.PendingRegistration{Verified: true}
+
+		if err = rows.Scan(&registration.Username, &registration.DisplayName, &registration.Email, &registration.Hash, &t); err != nil {
+			return nil, err
+		}
+
+		registration.Time = time.Unix(t, 0)
+		registrations = append(registrations, registration)
+	}
+
+	return registrations, nil
+}
+
+// SavePendingRegistration save the pending registration of an account, replacing the existing one if any.
+func (p *SQLProvider) SavePendingRegistration(registration models.PendingRegistration) error {
+	_, err := p.db.Exec(p.sqlUpsertPendingRegistration, registration.Username, registration.DisplayName,
+		registration.Email, registration.Hash, registration.Verified, registration.Time.Unix())
+
+	return err
+}
+
+// DeletePendingRegistration remove the pending registration of an account.
+func (p *SQLProvider) DeletePendingRegistration(username string) error {
+	_, err := p.db.Exec(p.sqlDeletePendingRegistration, username)
+	return err
+}
+
+// LoadLastLogin load the last successful authentication of a user, nil if they never authenticated.
+func (p *SQLProvider) LoadLastLogin(username string) (*models.LastLogin, error) {
+	var t int64
+
+	login := models.LastLogin{Username: username}
+
+	if err := p.db.QueryRow(p.sqlGetLastLogin, username).Scan(&login.IP, &login.Method, &t); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	login.Time = time.Unix(t, 0)
+
+	return &login, nil
+}
+
+// SaveLastLogins save the last successful authentication of several users in a single transaction, replacing the
+// previous ones.
+func (p *SQLProvider) SaveLastLogins(logins []models.LastLogin) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, login := range logins {
+		if _, err = tx.Exec(p.sqlUpsertLastLogin, login.Username, login.IP, login.Method, login.Time.Unix()); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PruneIdentityVerificationTokens removes the identity verification tokens considered expired by the given function.
+// The tokens are removed in transactions of at most batchSize deletions and the number of removed tokens is returned.
+func (p *SQLProvider) PruneIdentityVerificationTokens(isExpired func(token string) bool, batchSize int) (int, error) {
+	rows, err := p.db.Query(p.sqlGetIdentityVerificationTokens)
+	if err != nil {
+		return 0, err
+	}
+
+	var expired []string
+
+	for rows.Next() {
+		var token string
+
+		if err := rows.Scan(&token); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		if isExpired(token) {
+			expired = append(expired, token)
+		}
+	}
+
+	err = rows.Err()
+
+	rows.Close()
+
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for start := 0; start < len(expired); start += batchSize {
+		end := start + batchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+
+		tx, err := p.db.Begin()
+		if err != nil {
+			return count, err
+		}
+
+		for _, token := range expired[start:end] {
+			if _, err := tx.Exec(p.sqlDeleteIdentityVerificationToken, token); err != nil {
+				_ = tx.Rollback()
+				return count, err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return count, err
+		}
+
+		count += end - start
+	}
+
+	return count, nil
+}
+
+// PruneAuthenticationLogs removes the authentication logs older than the given date and returns the number of removed
+// logs.
+func (p *SQLProvider) PruneAuthenticationLogs(before time.Time) (int, error) {
+	return p.pruneBefore(p.sqlDeleteAuthenticationLogsBefore, before)
+}
+
+// PruneLoginDevices removes the login devices the users did not log in from since the given date and returns the number
+// of removed devices.
+func (p *SQLProvider) PruneLoginDevices(before time.Time) (int, error) {
+	return p.pruneBefore(p.sqlDeleteLoginDevicesBefore, before)
+}
+
+// pruneBefore removes the records older than the given date with the given statement.
+func (p *SQLProvider) pruneBefore(deleteBefore string, before time.Time) (int, error) {
+	result, err := p.db.Exec(deleteBefore, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+// DeleteFailedAuthenticationLogs removes the failed authentication attempts of a user, which lifts any ban applied by
+// the regulation. The number of removed attempts is returned.
+func (p *SQLProvider) DeleteFailedAuthenticationLogs(username string) (int, error) {
+	result, err := p.db.Exec(p.sqlDeleteFailedAuthenticationLogs, username, false)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
+
+// StartupCheck checks the connection to the database is working.
+func (p *SQLProvider) StartupCheck() (bool, error) {
+	if err := p.db.Ping(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"sort"
 	"testing"
@@ -16,7 +18,7 @@ import (
 	"github.com/authelia/authelia/internal/models"
 )
 
-const currentSchemaMockSchemaVersion = "1"
+const currentSchemaMockSchemaVersion = "9"
 
 func TestSQLInitializeDatabase(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
@@ -50,6 +52,82 @@ func TestSQLInitializeDatabase(t *testing.T) {
 		WithArgs("schema", "version", "1").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", passwordHistoryTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS pwd_usr_time_idx ON %s .*", passwordHistoryTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", loginDevicesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS dev_usr_idx ON %s .*", loginDevicesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", secondFactorVersionsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "4").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", verifiedEmailsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN reservation .*", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "6").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", pendingRegistrationsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "7").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", lastLoginsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "8").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "9").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
 	mock.ExpectCommit()
 
 	err := provider.initialize(provider.db)
@@ -83,12 +161,540 @@ func TestSQLUpgradeDatabase(t *testing.T) {
 		WithArgs("schema", "version", "1").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", passwordHistoryTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS pwd_usr_time_idx ON %s .*", passwordHistoryTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", loginDevicesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS dev_usr_idx ON %s .*", loginDevicesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", secondFactorVersionsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "4").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", verifiedEmailsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN reservation .*", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "6").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", pendingRegistrationsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "7").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", lastLoginsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "8").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "9").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
 	mock.ExpectCommit()
 
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 }
 
+func TestSQLUpgradeDatabaseFromVersion1(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow("1"))
+
+	mock.ExpectBegin()
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", passwordHistoryTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS pwd_usr_time_idx ON %s .*", passwordHistoryTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", loginDevicesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS dev_usr_idx ON %s .*", loginDevicesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", secondFactorVersionsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "4").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", verifiedEmailsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN reservation .*", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "6").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", pendingRegistrationsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "7").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", lastLoginsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "8").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "9").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectCommit()
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderMethodsPasswordHistory(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(configTableName).
+			AddRow(passwordHistoryTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, hash, time\\) VALUES \\(\\?, \\?, \\?\\)", passwordHistoryTableName)).
+		WithArgs(unitTestUser, "$6$rounds=50000$salt$hash", int64(1577880001)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = provider.AppendPasswordHistory(models.PasswordHistoryEntry{
+		Username: unitTestUser,
+		Hash:     "$6$rounds=50000$salt$hash",
+		Time:     time.Unix(1577880001, 0),
+	})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT hash, time FROM %s WHERE username=\\? ORDER BY time DESC LIMIT \\?", passwordHistoryTableName)).
+		WithArgs(unitTestUser, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"hash", "time"}).
+			AddRow("hash2", 1577880002).
+			AddRow("hash1", 1577880001))
+
+	entries, err := provider.LoadLatestPasswordHistory(unitTestUser, 3)
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.PasswordHistoryEntry{
+		{Username: unitTestUser, Hash: "hash2", Time: time.Unix(1577880002, 0)},
+		{Username: unitTestUser, Hash: "hash1", Time: time.Unix(1577880001, 0)},
+	}, entries)
+}
+
+func TestSQLProviderMethodsLoginDevices(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(configTableName).
+			AddRow(loginDevicesTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET time=\\? WHERE username=\\? AND fingerprint=\\? AND ip=\\?", loginDevicesTableName)).
+		WithArgs(int64(1577880001), unitTestUser, "fingerprint", "192.168.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, fingerprint, ip, time\\) VALUES \\(\\?, \\?, \\?, \\?\\)", loginDevicesTableName)).
+		WithArgs(unitTestUser, "fingerprint", "192.168.0.1", int64(1577880001)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = provider.SaveLoginDevice(models.LoginDevice{
+		Username:    unitTestUser,
+		Fingerprint: "fingerprint",
+		IP:          "192.168.0.1",
+		Time:        time.Unix(1577880001, 0),
+	})
+	assert.NoError(t, err)
+
+	// The time of a known device is refreshed instead of recording it again.
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET time=\\? WHERE username=\\? AND fingerprint=\\? AND ip=\\?", loginDevicesTableName)).
+		WithArgs(int64(1577880002), unitTestUser, "fingerprint", "192.168.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SaveLoginDevice(models.LoginDevice{
+		Username:    unitTestUser,
+		Fingerprint: "fingerprint",
+		IP:          "192.168.0.1",
+		Time:        time.Unix(1577880002, 0),
+	})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT fingerprint, ip, time FROM %s WHERE username=\\?", loginDevicesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "ip", "time"}).
+			AddRow("fingerprint", "192.168.0.1", 1577880001).
+			AddRow("fingerprint", "192.168.0.2", 1577880002))
+
+	devices, err := provider.LoadLoginDevices(unitTestUser)
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.LoginDevice{
+		{Username: unitTestUser, Fingerprint: "fingerprint", IP: "192.168.0.1", Time: time.Unix(1577880001, 0)},
+		{Username: unitTestUser, Fingerprint: "fingerprint", IP: "192.168.0.2", Time: time.Unix(1577880002, 0)},
+	}, devices)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderMethodsSecondFactorVersion(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(configTableName).
+			AddRow(secondFactorVersionsTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT version FROM %s WHERE username=\\?", secondFactorVersionsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	version, err := provider.LoadSecondFactorVersion(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), version)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, version\\) VALUES \\(\\?, \\?\\)", secondFactorVersionsTableName)).
+		WithArgs(unitTestUser, int64(1577880001000000000)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = provider.SaveSecondFactorVersion(unitTestUser, 1577880001000000000)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT version FROM %s WHERE username=\\?", secondFactorVersionsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).
+			AddRow(1577880001000000000))
+
+	version, err = provider.LoadSecondFactorVersion(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1577880001000000000), version)
+}
+
+func TestSQLProviderMethodsVerifiedEmail(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(configTableName).
+			AddRow(verifiedEmailsTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT email FROM %s WHERE username=\\?", verifiedEmailsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
+
+	email, err := provider.LoadVerifiedEmail(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, "", email)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, email, time\\) VALUES \\(\\?, \\?, \\?\\)", verifiedEmailsTableName)).
+		WithArgs(unitTestUser, "john@example.com", int64(1577880001)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = provider.SaveVerifiedEmail(unitTestUser, "john@example.com", time.Unix(1577880001, 0))
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT email FROM %s WHERE username=\\?", verifiedEmailsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).
+			AddRow("john@example.com"))
+
+	email, err = provider.LoadVerifiedEmail(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", email)
+}
+
+func TestSQLProviderMethodsPendingRegistrations(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(configTableName).
+			AddRow(pendingRegistrationsTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT display_name, email, hash, verified, time FROM %s WHERE username=\\?", pendingRegistrationsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"display_name", "email", "hash", "verified", "time"}))
+
+	registration, err := provider.LoadPendingRegistration(unitTestUser)
+	require.NoError(t, err)
+	assert.Nil(t, registration)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, display_name, email, hash, verified, time\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?\\)", pendingRegistrationsTableName)).
+		WithArgs(unitTestUser, "John Doe", "john@example.com", "hash", false, int64(1577880001)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = provider.SavePendingRegistration(models.PendingRegistration{
+		Username:    unitTestUser,
+		DisplayName: "John Doe",
+		Email:       "john@example.com",
+		Hash:        "hash",
+		Time:        time.Unix(1577880001, 0),
+	})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT display_name, email, hash, verified, time FROM %s WHERE username=\\?", pendingRegistrationsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"display_name", "email", "hash", "verified", "time"}).
+			AddRow("John Doe", "john@example.com", "hash", true, 1577880001))
+
+	registration, err = provider.LoadPendingRegistration(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, &models.PendingRegistration{
+		Username:    unitTestUser,
+		DisplayName: "John Doe",
+		Email:       "john@example.com",
+		Hash:        "hash",
+		Verified:    true,
+		Time:        time.Unix(1577880001, 0),
+	}, registration)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, display_name, email, hash, time FROM %s WHERE verified=\\? ORDER BY time ASC", pendingRegistrationsTableName)).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "display_name", "email", "hash", "time"}).
+			AddRow(unitTestUser, "John Doe", "john@example.com", "hash", 1577880001).
+			AddRow("harry", "Harry Potter", "harry@example.com", "hash2", 1577880002))
+
+	registrations, err := provider.LoadVerifiedPendingRegistrations()
+	require.NoError(t, err)
+	assert.Equal(t, []models.PendingRegistration{
+		{Username: unitTestUser, DisplayName: "John Doe", Email: "john@example.com", Hash: "hash", Verified: true, Time: time.Unix(1577880001, 0)},
+		{Username: "harry", DisplayName: "Harry Potter", Email: "harry@example.com", Hash: "hash2", Verified: true, Time: time.Unix(1577880002, 0)},
+	}, registrations)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\?", pendingRegistrationsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeletePendingRegistration(unitTestUser)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderMethodsLastLogins(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(configTableName).
+			AddRow(lastLoginsTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT ip, method, time FROM %s WHERE username=\\?", lastLoginsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"ip", "method", "time"}))
+
+	login, err := provider.LoadLastLogin(unitTestUser)
+	require.NoError(t, err)
+	assert.Nil(t, login)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, ip, method, time\\) VALUES \\(\\?, \\?, \\?, \\?\\)", lastLoginsTableName)).
+		WithArgs(unitTestUser, "192.168.1.10", "password", int64(1577880001)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, ip, method, time\\) VALUES \\(\\?, \\?, \\?, \\?\\)", lastLoginsTableName)).
+		WithArgs("harry", "192.168.1.11", "totp", int64(1577880002)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = provider.SaveLastLogins([]models.LastLogin{
+		{Username: unitTestUser, IP: "192.168.1.10", Method: "password", Time: time.Unix(1577880001, 0)},
+		{Username: "harry", IP: "192.168.1.11", Method: "totp", Time: time.Unix(1577880002, 0)},
+	})
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT ip, method, time FROM %s WHERE username=\\?", lastLoginsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"ip", "method", "time"}).
+			AddRow("192.168.1.10", "password", 1577880001))
+
+	login, err = provider.LoadLastLogin(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, &models.LastLogin{
+		Username: unitTestUser,
+		IP:       "192.168.1.10",
+		Method:   "password",
+		Time:     time.Unix(1577880001, 0),
+	}, login)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
@@ -107,7 +713,7 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
 		WithArgs(args...).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
-			AddRow("1"))
+			AddRow(currentSchemaMockSchemaVersion))
 
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
@@ -115,26 +721,32 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 	attempts := []models.AuthenticationAttempt{
 		{Username: unitTestUser, Successful: true, Time: time.Unix(1577880001, 0)},
 		{Username: unitTestUser, Successful: true, Time: time.Unix(1577880002, 0)},
-		{Username: unitTestUser, Successful: false, Time: time.Unix(1577880003, 0)},
+		{Username: unitTestUser, Successful: false, Time: time.Unix(1577880003, 0), Reservation: "abc"},
 	}
 
-	rows := sqlmock.NewRows([]string{"successful", "time"})
+	rows := sqlmock.NewRows([]string{"successful", "time", "reservation"})
 
 	for id, attempt := range attempts {
-		args = []driver.Value{attempt.Username, attempt.Successful, attempt.Time.Unix()}
+		reservation := sql.NullString{String: attempt.Reservation, Valid: attempt.Reservation != ""}
+
+		args = []driver.Value{attempt.Username, attempt.Successful, attempt.Time.Unix(), reservation}
 		mock.ExpectExec(
-			fmt.Sprintf("INSERT INTO %s \\(username, successful, time\\) VALUES \\(\\?, \\?, \\?\\)", authenticationLogsTableName)).
+			fmt.Sprintf("INSERT INTO %s \\(username, successful, time, reservation\\) VALUES \\(\\?, \\?, \\?, \\?\\)", authenticationLogsTableName)).
 			WithArgs(args...).
 			WillReturnResult(sqlmock.NewResult(int64(id), 1))
 
 		err := provider.AppendAuthenticationLog(attempt)
 		assert.NoError(t, err)
-		rows.AddRow(attempt.Successful, attempt.Time.Unix())
+		if reservation.Valid {
+			rows.AddRow(attempt.Successful, attempt.Time.Unix(), reservation.String)
+		} else {
+			rows.AddRow(attempt.Successful, attempt.Time.Unix(), nil)
+		}
 	}
 
 	args = []driver.Value{1577880000, unitTestUser}
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
+		fmt.Sprintf("SELECT successful, time, reservation FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
 		WithArgs(args...).
 		WillReturnRows(rows)
 
@@ -151,12 +763,28 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 	assert.Equal(t, unitTestUser, results[2].Username)
 	assert.Equal(t, false, results[2].Successful)
 	assert.Equal(t, time.Unix(1577880003, 0), results[2].Time)
+	assert.Equal(t, "", results[1].Reservation)
+	assert.Equal(t, "abc", results[2].Reservation)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET successful=\\?, reservation=NULL WHERE username=\\? AND reservation=\\?", authenticationLogsTableName)).
+		WithArgs(true, unitTestUser, "abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.ConfirmAuthenticationLog(unitTestUser, "abc", true))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND reservation=\\?", authenticationLogsTableName)).
+		WithArgs(unitTestUser, "abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.DeleteAuthenticationLog(unitTestUser, "abc"))
 
 	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
+		fmt.Sprintf("SELECT successful, time, reservation FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
 		WithArgs(args...).
-		WillReturnRows(sqlmock.NewRows([]string{"successful", "time"}))
+		WillReturnRows(sqlmock.NewRows([]string{"successful", "time", "reservation"}))
 
 	results, err = provider.LoadLatestAuthenticationLogs(unitTestUser, after)
 	assert.NoError(t, err)
@@ -385,8 +1013,18 @@ func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 		WithArgs(fakeIdentityVerificationToken).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = provider.RemoveIdentityVerificationToken(fakeIdentityVerificationToken)
+	consumed, err := provider.ConsumeIdentityVerificationToken(fakeIdentityVerificationToken)
 	assert.NoError(t, err)
+	assert.True(t, consumed)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE token=\\?", identityVerificationTokensTableName)).
+		WithArgs(fakeIdentityVerificationToken).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	consumed, err = provider.ConsumeIdentityVerificationToken(fakeIdentityVerificationToken)
+	assert.NoError(t, err)
+	assert.False(t, consumed)
 
 	mock.ExpectQuery(
 		fmt.Sprintf("SELECT EXISTS \\(SELECT \\* FROM %s WHERE token=\\?\\)", identityVerificationTokensTableName)).
@@ -398,3 +1036,156 @@ func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, valid)
 }
+
+func TestSQLProviderStartupCheck(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	ok, err := provider.StartupCheck()
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	mock.ExpectClose()
+	require.NoError(t, provider.db.Close())
+
+	ok, err = provider.StartupCheck()
+	assert.False(t, ok)
+	assert.EqualError(t, err, "sql: database is closed")
+}
+
+func TestSQLProviderPruneIdentityVerificationTokens(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT token FROM %s", identityVerificationTokensTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow("expired1").AddRow("valid").AddRow("expired2").AddRow("expired3"))
+
+	for _, batch := range [][]string{{"expired1", "expired2"}, {"expired3"}} {
+		mock.ExpectBegin()
+
+		for _, token := range batch {
+			mock.ExpectExec(
+				fmt.Sprintf("DELETE FROM %s WHERE token=\\?", identityVerificationTokensTableName)).
+				WithArgs(token).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		mock.ExpectCommit()
+	}
+
+	count, err := provider.PruneIdentityVerificationTokens(func(token string) bool {
+		return token != "valid"
+	}, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderPruneAuthenticationLogs(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	before := time.Unix(1000, 0)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE time<\\?", authenticationLogsTableName)).
+		WithArgs(before.Unix()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	count, err := provider.PruneAuthenticationLogs(before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderPruneLoginDevices(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	before := time.Unix(1000, 0)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE time<\\?", loginDevicesTableName)).
+		WithArgs(before.Unix()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	count, err := provider.PruneLoginDevices(before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderPruneIdentityVerificationTokensFailsOnRowsError(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT token FROM %s", identityVerificationTokensTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow("expired1").RowError(0, errors.New("connection lost")))
+
+	count, err := provider.PruneIdentityVerificationTokens(func(token string) bool {
+		return true
+	}, 2)
+
+	assert.EqualError(t, err, "connection lost")
+	assert.Equal(t, 0, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderDeleteFailedAuthenticationLogs(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND successful=\\?", authenticationLogsTableName)).
+		WithArgs(unitTestUser, false).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	count, err := provider.DeleteFailedAuthenticationLogs(unitTestUser)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderReencryptTOTPSecrets(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.cipher = newValueCipher("a_very_long_storage_encryption_key", []string{"an_old_very_long_storage_encryption_key"})
+
+	previous, err := newValueCipher("an_old_very_long_storage_encryption_key", nil).encrypt("PREVIOUS")
+	require.NoError(t, err)
+
+	current, err := provider.cipher.encrypt("CURRENT")
+	require.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, secret FROM %s WHERE username>\\? ORDER BY username ASC LIMIT \\?", totpSecretsTableName)).
+		WithArgs("", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "secret"}).AddRow("alice", "CLEAR").AddRow("bob", previous))
+
+	for _, username := range []string{"alice", "bob"} {
+		mock.ExpectExec(
+			fmt.Sprintf("REPLACE INTO %s \\(username, secret\\) VALUES \\(\\?, \\?\\)", totpSecretsTableName)).
+			WithArgs(username, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, secret FROM %s WHERE username>\\? ORDER BY username ASC LIMIT \\?", totpSecretsTableName)).
+		WithArgs("bob", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "secret"}).AddRow("carol", current))
+
+	count, err := provider.ReencryptTOTPSecrets(2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderShouldNotReencryptTOTPSecretsWithoutEncryptionKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	count, err := provider.ReencryptTOTPSecrets(2)
+
+	assert.Equal(t, ErrNoEncryptionKey, err)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldBuildSQLiteConnectionStringWithPragmas(t *testing.T) {
+	assert.Equal(t, "/config/db.sqlite3?_journal_mode=WAL&_busy_timeout=5000",
+		sqliteConnectionString(schema.LocalStorageConfiguration{Path: "/config/db.sqlite3", BusyTimeout: "5s"}))
+
+	assert.Equal(t, "/config/db.sqlite3?_journal_mode=WAL&_busy_timeout=0",
+		sqliteConnectionString(schema.LocalStorageConfiguration{Path: "/config/db.sqlite3"}))
+}
+
+func TestShouldApplySQLitePragmasOnEveryConnection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authelia-sqlite")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	provider := NewSQLiteProvider(schema.LocalStorageConfiguration{
+		Path:        filepath.Join(dir, "db.sqlite3"),
+		BusyTimeout: "3s",
+	})
+
+	defer provider.db.Close()
+
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		// Keep the previous connection open so that a new one is opened by the pool.
+		conn, err := provider.db.Conn(ctx)
+		require.NoError(t, err)
+
+		defer conn.Close()
+
+		var journalMode string
+
+		var busyTimeout int
+
+		require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode))
+		require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout))
+
+		assert.Equal(t, "wal", journalMode)
+		assert.Equal(t, 3000, busyTimeout)
+	}
+}
+
+func TestShouldConfigureConnectionPool(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	configureConnectionPool(db, schema.SQLStorageConfiguration{MaxOpenConns: 7, MaxIdleConns: 3, ConnMaxLifetime: "1h"})
+
+	assert.Equal(t, 7, db.Stats().MaxOpenConnections)
+}
+
+func TestShouldKeepDefaultConnectionPool(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	configureConnectionPool(db, schema.SQLStorageConfiguration{})
+
+	assert.Equal(t, 0, db.Stats().MaxOpenConnections)
+}
@@ -22,20 +22,52 @@ func NewSQLMockProvider() (*SQLMockProvider, sqlmock.Sqlmock) {
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=?", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("REPLACE INTO %s (username, second_factor_method) VALUES (?, ?)", userPreferencesTableName),
+			sqlGetPreferences:               fmt.Sprintf("SELECT username, second_factor_method FROM %s", userPreferencesTableName),
 
 			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES (?)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
+			sqlGetIdentityVerificationTokens:          fmt.Sprintf("SELECT token FROM %s", identityVerificationTokensTableName),
 
 			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=?", totpSecretsTableName),
 			sqlUpsertTOTPSecret:        fmt.Sprintf("REPLACE INTO %s (username, secret) VALUES (?, ?)", totpSecretsTableName),
 			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
+			sqlGetTOTPSecretsAfter:     fmt.Sprintf("SELECT username, secret FROM %s WHERE username>? ORDER BY username ASC LIMIT ?", totpSecretsTableName),
+			sqlGetTOTPSecrets:          fmt.Sprintf("SELECT username, secret FROM %s", totpSecretsTableName),
 
 			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=?", u2fDeviceHandlesTableName),
 			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("REPLACE INTO %s (username, keyHandle, publicKey) VALUES (?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlGetU2FDeviceHandles:          fmt.Sprintf("SELECT username, keyHandle, publicKey FROM %s", u2fDeviceHandlesTableName),
 
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlInsertAuthenticationLog:         fmt.Sprintf("INSERT INTO %s (username, successful, time, reservation) VALUES (?, ?, ?, ?)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:     fmt.Sprintf("SELECT successful, time, reservation FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlUpdateReservedAuthenticationLog: fmt.Sprintf("UPDATE %s SET successful=?, reservation=NULL WHERE username=? AND reservation=?", authenticationLogsTableName),
+			sqlDeleteReservedAuthenticationLog: fmt.Sprintf("DELETE FROM %s WHERE username=? AND reservation=?", authenticationLogsTableName),
+
+			sqlDeleteAuthenticationLogsBefore: fmt.Sprintf("DELETE FROM %s WHERE time<?", authenticationLogsTableName),
+			sqlDeleteFailedAuthenticationLogs: fmt.Sprintf("DELETE FROM %s WHERE username=? AND successful=?", authenticationLogsTableName),
+
+			sqlInsertPasswordHistory:    fmt.Sprintf("INSERT INTO %s (username, hash, time) VALUES (?, ?, ?)", passwordHistoryTableName),
+			sqlGetLatestPasswordHistory: fmt.Sprintf("SELECT hash, time FROM %s WHERE username=? ORDER BY time DESC LIMIT ?", passwordHistoryTableName),
+
+			sqlInsertLoginDevice:        fmt.Sprintf("INSERT INTO %s (username, fingerprint, ip, time) VALUES (?, ?, ?, ?)", loginDevicesTableName),
+			sqlUpdateLoginDeviceTime:    fmt.Sprintf("UPDATE %s SET time=? WHERE username=? AND fingerprint=? AND ip=?", loginDevicesTableName),
+			sqlGetLoginDevices:          fmt.Sprintf("SELECT fingerprint, ip, time FROM %s WHERE username=? ORDER BY time DESC LIMIT %d", loginDevicesTableName, loginDevicesLoadLimit),
+			sqlDeleteLoginDevicesBefore: fmt.Sprintf("DELETE FROM %s WHERE time<?", loginDevicesTableName),
+
+			sqlGetSecondFactorVersion:    fmt.Sprintf("SELECT version FROM %s WHERE username=?", secondFactorVersionsTableName),
+			sqlUpsertSecondFactorVersion: fmt.Sprintf("REPLACE INTO %s (username, version) VALUES (?, ?)", secondFactorVersionsTableName),
+
+			sqlGetVerifiedEmail:    fmt.Sprintf("SELECT email FROM %s WHERE username=?", verifiedEmailsTableName),
+			sqlUpsertVerifiedEmail: fmt.Sprintf("REPLACE INTO %s (username, email, time) VALUES (?, ?, ?)", verifiedEmailsTableName),
+
+			sqlGetPendingRegistration:          fmt.Sprintf("SELECT display_name, email, hash, verified, time FROM %s WHERE username=?", pendingRegistrationsTableName),
+			sqlGetVerifiedPendingRegistrations: fmt.Sprintf("SELECT username, display_name, email, hash, time FROM %s WHERE verified=? ORDER BY time ASC", pendingRegistrationsTableName),
+			sqlUpsertPendingRegistration:       fmt.Sprintf("REPLACE INTO %s (username, display_name, email, hash, verified, time) VALUES (?, ?, ?, ?, ?, ?)", pendingRegistrationsTableName),
+			sqlDeletePendingRegistration:       fmt.Sprintf("DELETE FROM %s WHERE username=?", pendingRegistrationsTableName),
+
+			sqlGetLastLogin:    fmt.Sprintf("SELECT ip, method, time FROM %s WHERE username=?", lastLoginsTableName),
+			sqlUpsertLastLogin: fmt.Sprintf("REPLACE INTO %s (username, ip, method, time) VALUES (?, ?, ?, ?)", lastLoginsTableName),
 
 			sqlGetExistingTables: "SELECT name FROM sqlite_master WHERE type='table'",
 
@@ -13,6 +13,12 @@ func (s SchemaVersion) ToString() string {
 	return strconv.Itoa(int(s))
 }
 
+// storedTOTPSecret is a TOTP secret as stored in the database, possibly encrypted.
+type storedTOTPSecret struct {
+	username string
+	secret   string
+}
+
 type transaction interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
@@ -74,3 +74,153 @@ func (p *SQLProvider) upgradeSchemaToVersion001(tx transaction, tables []string)
 
 	return nil
 }
+
+// upgradeSchemaToVersion002 upgrades the schema to version 2.
+func (p *SQLProvider) upgradeSchemaToVersion002(tx transaction, tables []string) error {
+	version := SchemaVersion(2)
+
+	err := p.upgradeCreateTableStatements(tx, p.sqlUpgradesCreateTableStatements[version], tables)
+	if err != nil {
+		return err
+	}
+
+	if p.name != "mysql" {
+		err = p.upgradeRunMultipleStatements(tx, p.sqlUpgradesCreateTableIndexesStatements[version])
+		if err != nil {
+			return fmt.Errorf("Unable to create index: %v", err)
+		}
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion003 upgrades the schema to version 3.
+func (p *SQLProvider) upgradeSchemaToVersion003(tx transaction, tables []string) error {
+	version := SchemaVersion(3)
+
+	err := p.upgradeCreateTableStatements(tx, p.sqlUpgradesCreateTableStatements[version], tables)
+	if err != nil {
+		return err
+	}
+
+	if p.name != "mysql" {
+		err = p.upgradeRunMultipleStatements(tx, p.sqlUpgradesCreateTableIndexesStatements[version])
+		if err != nil {
+			return fmt.Errorf("Unable to create index: %v", err)
+		}
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion004 upgrades the schema to version 4.
+func (p *SQLProvider) upgradeSchemaToVersion004(tx transaction, tables []string) error {
+	version := SchemaVersion(4)
+
+	err := p.upgradeCreateTableStatements(tx, p.sqlUpgradesCreateTableStatements[version], tables)
+	if err != nil {
+		return err
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion005 upgrades the schema to version 5.
+func (p *SQLProvider) upgradeSchemaToVersion005(tx transaction, tables []string) error {
+	version := SchemaVersion(5)
+
+	err := p.upgradeCreateTableStatements(tx, p.sqlUpgradesCreateTableStatements[version], tables)
+	if err != nil {
+		return err
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion006 upgrades the schema to version 6.
+func (p *SQLProvider) upgradeSchemaToVersion006(tx transaction) error {
+	version := SchemaVersion(6)
+
+	err := p.upgradeRunMultipleStatements(tx, sqlUpgradesAlterTableStatements[version])
+	if err != nil {
+		return err
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion007 upgrades the schema to version 7.
+func (p *SQLProvider) upgradeSchemaToVersion007(tx transaction, tables []string) error {
+	version := SchemaVersion(7)
+
+	err := p.upgradeCreateTableStatements(tx, p.sqlUpgradesCreateTableStatements[version], tables)
+	if err != nil {
+		return err
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion008 upgrades the schema to version 8.
+func (p *SQLProvider) upgradeSchemaToVersion008(tx transaction, tables []string) error {
+	version := SchemaVersion(8)
+
+	err := p.upgradeCreateTableStatements(tx, p.sqlUpgradesCreateTableStatements[version], tables)
+	if err != nil {
+		return err
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// upgradeSchemaToVersion009 upgrades the schema to version 9, widening the TOTP secret column for the encrypted secrets.
+func (p *SQLProvider) upgradeSchemaToVersion009(tx transaction) error {
+	version := SchemaVersion(9)
+
+	err := p.upgradeRunMultipleStatements(tx, p.sqlUpgradesAlterColumnStatements[version])
+	if err != nil {
+		return err
+	}
+
+	err = p.upgradeFinalize(tx, version)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
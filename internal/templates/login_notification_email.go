@@ -0,0 +1,32 @@
+package templates
+
+import (
+	"text/template"
+)
+
+// LoginNotificationEmailTemplate the template of email that the user will receive when they log in from an
+// unrecognised device or IP address.
+var LoginNotificationEmailTemplate *template.Template
+
+func init() {
+	t, err := template.New("login_notification_email_template").Parse(emailLoginNotificationContent)
+	if err != nil {
+		panic(err)
+	}
+
+	LoginNotificationEmailTemplate = t
+}
+
+const emailLoginNotificationContent = `
+A login to your account from a device or location we do not recognise has been detected.
+
+Time: {{.time}}
+IP address: {{.ip}}
+{{- if .location}}
+Approximate location: {{.location}}
+{{- end}}
+Device: {{.userAgent}}
+
+If this was you, you can safely ignore this email.
+If you did not log in, your credentials might have been compromised. You should reset your password and contact an administrator.
+`
@@ -0,0 +1,26 @@
+package templates
+
+import (
+	"text/template"
+)
+
+// RegistrationApprovedEmailTemplate the template of email that the user will receive when an administrator approved
+// the account they registered.
+var RegistrationApprovedEmailTemplate *template.Template
+
+func init() {
+	t, err := template.New("registration_approved_email_template").Parse(emailRegistrationApprovedContent)
+	if err != nil {
+		panic(err)
+	}
+
+	RegistrationApprovedEmailTemplate = t
+}
+
+const emailRegistrationApprovedContent = `
+Your account {{.username}} has been approved by an administrator, you can now log in.
+{{- if .url}}
+
+{{.url}}
+{{- end}}
+`
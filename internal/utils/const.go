@@ -49,3 +49,22 @@ const TLS11 = "1.1"
 
 // TLS10 is the textual representation of TLS 1.0.
 const TLS10 = "1.0"
+
+// ProxySchemeSOCKS5 is the URL scheme of SOCKS5 proxies.
+const ProxySchemeSOCKS5 = "socks5"
+
+// ProxySchemeHTTP is the URL scheme of HTTP proxies supporting the CONNECT method.
+const ProxySchemeHTTP = "http"
+
+// ProxyDefaultTimeout is the default time allowed to connect to a proxy and to complete the handshake with it.
+const ProxyDefaultTimeout = 10 * time.Second
+
+const (
+	socks5Version        = 0x05
+	socks5AuthNone       = 0x00
+	socks5AuthPassword   = 0x02
+	socks5CommandConnect = 0x01
+	socks5AddressIPv4    = 0x01
+	socks5AddressDomain  = 0x03
+	socks5AddressIPv6    = 0x04
+)
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Dialer is the interface of types able to establish a connection to an address.
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// NewProxyDialer creates a Dialer establishing connections through the proxy described by the given URL. Supported
+// schemes are socks5 and http (CONNECT method). Credentials are taken from the user info of the URL if present. The
+// timeout bounds both the connection to the proxy and the handshake with it, ProxyDefaultTimeout is used if it is 0.
+func NewProxyDialer(proxyURL *url.URL, forward Dialer, timeout time.Duration) (Dialer, error) {
+	if timeout <= 0 {
+		timeout = ProxyDefaultTimeout
+	}
+
+	if forward == nil {
+		forward = &net.Dialer{Timeout: timeout}
+	}
+
+	dialer := proxyDialer{
+		address: proxyURL.Host,
+		forward: forward,
+		timeout: timeout,
+	}
+
+	if proxyURL.User != nil {
+		dialer.username = proxyURL.User.Username()
+		dialer.password, _ = proxyURL.User.Password()
+	}
+
+	switch proxyURL.Scheme {
+	case ProxySchemeSOCKS5:
+		return &socks5Dialer{dialer}, nil
+	case ProxySchemeHTTP:
+		return &httpConnectDialer{dialer}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s, should be one of %s or %s", proxyURL.Scheme, ProxySchemeSOCKS5, ProxySchemeHTTP)
+	}
+}
+
+type proxyDialer struct {
+	address  string
+	username string
+	password string
+	forward  Dialer
+	timeout  time.Duration
+}
+
+// dial connects to the proxy and runs the handshake on the connection before the deadline given by the timeout. The
+// deadline is cleared once the handshake succeeds.
+func (d *proxyDialer) dial(network string, handshake func(conn net.Conn) error) (net.Conn, error) {
+	conn, err := d.forward.Dial(network, d.address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.SetDeadline(time.Now().Add(d.timeout)); err == nil {
+		if err = handshake(conn); err == nil {
+			err = conn.SetDeadline(time.Time{})
+		}
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+type socks5Dialer struct {
+	proxyDialer
+}
+
+// Dial connects to the address through the SOCKS5 proxy.
+func (d *socks5Dialer) Dial(network, address string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %s", portStr)
+	}
+
+	if len(host) > 255 {
+		return nil, fmt.Errorf("host name %s is too long", host)
+	}
+
+	return d.dial(network, func(conn net.Conn) error {
+		if err := d.handshake(conn, host, port); err != nil {
+			return fmt.Errorf("socks5 proxy %s: %w", d.address, err)
+		}
+
+		return nil
+	})
+}
+
+func (d *socks5Dialer) handshake(conn net.Conn, host string, port int) error {
+	methods := []byte{socks5AuthNone}
+	if d.username != "" {
+		methods = append(methods, socks5AuthPassword)
+	}
+
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+
+	if buf[0] != socks5Version {
+		return fmt.Errorf("unexpected protocol version %d", buf[0])
+	}
+
+	switch buf[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if err := d.authenticate(conn); err != nil {
+			return err
+		}
+	default:
+		return errors.New("no acceptable authentication method")
+	}
+
+	request := []byte{socks5Version, socks5CommandConnect, 0x00}
+
+	if ip := net.ParseIP(host); ip == nil {
+		request = append(request, socks5AddressDomain, byte(len(host)))
+		request = append(request, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(request, socks5AddressIPv4)
+		request = append(request, ip4...)
+	} else {
+		request = append(request, socks5AddressIPv6)
+		request = append(request, ip.To16()...)
+	}
+
+	request = append(request, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	buf = make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+
+	if buf[1] != 0x00 {
+		return fmt.Errorf("connection refused by proxy with code %d", buf[1])
+	}
+
+	var length int
+
+	switch buf[3] {
+	case socks5AddressIPv4:
+		length = net.IPv4len
+	case socks5AddressIPv6:
+		length = net.IPv6len
+	case socks5AddressDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+
+		length = int(l[0])
+	default:
+		return fmt.Errorf("unexpected address type %d", buf[3])
+	}
+
+	// Discard the bound address and port.
+	_, err := io.ReadFull(conn, make([]byte, length+2))
+
+	return err
+}
+
+func (d *socks5Dialer) authenticate(conn net.Conn) error {
+	if len(d.username) > 255 || len(d.password) > 255 {
+		return errors.New("username or password is too long")
+	}
+
+	request := []byte{0x01, byte(len(d.username))}
+	request = append(request, d.username...)
+	request = append(request, byte(len(d.password)))
+	request = append(request, d.password...)
+
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+
+	if buf[1] != 0x00 {
+		return errors.New("authentication failed")
+	}
+
+	return nil
+}
+
+type httpConnectDialer struct {
+	proxyDialer
+}
+
+// Dial connects to the address through the HTTP proxy using the CONNECT method.
+func (d *httpConnectDialer) Dial(network, address string) (net.Conn, error) {
+	return d.dial(network, func(conn net.Conn) error {
+		return d.handshake(conn, address)
+	})
+}
+
+func (d *httpConnectDialer) handshake(conn net.Conn, address string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if d.username != "" {
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(d.username+":"+d.password)))
+	}
+
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("http proxy %s: %w", d.address, err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return fmt.Errorf("http proxy %s: %w", d.address, err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http proxy %s: connection refused with status %s", d.address, resp.Status)
+	}
+
+	if reader.Buffered() != 0 {
+		return fmt.Errorf("http proxy %s: unexpected data received before the connection was established", d.address)
+	}
+
+	return nil
+}
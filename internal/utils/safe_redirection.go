@@ -17,3 +17,28 @@ func IsRedirectionSafe(url url.URL, protectedDomain string) bool {
 
 	return true
 }
+
+// IsRedirectionAllowed determines if the host of a redirection URL matches one of the allowed domains. An allowed
+// domain is either an exact domain or a wildcard of the form *.example.com matching any subdomain of example.com.
+// An empty list of allowed domains allows any host.
+func IsRedirectionAllowed(url url.URL, allowedDomains []string) bool {
+	if len(allowedDomains) == 0 {
+		return true
+	}
+
+	hostname := strings.ToLower(url.Hostname())
+
+	for _, domain := range allowedDomains {
+		domain = strings.ToLower(domain)
+
+		if strings.HasPrefix(domain, "*.") {
+			if strings.HasSuffix(hostname, domain[1:]) {
+				return true
+			}
+		} else if hostname == domain {
+			return true
+		}
+	}
+
+	return false
+}
@@ -18,6 +18,29 @@ func TestShouldReturnFalseOnBadScheme(t *testing.T) {
 	assert.True(t, isURLSafe("https://secure.example.com", "example.com"))
 }
 
+func isURLAllowed(requestURI string, allowedDomains []string) bool {
+	url, _ := url.ParseRequestURI(requestURI)
+	return IsRedirectionAllowed(*url, allowedDomains)
+}
+
+func TestShouldAllowAnyDomainWhenAllowlistEmpty(t *testing.T) {
+	assert.True(t, isURLAllowed("https://secure.example.com", nil))
+	assert.True(t, isURLAllowed("https://secure.example.com", []string{}))
+}
+
+func TestShouldOnlyAllowDomainsInAllowlist(t *testing.T) {
+	allowedDomains := []string{"home.example.com", "*.apps.example.com"}
+
+	assert.True(t, isURLAllowed("https://home.example.com/path", allowedDomains))
+	assert.True(t, isURLAllowed("https://HOME.example.com", allowedDomains))
+	assert.True(t, isURLAllowed("https://grafana.apps.example.com", allowedDomains))
+	assert.True(t, isURLAllowed("https://a.b.apps.example.com:8080", allowedDomains))
+	assert.False(t, isURLAllowed("https://apps.example.com", allowedDomains))
+	assert.False(t, isURLAllowed("https://secure.example.com", allowedDomains))
+	assert.False(t, isURLAllowed("https://home.example.com.evil.com", allowedDomains))
+	assert.False(t, isURLAllowed("https://evilapps.example.com", allowedDomains))
+}
+
 func TestShouldReturnFalseOnBadDomain(t *testing.T) {
 	assert.False(t, isURLSafe("https://secure.example.com.c", "example.com"))
 	assert.False(t, isURLSafe("https://secure.example.comc", "example.com"))
@@ -13,13 +13,17 @@ import {
     ResetPasswordStep1Route,
     RegisterSecurityKeyRoute,
     RegisterOneTimePasswordRoute,
+    EmailVerificationRoute,
+    RegistrationVerificationRoute,
     LogoutRoute,
 } from "./Routes";
 import * as themes from "./themes";
 import { getBasePath } from "./utils/BasePath";
-import { getRememberMe, getResetPassword, getTheme } from "./utils/Configuration";
+import { getOIDCUpstream, getRememberMe, getResetPassword, getTheme } from "./utils/Configuration";
 import RegisterOneTimePassword from "./views/DeviceRegistration/RegisterOneTimePassword";
 import RegisterSecurityKey from "./views/DeviceRegistration/RegisterSecurityKey";
+import EmailVerification from "./views/EmailVerification/EmailVerification";
+import RegistrationVerification from "./views/RegistrationVerification/RegistrationVerification";
 import LoginPortal from "./views/LoginPortal/LoginPortal";
 import SignOut from "./views/LoginPortal/SignOut/SignOut";
 import ResetPasswordStep1 from "./views/ResetPassword/ResetPasswordStep1";
@@ -62,11 +66,21 @@ const App: React.FC = () => {
                         <Route path={RegisterOneTimePasswordRoute} exact>
                             <RegisterOneTimePassword />
                         </Route>
+                        <Route path={EmailVerificationRoute} exact>
+                            <EmailVerification />
+                        </Route>
+                        <Route path={RegistrationVerificationRoute} exact>
+                            <RegistrationVerification />
+                        </Route>
                         <Route path={LogoutRoute} exact>
                             <SignOut />
                         </Route>
                         <Route path={FirstFactorRoute}>
-                            <LoginPortal rememberMe={getRememberMe()} resetPassword={getResetPassword()} />
+                            <LoginPortal
+                                rememberMe={getRememberMe()}
+                                resetPassword={getResetPassword()}
+                                oidcUpstream={getOIDCUpstream()}
+                            />
                         </Route>
                         <Route path="/">
                             <Redirect to={FirstFactorRoute} />
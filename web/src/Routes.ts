@@ -10,4 +10,6 @@ export const ResetPasswordStep1Route = "/reset-password/step1";
 export const ResetPasswordStep2Route = "/reset-password/step2";
 export const RegisterSecurityKeyRoute = "/security-key/register";
 export const RegisterOneTimePasswordRoute = "/one-time-password/register";
+export const EmailVerificationRoute = "/email/verify";
+export const RegistrationVerificationRoute = "/registration/verify";
 export const LogoutRoute = "/logout";
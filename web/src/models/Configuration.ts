@@ -2,6 +2,7 @@ import { SecondFactorMethod } from "./Methods";
 
 export interface Configuration {
     available_methods: Set<SecondFactorMethod>;
+    default_method?: SecondFactorMethod;
     second_factor_enabled: boolean;
     totp_period: number;
 }
@@ -1,8 +1,15 @@
 import { SecondFactorMethod } from "./Methods";
 
+export interface LastLogin {
+    time: string;
+    ip: string;
+    method: string;
+}
+
 export interface UserInfo {
     display_name: string;
     method: SecondFactorMethod;
     has_u2f: boolean;
     has_totp: boolean;
+    last_login?: LastLogin;
 }
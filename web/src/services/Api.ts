@@ -23,6 +23,12 @@ export const CompleteResetPasswordPath = basePath + "/api/reset-password/identit
 // Do the password reset during completion.
 export const ResetPasswordPath = basePath + "/api/reset-password";
 
+export const CompleteEmailVerificationPath = basePath + "/api/email/verify/identity/finish";
+
+export const CompleteRegistrationPath = basePath + "/api/registration/identity/finish";
+
+export const OIDCUpstreamAuthorizePath = basePath + "/api/oidc-upstream/authorize";
+
 export const LogoutPath = basePath + "/api/logout";
 export const StatePath = basePath + "/api/state";
 export const UserInfoPath = basePath + "/api/user/info";
@@ -5,11 +5,16 @@ import { toEnum, Method2FA } from "./UserPreferences";
 
 interface ConfigurationPayload {
     available_methods: Method2FA[];
+    default_method?: Method2FA;
     second_factor_enabled: boolean;
     totp_period: number;
 }
 
 export async function getConfiguration(): Promise<Configuration> {
     const config = await Get<ConfigurationPayload>(ConfigurationPath);
-    return { ...config, available_methods: new Set(config.available_methods.map(toEnum)) };
+    return {
+        ...config,
+        available_methods: new Set(config.available_methods.map(toEnum)),
+        default_method: config.default_method ? toEnum(config.default_method) : undefined,
+    };
 }
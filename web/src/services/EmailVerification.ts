@@ -0,0 +1,6 @@
+import { CompleteEmailVerificationPath } from "./Api";
+import { PostWithOptionalResponse } from "./Client";
+
+export async function completeEmailVerificationProcess(token: string) {
+    return PostWithOptionalResponse(CompleteEmailVerificationPath, { token });
+}
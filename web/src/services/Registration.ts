@@ -0,0 +1,10 @@
+import { CompleteRegistrationPath } from "./Api";
+import { Post } from "./Client";
+
+interface CompleteRegistrationBody {
+    approvalRequired: boolean;
+}
+
+export async function completeRegistrationProcess(token: string) {
+    return Post<CompleteRegistrationBody>(CompleteRegistrationPath, { token });
+}
@@ -1,6 +1,18 @@
 import { LogoutPath } from "./Api";
 import { PostWithOptionalResponse } from "./Client";
 
-export async function signOut() {
-    return PostWithOptionalResponse(LogoutPath);
+interface SignOutBody {
+    targetURL?: string;
+}
+
+interface SignOutResponse {
+    redirect: string;
+}
+
+export async function signOut(targetURL: string | undefined) {
+    const body: SignOutBody = {};
+    if (targetURL) {
+        body.targetURL = targetURL;
+    }
+    return PostWithOptionalResponse<SignOutResponse>(LogoutPath, body);
 }
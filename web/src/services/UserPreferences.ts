@@ -1,5 +1,5 @@
 import { SecondFactorMethod } from "../models/Methods";
-import { UserInfo } from "../models/UserInfo";
+import { LastLogin, UserInfo } from "../models/UserInfo";
 import { UserInfoPath, UserInfo2FAMethodPath } from "./Api";
 import { Get, PostWithOptionalResponse } from "./Client";
 
@@ -10,6 +10,7 @@ export interface UserInfoPayload {
     method: Method2FA;
     has_u2f: boolean;
     has_totp: boolean;
+    last_login?: LastLogin;
 }
 
 export interface MethodPreferencePayload {
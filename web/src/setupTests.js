@@ -3,5 +3,6 @@ import Adapter from "enzyme-adapter-react-16";
 document.body.setAttribute("data-basepath", "");
 document.body.setAttribute("data-rememberme", "true");
 document.body.setAttribute("data-resetpassword", "true");
+document.body.setAttribute("data-oidcupstream", "");
 document.body.setAttribute("data-theme", "light");
 configure({ adapter: new Adapter() });
@@ -15,6 +15,10 @@ export function getResetPassword() {
     return getEmbeddedVariable("resetpassword") === "true";
 }
 
+export function getOIDCUpstream() {
+    return getEmbeddedVariable("oidcupstream");
+}
+
 export function getTheme() {
     return getEmbeddedVariable("theme");
 }
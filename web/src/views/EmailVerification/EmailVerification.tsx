@@ -0,0 +1,68 @@
+import React, { useCallback, useEffect, useState } from "react";
+
+import { Button, makeStyles, Typography } from "@material-ui/core";
+import { useHistory, useLocation } from "react-router";
+
+import { useNotifications } from "../../hooks/NotificationsContext";
+import LoginLayout from "../../layouts/LoginLayout";
+import { FirstFactorRoute } from "../../Routes";
+import { completeEmailVerificationProcess } from "../../services/EmailVerification";
+import { extractIdentityToken } from "../../utils/IdentityToken";
+
+const EmailVerification = function () {
+    const style = useStyles();
+    const history = useHistory();
+    const location = useLocation();
+    const [verified, setVerified] = useState(false);
+    const { createSuccessNotification, createErrorNotification } = useNotifications();
+
+    const processToken = extractIdentityToken(location.search);
+
+    const completeProcess = useCallback(async () => {
+        if (!processToken) {
+            createErrorNotification("No verification token provided");
+            return;
+        }
+
+        try {
+            await completeEmailVerificationProcess(processToken);
+            setVerified(true);
+            createSuccessNotification("Your email address has been verified.");
+        } catch (err) {
+            console.error(err);
+            createErrorNotification(
+                "There was an issue verifying your email address. The verification token might have expired.",
+            );
+        }
+    }, [processToken, createSuccessNotification, createErrorNotification]);
+
+    useEffect(() => {
+        completeProcess();
+    }, [completeProcess]);
+
+    const handleLoginClick = () => {
+        history.push(FirstFactorRoute);
+    };
+
+    return (
+        <LoginLayout title="Verify Email">
+            <Typography className={style.instruction}>
+                {verified
+                    ? "Your email address has been verified, you can now log in."
+                    : "Log in again to receive a new verification link if this one has expired."}
+            </Typography>
+            <Button id="login-button" variant="contained" color="primary" onClick={handleLoginClick}>
+                Log in
+            </Button>
+        </LoginLayout>
+    );
+};
+
+export default EmailVerification;
+
+const useStyles = makeStyles((theme) => ({
+    instruction: {
+        paddingTop: theme.spacing(4),
+        paddingBottom: theme.spacing(4),
+    },
+}));
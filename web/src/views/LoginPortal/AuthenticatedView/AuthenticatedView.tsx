@@ -1,14 +1,16 @@
 import React from "react";
 
-import { Grid, makeStyles, Button } from "@material-ui/core";
+import { Grid, makeStyles, Button, Typography } from "@material-ui/core";
 import { useHistory } from "react-router";
 
 import LoginLayout from "../../../layouts/LoginLayout";
+import { LastLogin } from "../../../models/UserInfo";
 import { LogoutRoute as SignOutRoute } from "../../../Routes";
 import Authenticated from "../Authenticated";
 
 export interface Props {
     name: string;
+    lastLogin?: LastLogin;
 }
 
 const AuthenticatedView = function (props: Props) {
@@ -30,6 +32,13 @@ const AuthenticatedView = function (props: Props) {
                 <Grid item xs={12} className={style.mainContainer}>
                     <Authenticated />
                 </Grid>
+                {props.lastLogin ? (
+                    <Grid item xs={12}>
+                        <Typography variant="caption" id="last-login">
+                            Last login on {new Date(props.lastLogin.time).toLocaleString()} from {props.lastLogin.ip}
+                        </Typography>
+                    </Grid>
+                ) : null}
             </Grid>
         </LoginLayout>
     );
@@ -10,12 +10,14 @@ import { useRedirectionURL } from "../../../hooks/RedirectionURL";
 import { useRequestMethod } from "../../../hooks/RequestMethod";
 import LoginLayout from "../../../layouts/LoginLayout";
 import { ResetPasswordStep1Route } from "../../../Routes";
+import { OIDCUpstreamAuthorizePath } from "../../../services/Api";
 import { postFirstFactor } from "../../../services/FirstFactor";
 
 export interface Props {
     disabled: boolean;
     rememberMe: boolean;
     resetPassword: boolean;
+    oidcUpstream: string;
 
     onAuthenticationStart: () => void;
     onAuthenticationFailure: () => void;
@@ -66,7 +68,13 @@ const FirstFactorForm = function (props: Props) {
             props.onAuthenticationSuccess(res ? res.redirect : undefined);
         } catch (err) {
             console.error(err);
-            createErrorNotification("Incorrect username or password.");
+            if (err.message.includes("Please verify your email address")) {
+                createErrorNotification(
+                    "Please verify your email address using the link which has been sent to you, then log in again.",
+                );
+            } else {
+                createErrorNotification("Incorrect username or password.");
+            }
             props.onAuthenticationFailure();
             setPassword("");
             passwordRef.current.focus();
@@ -77,6 +85,17 @@ const FirstFactorForm = function (props: Props) {
         history.push(ResetPasswordStep1Route);
     };
 
+    const handleOIDCUpstreamClick = () => {
+        const query = new URLSearchParams();
+        if (redirectionURL) {
+            query.set("rd", redirectionURL);
+        }
+        if (requestMethod) {
+            query.set("rm", requestMethod);
+        }
+        window.location.href = `${OIDCUpstreamAuthorizePath}?${query.toString()}`;
+    };
+
     return (
         <LoginLayout id="first-factor-stage" title="Sign in" showBrand>
             <Grid container spacing={2} className={style.root}>
@@ -197,6 +216,20 @@ const FirstFactorForm = function (props: Props) {
                         Sign in
                     </Button>
                 </Grid>
+                {props.oidcUpstream !== "" ? (
+                    <Grid item xs={12}>
+                        <Button
+                            id="oidc-upstream-button"
+                            variant="outlined"
+                            color="primary"
+                            fullWidth
+                            disabled={disabled}
+                            onClick={handleOIDCUpstreamClick}
+                        >
+                            Sign in with {props.oidcUpstream}
+                        </Button>
+                    </Grid>
+                ) : null}
             </Grid>
         </LoginLayout>
     );
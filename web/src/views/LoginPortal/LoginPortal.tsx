@@ -26,6 +26,7 @@ import SecondFactorForm from "./SecondFactor/SecondFactorForm";
 export interface Props {
     rememberMe: boolean;
     resetPassword: boolean;
+    oidcUpstream: string;
 }
 
 const LoginPortal = function (props: Props) {
@@ -132,6 +133,7 @@ const LoginPortal = function (props: Props) {
                         disabled={firstFactorDisabled}
                         rememberMe={props.rememberMe}
                         resetPassword={props.resetPassword}
+                        oidcUpstream={props.oidcUpstream}
                         onAuthenticationStart={() => setFirstFactorDisabled(true)}
                         onAuthenticationFailure={() => setFirstFactorDisabled(false)}
                         onAuthenticationSuccess={handleAuthSuccess}
@@ -150,7 +152,7 @@ const LoginPortal = function (props: Props) {
                 ) : null}
             </Route>
             <Route path={AuthenticatedRoute} exact>
-                {userInfo ? <AuthenticatedView name={userInfo.display_name} /> : null}
+                {userInfo ? <AuthenticatedView name={userInfo.display_name} lastLogin={userInfo.last_login} /> : null}
             </Route>
             <Route path="/">
                 <Redirect to={FirstFactorRoute} />
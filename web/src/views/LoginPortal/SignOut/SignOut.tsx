@@ -18,11 +18,14 @@ const SignOut = function (props: Props) {
     const { createErrorNotification } = useNotifications();
     const redirectionURL = useRedirectionURL();
     const [timedOut, setTimedOut] = useState(false);
+    const [safeRedirect, setSafeRedirect] = useState<string | undefined>(undefined);
 
     const doSignOut = useCallback(async () => {
         try {
-            // TODO(c.michaud): pass redirection URL to backend for validation.
-            await signOut();
+            const res = await signOut(redirectionURL);
+            if (res && res.redirect) {
+                setSafeRedirect(res.redirect);
+            }
             setTimeout(() => {
                 if (!mounted) {
                     return;
@@ -33,15 +36,15 @@ const SignOut = function (props: Props) {
             console.error(err);
             createErrorNotification("There was an issue signing out");
         }
-    }, [createErrorNotification, setTimedOut, mounted]);
+    }, [createErrorNotification, setTimedOut, setSafeRedirect, mounted, redirectionURL]);
 
     useEffect(() => {
         doSignOut();
     }, [doSignOut]);
 
     if (timedOut) {
-        if (redirectionURL) {
-            window.location.href = redirectionURL;
+        if (safeRedirect) {
+            window.location.href = safeRedirect;
         } else {
             return <Redirect to={FirstFactorRoute} />;
         }
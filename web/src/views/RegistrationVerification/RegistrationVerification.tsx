@@ -0,0 +1,71 @@
+import React, { useCallback, useEffect, useState } from "react";
+
+import { Button, makeStyles, Typography } from "@material-ui/core";
+import { useHistory, useLocation } from "react-router";
+
+import { useNotifications } from "../../hooks/NotificationsContext";
+import LoginLayout from "../../layouts/LoginLayout";
+import { FirstFactorRoute } from "../../Routes";
+import { completeRegistrationProcess } from "../../services/Registration";
+import { extractIdentityToken } from "../../utils/IdentityToken";
+
+const RegistrationVerification = function () {
+    const style = useStyles();
+    const history = useHistory();
+    const location = useLocation();
+    const [instruction, setInstruction] = useState("Confirming your registration...");
+    const { createSuccessNotification, createErrorNotification } = useNotifications();
+
+    const processToken = extractIdentityToken(location.search);
+
+    const completeProcess = useCallback(async () => {
+        if (!processToken) {
+            createErrorNotification("No verification token provided");
+            return;
+        }
+
+        try {
+            const res = await completeRegistrationProcess(processToken);
+            if (res.approvalRequired) {
+                setInstruction(
+                    "Your email address has been verified. You will be notified once an administrator approved your account.",
+                );
+            } else {
+                setInstruction("Your account has been created, you can now log in.");
+            }
+            createSuccessNotification("Your registration has been confirmed.");
+        } catch (err) {
+            console.error(err);
+            setInstruction("Register again to receive a new confirmation link if this one has expired.");
+            createErrorNotification(
+                "There was an issue confirming your registration. The confirmation token might have expired.",
+            );
+        }
+    }, [processToken, createSuccessNotification, createErrorNotification]);
+
+    useEffect(() => {
+        completeProcess();
+    }, [completeProcess]);
+
+    const handleLoginClick = () => {
+        history.push(FirstFactorRoute);
+    };
+
+    return (
+        <LoginLayout title="Confirm Registration">
+            <Typography className={style.instruction}>{instruction}</Typography>
+            <Button id="login-button" variant="contained" color="primary" onClick={handleLoginClick}>
+                Log in
+            </Button>
+        </LoginLayout>
+    );
+};
+
+export default RegistrationVerification;
+
+const useStyles = makeStyles((theme) => ({
+    instruction: {
+        paddingTop: theme.spacing(4),
+        paddingBottom: theme.spacing(4),
+    },
+}));
@@ -75,6 +75,10 @@ const ResetPasswordStep2 = function () {
             console.error(err);
             if (err.message.includes("0000052D.")) {
                 createErrorNotification("Your supplied password does not meet the password policy requirements.");
+            } else if (err.message.includes("Your new password was used recently")) {
+                createErrorNotification("Your new password was used recently, please choose another one.");
+            } else if (err.message.includes("Your password was changed too recently")) {
+                createErrorNotification("Your password was changed too recently, please retry later.");
             } else {
                 createErrorNotification("There was an issue resetting the password.");
             }